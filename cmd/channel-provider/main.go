@@ -0,0 +1,168 @@
+// channel-provider 本地联调用的 webhook 渠道服务商。serve 接收并打印客服回复，-fail 模拟服务商故障；
+// send 以渠道用户身份签名后向服务推送一条客户消息，-eml 改为上传一封原始邮件(邮件渠道):
+//
+//	go run ./cmd/channel-provider serve [-addr :9091] [-secret ...] [-fail]
+//	go run ./cmd/channel-provider send -url http://localhost:8080/api/channels/sms/inbound -from +15550100 [-name Ann] [-id msg-1] [-secret ...] text
+//	go run ./cmd/channel-provider send -url http://localhost:8080/api/channels/email/inbound -eml message.eml [-secret ...]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/infrastructure/channel"
+)
+
+// envChannelSecret 未通过 -secret 指定时读取的环境变量
+const envChannelSecret = "CLAND_CHANNEL_SECRET"
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: channel-provider serve|send [flags]")
+	}
+	switch args[0] {
+	case "serve":
+		return serveChannelProvider(args[1:])
+	case "send":
+		return sendChannelMessage(args[1:])
+	default:
+		return fmt.Errorf("unknown channel-provider command %q", args[0])
+	}
+}
+
+func channelSecret(fs *flag.FlagSet, secret string) (string, error) {
+	if secret == "" {
+		secret = os.Getenv(envChannelSecret)
+	}
+	if secret == "" {
+		fs.Usage()
+		return "", errors.New("secret is required")
+	}
+	return secret, nil
+}
+
+func serveChannelProvider(args []string) error {
+	fs := flag.NewFlagSet("channel-provider serve", flag.ContinueOnError)
+	addr := fs.String("addr", ":9091", "listen address")
+	secret := fs.String("secret", "", "channel secret (default $"+envChannelSecret+")")
+	fail := fs.Bool("fail", false, "respond 503 to every reply")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	key, err := channelSecret(fs, *secret)
+	if err != nil {
+		return err
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		delivery := r.Header.Get(utils.WebhookDeliveryHeader)
+		if err := utils.VerifyWebhookSignature(key, r.Header.Get(utils.WebhookSignatureHeader), body, 5*time.Minute); err != nil {
+			log.Printf("rejected reply %s: %v", delivery, err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if *fail {
+			log.Printf("failing reply %s", delivery)
+			http.Error(w, "simulated outage", http.StatusServiceUnavailable)
+			return
+		}
+		var msg channel.WebhookMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content := msg.Text
+		if msg.URL != "" {
+			content = "[" + msg.Type + "] " + msg.URL
+		}
+		log.Printf("reply %s to %s: %s", msg.ID, msg.To, content)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("channel provider listening on %s", *addr)
+	return http.ListenAndServe(*addr, handler)
+}
+
+func sendChannelMessage(args []string) error {
+	fs := flag.NewFlagSet("channel-provider send", flag.ContinueOnError)
+	url := fs.String("url", "http://localhost:8080/api/channels/sms/inbound", "inbound endpoint of the channel")
+	from := fs.String("from", "", "sender id on the provider, e.g. a phone number")
+	name := fs.String("name", "", "sender display name")
+	id := fs.String("id", "", "provider message id (default a new one); resend an id to try deduplication")
+	eml := fs.String("eml", "", "upload this raw email file instead of a JSON message")
+	secret := fs.String("secret", "", "channel secret (default $"+envChannelSecret+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	key, err := channelSecret(fs, *secret)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	contentType := "application/json"
+	if *eml != "" {
+		if body, err = os.ReadFile(*eml); err != nil {
+			return err
+		}
+		contentType = "message/rfc822"
+		*id = *eml
+	} else {
+		if *from == "" || fs.NArg() == 0 {
+			fs.Usage()
+			return errors.New("-from and the message text are required")
+		}
+		if *id == "" {
+			*id = utils.GenerateMessageID()
+		}
+		body, err = json.Marshal(channel.WebhookInbound{Messages: []channel.WebhookMessage{{
+			ID:        *id,
+			From:      &channel.WebhookSender{ID: *from, Name: *name},
+			Type:      channel.WebhookMessageText,
+			Text:      fs.Arg(0),
+			Timestamp: time.Now().UnixMilli(),
+		}}})
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, *url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(utils.WebhookSignatureHeader, utils.SignWebhook(key, time.Now(), body))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	log.Printf("sent %s: %s %s", *id, resp.Status, respBody)
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// mail-sink 本地联调用的 SMTP 服务端，接收邮件后打印信封与正文，-dir 时另存为 .eml 文件；
+// -fail 拒收每封邮件以观察发件箱重试:
+//
+//	go run ./cmd/mail-sink [-addr :2525] [-dir mail] [-fail]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("mail-sink", flag.ContinueOnError)
+	addr := fs.String("addr", ":2525", "listen address")
+	dir := fs.String("dir", "", "save received mail to this directory")
+	fail := fs.Bool("fail", false, "reject every message with 451")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0o750); err != nil {
+			return err
+		}
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("mail sink listening on %s", *addr)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go serveMailSink(conn, *dir, *fail)
+	}
+}
+
+// serveMailSink 处理一个 SMTP 连接，只实现投递所需的最小命令集，不支持 STARTTLS 与认证
+func serveMailSink(conn net.Conn, dir string, fail bool) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) { tp.PrintfLine("%d %s", code, msg) }
+
+	var from string
+	var rcpts []string
+	reply(220, "cland mail sink ready")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply(250, "hello "+arg)
+		case "MAIL":
+			from, rcpts = strings.TrimPrefix(arg, "FROM:"), nil
+			reply(250, "ok")
+		case "RCPT":
+			rcpts = append(rcpts, strings.TrimPrefix(arg, "TO:"))
+			reply(250, "ok")
+		case "DATA":
+			reply(354, "end data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			if fail {
+				log.Printf("rejecting mail from %s to %s", from, strings.Join(rcpts, ", "))
+				reply(451, "simulated failure")
+				continue
+			}
+			log.Printf("received mail from %s to %s:\n%s", from, strings.Join(rcpts, ", "), data)
+			if dir != "" {
+				name := filepath.Join(dir, fmt.Sprintf("%d.eml", time.Now().UnixNano()))
+				if err := os.WriteFile(name, data, 0o640); err != nil {
+					log.Printf("failed to save mail: %v", err)
+				}
+			}
+			reply(250, "queued")
+		case "RSET":
+			from, rcpts = "", nil
+			reply(250, "ok")
+		case "NOOP":
+			reply(250, "ok")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(502, "command not implemented")
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// webhook-receiver 本地联调用的 webhook 接收端，校验签名后打印事件；-fail 模拟对端故障以观察重试:
+//
+//	go run ./cmd/webhook-receiver [-addr :9090] [-secret whsec_...] [-fail]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+)
+
+// envWebhookSecret 未通过 -secret 指定时读取的环境变量
+const envWebhookSecret = "CLAND_WEBHOOK_SECRET"
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("webhook-receiver", flag.ContinueOnError)
+	addr := fs.String("addr", ":9090", "listen address")
+	secret := fs.String("secret", "", "subscription secret (default $"+envWebhookSecret+")")
+	tolerance := fs.Duration("tolerance", 5*time.Minute, "allowed signature timestamp skew")
+	fail := fs.Bool("fail", false, "respond 500 to every delivery")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *secret == "" {
+		*secret = os.Getenv(envWebhookSecret)
+	}
+	if *secret == "" {
+		fs.Usage()
+		return errors.New("secret is required")
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		event := r.Header.Get(utils.WebhookEventHeader)
+		delivery := r.Header.Get(utils.WebhookDeliveryHeader)
+		if err := utils.VerifyWebhookSignature(*secret, r.Header.Get(utils.WebhookSignatureHeader), body, *tolerance); err != nil {
+			log.Printf("rejected %s delivery %s: %v", event, delivery, err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if *fail {
+			log.Printf("failing %s delivery %s", event, delivery)
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("received %s delivery %s: %s", event, delivery, body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("webhook receiver listening on %s", *addr)
+	return http.ListenAndServe(*addr, handler)
+}
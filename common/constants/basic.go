@@ -16,7 +16,8 @@ const (
 	StatusError   = 500
 
 	//业务相关
-	KEY_USER_ID = "cland-cid"
+	KEY_USER_ID    = "cland-cid"
+	KEY_AGENT_TEAM = "cland-agent-team" // 客服所属团队
 
 	// Cookie配置
 	CookieMaxAgeOneYear = 31536000 // 1年
@@ -24,6 +25,14 @@ const (
 	CookieSecure        = true
 	CookieHttpOnly      = true
 
+	// 幂等发送
+	HeaderIdempotencyKey   = "Idempotency-Key"
+	HeaderIdempotentReplay = "Idempotent-Replayed"
+	IdempotencyKeyMaxLen   = 255
+
+	// DefaultIdempotencyKeyWindow 未配置 server.idempotency_window 时的幂等键去重窗口(秒, 24小时)
+	DefaultIdempotencyKeyWindow = 86400
+
 	// 错误码
 	ErrorCodeUserInitFailed = 50010010001
 )
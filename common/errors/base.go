@@ -7,5 +7,55 @@ type Error struct {
 
 var Err400 = Error{Code: 40010010000, Msg: "Invalid parameter"}
 var ErrUserIDMissing = Error{Code: 40010010001, Msg: "Invalid parameter: user_id is missing"}
+var ErrAccountInvalid = Error{Code: 40010010002, Msg: "Invalid parameter: username must be 3-50 letters, digits, '.', '_' or '-', password 8-72 characters and role one of agent, supervisor, admin"}
+var ErrEmailInvalid = Error{Code: 40010010003, Msg: "Invalid parameter: email must be a valid address of at most 255 characters"}
+var ErrReplyToInvalid = Error{Code: 40010020001, Msg: "Invalid parameter: replyTo must reference a message in the same session"}
+var ErrEmojiInvalid = Error{Code: 40010020002, Msg: "Invalid parameter: emoji must be between 1 and 32 bytes"}
+var ErrRatingInvalid = Error{Code: 40010030001, Msg: "Invalid parameter: score must be between 1 and 5 and comment at most 1000 characters"}
+var ErrSessionNotClosed = Error{Code: 40010030002, Msg: "Session is not closed yet"}
+var ErrSessionNotActive = Error{Code: 40010030003, Msg: "Session is not active"}
+var ErrTranscriptInvalid = Error{Code: 40010030004, Msg: "Invalid parameter: format must be json, txt or html and tz an IANA time zone"}
+var ErrCannedResponseInvalid = Error{Code: 40010040001, Msg: "Invalid parameter: shortcut must start with '/' and title/body are required"}
+var ErrWebhookInvalid = Error{Code: 40010050001, Msg: "Invalid parameter: url must be http(s), events must be known event types and the subscription must be active"}
+var ErrChannelPayloadInvalid = Error{Code: 40010080001, Msg: "Invalid parameter: channel payload could not be parsed"}
+
+var Err401 = Error{Code: 40110010000, Msg: "Unauthorized"}
+var ErrTokenInvalid = Error{Code: 40110010001, Msg: "Unauthorized: token is invalid or expired"}
+var ErrInvalidCredentials = Error{Code: 40110010002, Msg: "Unauthorized: invalid username or password"}
+var ErrRefreshTokenInvalid = Error{Code: 40110010003, Msg: "Unauthorized: refresh token is invalid, expired or revoked"}
+var ErrTokenRevoked = Error{Code: 40110010004, Msg: "Unauthorized: token has been revoked"}
+var ErrIdentityTokenInvalid = Error{Code: 40110010005, Msg: "Unauthorized: identity token is invalid, expired or too old"}
+var ErrChannelSignatureInvalid = Error{Code: 40110080001, Msg: "Unauthorized: channel request signature is invalid or expired"}
+
+var Err403 = Error{Code: 40310010000, Msg: "Forbidden"}
+var ErrRoleForbidden = Error{Code: 40310010001, Msg: "Forbidden: role is not allowed to perform this action"}
+var ErrSenderMismatch = Error{Code: 40310020002, Msg: "Forbidden: messages can only be sent as yourself"}
+var ErrAckForbidden = Error{Code: 40310020003, Msg: "Forbidden: only the recipient can acknowledge a message"}
+var ErrInternalNoteForbidden = Error{Code: 40310020001, Msg: "Forbidden: only agents can send internal notes"}
+var ErrNotificationForbidden = Error{Code: 40310020004, Msg: "Forbidden: only staff can send notifications"}
+var ErrNotSessionCustomer = Error{Code: 40310030001, Msg: "Forbidden: only the session customer can rate it"}
+var ErrSupervisorOnly = Error{Code: 40310030002, Msg: "Forbidden: only supervisors can perform this action"}
+var ErrSessionAccessDenied = Error{Code: 40310030003, Msg: "Forbidden: session belongs to another user"}
+
+var Err404 = Error{Code: 40410010000, Msg: "Resource not found"}
+var ErrAccountNotFound = Error{Code: 40410010001, Msg: "Account not found"}
+var ErrMessageNotFound = Error{Code: 40410020001, Msg: "Message not found"}
+var ErrSessionNotFound = Error{Code: 40410030001, Msg: "Session not found"}
+var ErrCannedResponseNotFound = Error{Code: 40410040001, Msg: "Canned response not found"}
+var ErrWebhookNotFound = Error{Code: 40410050001, Msg: "Webhook subscription not found"}
+var ErrWebhookDeliveryNotFound = Error{Code: 40410050002, Msg: "Webhook delivery not found"}
+var ErrArchiveNotFound = Error{Code: 40410060001, Msg: "No archive found for this session"}
+var ErrCustomerNotFound = Error{Code: 40410070001, Msg: "Customer not found or already erased"}
+var ErrChannelNotFound = Error{Code: 40410080001, Msg: "Channel not found"}
+var ErrAttachmentNotFound = Error{Code: 40410080002, Msg: "Attachment not found"}
+
+var ErrUsernameTaken = Error{Code: 40910010001, Msg: "Conflict: username already taken"}
+var ErrMsgIDConflict = Error{Code: 40910020001, Msg: "Conflict: msgId already used by another sender"}
+var ErrAlreadyRated = Error{Code: 40910030001, Msg: "Conflict: session already rated"}
+var ErrCannedShortcutTaken = Error{Code: 40910040001, Msg: "Conflict: shortcut already used in this scope"}
+var ErrRetentionRunning = Error{Code: 40910060001, Msg: "Conflict: a retention run is already in progress"}
+
+var ErrIdempotencyKeyReused = Error{Code: 42210020001, Msg: "Idempotency-Key reused with a different request"}
 
 var Err500 = Error{Code: 50010010000, Msg: "系统异常"}
+var ErrIdentityDisabled = Error{Code: 50110010001, Msg: "Identity linking is not configured"}
@@ -0,0 +1,26 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// WriterExporter 将 span 按 JSON Lines 写入 w(标准输出或文件)，便于离线查看与导入其他系统
+type WriterExporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+var _ Exporter = (*WriterExporter)(nil)
+
+// NewWriterExporter 创建写入 w 的导出器
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{enc: json.NewEncoder(w)}
+}
+
+func (e *WriterExporter) ExportSpan(span *SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(span)
+}
@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// recordingExporter 记录导出的 span
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []*SpanData
+}
+
+func (e *recordingExporter) ExportSpan(span *SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// useExporter 在测试期间替换全局导出器与采样比例
+func useExporter(t *testing.T, ratio float64) *recordingExporter {
+	t.Helper()
+	e := &recordingExporter{}
+	SetExporter(e)
+	SetSampleRatio(ratio)
+	t.Cleanup(func() {
+		SetExporter(nil)
+		SetSampleRatio(1)
+	})
+	return e
+}
+
+func TestParseTraceparent(t *testing.T) {
+	const traceID, spanID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	tests := []struct {
+		name    string
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{name: "sampled", value: "00-" + traceID + "-" + spanID + "-01", ok: true, sampled: true},
+		{name: "not sampled", value: "00-" + traceID + "-" + spanID + "-00", ok: true},
+		{name: "other flags", value: "00-" + traceID + "-" + spanID + "-03", ok: true, sampled: true},
+		{name: "surrounding spaces", value: " 00-" + traceID + "-" + spanID + "-01 ", ok: true, sampled: true},
+		{name: "future version with extra field", value: "cc-" + traceID + "-" + spanID + "-01-what", ok: true, sampled: true},
+		{name: "version 00 with extra field", value: "00-" + traceID + "-" + spanID + "-01-what"},
+		{name: "forbidden version", value: "ff-" + traceID + "-" + spanID + "-01"},
+		{name: "zero trace id", value: "00-00000000000000000000000000000000-" + spanID + "-01"},
+		{name: "zero span id", value: "00-" + traceID + "-0000000000000000-01"},
+		{name: "short trace id", value: "00-" + traceID[1:] + "-" + spanID + "-01"},
+		{name: "not hex", value: "00-" + traceID + "-00f067aa0ba902bz-01"},
+		{name: "missing flags", value: "00-" + traceID + "-" + spanID},
+		{name: "empty", value: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("ParseTraceparent(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.TraceID.String() != traceID || sc.SpanID.String() != spanID || sc.Sampled != tt.sampled || !sc.Remote {
+				t.Errorf("ParseTraceparent(%q) = %+v", tt.value, sc)
+			}
+		})
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	_, span := Start(context.Background(), "root")
+	value := span.SpanContext().Traceparent()
+	sc, ok := ParseTraceparent(value)
+	if !ok || sc.TraceID != span.SpanContext().TraceID || sc.SpanID != span.SpanContext().SpanID {
+		t.Fatalf("ParseTraceparent(%q) = %+v, %v, want the span's own context", value, sc, ok)
+	}
+}
+
+func TestStartContinuesRemoteTrace(t *testing.T) {
+	e := useExporter(t, 0)
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	// 调用方已采样时沿用其采样标记，不受本地采样比例影响
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+	ctx, server := Start(ctx, "HTTP GET /api/sessions")
+	_, child := Start(ctx, "ChatUseCase.GetSessionMessages")
+	child.SetAttribute("session.id", "s1")
+	child.End()
+	server.End()
+	server.End()
+
+	if len(e.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(e.spans))
+	}
+	childData, serverData := e.spans[0], e.spans[1]
+	if serverData.TraceID != remote.TraceID.String() || serverData.ParentSpanID != remote.SpanID.String() {
+		t.Errorf("server span = %+v, want trace %s with parent %s", serverData, remote.TraceID, remote.SpanID)
+	}
+	if childData.TraceID != remote.TraceID.String() || childData.ParentSpanID != serverData.SpanID {
+		t.Errorf("child span = %+v, want parent %s", childData, serverData.SpanID)
+	}
+	if childData.Attributes["session.id"] != "s1" {
+		t.Errorf("child attributes = %v", childData.Attributes)
+	}
+}
+
+func TestStartSampling(t *testing.T) {
+	e := useExporter(t, 0)
+
+	// 调用方未采样时不导出，但仍生成追踪 ID 供日志关联
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := Start(ContextWithRemoteSpanContext(context.Background(), remote), "unsampled")
+	span.End()
+	if len(e.spans) != 0 {
+		t.Fatalf("exported %d spans of an unsampled trace, want 0", len(e.spans))
+	}
+	if span.SpanContext().TraceID != remote.TraceID {
+		t.Errorf("trace id = %s, want %s", span.SpanContext().TraceID, remote.TraceID)
+	}
+
+	// 新追踪按本地采样比例
+	_, root := Start(context.Background(), "root")
+	root.End()
+	if len(e.spans) != 0 || !root.SpanContext().IsValid() {
+		t.Fatalf("ratio 0: exported %d spans, context %+v", len(e.spans), root.SpanContext())
+	}
+	SetSampleRatio(1)
+	_, root = Start(context.Background(), "root")
+	root.End()
+	if len(e.spans) != 1 || e.spans[0].ParentSpanID != "" {
+		t.Fatalf("ratio 1: exported %+v, want one root span", e.spans)
+	}
+}
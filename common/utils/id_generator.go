@@ -9,6 +9,30 @@ func GenerateClandCID() string {
 	return "c" + uuid.New().String()
 }
 
+// idempotencyNamespace is the UUID namespace used to derive message IDs from idempotency keys
+var idempotencyNamespace = uuid.MustParse("6f1c2a9e-3d4b-4e8a-9c71-0b5d2f8e4a13")
+
+// GenerateAccountID generates a staff account ID in a+uuid format
+func GenerateAccountID() string {
+	return "a" + uuid.New().String()
+}
+
+// GenerateMessageID generates a message ID in m+uuid format
+func GenerateMessageID() string {
+	return "m" + uuid.New().String()
+}
+
+// GenerateIdempotentMessageID derives a stable message ID from the sender and its idempotency key,
+// so retries of the same request map to the same msgId
+func GenerateIdempotentMessageID(src, key string) string {
+	return "m" + uuid.NewSHA1(idempotencyNamespace, []byte(src+"|"+key)).String()
+}
+
+// GenerateCannedResponseID generates a canned response ID in cr+uuid format
+func GenerateCannedResponseID() string {
+	return "cr" + uuid.New().String()
+}
+
 // GenerateSessionID generates a session ID in se+uuid format
 func GenerateSessionID() string {
 	return "se" + uuid.New().String()
@@ -54,3 +78,23 @@ func IsValidSubSessionID(id string) bool {
 	_, err := uuid.Parse(id[2:])
 	return err == nil
 }
+
+// GenerateWebhookID generates a webhook subscription ID in wh+uuid format
+func GenerateWebhookID() string {
+	return "wh" + uuid.New().String()
+}
+
+// GenerateWebhookDeliveryID generates a webhook delivery ID in wd+uuid format
+func GenerateWebhookDeliveryID() string {
+	return "wd" + uuid.New().String()
+}
+
+// GenerateEventID generates a domain event ID in ev+uuid format
+func GenerateEventID() string {
+	return "ev" + uuid.New().String()
+}
+
+// GenerateRetentionRunID generates a retention run ID in rr+uuid format
+func GenerateRetentionRunID() string {
+	return "rr" + uuid.New().String()
+}
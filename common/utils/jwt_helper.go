@@ -1,45 +1,275 @@
 package utils
 
 import (
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// Supported JWT signing algorithms
 const (
-	TokenExpiration = 24 * time.Hour
-	SecretKey       = "your-secret-key" // TODO: Move to config
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// DefaultTokenExpiration 未配置时访问令牌的有效期
+const DefaultTokenExpiration = 15 * time.Minute
+
+var (
+	// ErrMissingToken 请求未携带 Bearer 令牌
+	ErrMissingToken = errors.New("missing bearer token")
+	// ErrUnknownKeyID 令牌头中的 kid 不在已配置的密钥中
+	ErrUnknownKeyID = errors.New("unknown signing key id")
+	// ErrNoSigningKey 仅用于验证的 JWTManager 不能签发令牌
+	ErrNoSigningKey = errors.New("no signing key configured")
 )
 
 type Claims struct {
 	UserID string `json:"sub"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// IdentityClaims 主站签发的身份令牌，sub 为主站客户 ID
+type IdentityClaims struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID string) (string, error) {
+// JWTKeyOptions 单个签名密钥；HS256 使用 Secret，RS256/EdDSA 使用 PEM 编码的密钥对，
+// 仅用于验证的轮换旧密钥可以只提供公钥
+type JWTKeyOptions struct {
+	ID            string
+	Algorithm     string
+	Secret        []byte
+	PrivateKeyPEM []byte
+	PublicKeyPEM  []byte
+}
+
+// JWTOptions 令牌签发与验证配置
+type JWTOptions struct {
+	Issuer       string
+	Audience     string // 非空时要求令牌的 aud 包含该值
+	TTL          time.Duration
+	SigningKeyID string // 签发新令牌使用的密钥，其余密钥只用于验证；为空时只能验证
+	Keys         []JWTKeyOptions
+}
+
+type jwtKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// JWTManager 按 kid 选择密钥签发与验证令牌，支持密钥轮换
+type JWTManager struct {
+	issuer   string
+	audience string
+	ttl      time.Duration
+	signing  *jwtKey
+	keys     map[string]*jwtKey
+	methods  []string
+}
+
+// NewJWTManager creates a JWTManager from parsed key options
+func NewJWTManager(opts JWTOptions) (*JWTManager, error) {
+	m := &JWTManager{
+		issuer:   opts.Issuer,
+		audience: opts.Audience,
+		ttl:      opts.TTL,
+		keys:     make(map[string]*jwtKey, len(opts.Keys)),
+	}
+	if m.ttl <= 0 {
+		m.ttl = DefaultTokenExpiration
+	}
+
+	seen := make(map[string]bool)
+	for _, o := range opts.Keys {
+		if o.ID == "" {
+			return nil, errors.New("jwt key id is required")
+		}
+		if _, dup := m.keys[o.ID]; dup {
+			return nil, fmt.Errorf("duplicate jwt key id %q", o.ID)
+		}
+		key, err := parseJWTKey(o)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", o.ID, err)
+		}
+		m.keys[o.ID] = key
+		if alg := key.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			m.methods = append(m.methods, alg)
+		}
+	}
+
+	if len(m.keys) == 0 {
+		return nil, errors.New("at least one jwt key is required")
+	}
+	if opts.SigningKeyID == "" {
+		return m, nil
+	}
+	m.signing = m.keys[opts.SigningKeyID]
+	if m.signing == nil {
+		return nil, fmt.Errorf("signing key %q is not configured", opts.SigningKeyID)
+	}
+	if m.signing.signKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", opts.SigningKeyID)
+	}
+	return m, nil
+}
+
+func parseJWTKey(o JWTKeyOptions) (*jwtKey, error) {
+	key := &jwtKey{id: o.ID}
+	switch o.Algorithm {
+	case JWTAlgHS256:
+		if len(o.Secret) < 32 {
+			return nil, errors.New("HS256 secret must be at least 32 bytes")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = o.Secret
+		key.verifyKey = o.Secret
+	case JWTAlgRS256:
+		key.method = jwt.SigningMethodRS256
+		if len(o.PrivateKeyPEM) > 0 {
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(o.PrivateKeyPEM)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = private
+			key.verifyKey = &private.PublicKey
+		}
+		if len(o.PublicKeyPEM) > 0 {
+			public, err := jwt.ParseRSAPublicKeyFromPEM(o.PublicKeyPEM)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = public
+		}
+	case JWTAlgEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+		if len(o.PrivateKeyPEM) > 0 {
+			private, err := jwt.ParseEdPrivateKeyFromPEM(o.PrivateKeyPEM)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := private.(crypto.Signer)
+			if !ok {
+				return nil, errors.New("invalid EdDSA private key")
+			}
+			key.signKey = private
+			key.verifyKey = signer.Public()
+		}
+		if len(o.PublicKeyPEM) > 0 {
+			public, err := jwt.ParseEdPublicKeyFromPEM(o.PublicKeyPEM)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = public
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", o.Algorithm)
+	}
+	if key.verifyKey == nil {
+		return nil, errors.New("public or private key is required")
+	}
+	return key, nil
+}
+
+// TTL 访问令牌有效期
+func (m *JWTManager) TTL() time.Duration {
+	return m.ttl
+}
+
+// Generate 使用当前签名密钥签发访问令牌，每个令牌带唯一 jti 以便撤销
+func (m *JWTManager) Generate(userID, role string) (string, *Claims, error) {
+	if m.signing == nil {
+		return "", nil, ErrNoSigningKey
+	}
+	now := time.Now()
 	claims := &Claims{
 		UserID: userID,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExpiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+			Issuer:    m.issuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(SecretKey))
+	token := jwt.NewWithClaims(m.signing.method, claims)
+	token.Header["kid"] = m.signing.id
+	signed, err := token.SignedString(m.signing.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
-func ValidateJWT(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(SecretKey), nil
-	})
-	if err != nil {
+// Validate 按令牌头中的 kid 选择密钥验证签名、有效期与签发方
+func (m *JWTManager) Validate(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := m.ParseWithClaims(tokenString, claims); err != nil {
 		return nil, err
 	}
+	return claims, nil
+}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+// ParseWithClaims 验证令牌并解析到指定的 claims，用于校验其他系统签发的令牌
+func (m *JWTManager) ParseWithClaims(tokenString string, claims jwt.Claims) error {
+	opts := []jwt.ParserOption{jwt.WithValidMethods(m.methods), jwt.WithExpirationRequired()}
+	if m.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(m.issuer))
+	}
+	if m.audience != "" {
+		opts = append(opts, jwt.WithAudience(m.audience))
 	}
 
-	return nil, jwt.ErrInvalidKey
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		key, ok := m.lookupKey(token)
+		if !ok {
+			return nil, ErrUnknownKeyID
+		}
+		// 防止用一个密钥的材料按另一种算法伪造签名
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.verifyKey, nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return jwt.ErrInvalidKey
+	}
+	return nil
+}
+
+// lookupKey 按 kid 查找密钥；外部系统的令牌可能不带 kid，只配置了一个密钥时直接使用它
+func (m *JWTManager) lookupKey(token *jwt.Token) (*jwtKey, bool) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(m.keys) == 1 {
+		for _, key := range m.keys {
+			return key, true
+		}
+	}
+	key, ok := m.keys[kid]
+	return key, ok
+}
+
+// BearerToken 读取 Authorization: Bearer <token>
+func BearerToken(header string) (string, error) {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", ErrMissingToken
+	}
+	return strings.TrimSpace(header[len(prefix):]), nil
 }
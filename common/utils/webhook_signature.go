@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-Cland-Signature"
+	WebhookEventHeader     = "X-Cland-Event"
+	WebhookDeliveryHeader  = "X-Cland-Delivery"
+)
+
+var (
+	// ErrWebhookSignatureInvalid 签名头格式错误或签名不匹配
+	ErrWebhookSignatureInvalid = errors.New("invalid webhook signature")
+	// ErrWebhookSignatureExpired 签名时间超出允许的偏差，可能是重放请求
+	ErrWebhookSignatureExpired = errors.New("webhook signature timestamp out of tolerance")
+)
+
+// GenerateWebhookSecret 生成订阅的签名密钥
+func GenerateWebhookSecret() (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// SignWebhook 计算签名头 t=<unix 秒>,v1=<hex(HMAC-SHA256(secret, "<t>.<body>"))>，
+// 签名覆盖时间戳，接收方可据此拒绝过旧的请求
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + webhookMAC(secret, ts, body)
+}
+
+// VerifyWebhookSignature 校验签名头，tolerance 为允许的时间偏差，0 表示不检查
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrWebhookSignatureInvalid
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return ErrWebhookSignatureExpired
+		}
+	}
+
+	expected := webhookMAC(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrWebhookSignatureInvalid
+}
+
+func webhookMAC(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"session.created"}`)
+	now := time.Now()
+	header := SignWebhook("whsec_test", now, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		want      error
+	}{
+		{name: "valid", secret: "whsec_test", header: header, body: body, tolerance: time.Minute},
+		{name: "tampered body", secret: "whsec_test", header: header, body: []byte(`{"type":"session.closed"}`), tolerance: time.Minute, want: ErrWebhookSignatureInvalid},
+		{name: "wrong secret", secret: "whsec_other", header: header, body: body, tolerance: time.Minute, want: ErrWebhookSignatureInvalid},
+		{name: "expired", secret: "whsec_test", header: SignWebhook("whsec_test", now.Add(-time.Hour), body), body: body, tolerance: time.Minute, want: ErrWebhookSignatureExpired},
+		{name: "expiry not checked", secret: "whsec_test", header: SignWebhook("whsec_test", now.Add(-time.Hour), body), body: body},
+		{name: "missing signature", secret: "whsec_test", header: strings.Split(header, ",")[0], body: body, want: ErrWebhookSignatureInvalid},
+		{name: "rotated secret", secret: "whsec_test", header: header + ",v1=" + strings.Repeat("0", 64), body: body, tolerance: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.secret, tt.header, tt.body, tt.tolerance)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyWebhookSignature error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// Audit log action values
+const (
+	AuditActionMonitorStart = "monitor_start"
+	AuditActionMonitorStop  = "monitor_stop"
+	AuditActionWhisper      = "whisper"
+	AuditActionBargeIn      = "barge_in"
+)
+
+// AuditLog 操作审计日志
+type AuditLog struct {
+	ID        int64                  `json:"id"`
+	ActorID   string                 `json:"actorId"`
+	ActorRole string                 `json:"actorRole"`
+	Action    string                 `json:"action"`
+	SessionID string                 `json:"sessionId,omitempty"`
+	TargetID  string                 `json:"targetId,omitempty"` // 操作对象，如被接管的客服
+	Detail    map[string]interface{} `json:"detail,omitempty"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+// AuditQuery 审计日志查询条件，空字段不过滤
+type AuditQuery struct {
+	SessionID string
+	ActorID   string
+	Action    string
+	Limit     int
+}
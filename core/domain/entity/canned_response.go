@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"regexp"
+	"time"
+)
+
+// Canned response scope values
+const (
+	CannedScopeTeam     = "team"     // 团队共享
+	CannedScopePersonal = "personal" // 客服个人
+)
+
+// cannedVariablePattern 匹配 {{customer.name}} 或带默认值的 {{customer.name|您}}
+var cannedVariablePattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_.]+)\s*(?:\|([^}]*))?\}\}`)
+
+// CannedResponse 快捷回复
+type CannedResponse struct {
+	ID        string    `json:"id"`
+	Shortcut  string    `json:"shortcut"` // 如 /refund
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`  // 支持 {{customer.name}} 等变量
+	Scope     string    `json:"scope"` // team, personal
+	Team      string    `json:"team,omitempty"`
+	OwnerID   string    `json:"ownerId,omitempty"` // personal 快捷回复所属客服
+	Variables []string  `json:"variables"`         // Body 中引用的变量名
+	CreatedBy string    `json:"createdBy"`
+	UpdatedBy string    `json:"updatedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CannedResponseFilter 快捷回复查询条件，返回对该客服可见的记录
+type CannedResponseFilter struct {
+	AgentID        string // 可见个人快捷回复的客服
+	Team           string // 可见该团队及全局(无团队)的团队快捷回复
+	ShortcutPrefix string
+}
+
+// VisibleTo 判断快捷回复是否对客服可见
+func (c *CannedResponse) VisibleTo(agentID, team string) bool {
+	if c.Scope == CannedScopePersonal {
+		return c.OwnerID == agentID
+	}
+	return c.Team == "" || c.Team == team
+}
+
+// ParseCannedVariables 解析正文中引用的变量名(去重，保持出现顺序)
+func ParseCannedVariables(body string) []string {
+	vars := []string{}
+	seen := make(map[string]bool)
+	for _, m := range cannedVariablePattern.FindAllStringSubmatch(body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			vars = append(vars, m[1])
+		}
+	}
+	return vars
+}
+
+// ExpandCannedBody 用 values 替换正文变量；缺少取值时使用默认值，未知变量保持原样
+func ExpandCannedBody(body string, values map[string]string) string {
+	return cannedVariablePattern.ReplaceAllStringFunc(body, func(placeholder string) string {
+		m := cannedVariablePattern.FindStringSubmatch(placeholder)
+		value, known := values[m[1]]
+		if value != "" {
+			return value
+		}
+		if m[2] != "" {
+			return m[2]
+		}
+		if known {
+			return ""
+		}
+		return placeholder
+	})
+}
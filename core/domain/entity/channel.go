@@ -0,0 +1,47 @@
+package entity
+
+import "time"
+
+// ChannelIdentity 外部渠道用户(邮箱地址、手机号、即时通讯账号等)与客户记录的对应关系
+type ChannelIdentity struct {
+	Channel    string    `json:"channel"`
+	ExternalID string    `json:"externalId"`
+	CID        string    `json:"cid"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ChannelThread 渠道侧的会话线索标识(如邮件的 Message-ID)与会话的对应关系，
+// 客户回复时据此归入原会话，客服回复时据此生成线索引用
+type ChannelThread struct {
+	Channel   string    `json:"channel"`
+	ThreadID  string    `json:"threadId"`
+	SessionID string    `json:"sessionId"`
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// InboundMessage 渠道适配器从服务商回调中解析出的一条客户消息。
+// Message 只填写内容相关字段(ContentType、Content、Ts、Ext)，消息 ID、会话与收发地址由服务端补全
+type InboundMessage struct {
+	ProviderMsgID string // 服务商侧的消息标识，服务商重复回调时据此去重
+	ExternalID    string // 服务商侧的发送方标识
+	Name          string // 发送方展示名称，可为空
+	Email         string // 发送方邮箱，可为空
+	Message       *Message
+
+	// 按线索归属会话的渠道(如邮件)填写: 能按 ThreadRefs 找到进行中的会话时归入该会话，否则新建会话；
+	// 未设置 Threaded 时归入客户在该渠道最近一个进行中的会话
+	Threaded   bool
+	ThreadID   string   // 本条消息的线索标识
+	ThreadRefs []string // 引用的线索标识，如 In-Reply-To 与 References
+	Subject    string
+}
+
+// OutboundMessage 经渠道发回给客户的客服回复
+type OutboundMessage struct {
+	ExternalID string // 服务商侧的接收方标识
+	Message    *Message
+	ThreadRefs []string // 会话已有的线索标识，按时间顺序
+	Subject    string   // 会话线索的主题
+	ThreadID   string   // 发送成功后由渠道填写本条回复的线索标识
+}
@@ -11,6 +11,7 @@ const (
 	MsgTypeMessage      = 1 // 普通消息
 	MsgTypeNotification = 2 // 通知
 	MsgTypeAck          = 3 // 确认
+	MsgTypeInternalNote = 4 // 内部备注，仅客服可见
 )
 
 type MsgType uint8
@@ -85,21 +86,65 @@ type Message struct {
 	Src          string                 `json:"src"` // U:user_xxx, A:agent_xxx, S:system, UA:admin_xxx
 	Dst          string                 `json:"dst"`
 	Content      string                 `json:"content"`
-	ContentType  uint8                  `json:"contentType"` // 1=TEXT, 2=IMAGE, 3=FILE
-	Ts           StringTimestamp        `json:"ts"`          // Unix毫秒时间戳
-	Status       uint8                  `json:"status"`      // 1=NEW, ..., 7=READ
-	Ext          map[string]interface{} `json:"ext"`         // 扩展字段(JSON object)
+	ContentType  uint8                  `json:"contentType"`            // 1=TEXT, 2=IMAGE, 3=FILE
+	Ts           StringTimestamp        `json:"ts"`                     // Unix毫秒时间戳
+	Status       uint8                  `json:"status"`                 // 1=NEW, ..., 7=READ
+	Ext          map[string]interface{} `json:"ext"`                    // 扩展字段(JSON object)
+	ReplyTo      string                 `json:"replyTo,omitempty"`      // 引用的消息ID(同一会话内)
+	ReplyPreview *MessagePreview        `json:"replyPreview,omitempty"` // 被引用消息摘要(仅查询时填充)
+	Reactions    []ReactionSummary      `json:"reactions,omitempty"`    // 表情回应统计(仅查询时填充)
 	CreatedBy    string                 `json:"createdBy"`
 	UpdatedBy    string                 `json:"updatedBy"`
 	CreatedAt    time.Time              `json:"createdAt"`
 	UpdatedAt    time.Time              `json:"updatedAt"`
 }
 
+// previewContentLimit 引用摘要的最大字符数
+const previewContentLimit = 100
+
+// MessagePreview 被引用消息的精简摘要
+type MessagePreview struct {
+	MsgID       string          `json:"msgId"`
+	Src         string          `json:"src"`
+	Content     string          `json:"content"`
+	ContentType uint8           `json:"contentType"`
+	Ts          StringTimestamp `json:"ts"`
+}
+
+// IsInternal 是否为客服内部备注
+func (m *Message) IsInternal() bool {
+	return m.MsgType == MsgTypeInternalNote
+}
+
+// Preview 生成消息摘要，文本内容超长时截断
+func (m *Message) Preview() *MessagePreview {
+	content := m.Content
+	if runes := []rune(content); len(runes) > previewContentLimit {
+		content = string(runes[:previewContentLimit]) + "..."
+	}
+	return &MessagePreview{
+		MsgID:       m.MsgID,
+		Src:         m.Src,
+		Content:     content,
+		ContentType: m.ContentType,
+		Ts:          m.Ts,
+	}
+}
+
+// Session status and channel values
+const (
+	SessionStatusActive = "active" // 进行中
+	SessionStatusClosed = "closed" // 已关闭
+
+	ChannelWeb = "web" // 网页 Socket.IO 组件
+)
+
 // Session 会话实体
 type Session struct {
 	ID           string    `json:"id"`
 	CID          string    `json:"cid"` // Customer ID
 	AgentId      string    `json:"agentId"`
+	Channel      string    `json:"channel"` // 接入渠道, 默认 web
 	SessionID    string    `json:"sessionId"`
 	SubSessionID string    `json:"subSessionId"`
 	StartTime    time.Time `json:"startTime"`
@@ -113,15 +158,34 @@ type Session struct {
 
 // User 用户实体
 type User struct {
-	ID         string    `json:"id"`
-	UID        string    `json:"uid"` // Unique user ID
-	Username   string    `json:"username"`
-	Query      string    `json:"query"`
-	Role       string    `json:"role"`   // customer, agent, admin
-	Status     string    `json:"status"` // online, offline, busy
-	CreatedBy  string    `json:"createdBy"`
-	UpdatedBy  string    `json:"updatedBy"`
-	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
-	LastActive time.Time `json:"lastActive"`
+	ID                 string    `json:"id"`
+	UID                string    `json:"uid"` // Unique user ID
+	Username           string    `json:"username"`
+	DisplayName        string    `json:"displayName"`
+	Email              string    `json:"email"`
+	ExternalID         string    `json:"externalId,omitempty"` // 主站客户 ID，访客登录主站后关联
+	Query              string    `json:"query"`
+	Role               string    `json:"role"`               // customer, agent, supervisor, admin
+	Status             string    `json:"status"`             // online, offline, busy
+	Skills             []string  `json:"skills"`             // 客服技能标签，用于分配会话
+	MaxConcurrentChats int       `json:"maxConcurrentChats"` // 客服同时接待的会话上限，0 表示不限制
+	PasswordHash       string    `json:"-"`                  // bcrypt 哈希，访客为空
+	CreatedBy          string    `json:"createdBy"`
+	UpdatedBy          string    `json:"updatedBy"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+	LastActive         time.Time `json:"lastActive"`
+}
+
+// Name 展示名称，未设置时使用用户名
+func (u *User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
+}
+
+// UserFilter 员工账号查询条件
+type UserFilter struct {
+	Role string // 为空时返回全部员工账号
 }
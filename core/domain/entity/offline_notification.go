@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+// OfflineNotification 客户离线提醒的发送记录，用于限制发送频率并避免重复提醒同一批消息
+type OfflineNotification struct {
+	CID        string    `json:"cid"`
+	LastSentAt time.Time `json:"lastSentAt"`
+	LastMsgTs  int64     `json:"lastMsgTs"` // 已提醒过的最新消息时间戳(毫秒)
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// OfflineDigest 发给离线客户的未读回复摘要
+type OfflineDigest struct {
+	CID      string         `json:"cid"`
+	Name     string         `json:"name"`
+	Email    string         `json:"email"`
+	ChatURL  string         `json:"chatUrl"` // 返回最近一个会话的链接
+	Replies  []OfflineReply `json:"replies"`
+	Timezone string         `json:"timezone"`
+}
+
+// OfflineReply 摘要中的一条客服回复
+type OfflineReply struct {
+	MsgID         string    `json:"msgId"`
+	SessionID     string    `json:"sessionId"`
+	SenderName    string    `json:"senderName"`
+	SentAt        time.Time `json:"sentAt"`
+	Content       string    `json:"content"`
+	AttachmentURL string    `json:"attachmentUrl,omitempty"`
+}
+
+// IsAttachment 是否为图片或文件消息
+func (r *OfflineReply) IsAttachment() bool {
+	return r.AttachmentURL != ""
+}
@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox event status
+const (
+	OutboxPending   = "pending"   // 等待分发或重试
+	OutboxPublished = "published" // 所有下游均已处理
+	OutboxFailed    = "failed"    // 重试次数用尽，需人工排查
+)
+
+// OutboxEvent 发件箱记录，与产生它的业务数据在同一事务内写入，由分发器至少投递一次
+type OutboxEvent struct {
+	ID            string          `json:"id"` // 同时作为下游去重用的事件 ID
+	EventType     string          `json:"eventType"`
+	AggregateID   string          `json:"aggregateId"` // 消息 ID 或会话 ID
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	LastError     string          `json:"lastError"`
+	// CompletedSinks 已成功处理本事件的下游，重试时只分发给其余下游
+	CompletedSinks []string  `json:"completedSinks,omitempty"`
+	PublishedAt    time.Time `json:"publishedAt"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// NewOutboxEvent 编码事件数据，生成一条立即可分发的记录
+func NewOutboxEvent(id, eventType, aggregateID string, data interface{}) (*OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &OutboxEvent{
+		ID:            id,
+		EventType:     eventType,
+		AggregateID:   aggregateID,
+		Payload:       payload,
+		Status:        OutboxPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+// SinkCompleted 下游 name 是否已成功处理本事件
+func (e *OutboxEvent) SinkCompleted(name string) bool {
+	for _, sink := range e.CompletedSinks {
+		if sink == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkSinkCompleted 记录下游 name 已成功处理本事件
+func (e *OutboxEvent) MarkSinkCompleted(name string) {
+	if !e.SinkCompleted(name) {
+		e.CompletedSinks = append(e.CompletedSinks, name)
+	}
+}
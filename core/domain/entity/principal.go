@@ -0,0 +1,78 @@
+package entity
+
+// User role values
+const (
+	RoleCustomer   = "customer"
+	RoleAgent      = "agent"
+	RoleSupervisor = "supervisor"
+	RoleAdmin      = "admin"
+	RoleSystem     = "system" // 服务内部的后台任务
+)
+
+// IsStaffRole 是否为员工账号角色(客服、主管、管理员)
+func IsStaffRole(role string) bool {
+	return role == RoleAgent || role == RoleSupervisor || role == RoleAdmin
+}
+
+// Principal 发起请求的身份
+type Principal struct {
+	UserID string `json:"userId"`
+	Role   string `json:"role"`
+}
+
+// IsAuthenticated 是否为已认证身份
+func (p Principal) IsAuthenticated() bool {
+	return p.UserID != "" || p.IsSystem()
+}
+
+// IsSystem 服务内部身份，不受访问控制限制
+func (p Principal) IsSystem() bool {
+	return p.Role == RoleSystem
+}
+
+// HasRole 是否为指定角色之一
+func (p Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Address 该身份发送消息时使用的地址，客户 U:xxx，其他员工 A:xxx
+func (p Principal) Address() string {
+	if p.IsStaff() {
+		return "A:" + p.UserID
+	}
+	return "U:" + p.UserID
+}
+
+// IsStaff 客服、主管或管理员
+func (p Principal) IsStaff() bool {
+	return p.Role == RoleAgent || p.IsSupervisor()
+}
+
+// IsSupervisor 主管或管理员(含服务内部身份)
+func (p Principal) IsSupervisor() bool {
+	return p.Role == RoleSupervisor || p.Role == RoleAdmin || p.IsSystem()
+}
+
+// CanSee 判断消息对该身份是否可见，内部备注仅客服、主管与管理员可见
+func (p Principal) CanSee(m *Message) bool {
+	return !m.IsInternal() || p.IsStaff()
+}
+
+// FilterVisible 过滤掉对该身份不可见的消息
+func (p Principal) FilterVisible(messages []*Message) []*Message {
+	if p.IsStaff() {
+		return messages
+	}
+	visible := messages[:0:0]
+	for _, m := range messages {
+		if p.CanSee(m) {
+			visible = append(visible, m)
+		}
+	}
+	return visible
+}
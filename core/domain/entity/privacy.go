@@ -0,0 +1,54 @@
+package entity
+
+import "time"
+
+// Audit log actions of data subject requests
+const (
+	AuditActionDataExport = "data_export"
+	AuditActionDataErase  = "data_erase"
+)
+
+// DataExportFormat 导出包格式版本，字段变更时递增
+const DataExportFormat = "cland.data-export/v1"
+
+// ErasedContent 擦除后的消息内容
+const ErasedContent = "[erased]"
+
+// DataExport 数据主体访问请求的导出包
+type DataExport struct {
+	Format           string           `json:"format"`
+	CID              string           `json:"cid"`
+	ExportedAt       time.Time        `json:"exportedAt"`
+	User             *User            `json:"user"`
+	Sessions         []*Session       `json:"sessions"`
+	Messages         []*Message       `json:"messages"`         // 客户全部会话中的消息，含客服回复
+	ArchivedMessages []*Message       `json:"archivedMessages"` // 已按保留策略清理、仍在冷存储中的消息
+	Attachments      []Attachment     `json:"attachments"`
+	Reactions        []*Reaction      `json:"reactions"` // 客户本人的表情回应
+	Ratings          []*SessionRating `json:"ratings"`
+}
+
+// Attachment 图片或文件消息引用的附件
+type Attachment struct {
+	MsgID       string                 `json:"msgId"`
+	SessionID   string                 `json:"sessionId"`
+	ContentType uint8                  `json:"contentType"` // 2=IMAGE, 3=FILE
+	URL         string                 `json:"url"`         // 消息内容中的附件地址
+	Ext         map[string]interface{} `json:"ext,omitempty"`
+}
+
+// ErasureResult 数据擦除各表受影响的记录数
+type ErasureResult struct {
+	CID               string    `json:"cid"`
+	ErasedAt          time.Time `json:"erasedAt"`
+	Users             int64     `json:"users"` // 含已合并到该客户的访客记录
+	Sessions          int64     `json:"sessions"`
+	Messages          int64     `json:"messages"`
+	Reactions         int64     `json:"reactions"`
+	Ratings           int64     `json:"ratings"`
+	RefreshTokens     int64     `json:"refreshTokens"`
+	OutboxEvents      int64     `json:"outboxEvents"`
+	WebhookDeliveries int64     `json:"webhookDeliveries"`
+	Attachments       int64     `json:"attachments"`
+	Archives          int64     `json:"archives"`
+}
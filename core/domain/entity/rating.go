@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// SessionRating 会话满意度评价(CSAT)，每个会话仅一条
+type SessionRating struct {
+	SessionID string    `json:"sessionId"`
+	CID       string    `json:"cid"`
+	AgentID   string    `json:"agentId"`
+	Score     int       `json:"score"` // 1-5 星
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RatingAggregate 满意度统计结果，Day 仅在按天统计时填充(yyyy-mm-dd, UTC)
+type RatingAggregate struct {
+	AgentID   string  `json:"agentId"`
+	Day       string  `json:"day,omitempty"`
+	Count     int     `json:"count"`
+	Average   float64 `json:"average"`
+	Satisfied int     `json:"satisfied"` // 4星及以上的评价数
+	CSAT      float64 `json:"csat"`      // Satisfied/Count 百分比
+}
+
+// RatingQuery 满意度统计条件，时间区间为 [From, To)
+type RatingQuery struct {
+	AgentID    string
+	From       time.Time
+	To         time.Time
+	GroupByDay bool
+}
+
+// SatisfiedScore 计入满意的最低分
+const SatisfiedScore = 4
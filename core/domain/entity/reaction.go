@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// Reaction 消息表情回应，每个(msg, user, emoji)仅保留一条
+type Reaction struct {
+	MsgID     string    `json:"msgId"`
+	SessionID string    `json:"sessionId"`
+	UserID    string    `json:"userId"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ReactionSummary 按表情聚合后的回应统计
+type ReactionSummary struct {
+	Emoji string   `json:"emoji"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
+}
+
+// SummarizeReactions 按表情聚合回应，保持表情首次出现的顺序
+func SummarizeReactions(reactions []*Reaction) []ReactionSummary {
+	var summaries []ReactionSummary
+	index := make(map[string]int)
+	for _, r := range reactions {
+		i, ok := index[r.Emoji]
+		if !ok {
+			i = len(summaries)
+			index[r.Emoji] = i
+			summaries = append(summaries, ReactionSummary{Emoji: r.Emoji})
+		}
+		summaries[i].Count++
+		summaries[i].Users = append(summaries[i].Users, r.UserID)
+	}
+	return summaries
+}
+
+// UserIDFromAddress 从 src/dst 地址(如 U:xxx、UA:xxx)中提取用户ID
+func UserIDFromAddress(addr string) string {
+	if i := strings.IndexByte(addr, ':'); i >= 0 {
+		return addr[i+1:]
+	}
+	return addr
+}
@@ -0,0 +1,65 @@
+package entity
+
+import "time"
+
+// Retention action
+const (
+	RetentionDelete    = "delete"    // 删除消息及其表情回应
+	RetentionAnonymize = "anonymize" // 保留消息元数据，清除内容与扩展字段
+)
+
+// Retention run status
+const (
+	RetentionRunRunning   = "running"
+	RetentionRunCompleted = "completed"
+	RetentionRunFailed    = "failed" // 任务被中断，未处理的会话在下一轮继续
+)
+
+// AnonymizedContent 匿名化后的消息内容
+const AnonymizedContent = "[redacted]"
+
+// RetentionPolicy 渠道的消息保留策略，会话关闭超过 After 后按 Action 清理，
+// Archive 为 true 时先将完整消息归档到冷存储
+type RetentionPolicy struct {
+	After   time.Duration `json:"after"`
+	Action  string        `json:"action"` // delete, anonymize
+	Archive bool          `json:"archive"`
+}
+
+// SessionRetention 会话的保留处理记录。归档与清理分步保存，任务中断后从未完成的步骤继续
+type SessionRetention struct {
+	SessionID    string    `json:"sessionId"`
+	Channel      string    `json:"channel"`
+	Action       string    `json:"action"`
+	ArchiveKey   string    `json:"archiveKey,omitempty"` // 冷存储中的归档文件，未归档时为空
+	MessageCount int       `json:"messageCount"`         // 归档的消息条数
+	ArchivedAt   time.Time `json:"archivedAt"`
+	PurgedAt     time.Time `json:"purgedAt"`
+	RestoredAt   time.Time `json:"restoredAt"` // 管理员恢复供查看的时间，到期后重新清理
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// RetentionRun 一轮保留任务的进度与结果
+type RetentionRun struct {
+	ID                 string    `json:"id"`
+	Status             string    `json:"status"`
+	StartedAt          time.Time `json:"startedAt"`
+	FinishedAt         time.Time `json:"finishedAt"`
+	SessionsScanned    int       `json:"sessionsScanned"`
+	SessionsArchived   int       `json:"sessionsArchived"`
+	SessionsPurged     int       `json:"sessionsPurged"`
+	SessionsFailed     int       `json:"sessionsFailed"`
+	MessagesDeleted    int64     `json:"messagesDeleted"`
+	MessagesAnonymized int64     `json:"messagesAnonymized"`
+	LastError          string    `json:"lastError,omitempty"`
+}
+
+// RetentionQuery 到期会话查询条件，按会话 ID 分页
+type RetentionQuery struct {
+	Channels        []string  // 只查询这些渠道，为空时不限
+	ExcludeChannels []string  // 排除单独配置了策略的渠道
+	ClosedBefore    time.Time // 关闭时间早于该时间的会话到期
+	RestoredBefore  time.Time // 恢复时间早于该时间的会话重新到期
+	AfterSessionID  string    // 上一页最后一条会话的 ID
+	Limit           int
+}
@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// RefreshToken 服务端保存的刷新令牌，只保存哈希。
+// 同一次登录轮换出的令牌属于同一 family，旧令牌被重复使用时整个 family 作废。
+type RefreshToken struct {
+	TokenHash  string    `json:"-"`
+	UserID     string    `json:"userId"`
+	FamilyID   string    `json:"familyId"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	RevokedAt  time.Time `json:"revokedAt"`  // 零值表示仍有效
+	ReplacedBy string    `json:"replacedBy"` // 轮换后新令牌的哈希
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Revoked 是否已撤销
+func (t *RefreshToken) Revoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+// Expired 是否已过期
+func (t *RefreshToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// RevokedToken 已撤销的访问令牌(按 jti)，保留到令牌自然过期
+type RevokedToken struct {
+	JTI       string    `json:"jti"`
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	RevokedAt time.Time `json:"revokedAt"`
+}
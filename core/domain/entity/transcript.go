@@ -0,0 +1,58 @@
+package entity
+
+import "time"
+
+// 会话记录导出格式
+const (
+	TranscriptJSON = "json"
+	TranscriptText = "txt"
+	TranscriptHTML = "html"
+)
+
+// TranscriptSenderSystem 系统通知在会话记录中的发送方角色
+const TranscriptSenderSystem = "system"
+
+// Transcript 面向客户的会话记录，不含内部备注，时间已转换为请求方的时区
+type Transcript struct {
+	SessionID   string            `json:"sessionId"`
+	CID         string            `json:"cid"`
+	Channel     string            `json:"channel"`
+	StartTime   time.Time         `json:"startTime"`
+	EndTime     time.Time         `json:"endTime"`
+	Timezone    string            `json:"timezone"` // IANA 时区名，如 Asia/Shanghai
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Entries     []TranscriptEntry `json:"entries"`
+}
+
+// TranscriptEntry 会话记录中的一条消息
+type TranscriptEntry struct {
+	MsgID       string    `json:"msgId"`
+	SenderID    string    `json:"senderId"`
+	SenderName  string    `json:"senderName"`
+	SenderRole  string    `json:"senderRole"` // customer, agent, supervisor, admin, system
+	SentAt      time.Time `json:"sentAt"`
+	ContentType uint8     `json:"contentType"`
+	Content     string    `json:"content"`
+	// AttachmentURL 图片或文件消息的附件地址，Content 此时为同一地址
+	AttachmentURL string `json:"attachmentUrl,omitempty"`
+}
+
+// IsAttachment 是否为图片或文件消息
+func (e *TranscriptEntry) IsAttachment() bool {
+	return e.AttachmentURL != ""
+}
+
+// MailMessage 待发送的邮件，同时提供纯文本与 HTML 正文
+type MailMessage struct {
+	From     string
+	To       []string
+	Bcc      []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+
+	// 邮件线索，回复邮件时填写；MessageID 为空时自动生成，均不含尖括号
+	MessageID  string
+	InReplyTo  string
+	References []string
+}
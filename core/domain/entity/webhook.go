@@ -0,0 +1,89 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types
+const (
+	WebhookEventMessageCreated  = "message.created"
+	WebhookEventSessionCreated  = "session.created"
+	WebhookEventSessionClosed   = "session.closed"
+	WebhookEventSessionAssigned = "session.assigned"
+	WebhookEventRatingSubmitted = "rating.submitted"
+)
+
+// WebhookEventTypes 可订阅的全部事件类型
+var WebhookEventTypes = []string{
+	WebhookEventMessageCreated,
+	WebhookEventSessionCreated,
+	WebhookEventSessionClosed,
+	WebhookEventSessionAssigned,
+	WebhookEventRatingSubmitted,
+}
+
+// IsWebhookEventType 是否为可订阅的事件类型
+func IsWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery status
+const (
+	WebhookDeliveryPending   = "pending"   // 等待首次投递或重试
+	WebhookDeliverySucceeded = "succeeded" // 对端返回 2xx
+	WebhookDeliveryFailed    = "failed"    // 重试次数用尽或订阅已失效
+)
+
+// WebhookSubscription 外部系统的事件订阅，投递时用 Secret 计算 HMAC 签名
+type WebhookSubscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Secret      string    `json:"secret,omitempty"` // 仅在创建时返回
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedBy   string    `json:"createdBy"`
+	UpdatedBy   string    `json:"updatedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Subscribes 是否订阅了该事件
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 一次事件投递及其重试状态，作为投递日志持久化
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscriptionId"`
+	EventID        string          `json:"eventId"` // 重放时保持不变，接收方可据此去重
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastError      string          `json:"lastError"`
+	ResponseStatus int             `json:"responseStatus"`
+	DeliveredAt    time.Time       `json:"deliveredAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
+
+// WebhookDeliveryQuery 投递日志查询条件
+type WebhookDeliveryQuery struct {
+	SubscriptionID string
+	Status         string
+	Limit          int
+}
@@ -3,13 +3,29 @@ package repository
 import (
 	"cland.org/cland-chat-service/core/domain/entity"
 	"context"
+	"errors"
+	"io"
+	"time"
 )
 
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("not found")
+
+// ErrDuplicateKey 主键或唯一键冲突
+var ErrDuplicateKey = errors.New("duplicate key")
+
 // MessageRepository 消息仓储接口
 type MessageRepository interface {
 	Create(ctx context.Context, message *entity.Message) error
 	GetByID(ctx context.Context, msgID string) (*entity.Message, error)
 	GetBySessionID(ctx context.Context, sessionID string) ([]*entity.Message, error)
+	GetReplies(ctx context.Context, msgID string) ([]*entity.Message, error)
+	// GetLatestExcludingSrc 返回会话中发送方不是 src 的最新一条消息，没有时返回 ErrNotFound
+	GetLatestExcludingSrc(ctx context.Context, sessionID, src string) (*entity.Message, error)
+	// GetBySessionIDAfter 按时间顺序返回会话中时间戳(毫秒)晚于 afterTs 的消息
+	GetBySessionIDAfter(ctx context.Context, sessionID string, afterTs int64) ([]*entity.Message, error)
+	// GetOfflineByDst 按时间顺序返回发给 dst 且仍为离线状态的消息
+	GetOfflineByDst(ctx context.Context, dst string) ([]*entity.Message, error)
 	UpdateStatus(ctx context.Context, msgID string, status uint8) error
 }
 
@@ -18,7 +34,11 @@ type SessionRepository interface {
 	Create(ctx context.Context, session *entity.Session) error
 	GetByID(ctx context.Context, id string) (*entity.Session, error)
 	UpdateStatus(ctx context.Context, id string, status string) error
+	Close(ctx context.Context, id string, endTime time.Time) error
+	UpdateAgent(ctx context.Context, id string, agentID string) error
 	ListActive(ctx context.Context) ([]*entity.Session, error)
+	// ListByCID 按开始时间倒序列出客户的全部会话
+	ListByCID(ctx context.Context, cid string) ([]*entity.Session, error)
 }
 
 // UserRepository 用户仓储接口
@@ -27,4 +47,172 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*entity.User, error)
 	UpdateStatus(ctx context.Context, id string, status string) error
 	ListAgents(ctx context.Context) ([]*entity.User, error)
+	// GetByUsername 按登录名查找员工账号，访客不参与
+	GetByUsername(ctx context.Context, username string) (*entity.User, error)
+	Update(ctx context.Context, user *entity.User) error
+	Delete(ctx context.Context, id, deletedBy string) error
+	List(ctx context.Context, filter entity.UserFilter) ([]*entity.User, error)
+	// GetByExternalID 按主站客户 ID 查找已关联的客户记录
+	GetByExternalID(ctx context.Context, externalID string) (*entity.User, error)
+}
+
+// IdentityRepository 客户身份合并仓储接口
+type IdentityRepository interface {
+	// MergeCustomer 在一个事务内把 fromCID 的会话、消息、回应与评价迁移到 intoCID，
+	// 并软删除 fromCID 的记录；返回迁移的会话数
+	MergeCustomer(ctx context.Context, fromCID, intoCID, mergedBy string) (int64, error)
+}
+
+// ReactionRepository 表情回应仓储接口
+type ReactionRepository interface {
+	Add(ctx context.Context, reaction *entity.Reaction) error
+	Remove(ctx context.Context, msgID, userID, emoji string) error
+	ListByMessageID(ctx context.Context, msgID string) ([]*entity.Reaction, error)
+	ListBySessionID(ctx context.Context, sessionID string) ([]*entity.Reaction, error)
+}
+
+// RatingRepository 满意度评价仓储接口
+type RatingRepository interface {
+	Create(ctx context.Context, rating *entity.SessionRating) error
+	GetBySessionID(ctx context.Context, sessionID string) (*entity.SessionRating, error)
+	Aggregate(ctx context.Context, query entity.RatingQuery) ([]*entity.RatingAggregate, error)
+}
+
+// CannedResponseRepository 快捷回复仓储接口
+type CannedResponseRepository interface {
+	Create(ctx context.Context, canned *entity.CannedResponse) error
+	GetByID(ctx context.Context, id string) (*entity.CannedResponse, error)
+	Update(ctx context.Context, canned *entity.CannedResponse) error
+	Delete(ctx context.Context, id string, deletedBy string) error
+	List(ctx context.Context, filter entity.CannedResponseFilter) ([]*entity.CannedResponse, error)
+}
+
+// AuditRepository 审计日志仓储接口
+type AuditRepository interface {
+	Create(ctx context.Context, log *entity.AuditLog) error
+	List(ctx context.Context, query entity.AuditQuery) ([]*entity.AuditLog, error)
+}
+
+// TokenRepository 刷新令牌与访问令牌撤销列表仓储接口
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	// RevokeRefreshToken 撤销仍有效的刷新令牌；已撤销或不存在时返回 ErrNotFound，保证同一令牌只能轮换一次
+	RevokeRefreshToken(ctx context.Context, tokenHash, replacedBy string) error
+	RevokeRefreshFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+	RevokeAccessToken(ctx context.Context, token *entity.RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// PurgeExpired 删除在 before 之前过期的刷新令牌与撤销记录
+	PurgeExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// WebhookRepository webhook 订阅与投递日志仓储接口
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id, deletedBy string) error
+	ListSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error)
+	CreateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error)
+	// UpdateDelivery 保存一次投递尝试后的状态、重试次数与下次投递时间
+	UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// ListDueDeliveries 按计划时间返回到期待投递的记录
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, query entity.WebhookDeliveryQuery) ([]*entity.WebhookDelivery, error)
+}
+
+// OutboxRepository 事务性发件箱仓储接口，业务数据与待分发事件在同一事务内写入，
+// 任一写入失败则整体回滚
+type OutboxRepository interface {
+	// CreateMessage 保存消息并写入事件
+	CreateMessage(ctx context.Context, message *entity.Message, events ...*entity.OutboxEvent) error
+	// CreateSession 新建会话并写入事件
+	CreateSession(ctx context.Context, session *entity.Session, events ...*entity.OutboxEvent) error
+	// CloseSession 关闭会话并写入事件
+	CloseSession(ctx context.Context, sessionID string, endTime time.Time, events ...*entity.OutboxEvent) error
+	// AssignSession 改派会话负责客服并写入事件
+	AssignSession(ctx context.Context, sessionID, agentID string, events ...*entity.OutboxEvent) error
+	// ListDue 按写入顺序返回到期待分发的记录
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*entity.OutboxEvent, error)
+	// Update 保存一次分发后的状态、重试次数与下次分发时间
+	Update(ctx context.Context, event *entity.OutboxEvent) error
+	// CountPending 返回等待分发或重试的记录数
+	CountPending(ctx context.Context) (int, error)
+}
+
+// RetentionRepository 消息保留与归档仓储接口
+type RetentionRepository interface {
+	// ListDueSessions 按会话 ID 顺序返回到期待清理的已关闭会话，已清理且未被恢复的会话不再返回
+	ListDueSessions(ctx context.Context, query entity.RetentionQuery) ([]*entity.Session, error)
+	GetSessionRetention(ctx context.Context, sessionID string) (*entity.SessionRetention, error)
+	// SaveSessionRetention 新建或覆盖会话的保留处理记录
+	SaveSessionRetention(ctx context.Context, record *entity.SessionRetention) error
+	// DeleteMessages 删除会话中最多 limit 条消息及其表情回应，返回删除的条数
+	DeleteMessages(ctx context.Context, sessionID string, limit int) (int64, error)
+	// AnonymizeMessages 匿名化会话中最多 limit 条尚未匿名化的消息，返回处理的条数
+	AnonymizeMessages(ctx context.Context, sessionID string, limit int) (int64, error)
+	// RestoreMessages 按归档内容写回消息，已存在的消息被覆盖
+	RestoreMessages(ctx context.Context, messages []*entity.Message) error
+	CreateRun(ctx context.Context, run *entity.RetentionRun) error
+	UpdateRun(ctx context.Context, run *entity.RetentionRun) error
+	// ListRuns 按开始时间倒序返回最近的任务记录
+	ListRuns(ctx context.Context, limit int) ([]*entity.RetentionRun, error)
+}
+
+// ArchiveStore 冷存储接口，key 为以 / 分隔的相对路径
+type ArchiveStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get 读取归档文件，不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除归档文件，不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// PrivacyRepository 数据主体请求仓储接口
+type PrivacyRepository interface {
+	// EraseCustomer 在同一事务内擦除客户及已合并到该客户的访客记录: 用户与会话软删除，个人信息、
+	// 消息内容与评价留言被替换，表情回应、令牌、保留记录与含客户数据的事件被删除。
+	// 客户不存在时返回 ErrNotFound，重复擦除不报错
+	EraseCustomer(ctx context.Context, cid, erasedBy string) (*entity.ErasureResult, error)
+}
+
+// OfflineNotificationRepository 离线客户邮件提醒仓储接口
+type OfflineNotificationRepository interface {
+	// ListPendingCustomers 返回留有邮箱且有待提醒回复的客户: 发给客户的普通消息仍为离线状态，不晚于 sentBefore
+	// 且晚于上次提醒过的消息，上次提醒不晚于 notifiedBefore
+	ListPendingCustomers(ctx context.Context, sentBefore, notifiedBefore time.Time, limit int) ([]string, error)
+	// ListPendingReplies 按时间顺序返回发给客户、仍为离线状态且晚于 afterTs(毫秒)的普通消息
+	ListPendingReplies(ctx context.Context, cid string, afterTs int64) ([]*entity.Message, error)
+	// GetState 返回客户的提醒记录，未提醒过时返回 ErrNotFound
+	GetState(ctx context.Context, cid string) (*entity.OfflineNotification, error)
+	// SaveState 新建或覆盖客户的提醒记录
+	SaveState(ctx context.Context, state *entity.OfflineNotification) error
+}
+
+// ChannelIdentityRepository 渠道用户与客户对应关系仓储接口
+type ChannelIdentityRepository interface {
+	// Get 按渠道与服务商侧用户标识查找，不存在时返回 ErrNotFound
+	Get(ctx context.Context, channel, externalID string) (*entity.ChannelIdentity, error)
+	// GetByCID 返回客户在该渠道的标识，不存在时返回 ErrNotFound
+	GetByCID(ctx context.Context, channel, cid string) (*entity.ChannelIdentity, error)
+	// Create 新建对应关系，同一渠道用户已存在时返回 ErrDuplicateKey
+	Create(ctx context.Context, identity *entity.ChannelIdentity) error
+}
+
+// ChannelThreadRepository 渠道线索与会话对应关系仓储接口
+type ChannelThreadRepository interface {
+	// Save 记录线索，同一线索已存在时不报错
+	Save(ctx context.Context, thread *entity.ChannelThread) error
+	// FindSession 返回 threadIDs 中最近记录的一条线索，均不存在时返回 ErrNotFound
+	FindSession(ctx context.Context, channel string, threadIDs []string) (*entity.ChannelThread, error)
+	// ListBySession 按记录时间顺序列出会话的线索
+	ListBySession(ctx context.Context, channel, sessionID string) ([]*entity.ChannelThread, error)
+}
+
+// TxManager 工作单元接口。fn 内使用传入的 ctx 调用的仓储方法在同一事务内执行，
+// fn 返回错误时全部回滚；ctx 已处于事务中时直接加入该事务
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
@@ -0,0 +1,188 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+const (
+	// 单封上传邮件的大小上限
+	maxInboundEmail = 25 << 20
+	// 外发邮件 References 头保留的最大引用数，超出时保留第一封与最近的若干封
+	maxReferences = 10
+)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^\w.\-]+`)
+
+// EmailOptions 邮件渠道配置
+type EmailOptions struct {
+	Name    string // 渠道名，默认 email
+	Address string // 客服邮箱，作为回复的发件人，并用于生成 Message-ID
+	Secret  string // 通过 HTTP 上传原始邮件时的签名密钥
+}
+
+// EmailChannel 邮件渠道: 原始 MIME 邮件签名后 POST 到 /api/channels/<name>/inbound，
+// 或由 MaildirPoller 从本地 maildir 读取；按 In-Reply-To/References 归入原会话，
+// 客服回复以带线索引用的邮件发出
+type EmailChannel struct {
+	opts        EmailOptions
+	sender      usecase.MailSender
+	attachments *usecase.AttachmentUseCase
+	domain      string
+}
+
+var _ usecase.Channel = (*EmailChannel)(nil)
+
+// NewEmailChannel 创建邮件渠道
+func NewEmailChannel(opts EmailOptions, sender usecase.MailSender, attachments *usecase.AttachmentUseCase) *EmailChannel {
+	if opts.Name == "" {
+		opts.Name = "email"
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(opts.Address, "@"); at >= 0 {
+		domain = strings.Trim(opts.Address[at+1:], "<> ")
+	}
+	return &EmailChannel{opts: opts, sender: sender, attachments: attachments, domain: domain}
+}
+
+func (c *EmailChannel) Name() string {
+	return c.opts.Name
+}
+
+func (c *EmailChannel) ParseInbound(ctx context.Context, header http.Header, body []byte) ([]*entity.InboundMessage, error) {
+	if len(body) > maxInboundEmail {
+		return nil, fmt.Errorf("%w: body exceeds %d bytes", usecase.ErrInvalidChannelPayload, maxInboundEmail)
+	}
+	if err := utils.VerifyWebhookSignature(c.opts.Secret, header.Get(utils.WebhookSignatureHeader), body, webhookSignatureTolerance); err != nil {
+		return nil, fmt.Errorf("%w: %v", usecase.ErrChannelUnauthorized, err)
+	}
+	return c.Parse(ctx, body)
+}
+
+// Parse 解析一封原始邮件: 正文(去除引用与签名)为一条文本消息，每个附件保存后为一条图片或文件消息。
+// 自动回复与客服邮箱自己发出的邮件被忽略，返回空列表
+func (c *EmailChannel) Parse(ctx context.Context, raw []byte) ([]*entity.InboundMessage, error) {
+	email, err := parseEmail(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", usecase.ErrInvalidChannelPayload, err)
+	}
+	if email.From == nil || email.From.Address == "" {
+		return nil, fmt.Errorf("%w: email has no sender", usecase.ErrInvalidChannelPayload)
+	}
+	if email.AutoReply || strings.EqualFold(email.From.Address, c.opts.Address) {
+		return []*entity.InboundMessage{}, nil
+	}
+	if email.MessageID == "" {
+		// 没有 Message-ID 时以内容摘要代替，保证重复投递可以去重
+		sum := sha256.Sum256(raw)
+		email.MessageID = hex.EncodeToString(sum[:16]) + "@generated"
+	}
+
+	ts := entity.StringTimestamp(email.Date.UnixMilli())
+	newInbound := func(providerMsgID string, msg *entity.Message) *entity.InboundMessage {
+		msg.Ts = ts
+		return &entity.InboundMessage{
+			ProviderMsgID: providerMsgID,
+			ExternalID:    email.From.Address,
+			Name:          email.From.Name,
+			Email:         email.From.Address,
+			Message:       msg,
+			Threaded:      true,
+			ThreadID:      email.MessageID,
+			ThreadRefs:    email.References,
+			Subject:       email.Subject,
+		}
+	}
+
+	var inbound []*entity.InboundMessage
+	text := email.Text
+	if text == "" && len(email.Attachments) == 0 {
+		text = email.Subject
+	}
+	if text != "" {
+		inbound = append(inbound, newInbound(email.MessageID, &entity.Message{
+			ContentType: entity.ContentTypeText,
+			Content:     text,
+			Ext:         map[string]interface{}{"subject": email.Subject},
+		}))
+	}
+
+	sum := sha256.Sum256([]byte(email.MessageID))
+	dir := "email/" + hex.EncodeToString(sum[:16])
+	for i, att := range email.Attachments {
+		key := fmt.Sprintf("%s/%d-%s", dir, i+1, sanitizeFilename(att.Filename))
+		url, err := c.attachments.Save(ctx, key, bytes.NewReader(att.Data))
+		if err != nil {
+			return nil, fmt.Errorf("save attachment %q: %w", att.Filename, err)
+		}
+		contentType := uint8(entity.ContentTypeFile)
+		if strings.HasPrefix(att.ContentType, "image/") {
+			contentType = entity.ContentTypeImage
+		}
+		inbound = append(inbound, newInbound(fmt.Sprintf("%s#%d", email.MessageID, i+1), &entity.Message{
+			ContentType: contentType,
+			Content:     url,
+			Ext:         map[string]interface{}{"filename": att.Filename, "size": len(att.Data)},
+		}))
+	}
+	return inbound, nil
+}
+
+// sanitizeFilename 去除文件名中的路径与特殊字符
+func sanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Trim(unsafeFilenameChars.ReplaceAllString(name, "_"), "._")
+	if name == "" {
+		return "attachment"
+	}
+	if len(name) > 100 {
+		name = name[len(name)-100:]
+	}
+	return name
+}
+
+func (c *EmailChannel) Send(ctx context.Context, out *entity.OutboundMessage) error {
+	msg := out.Message
+	body := msg.Content
+	if msg.ContentType == entity.ContentTypeImage || msg.ContentType == entity.ContentTypeFile {
+		body = "Attachment: " + msg.Content
+	}
+
+	messageID := msg.MsgID + "@" + c.domain
+	mail := &entity.MailMessage{
+		From:       c.opts.Address,
+		To:         []string{out.ExternalID},
+		Subject:    replySubject(out.Subject),
+		TextBody:   body,
+		MessageID:  messageID,
+		References: trimReferences(out.ThreadRefs),
+	}
+	if n := len(out.ThreadRefs); n > 0 {
+		mail.InReplyTo = out.ThreadRefs[n-1]
+	}
+	if err := c.sender.Send(ctx, mail); err != nil {
+		return err
+	}
+	out.ThreadID = messageID
+	return nil
+}
+
+// trimReferences 限制 References 头的长度，保留线索的第一封与最近的邮件
+func trimReferences(refs []string) []string {
+	if len(refs) <= maxReferences {
+		return refs
+	}
+	trimmed := append([]string{refs[0]}, refs[len(refs)-maxReferences+1:]...)
+	return trimmed
+}
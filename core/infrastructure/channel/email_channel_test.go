@@ -0,0 +1,76 @@
+package channel
+
+import (
+	"context"
+	"testing"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+func TestEmailChannelThreadsReplyIntoSession(t *testing.T) {
+	ctx := context.Background()
+	messages := infrarepo.NewMemoryMessageRepository()
+	sessions := infrarepo.NewMemorySessionRepository()
+	users := infrarepo.NewMemoryUserRepository()
+	identities := infrarepo.NewMemoryChannelIdentityRepository()
+	chatUC := usecase.NewChatUseCase(messages, sessions, users, infrarepo.NewMemoryReactionRepository())
+	channelUC := usecase.NewChannelUseCase(chatUC, users, messages, identities, zap.NewNop())
+	channelUC.SetThreadRepository(infrarepo.NewMemoryChannelThreadRepository())
+	email := NewEmailChannel(EmailOptions{Address: "support@example.com"}, nil,
+		usecase.NewAttachmentUseCase(infrarepo.NewLocalArchiveStore(t.TempDir())))
+	if err := channelUC.Register(email); err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(fixture string) {
+		t.Helper()
+		inbound, err := email.Parse(ctx, emailFixture(t, fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := channelUC.Receive(ctx, email.Name(), inbound); err != nil {
+			t.Fatal(err)
+		}
+	}
+	customerSessions := func() []*entity.Session {
+		t.Helper()
+		identity, err := identities.Get(ctx, email.Name(), "ann@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := sessions.ListByCID(ctx, identity.CID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	// 首封邮件新建会话，正文与附件各为一条消息
+	receive("new_thread.eml")
+	list := customerSessions()
+	if len(list) != 1 {
+		t.Fatalf("customer has %d sessions after the first email, want 1", len(list))
+	}
+	// 回复邮件按 In-Reply-To/References 归入原会话，重复投递不重复写入
+	receive("reply.eml")
+	receive("reply.eml")
+	if list := customerSessions(); len(list) != 1 {
+		t.Fatalf("customer has %d sessions after the reply, want the reply threaded into 1", len(list))
+	}
+	stored, err := messages.GetBySessionID(ctx, list[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := map[string]bool{}
+	for _, msg := range stored {
+		if msg.ContentType == entity.ContentTypeText {
+			texts[msg.Content] = true
+		}
+	}
+	if len(stored) != 3 || len(texts) != 2 || !texts["The box was also damaged."] {
+		t.Fatalf("session has %d messages, text %v, want text, photo and the stripped reply", len(stored), texts)
+	}
+}
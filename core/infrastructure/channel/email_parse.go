@@ -0,0 +1,289 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 嵌套 multipart 的最大层数与单封邮件的最大附件数
+	maxMIMEDepth       = 10
+	maxEmailAttachment = 20
+)
+
+// parsedEmail 解析后的入站邮件
+type parsedEmail struct {
+	MessageID   string
+	References  []string // References 与 In-Reply-To 中的 Message-ID，不含尖括号
+	From        *mail.Address
+	Subject     string
+	Date        time.Time
+	AutoReply   bool   // 自动回复、退信等自动生成的邮件
+	Text        string // 去除引用的原文与签名后的正文
+	Attachments []emailAttachment
+}
+
+// emailAttachment 邮件附件
+type emailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+var (
+	msgIDPattern = regexp.MustCompile(`<([^<>\s]+)>`)
+
+	// 回复邮件中引用原文的开头，例如 "On Mon, 1 Jan 2026 at 10:00, Ann <ann@example.com> wrote:"
+	replyHeaderPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^on\s.+\swrote:$`),
+		regexp.MustCompile(`^.+写道[:：]$`),
+		regexp.MustCompile(`(?i)^-{2,}\s*original message\s*-{2,}$`),
+		regexp.MustCompile(`^_{10,}$`), // Outlook 在原文邮件头前插入的分隔线
+	}
+	outlookHeaderPattern = regexp.MustCompile(`(?i)^(from|发件人)[:：]\s*\S`)
+
+	htmlQuotePattern   = regexp.MustCompile(`(?i)<blockquote|<div[^>]+(class="gmail_quote"|id="divRplyFwdMsg")`)
+	htmlDropPattern    = regexp.MustCompile(`(?is)<(style|script|head)[^>]*>.*?</(style|script|head)>`)
+	htmlBreakPattern   = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|li|h[1-6])>`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]*>`)
+	blankLinesPattern  = regexp.MustCompile(`\n{3,}`)
+	replyPrefixPattern = regexp.MustCompile(`(?i)^\s*((re|fw|fwd|aw|回复|答复|转发)\s*[:：]\s*)+`)
+)
+
+// parseEmail 解析 RFC 5322 原始邮件
+func parseEmail(raw []byte) (*parsedEmail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	dec := &mime.WordDecoder{CharsetReader: charsetReader}
+	header := msg.Header
+
+	email := &parsedEmail{
+		MessageID: firstMsgID(header.Get("Message-Id")),
+		Date:      time.Now(),
+	}
+	if from, err := (&mail.AddressParser{WordDecoder: dec}).Parse(header.Get("From")); err == nil {
+		from.Address = strings.ToLower(from.Address)
+		email.From = from
+	}
+	if subject, err := dec.DecodeHeader(header.Get("Subject")); err == nil {
+		email.Subject = strings.TrimSpace(subject)
+	}
+	if date, err := header.Date(); err == nil {
+		email.Date = date
+	}
+	email.References = collectMsgIDs(header.Get("References"), header.Get("In-Reply-To"))
+	auto := strings.ToLower(header.Get("Auto-Submitted"))
+	email.AutoReply = (auto != "" && auto != "no") || header.Get("X-Autoreply") != "" ||
+		strings.EqualFold(header.Get("Precedence"), "auto_reply")
+
+	var text, htmlBody string
+	var walk func(h textproto.MIMEHeader, body io.Reader, depth int) error
+	walk = func(h textproto.MIMEHeader, body io.Reader, depth int) error {
+		if depth > maxMIMEDepth {
+			return errors.New("mime parts nested too deeply")
+		}
+		mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+		if err != nil {
+			mediaType, params = "text/plain", map[string]string{}
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			mr := multipart.NewReader(body, params["boundary"])
+			for {
+				part, err := mr.NextRawPart()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if err := walk(part.Header, part, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+
+		data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+		if err != nil {
+			return err
+		}
+		disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+		filename := dparams["filename"]
+		if filename == "" {
+			filename = params["name"]
+		}
+		if decoded, err := dec.DecodeHeader(filename); err == nil {
+			filename = decoded
+		}
+
+		switch {
+		case disposition != "attachment" && filename == "" && mediaType == "text/plain" && text == "":
+			text = decodeCharset(data, params["charset"])
+		case disposition != "attachment" && filename == "" && mediaType == "text/html" && htmlBody == "":
+			htmlBody = decodeCharset(data, params["charset"])
+		case disposition == "attachment" || filename != "" || !strings.HasPrefix(mediaType, "text/"):
+			if len(email.Attachments) >= maxEmailAttachment {
+				return nil
+			}
+			if filename == "" {
+				filename = fmt.Sprintf("part-%d", len(email.Attachments)+1)
+			}
+			email.Attachments = append(email.Attachments, emailAttachment{Filename: filename, ContentType: mediaType, Data: data})
+		}
+		return nil
+	}
+	if err := walk(textproto.MIMEHeader(header), msg.Body, 0); err != nil {
+		return nil, err
+	}
+
+	if text == "" && htmlBody != "" {
+		text = htmlToText(htmlBody)
+	}
+	email.Text = stripQuotedReply(text)
+	return email, nil
+}
+
+// firstMsgID 取头部中的第一个 Message-ID，不含尖括号
+func firstMsgID(value string) string {
+	if m := msgIDPattern.FindStringSubmatch(value); m != nil {
+		return m[1]
+	}
+	return strings.Trim(strings.TrimSpace(value), "<>")
+}
+
+// collectMsgIDs 按出现顺序去重收集各头部中的 Message-ID
+func collectMsgIDs(values ...string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, m := range msgIDPattern.FindAllStringSubmatch(value, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				ids = append(ids, m[1])
+			}
+		}
+	}
+	return ids
+}
+
+// decodeTransfer 解码 Content-Transfer-Encoding
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// charsetReader 供头部解码使用，支持 UTF-8 与 Latin-1，其他字符集按原字节读取
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decodeCharset(data, charset)), nil
+}
+
+// decodeCharset 将正文转换为 UTF-8；不支持的字符集中的无效字节被替换
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		if !utf8.Valid(data) {
+			runes := make([]rune, len(data))
+			for i, b := range data {
+				runes[i] = rune(b)
+			}
+			return string(runes)
+		}
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+// htmlToText 将 HTML 正文转换为纯文本，引用的原文一并去除
+func htmlToText(s string) string {
+	if loc := htmlQuotePattern.FindStringIndex(s); loc != nil {
+		s = s[:loc[0]]
+	}
+	s = htmlDropPattern.ReplaceAllString(s, "")
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, " ", " ")
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(s, "\n\n"))
+}
+
+// stripQuotedReply 去除回复邮件中引用的原文与签名，只保留客户新写的内容
+func stripQuotedReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var kept []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+		if lines[i] == "-- " || line == "--" {
+			break // 签名分隔线
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		if isReplyHeader(trimmed) {
+			break
+		}
+		// 邮件客户端可能把过长的 "On ... wrote:" 折成两行
+		if i+1 < len(lines) && strings.HasPrefix(strings.ToLower(trimmed), "on ") &&
+			isReplyHeader(trimmed+" "+strings.TrimSpace(lines[i+1])) {
+			break
+		}
+		// Outlook 的原文邮件头: 空行后紧跟 From:，下一行为 Sent:/Date:
+		if outlookHeaderPattern.MatchString(trimmed) && i+1 < len(lines) &&
+			(i == 0 || strings.TrimSpace(lines[i-1]) == "") && isOutlookHeaderLine(lines[i+1]) {
+			break
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+func isReplyHeader(line string) bool {
+	for _, pattern := range replyHeaderPatterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func isOutlookHeaderLine(line string) bool {
+	line = strings.ToLower(strings.TrimSpace(line))
+	for _, prefix := range []string{"sent:", "date:", "发送时间:", "发送时间："} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// replySubject 回复邮件的主题，去掉已有的 Re:/Fwd: 前缀后加上 Re:
+func replySubject(subject string) string {
+	subject = strings.TrimSpace(replyPrefixPattern.ReplaceAllString(subject, ""))
+	if subject == "" {
+		return "Re: Your support request"
+	}
+	return "Re: " + subject
+}
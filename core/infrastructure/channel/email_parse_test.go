@@ -0,0 +1,142 @@
+package channel
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// emailFixture 读取 http/test/email 下的示例邮件
+func emailFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("../../../http/test/email", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestParseEmail(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		messageID   string
+		refs        []string
+		from        string
+		name        string
+		subject     string
+		date        time.Time
+		text        string
+		attachments []string
+	}{
+		{
+			fixture:     "new_thread.eml",
+			messageID:   "order42-1@mail.example.com",
+			from:        "ann@example.com",
+			name:        "Ann Example",
+			subject:     "Order 42 arrived broken",
+			date:        time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC),
+			text:        "Hello, my order 42 arrived broken. A photo is attached.",
+			attachments: []string{"photo.png"},
+		},
+		{
+			fixture:   "reply.eml",
+			messageID: "order42-2@mail.example.com",
+			refs:      []string{"order42-1@mail.example.com"},
+			from:      "ann@example.com",
+			name:      "Ann Example",
+			subject:   "Re: Order 42 arrived broken",
+			date:      time.Date(2026, 10, 12, 10, 5, 0, 0, time.UTC),
+			text:      "The box was also damaged.",
+		},
+		{
+			// multipart/alternative 取纯文本部分，Latin-1 与 quoted-printable 解码，去除 Outlook 引用的原文
+			fixture:   "alternative.eml",
+			messageID: "cafe-2@mail.example.com",
+			refs:      []string{"cafe-0@mail.example.com", "cafe-1@mail.example.com"},
+			from:      "rene@example.com",
+			name:      "René Dupont",
+			subject:   "Re: Commande café",
+			date:      time.Date(2026, 10, 13, 7, 30, 0, 0, time.UTC),
+			text:      "Le café est arrivé froid.",
+		},
+		{
+			// 只有 HTML 正文时转换为纯文本，去除 gmail_quote 引用
+			fixture:   "html_reply.eml",
+			messageID: "invoice-2@mail.example.cn",
+			refs:      []string{"invoice-1@mail.example.cn"},
+			from:      "zhang@example.cn",
+			name:      "张三",
+			subject:   "回复: 发票问题",
+			date:      time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+			text:      "第二个问题：发票在哪里？\n谢谢 & 祝好",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			email, err := parseEmail(emailFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if email.MessageID != tt.messageID || !reflect.DeepEqual(email.References, tt.refs) {
+				t.Errorf("Message-ID = %q, references = %v, want %q, %v", email.MessageID, email.References, tt.messageID, tt.refs)
+			}
+			if email.From == nil || email.From.Address != tt.from || email.From.Name != tt.name {
+				t.Errorf("From = %+v, want %s <%s>", email.From, tt.name, tt.from)
+			}
+			if email.Subject != tt.subject || !email.Date.Equal(tt.date) {
+				t.Errorf("Subject = %q, Date = %s, want %q, %s", email.Subject, email.Date, tt.subject, tt.date)
+			}
+			if email.Text != tt.text {
+				t.Errorf("Text = %q, want %q", email.Text, tt.text)
+			}
+			var attachments []string
+			for _, att := range email.Attachments {
+				attachments = append(attachments, att.Filename)
+			}
+			if !reflect.DeepEqual(attachments, tt.attachments) {
+				t.Errorf("attachments = %v, want %v", attachments, tt.attachments)
+			}
+			if email.AutoReply {
+				t.Error("AutoReply = true, want false")
+			}
+		})
+	}
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "Thanks!\r\n", want: "Thanks!"},
+		{name: "quoted lines", text: "Yes, please.\n> Shall we refund?\n> Support", want: "Yes, please."},
+		{name: "signature", text: "See you.\n-- \nAnn\nCEO", want: "See you."},
+		{name: "on wrote", text: "Fine.\n\nOn Mon, 12 Oct 2026 at 10:00, Support <support@example.com> wrote:\nold text", want: "Fine."},
+		{name: "wrapped on wrote", text: "Fine.\n\nOn Mon, 12 Oct 2026 at 10:00, Support\n<support@example.com> wrote:\nold text", want: "Fine."},
+		{name: "chinese header", text: "好的\n\n客服 <support@example.com> 写道：\n原文", want: "好的"},
+		{name: "original message", text: "Done.\n-----Original Message-----\nFrom: Support", want: "Done."},
+		{name: "outlook header", text: "Done.\n\nFrom: Support <support@example.com>\nSent: Monday\nold text", want: "Done."},
+		{name: "from in body", text: "From: my phone\nIt broke again.", want: "From: my phone\nIt broke again."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripQuotedReply(tt.text); got != tt.want {
+				t.Errorf("stripQuotedReply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEmailAutoReply(t *testing.T) {
+	raw := "From: ann@example.com\r\nSubject: Out of office\r\nAuto-Submitted: auto-replied\r\n\r\nI am away.\r\n"
+	email, err := parseEmail([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !email.AutoReply {
+		t.Error("AutoReply = false, want true")
+	}
+}
@@ -0,0 +1,89 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// MaildirPoller 定期读取本地 maildir 的 new 目录中的邮件交给邮件渠道，
+// 处理成功或无法解析的邮件移入 cur 目录，其他错误保留在 new 中下次重试
+type MaildirPoller struct {
+	dir       string
+	channel   *EmailChannel
+	channelUC *usecase.ChannelUseCase
+	log       *zap.Logger
+}
+
+// NewMaildirPoller 创建 maildir 轮询器
+func NewMaildirPoller(dir string, channel *EmailChannel, channelUC *usecase.ChannelUseCase, log *zap.Logger) *MaildirPoller {
+	return &MaildirPoller{dir: dir, channel: channel, channelUC: channelUC, log: log}
+}
+
+// Run 按 interval 轮询，直到 ctx 结束
+func (p *MaildirPoller) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := p.PollOnce(ctx); err != nil {
+			p.log.Error("Failed to poll maildir", zap.String("dir", p.dir), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollOnce 处理 new 目录中的全部邮件，返回处理成功的邮件数
+func (p *MaildirPoller) PollOnce(ctx context.Context) (int, error) {
+	newDir := filepath.Join(p.dir, "new")
+	entries, err := os.ReadDir(newDir)
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	processed := 0
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return processed, ctx.Err()
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		file := filepath.Join(newDir, entry.Name())
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			p.log.Error("Failed to read mail", zap.String("file", file), zap.Error(err))
+			continue
+		}
+
+		inbound, err := p.channel.Parse(ctx, raw)
+		if err == nil {
+			_, err = p.channelUC.Receive(ctx, p.channel.Name(), inbound)
+		}
+		switch {
+		case err == nil:
+			processed++
+		case errors.Is(err, usecase.ErrInvalidChannelPayload):
+			p.log.Warn("Skipping invalid mail", zap.String("file", file), zap.Error(err))
+		default:
+			p.log.Error("Failed to process mail, will retry", zap.String("file", file), zap.Error(err))
+			continue
+		}
+		// maildir 约定: 已读邮件移入 cur 并加上 ":2,S" 标记
+		if err := os.Rename(file, filepath.Join(p.dir, "cur", entry.Name()+":2,S")); err != nil {
+			p.log.Error("Failed to move mail to cur", zap.String("file", file), zap.Error(err))
+		}
+	}
+	return processed, nil
+}
@@ -0,0 +1,186 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+const (
+	// 消息类型
+	WebhookMessageText  = "text"
+	WebhookMessageImage = "image"
+	WebhookMessageFile  = "file"
+
+	// WebhookOutboundEvent 发往服务商的请求的 X-Cland-Event 头
+	WebhookOutboundEvent = "message.outbound"
+
+	// 签名时间允许的偏差
+	webhookSignatureTolerance = 5 * time.Minute
+	// 单次回调的请求体上限
+	maxInboundBody = 1 << 20
+)
+
+// WebhookInbound 服务商回调的请求体
+type WebhookInbound struct {
+	Messages []WebhookMessage `json:"messages"`
+}
+
+// WebhookMessage 回调中的一条客户消息，或发往服务商的一条客服回复
+type WebhookMessage struct {
+	ID        string         `json:"id"`
+	From      *WebhookSender `json:"from,omitempty"` // 入站消息的发送方
+	To        string         `json:"to,omitempty"`   // 出站消息的接收方
+	Type      string         `json:"type"`           // text, image, file
+	Text      string         `json:"text,omitempty"`
+	URL       string         `json:"url,omitempty"` // 图片与文件的地址
+	Timestamp int64          `json:"timestamp"`     // Unix 毫秒
+}
+
+// WebhookSender 服务商侧的发送方
+type WebhookSender struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// WebhookOptions HTTP webhook 渠道配置
+type WebhookOptions struct {
+	Name        string // 渠道名
+	OutboundURL string // 客服回复 POST 到该地址
+	Secret      string // 双向请求的 HMAC 签名密钥，格式与事件订阅的签名相同
+	Timeout     time.Duration
+}
+
+// WebhookChannel 通用的 HTTP webhook 渠道: 服务商把客户消息签名后 POST 到
+// /api/channels/<name>/inbound，客服回复同样签名后 POST 到服务商的 OutboundURL
+type WebhookChannel struct {
+	opts   WebhookOptions
+	client *http.Client
+}
+
+var _ usecase.Channel = (*WebhookChannel)(nil)
+
+// NewWebhookChannel 创建 HTTP webhook 渠道，client 为 nil 时使用按 Timeout 配置的默认客户端
+func NewWebhookChannel(opts WebhookOptions, client *http.Client) *WebhookChannel {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if client == nil {
+		client = &http.Client{Timeout: opts.Timeout}
+	}
+	return &WebhookChannel{opts: opts, client: client}
+}
+
+func (c *WebhookChannel) Name() string {
+	return c.opts.Name
+}
+
+func (c *WebhookChannel) ParseInbound(ctx context.Context, header http.Header, body []byte) ([]*entity.InboundMessage, error) {
+	if len(body) > maxInboundBody {
+		return nil, fmt.Errorf("%w: body exceeds %d bytes", usecase.ErrInvalidChannelPayload, maxInboundBody)
+	}
+	if err := utils.VerifyWebhookSignature(c.opts.Secret, header.Get(utils.WebhookSignatureHeader), body, webhookSignatureTolerance); err != nil {
+		return nil, fmt.Errorf("%w: %v", usecase.ErrChannelUnauthorized, err)
+	}
+
+	var payload WebhookInbound
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", usecase.ErrInvalidChannelPayload, err)
+	}
+	inbound := make([]*entity.InboundMessage, 0, len(payload.Messages))
+	for _, m := range payload.Messages {
+		if m.From == nil || m.From.ID == "" {
+			return nil, fmt.Errorf("%w: message %q has no sender", usecase.ErrInvalidChannelPayload, m.ID)
+		}
+		msg, err := toEntity(m)
+		if err != nil {
+			return nil, err
+		}
+		inbound = append(inbound, &entity.InboundMessage{
+			ProviderMsgID: m.ID,
+			ExternalID:    m.From.ID,
+			Name:          m.From.Name,
+			Email:         m.From.Email,
+			Message:       msg,
+		})
+	}
+	return inbound, nil
+}
+
+// toEntity 将服务商消息规范化为 entity.Message
+func toEntity(m WebhookMessage) (*entity.Message, error) {
+	msg := &entity.Message{Ts: entity.StringTimestamp(m.Timestamp)}
+	switch m.Type {
+	case WebhookMessageText, "":
+		if m.Text == "" {
+			return nil, fmt.Errorf("%w: message %q has no text", usecase.ErrInvalidChannelPayload, m.ID)
+		}
+		msg.ContentType = entity.ContentTypeText
+		msg.Content = m.Text
+	case WebhookMessageImage, WebhookMessageFile:
+		if m.URL == "" {
+			return nil, fmt.Errorf("%w: message %q has no url", usecase.ErrInvalidChannelPayload, m.ID)
+		}
+		msg.ContentType = entity.ContentTypeImage
+		if m.Type == WebhookMessageFile {
+			msg.ContentType = entity.ContentTypeFile
+		}
+		msg.Content = m.URL
+	default:
+		return nil, fmt.Errorf("%w: unknown message type %q", usecase.ErrInvalidChannelPayload, m.Type)
+	}
+	return msg, nil
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, out *entity.OutboundMessage) error {
+	msg := out.Message
+	payload := WebhookMessage{
+		ID:        msg.MsgID,
+		To:        out.ExternalID,
+		Type:      WebhookMessageText,
+		Timestamp: int64(msg.Ts),
+	}
+	switch msg.ContentType {
+	case entity.ContentTypeImage:
+		payload.Type, payload.URL = WebhookMessageImage, msg.Content
+	case entity.ContentTypeFile:
+		payload.Type, payload.URL = WebhookMessageFile, msg.Content
+	default:
+		payload.Text = msg.Content
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.OutboundURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(utils.WebhookEventHeader, WebhookOutboundEvent)
+	// 服务商可据此对重试去重
+	req.Header.Set(utils.WebhookDeliveryHeader, msg.MsgID)
+	req.Header.Set(utils.WebhookSignatureHeader, utils.SignWebhook(c.opts.Secret, time.Now(), body))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return errors.New(resp.Status + ": " + string(snippet))
+	}
+	return nil
+}
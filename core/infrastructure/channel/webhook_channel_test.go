@@ -0,0 +1,89 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+func signedHeader(secret string, body []byte) http.Header {
+	header := http.Header{}
+	header.Set(utils.WebhookSignatureHeader, utils.SignWebhook(secret, time.Now(), body))
+	return header
+}
+
+func TestWebhookChannelParseInbound(t *testing.T) {
+	c := NewWebhookChannel(WebhookOptions{Name: "sms", Secret: "chsec"}, nil)
+	body, _ := json.Marshal(WebhookInbound{Messages: []WebhookMessage{
+		{ID: "p1", From: &WebhookSender{ID: "+15550100", Name: "Ann"}, Type: WebhookMessageText, Text: "Hi", Timestamp: 1700000000000},
+		{ID: "p2", From: &WebhookSender{ID: "+15550100"}, Type: WebhookMessageImage, URL: "https://cdn.example.com/a.png"},
+	}})
+
+	inbound, err := c.ParseInbound(context.Background(), signedHeader("chsec", body), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inbound) != 2 {
+		t.Fatalf("parsed %d messages, want 2", len(inbound))
+	}
+	if in := inbound[0]; in.ProviderMsgID != "p1" || in.ExternalID != "+15550100" || in.Name != "Ann" ||
+		in.Message.Content != "Hi" || in.Message.ContentType != entity.ContentTypeText || in.Message.Ts != 1700000000000 {
+		t.Errorf("text message parsed as %+v / %+v", in, in.Message)
+	}
+	if in := inbound[1]; in.Message.ContentType != entity.ContentTypeImage || in.Message.Content != "https://cdn.example.com/a.png" {
+		t.Errorf("image message parsed as %+v", in.Message)
+	}
+
+	if _, err := c.ParseInbound(context.Background(), signedHeader("other", body), body); !errors.Is(err, usecase.ErrChannelUnauthorized) {
+		t.Errorf("wrong secret: error = %v, want ErrChannelUnauthorized", err)
+	}
+	noSender, _ := json.Marshal(WebhookInbound{Messages: []WebhookMessage{{ID: "p3", Text: "Hi"}}})
+	if _, err := c.ParseInbound(context.Background(), signedHeader("chsec", noSender), noSender); !errors.Is(err, usecase.ErrInvalidChannelPayload) {
+		t.Errorf("missing sender: error = %v, want ErrInvalidChannelPayload", err)
+	}
+}
+
+func TestWebhookChannelSend(t *testing.T) {
+	var got WebhookMessage
+	var header http.Header
+	status := http.StatusNoContent
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := utils.VerifyWebhookSignature("chsec", r.Header.Get(utils.WebhookSignatureHeader), body, time.Minute); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		header = r.Header
+		_ = json.Unmarshal(body, &got)
+		w.WriteHeader(status)
+	}))
+	defer provider.Close()
+
+	c := NewWebhookChannel(WebhookOptions{Name: "sms", Secret: "chsec", OutboundURL: provider.URL}, nil)
+	out := &entity.OutboundMessage{ExternalID: "+15550100", Message: &entity.Message{
+		MsgID: "m1", ContentType: entity.ContentTypeText, Content: "Your parcel ships today.", Ts: 1700000000000,
+	}}
+	if err := c.Send(context.Background(), out); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "m1" || got.To != "+15550100" || got.Type != WebhookMessageText || got.Text != "Your parcel ships today." {
+		t.Errorf("provider received %+v", got)
+	}
+	if header.Get(utils.WebhookEventHeader) != WebhookOutboundEvent || header.Get(utils.WebhookDeliveryHeader) != "m1" {
+		t.Errorf("event=%q delivery=%q", header.Get(utils.WebhookEventHeader), header.Get(utils.WebhookDeliveryHeader))
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := c.Send(context.Background(), out); err == nil {
+		t.Error("Send succeeded although the provider returned 503")
+	}
+}
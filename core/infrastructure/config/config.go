@@ -4,22 +4,38 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"cland.org/cland-chat-service/common/utils"
 	"github.com/spf13/viper"
 )
 
 // Config 应用配置
 type Config struct {
-	Server ServerConfig `mapstructure:"server"`
-	WS     WSConfig     `mapstructure:"ws"`
-	Log    LogConfig    `mapstructure:"log"`
-	Redis  RedisConfig  `mapstructure:"redis"`
-	DB     DBConfig     `mapstructure:"db"`
+	Server     ServerConfig             `mapstructure:"server"`
+	WS         WSConfig                 `mapstructure:"ws"`
+	Log        LogConfig                `mapstructure:"log"`
+	Redis      RedisConfig              `mapstructure:"redis"`
+	DB         DBConfig                 `mapstructure:"db"`
+	Session    SessionConfig            `mapstructure:"session"`
+	Auth       AuthConfig               `mapstructure:"auth"`
+	Webhook    WebhookConfig            `mapstructure:"webhook"`
+	Outbox     OutboxConfig             `mapstructure:"outbox"`
+	Retention  RetentionConfig          `mapstructure:"retention"`
+	Mail       MailConfig               `mapstructure:"mail"`
+	Transcript TranscriptConfig         `mapstructure:"transcript"`
+	Offline    OfflineConfig            `mapstructure:"offline_notify"`
+	Channels   map[string]ChannelConfig `mapstructure:"channels"` // 外部接入渠道，键为渠道名
+	Attachment AttachmentConfig         `mapstructure:"attachments"`
+	Tracing    TracingConfig            `mapstructure:"tracing"`
+	Health     HealthConfig             `mapstructure:"health"`
 }
 
 // WSConfig WebSocket配置
 type WSConfig struct {
-	Port int `mapstructure:"port"`
+	Port            int           `mapstructure:"port"`             // 为 0 时 Socket.IO 挂载到主服务的 /socket.io/，否则单独监听该端口
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // 关闭时等待客户端断开的时间
 }
 
 // ServerConfig 服务器配置
@@ -28,6 +44,8 @@ type ServerConfig struct {
 	Host           string   `mapstructure:"host"`
 	Port           int      `mapstructure:"port"`
 	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// IdempotencyWindow Idempotency-Key 的去重窗口，超出后用同一键发送视为新请求
+	IdempotencyWindow time.Duration `mapstructure:"idempotency_window"`
 }
 
 // LogConfig 日志配置
@@ -58,6 +76,190 @@ type DBConfig struct {
 	Name     string `mapstructure:"name"`
 }
 
+// SessionConfig 会话配置
+type SessionConfig struct {
+	CheckInterval time.Duration                   `mapstructure:"check_interval"`
+	Timeouts      map[string]SessionTimeoutConfig `mapstructure:"timeouts"` // 按渠道配置, default 为缺省策略
+}
+
+// SessionTimeoutConfig 会话不活跃超时配置, 0 表示不启用
+type SessionTimeoutConfig struct {
+	WarnAfter  time.Duration `mapstructure:"warn_after"`
+	CloseAfter time.Duration `mapstructure:"close_after"`
+}
+
+// WebhookConfig webhook 投递配置, 0 表示使用默认值
+type WebhookConfig struct {
+	PollInterval   time.Duration `mapstructure:"poll_interval"` // 扫描到期投递记录的间隔
+	Timeout        time.Duration `mapstructure:"timeout"`       // 单次请求超时
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"` // 每次失败后翻倍
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+}
+
+// OutboxConfig 发件箱分发配置, 0 表示使用默认值
+type OutboxConfig struct {
+	PollInterval   time.Duration `mapstructure:"poll_interval"` // 兜底扫描间隔，正常情况下写入后立即分发
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"` // 每次失败后翻倍
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+}
+
+// RetentionConfig 消息保留配置，未配置策略时不清理任何消息; 0 表示使用默认值
+type RetentionConfig struct {
+	Interval     time.Duration                    `mapstructure:"interval"`
+	SessionBatch int                              `mapstructure:"session_batch"` // 每次查询的到期会话数
+	DeleteBatch  int                              `mapstructure:"delete_batch"`  // 每批删除或匿名化的消息数
+	BatchPause   time.Duration                    `mapstructure:"batch_pause"`   // 每批之间暂停，限制删除速度
+	RestoreTTL   time.Duration                    `mapstructure:"restore_ttl"`   // 恢复供查看的会话保留多久后重新清理
+	ArchiveDir   string                           `mapstructure:"archive_dir"`   // 本地归档目录
+	Policies     map[string]RetentionPolicyConfig `mapstructure:"policies"`      // 按渠道配置, default 为缺省策略
+}
+
+// RetentionPolicyConfig 渠道保留策略，会话关闭超过 after 后按 action(delete/anonymize)清理
+type RetentionPolicyConfig struct {
+	After   time.Duration `mapstructure:"after"`
+	Action  string        `mapstructure:"action"`
+	Archive bool          `mapstructure:"archive"` // 清理前归档到 archive_dir
+}
+
+// MailConfig SMTP 发信配置，未配置 host 时不发送邮件
+type MailConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"` // 通过环境变量 CLAND_MAIL_PASSWORD 注入
+	From     string `mapstructure:"from"`
+}
+
+// TranscriptConfig 会话记录配置
+type TranscriptConfig struct {
+	Timezone     string   `mapstructure:"timezone"`       // 请求未指定时区及邮件使用的 IANA 时区，默认 UTC
+	EmailOnClose bool     `mapstructure:"email_on_close"` // 会话关闭后将记录发送到客户邮箱
+	Bcc          []string `mapstructure:"bcc"`            // 合规存档邮箱
+}
+
+// OfflineConfig 离线客户邮件提醒配置，需同时配置 mail.host; 0 表示使用默认值
+type OfflineConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Interval    time.Duration `mapstructure:"interval"`     // 扫描间隔
+	Delay       time.Duration `mapstructure:"delay"`        // 客服回复后客户持续离线多久才提醒
+	MinInterval time.Duration `mapstructure:"min_interval"` // 同一客户两次提醒的最短间隔
+	ChatURL     string        `mapstructure:"chat_url"`     // 返回会话的链接，{sessionId} 替换为会话 ID
+}
+
+// ChannelConfig 外部接入渠道配置
+type ChannelConfig struct {
+	Type        string        `mapstructure:"type"`         // 渠道实现: webhook 或 email
+	OutboundURL string        `mapstructure:"outbound_url"` // webhook: 客服回复发往服务商的地址
+	Secret      string        `mapstructure:"secret"`       // 通过环境变量 CLAND_CHANNEL_<NAME>_SECRET 注入
+	Timeout     time.Duration `mapstructure:"timeout"`
+
+	Address      string        `mapstructure:"address"`       // email: 客服邮箱，回复邮件的发件人
+	Maildir      string        `mapstructure:"maildir"`       // email: 轮询的本地 maildir，为空时只接收 HTTP 上传
+	PollInterval time.Duration `mapstructure:"poll_interval"` // email: maildir 轮询间隔
+}
+
+// AttachmentConfig 渠道消息附件(如邮件附件)的本地存储
+type AttachmentConfig struct {
+	Dir string `mapstructure:"dir"`
+}
+
+// TracingConfig 分布式追踪，Exporter 为 none、stdout 或 file
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`
+	File        string  `mapstructure:"file"`         // file 导出器写入的 JSON Lines 文件
+	SampleRatio float64 `mapstructure:"sample_ratio"` // 新追踪的采样比例(默认 1)，延续调用方追踪时沿用其采样标记
+}
+
+// HealthConfig 存活与就绪检查
+type HealthConfig struct {
+	CheckTimeout  time.Duration `mapstructure:"check_timeout"`  // 单个组件检查的超时时间
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"` // 就绪检查变为未就绪后等待多久再停止接收请求，留给负载均衡摘除实例
+}
+
+// AuthConfig 令牌配置
+type AuthConfig struct {
+	Issuer          string         `mapstructure:"issuer"`
+	AccessTokenTTL  time.Duration  `mapstructure:"access_token_ttl"`
+	RefreshTokenTTL time.Duration  `mapstructure:"refresh_token_ttl"`
+	SigningKey      string         `mapstructure:"signing_key"` // 签发新令牌使用的密钥 ID，其余密钥只用于验证
+	Keys            []JWTKeyConfig `mapstructure:"keys"`
+	Identity        IdentityConfig `mapstructure:"identity"`
+}
+
+// IdentityConfig 主站身份令牌校验配置。访客登录主站后，主站用共享密钥或私钥签发短期令牌，
+// 本服务只持有验证密钥；未配置密钥时不启用身份关联
+type IdentityConfig struct {
+	Issuer   string         `mapstructure:"issuer"`
+	Audience string         `mapstructure:"audience"`
+	MaxAge   time.Duration  `mapstructure:"max_age"` // 令牌签发后的最长可用时间，防止截获的令牌被长期重放
+	Keys     []JWTKeyConfig `mapstructure:"keys"`
+}
+
+// JWTKeyConfig JWT 密钥配置。HS256 使用 secret；RS256/EdDSA 使用 PEM 文件，
+// 轮换后仅用于验证旧令牌的密钥可以只配置公钥
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"id"` // 写入令牌头的 kid
+	Algorithm      string `mapstructure:"algorithm"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+// JWTOptions 读取密钥文件，生成令牌签发与验证配置
+func (c AuthConfig) JWTOptions() (utils.JWTOptions, error) {
+	opts := utils.JWTOptions{
+		Issuer:       c.Issuer,
+		TTL:          c.AccessTokenTTL,
+		SigningKeyID: c.SigningKey,
+	}
+	keys, err := loadJWTKeys(c.Keys)
+	opts.Keys = keys
+	return opts, err
+}
+
+// Enabled 是否配置了主站身份令牌的验证密钥
+func (c IdentityConfig) Enabled() bool {
+	return len(c.Keys) > 0
+}
+
+// JWTOptions 生成仅用于验证主站身份令牌的配置
+func (c IdentityConfig) JWTOptions() (utils.JWTOptions, error) {
+	opts := utils.JWTOptions{
+		Issuer:   c.Issuer,
+		Audience: c.Audience,
+	}
+	keys, err := loadJWTKeys(c.Keys)
+	opts.Keys = keys
+	return opts, err
+}
+
+// loadJWTKeys 读取 PEM 密钥文件
+func loadJWTKeys(configs []JWTKeyConfig) ([]utils.JWTKeyOptions, error) {
+	var keys []utils.JWTKeyOptions
+	for _, k := range configs {
+		key := utils.JWTKeyOptions{
+			ID:        k.ID,
+			Algorithm: k.Algorithm,
+			Secret:    []byte(k.Secret),
+		}
+		var err error
+		if k.PrivateKeyFile != "" {
+			if key.PrivateKeyPEM, err = os.ReadFile(k.PrivateKeyFile); err != nil {
+				return nil, fmt.Errorf("read private key of %q: %v", k.ID, err)
+			}
+		}
+		if k.PublicKeyFile != "" {
+			if key.PublicKeyPEM, err = os.ReadFile(k.PublicKeyFile); err != nil {
+				return nil, fmt.Errorf("read public key of %q: %v", k.ID, err)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 // Load 加载配置
 func Load() (*Config, error) {
 	// 设置配置文件路径
@@ -70,6 +272,9 @@ func Load() (*Config, error) {
 	viper.SetEnvPrefix("CLAND")
 	viper.AutomaticEnv()
 
+	// 未配置追踪采样比例时全部采样
+	viper.SetDefault("tracing.sample_ratio", 1)
+
 	// 读取配置文件
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config file: %v", err)
@@ -110,4 +315,33 @@ func overrideFromEnv(cfg *Config) {
 	if host := os.Getenv("CLAND_SERVER_HOST"); host != "" {
 		cfg.Server.Host = host
 	}
+
+	// 密钥不写入配置文件，通过环境变量注入当前签名密钥的 secret
+	if secret := os.Getenv("CLAND_AUTH_SECRET"); secret != "" {
+		for i := range cfg.Auth.Keys {
+			if cfg.Auth.Keys[i].ID == cfg.Auth.SigningKey {
+				cfg.Auth.Keys[i].Secret = secret
+			}
+		}
+	}
+
+	if password := os.Getenv("CLAND_MAIL_PASSWORD"); password != "" {
+		cfg.Mail.Password = password
+	}
+
+	for name, channel := range cfg.Channels {
+		if secret := os.Getenv("CLAND_CHANNEL_" + strings.ToUpper(name) + "_SECRET"); secret != "" {
+			channel.Secret = secret
+			cfg.Channels[name] = channel
+		}
+	}
+
+	// 与主站共享的 HS256 密钥同样只通过环境变量注入
+	if secret := os.Getenv("CLAND_IDENTITY_SECRET"); secret != "" {
+		for i := range cfg.Auth.Identity.Keys {
+			if cfg.Auth.Identity.Keys[i].Algorithm == utils.JWTAlgHS256 {
+				cfg.Auth.Identity.Keys[i].Secret = secret
+			}
+		}
+	}
 }
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// LoginRequest represents username/password credentials
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AccountRequest represents a staff account to create or update
+type AccountRequest struct {
+	Username           string   `json:"username"`
+	Password           string   `json:"password"` // required on create; resets the password on update when set
+	DisplayName        string   `json:"displayName"`
+	Email              string   `json:"email"`
+	Role               string   `json:"role"` // agent, supervisor or admin
+	Skills             []string `json:"skills"`
+	MaxConcurrentChats int      `json:"maxConcurrentChats"` // 0 means unlimited
+}
+
+// ChangePasswordRequest represents a self-service password change
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+type AccountHandler struct {
+	accountUC *usecase.AccountUseCase
+}
+
+func NewAccountHandler(accountUC *usecase.AccountUseCase) *AccountHandler {
+	return &AccountHandler{accountUC: accountUC}
+}
+
+// Login exchanges staff credentials for a JWT
+// @Summary Staff login
+// @Description Verifies the username and password of an agent, supervisor or admin and returns a JWT carrying the role
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handler.LoginRequest true "Credentials"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/auth/login [post]
+func (h *AccountHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Username == "" || req.Password == "" {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	result, err := h.accountUC.Login(c.Request.Context(), req.Username, req.Password)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// ListUsers lists staff accounts
+// @Summary List staff accounts
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param role query string false "agent, supervisor or admin"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/users [get]
+func (h *AccountHandler) ListUsers(c *gin.Context) {
+	users, err := h.accountUC.List(c.Request.Context(), c.Query("role"))
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	if users == nil {
+		users = []*entity.User{}
+	}
+	c.JSON(http.StatusOK, response.Success(users))
+}
+
+// CreateUser creates a staff account
+// @Summary Create staff account
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param request body handler.AccountRequest true "Account"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/users [post]
+func (h *AccountHandler) CreateUser(c *gin.Context) {
+	var req AccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	user := req.toEntity()
+	if err := h.accountUC.Create(c.Request.Context(), user, req.Password); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(user))
+}
+
+// GetCurrentUser returns the account of the caller
+// @Summary Get own account
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Bearer token of an agent, supervisor or admin"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} ErrorResponse
+// @Router /api/users/me [get]
+func (h *AccountHandler) GetCurrentUser(c *gin.Context) {
+	user, err := h.accountUC.Get(c.Request.Context(), principal(c).UserID)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(user))
+}
+
+// ChangePassword changes the caller's password
+// @Summary Change own password
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of an agent, supervisor or admin"
+// @Param request body handler.ChangePasswordRequest true "Old and new password"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/users/me/password [put]
+func (h *AccountHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	if err := h.accountUC.ChangePassword(c.Request.Context(), req.OldPassword, req.NewPassword); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(nil))
+}
+
+// GetUser returns a staff account
+// @Summary Get staff account
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "User ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/users/{id} [get]
+func (h *AccountHandler) GetUser(c *gin.Context) {
+	user, err := h.accountUC.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(user))
+}
+
+// UpdateUser updates a staff account's profile and role, and optionally resets its password
+// @Summary Update staff account
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "User ID"
+// @Param request body handler.AccountRequest true "Account"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/users/{id} [put]
+func (h *AccountHandler) UpdateUser(c *gin.Context) {
+	var req AccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	update := req.toEntity()
+	update.ID = c.Param("id")
+	user, err := h.accountUC.Update(c.Request.Context(), update, req.Password)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(user))
+}
+
+// DeleteUser deletes a staff account
+// @Summary Delete staff account
+// @Tags users
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "User ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/users/{id} [delete]
+func (h *AccountHandler) DeleteUser(c *gin.Context) {
+	if err := h.accountUC.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(nil))
+}
+
+func (r AccountRequest) toEntity() *entity.User {
+	return &entity.User{
+		Username:           r.Username,
+		DisplayName:        r.DisplayName,
+		Email:              r.Email,
+		Role:               r.Role,
+		Skills:             r.Skills,
+		MaxConcurrentChats: r.MaxConcurrentChats,
+	}
+}
+
+// writeAccountError 将账号相关错误映射为HTTP响应
+func writeAccountError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidAccount):
+		writeError(c, http.StatusBadRequest, cland_errors.ErrAccountInvalid)
+	case errors.Is(err, usecase.ErrInvalidCredentials):
+		writeError(c, http.StatusUnauthorized, cland_errors.ErrInvalidCredentials)
+	case errors.Is(err, usecase.ErrAccountNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrAccountNotFound)
+	case errors.Is(err, usecase.ErrUsernameTaken):
+		writeError(c, http.StatusConflict, cland_errors.ErrUsernameTaken)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
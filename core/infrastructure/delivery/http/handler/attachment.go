@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type AttachmentHandler struct {
+	attachmentUC *usecase.AttachmentUseCase
+}
+
+func NewAttachmentHandler(attachmentUC *usecase.AttachmentUseCase) *AttachmentHandler {
+	return &AttachmentHandler{attachmentUC: attachmentUC}
+}
+
+// GetAttachment downloads an attachment received through a channel
+// @Summary Download attachment
+// @Description Streams a file stored from a channel message, e.g. an email attachment.
+// @Description Attachment messages carry this URL as their content. Staff only
+// @Tags channels
+// @Produce octet-stream
+// @Param key path string true "Attachment key"
+// @Success 200 {file} file
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/attachments/{key} [get]
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	key := c.Param("key")
+	file, err := h.attachmentUC.Open(c.Request.Context(), key)
+	if err != nil {
+		if writeAuthError(c, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(c, http.StatusNotFound, cland_errors.ErrAttachmentNotFound)
+			return
+		}
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	_, _ = io.Copy(c.Writer, file)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshTokenRequest carries the refresh token returned by login, init or a previous refresh
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type AuthHandler struct {
+	authUC *usecase.AuthUseCase
+}
+
+func NewAuthHandler(authUC *usecase.AuthUseCase) *AuthHandler {
+	return &AuthHandler{authUC: authUC}
+}
+
+// RefreshToken exchanges a refresh token for a new token pair
+// @Summary Refresh tokens
+// @Description Rotates the refresh token: the old one stops working immediately. Presenting an already rotated
+// @Description refresh token again revokes every token of that login, forcing the user to sign in again
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body handler.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/auth/refresh [post]
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	tokens, err := h.authUC.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		writeTokenError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(tokens))
+}
+
+// Logout revokes the current access token and the login its refresh token belongs to
+// @Summary Logout
+// @Description Both the bearer token and the refresh token are optional; send whichever the client still holds
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string false "Bearer access token to revoke"
+// @Param request body handler.RefreshTokenRequest false "Refresh token to revoke"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Router /api/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshTokenRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			writeError(c, http.StatusBadRequest, cland_errors.Err400)
+			return
+		}
+	}
+	accessToken, _ := utils.BearerToken(c.GetHeader("Authorization"))
+
+	if err := h.authUC.Logout(c.Request.Context(), accessToken, req.RefreshToken); err != nil {
+		writeTokenError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(nil))
+}
+
+// writeTokenError 将令牌相关错误映射为HTTP响应
+func writeTokenError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRefreshToken):
+		writeError(c, http.StatusUnauthorized, cland_errors.ErrRefreshTokenInvalid)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
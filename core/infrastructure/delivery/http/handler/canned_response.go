@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"cland.org/cland-chat-service/common/constants"
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// CannedResponseRequest represents a canned response to create or update
+type CannedResponseRequest struct {
+	Shortcut string `json:"shortcut"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	Scope    string `json:"scope"` // team (default) or personal; ignored on update
+	Team     string `json:"team"`  // ignored on update
+}
+
+type CannedResponseHandler struct {
+	cannedUC *usecase.CannedResponseUseCase
+}
+
+func NewCannedResponseHandler(cannedUC *usecase.CannedResponseUseCase) *CannedResponseHandler {
+	return &CannedResponseHandler{cannedUC: cannedUC}
+}
+
+// agentTeam 读取请求头中的客服团队
+func agentTeam(c *gin.Context) string {
+	return c.GetHeader(constants.KEY_AGENT_TEAM)
+}
+
+// ListCannedResponses lists or searches the canned responses visible to an agent
+// @Summary List canned responses
+// @Description Lists the agent's personal canned responses plus team ones (global or of the agent's team),
+// @Description optionally filtered by shortcut prefix
+// @Tags canned-responses
+// @Produce json
+// @Param Authorization header string true "Bearer token of an agent, supervisor or admin"
+// @Param cland-agent-team header string false "Agent team"
+// @Param shortcut query string false "Shortcut prefix, e.g. /ref"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/canned-responses [get]
+func (h *CannedResponseHandler) ListCannedResponses(c *gin.Context) {
+	team := agentTeam(c)
+
+	list, err := h.cannedUC.Search(c.Request.Context(), team, c.Query("shortcut"))
+	if err != nil {
+		writeCannedResponseError(c, err)
+		return
+	}
+	if list == nil {
+		list = []*entity.CannedResponse{}
+	}
+	c.JSON(http.StatusOK, response.Success(list))
+}
+
+// SearchCannedResponses is the typeahead endpoint used while the agent types a shortcut
+// @Summary Search canned responses by shortcut
+// @Description Same as listing with a shortcut prefix; q may omit the leading '/'
+// @Tags canned-responses
+// @Produce json
+// @Param Authorization header string true "Bearer token of an agent, supervisor or admin"
+// @Param cland-agent-team header string false "Agent team"
+// @Param q query string true "Shortcut prefix"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/canned-responses/search [get]
+func (h *CannedResponseHandler) SearchCannedResponses(c *gin.Context) {
+	team := agentTeam(c)
+
+	prefix := c.Query("q")
+	if prefix == "" {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+	if prefix[0] != '/' {
+		prefix = "/" + prefix
+	}
+
+	list, err := h.cannedUC.Search(c.Request.Context(), team, prefix)
+	if err != nil {
+		writeCannedResponseError(c, err)
+		return
+	}
+	if list == nil {
+		list = []*entity.CannedResponse{}
+	}
+	c.JSON(http.StatusOK, response.Success(list))
+}
+
+// GetCannedResponse returns a canned response
+// @Summary Get canned response
+// @Tags canned-responses
+// @Produce json
+// @Param Authorization header string true "Bearer token of an agent, supervisor or admin"
+// @Param cland-agent-team header string false "Agent team"
+// @Param id path string true "Canned response ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} ErrorResponse
+// @Router /api/canned-responses/{id} [get]
+func (h *CannedResponseHandler) GetCannedResponse(c *gin.Context) {
+	team := agentTeam(c)
+
+	canned, err := h.cannedUC.Get(c.Request.Context(), c.Param("id"), team)
+	if err != nil {
+		writeCannedResponseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(canned))
+}
+
+// CreateCannedResponse creates a team or personal canned response
+// @Summary Create canned response
+// @Description Body may reference variables such as {{customer.name}}, {{agent.name}} or {{session.id}},
+// @Description with an optional default: {{customer.name|there}}
+// @Tags canned-responses
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of an agent, supervisor or admin"
+// @Param request body handler.CannedResponseRequest true "Canned response"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/canned-responses [post]
+func (h *CannedResponseHandler) CreateCannedResponse(c *gin.Context) {
+	var req CannedResponseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	canned := &entity.CannedResponse{
+		Shortcut: req.Shortcut,
+		Title:    req.Title,
+		Body:     req.Body,
+		Scope:    req.Scope,
+		Team:     req.Team,
+	}
+	if err := h.cannedUC.Create(c.Request.Context(), canned); err != nil {
+		writeCannedResponseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(canned))
+}
+
+// UpdateCannedResponse updates the shortcut, title and body of a canned response
+// @Summary Update canned response
+// @Tags canned-responses
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of an agent, supervisor or admin"
+// @Param cland-agent-team header string false "Agent team"
+// @Param id path string true "Canned response ID"
+// @Param request body handler.CannedResponseRequest true "Canned response"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/canned-responses/{id} [put]
+func (h *CannedResponseHandler) UpdateCannedResponse(c *gin.Context) {
+	team := agentTeam(c)
+
+	var req CannedResponseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	canned, err := h.cannedUC.Update(c.Request.Context(), &entity.CannedResponse{
+		ID:       c.Param("id"),
+		Shortcut: req.Shortcut,
+		Title:    req.Title,
+		Body:     req.Body,
+	}, team)
+	if err != nil {
+		writeCannedResponseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(canned))
+}
+
+// DeleteCannedResponse deletes a canned response
+// @Summary Delete canned response
+// @Tags canned-responses
+// @Produce json
+// @Param Authorization header string true "Bearer token of an agent, supervisor or admin"
+// @Param cland-agent-team header string false "Agent team"
+// @Param id path string true "Canned response ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} ErrorResponse
+// @Router /api/canned-responses/{id} [delete]
+func (h *CannedResponseHandler) DeleteCannedResponse(c *gin.Context) {
+	team := agentTeam(c)
+
+	if err := h.cannedUC.Delete(c.Request.Context(), c.Param("id"), team); err != nil {
+		writeCannedResponseError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(nil))
+}
+
+// writeCannedResponseError 将快捷回复相关错误映射为HTTP响应
+func writeCannedResponseError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidCannedResponse):
+		writeError(c, http.StatusBadRequest, cland_errors.ErrCannedResponseInvalid)
+	case errors.Is(err, usecase.ErrCannedShortcutTaken):
+		writeError(c, http.StatusConflict, cland_errors.ErrCannedShortcutTaken)
+	case errors.Is(err, usecase.ErrCannedResponseNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrCannedResponseNotFound)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type ChannelHandler struct {
+	channelUC *usecase.ChannelUseCase
+}
+
+func NewChannelHandler(channelUC *usecase.ChannelUseCase) *ChannelHandler {
+	return &ChannelHandler{channelUC: channelUC}
+}
+
+// ReceiveChannelMessages accepts customer messages pushed by a channel provider
+// @Summary Receive channel messages
+// @Description Called by the provider of a registered channel. The request is authenticated by the channel
+// @Description itself (the webhook channel checks the X-Cland-Signature header), not by a JWT.
+// @Description Messages are attached to the customer's active session on the channel, redelivered
+// @Description provider messages are accepted without being stored twice
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param channel path string true "Channel name"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/channels/{channel}/inbound [post]
+func (h *ChannelHandler) ReceiveChannelMessages(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.ErrChannelPayloadInvalid)
+		return
+	}
+
+	accepted, err := h.channelUC.HandleInbound(c.Request.Context(), c.Param("channel"), c.Request.Header, body)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, response.Success(gin.H{"accepted": accepted}))
+	case errors.Is(err, usecase.ErrChannelNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrChannelNotFound)
+	case errors.Is(err, usecase.ErrChannelUnauthorized):
+		writeError(c, http.StatusUnauthorized, cland_errors.ErrChannelSignatureInvalid)
+	case errors.Is(err, usecase.ErrInvalidChannelPayload):
+		writeError(c, http.StatusBadRequest, cland_errors.ErrChannelPayloadInvalid)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse represents an error response
+type ErrorResponse = response.Response
+
+// writeError 以统一的业务错误码返回错误响应
+func writeError(c *gin.Context, status int, e cland_errors.Error) {
+	c.JSON(status, response.Response{
+		Code: e.Code,
+		Msg:  e.Msg,
+	})
+}
+
+// writeAuthError 将认证与授权错误映射为 401/403，非授权错误返回 false
+func writeAuthError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, usecase.ErrUnauthenticated):
+		writeError(c, http.StatusUnauthorized, cland_errors.Err401)
+	case errors.Is(err, usecase.ErrForbidden):
+		writeError(c, http.StatusForbidden, cland_errors.ErrRoleForbidden)
+	case errors.Is(err, usecase.ErrSenderMismatch):
+		writeError(c, http.StatusForbidden, cland_errors.ErrSenderMismatch)
+	case errors.Is(err, usecase.ErrSessionAccessDenied):
+		writeError(c, http.StatusForbidden, cland_errors.ErrSessionAccessDenied)
+	case errors.Is(err, usecase.ErrAckForbidden):
+		writeError(c, http.StatusForbidden, cland_errors.ErrAckForbidden)
+	default:
+		return false
+	}
+	return true
+}
+
+// principal 返回请求身份(由认证中间件写入)
+func principal(c *gin.Context) entity.Principal {
+	return usecase.PrincipalFromContext(c.Request.Context())
+}
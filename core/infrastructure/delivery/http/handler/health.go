@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthUC *usecase.HealthUseCase
+}
+
+func NewHealthHandler(healthUC *usecase.HealthUseCase) *HealthHandler {
+	return &HealthHandler{healthUC: healthUC}
+}
+
+// Livez reports whether the process should keep running
+// @Summary Liveness probe
+// @Description Runs only the checks for failures the process cannot recover from, such as the
+// @Description WebSocket listener failing to bind. Returns 503 when the process should be restarted
+// @Tags health
+// @Produce json
+// @Success 200 {object} usecase.HealthReport
+// @Failure 503 {object} usecase.HealthReport
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	writeHealthReport(c, h.healthUC.Liveness(c.Request.Context()))
+}
+
+// Readyz reports whether the service can take traffic
+// @Summary Readiness probe
+// @Description Checks every component (database, WebSocket listener, outbox, schema version) and reports
+// @Description status and latency per component. Returns 503 when a component is down or the server is shutting down
+// @Tags health
+// @Produce json
+// @Success 200 {object} usecase.HealthReport
+// @Failure 503 {object} usecase.HealthReport
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	writeHealthReport(c, h.healthUC.Readiness(c.Request.Context()))
+}
+
+func writeHealthReport(c *gin.Context, report usecase.HealthReport) {
+	c.Header("Cache-Control", "no-store")
+	if !report.Up() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
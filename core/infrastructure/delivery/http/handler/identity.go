@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// IdentifyRequest carries the short-lived identity token signed by the main site
+type IdentifyRequest struct {
+	Token string `json:"token"`
+}
+
+type IdentityHandler struct {
+	identityUC *usecase.IdentityUseCase
+}
+
+func NewIdentityHandler(identityUC *usecase.IdentityUseCase) *IdentityHandler {
+	return &IdentityHandler{identityUC: identityUC}
+}
+
+// Identify links the calling visitor to a customer of the main site
+// @Summary Identify visitor
+// @Description Verifies an identity token issued by the main site (sub = external customer ID, optional name and email)
+// @Description and links the visitor's CID to that customer. If the customer already has a record, the visitor's
+// @Description sessions and messages are merged into it. The response carries the canonical CID and new tokens;
+// @Description the client must switch to them and reconnect the socket
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of the visitor"
+// @Param request body handler.IdentifyRequest true "Identity token"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /api/identify [post]
+func (h *IdentityHandler) Identify(c *gin.Context) {
+	var req IdentifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	result, err := h.identityUC.Identify(c.Request.Context(), req.Token)
+	if err != nil {
+		writeIdentityError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// writeIdentityError 将身份关联错误映射为HTTP响应
+func writeIdentityError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidIdentityToken):
+		writeError(c, http.StatusUnauthorized, cland_errors.ErrIdentityTokenInvalid)
+	case errors.Is(err, usecase.ErrTokenInvalid):
+		writeError(c, http.StatusUnauthorized, cland_errors.ErrTokenInvalid)
+	case errors.Is(err, usecase.ErrIdentityDisabled):
+		writeError(c, http.StatusNotImplemented, cland_errors.ErrIdentityDisabled)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
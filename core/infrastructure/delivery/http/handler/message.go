@@ -1,14 +1,18 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"cland.org/cland-chat-service/common/constants"
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/common/utils"
 	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
 	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
 	"cland.org/cland-chat-service/core/usecase"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // Message represents a chat message
@@ -23,6 +27,7 @@ type Message struct {
 	Status      entity.Status          `json:"status"`
 	Ts          string                 `json:"ts"`
 	Ext         map[string]interface{} `json:"ext"`
+	ReplyTo     string                 `json:"replyTo,omitempty"`
 }
 
 // MessageResponse represents the response structure for message operations
@@ -32,11 +37,16 @@ type MessageResponse struct {
 }
 
 type MessageHandler struct {
-	chatUC *usecase.ChatUseCase
+	chatUC            *usecase.ChatUseCase
+	idempotencyWindow time.Duration
 }
 
-func NewMessageHandler(chatUC *usecase.ChatUseCase) *MessageHandler {
-	return &MessageHandler{chatUC: chatUC}
+// NewMessageHandler 创建消息处理器，idempotencyWindow 不大于 0 时使用默认的 24 小时
+func NewMessageHandler(chatUC *usecase.ChatUseCase, idempotencyWindow time.Duration) *MessageHandler {
+	if idempotencyWindow <= 0 {
+		idempotencyWindow = time.Duration(constants.DefaultIdempotencyKeyWindow) * time.Second
+	}
+	return &MessageHandler{chatUC: chatUC, idempotencyWindow: idempotencyWindow}
 }
 
 // GetOfflineMessages retrieves offline messages for a user
@@ -45,25 +55,25 @@ func NewMessageHandler(chatUC *usecase.ChatUseCase) *MessageHandler {
 // @Tags messages
 // @Accept json
 // @Produce json
-// @Param userId query string true "User ID"
+// @Param userId query string false "User ID, defaults to the caller; only admins may read other users"
 // @Success 200 {object} MessageResponse
-// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/messages/offline [get]
 func (h *MessageHandler) GetOfflineMessages(c *gin.Context) {
 	userID := c.Query("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, response.Response{
-			Code: http.StatusBadRequest,
-			Msg:  "userId is required",
-		})
-		return
+		userID = principal(c).UserID
 	}
 
 	ctx := c.Request.Context()
 
 	messages, err := h.chatUC.GetOfflineMessages(ctx, userID)
 	if err != nil {
+		if writeAuthError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, response.Response{
 			Code: http.StatusInternalServerError,
 			Msg:  "failed to get offline messages",
@@ -81,20 +91,39 @@ func (h *MessageHandler) GetOfflineMessages(c *gin.Context) {
 
 // SendChatMessage sends a new chat message
 // @Summary Send chat message
-// @Description Sends a new chat message
+// @Description Sends a new chat message. Sends are idempotent on (sender, msgId); clients may
+// @Description instead pass an Idempotency-Key header, and retries within the dedup window (server.idempotency_window,
+// @Description 24h by default) return the originally stored message. A key reused after the window is treated as a new
+// @Description request: it stores a new message whose msgId is derived from the key and the expired message's msgId
+// @Description (key + "|" + previous msgId), and later retries with the key replay that new message. The sender is the authenticated caller
+// @Description and dst the recipient address (e.g. U:<cid> for an agent reply), defaulting to the bot. Agents may pass cannedResponseId
+// @Description to send a canned response whose variables are expanded server side, or set internal=true
+// @Description to leave a note that only agents can see.
 // @Tags messages
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Client generated idempotency key"
 // @Param message body handler.Message true "Message to send"
 // @Success 200 {object} MessageResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/messages [post]
 func (h *MessageHandler) SendChatMessage(c *gin.Context) {
 	var req struct {
+		MsgID     string `json:"msgId"`
 		SessionID string `json:"sessionId"`
 		Content   string `json:"content"`
-		SenderID  string `json:"senderId"`
+		SenderID  string `json:"senderId"` // 可选，必须与令牌中的用户一致
+		Dst       string `json:"dst"`      // 可选，接收方地址(如客服回复客户时为 U:<cid>)，默认发给机器人
+		ReplyTo   string `json:"replyTo"`
+		// CannedResponseID 客服发送快捷回复
+		CannedResponseID string `json:"cannedResponseId"`
+		// Internal 为 true 时发送仅客服可见的内部备注
+		Internal bool `json:"internal"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -105,27 +134,117 @@ func (h *MessageHandler) SendChatMessage(c *gin.Context) {
 		return
 	}
 
+	idempotencyKey := c.GetHeader(constants.HeaderIdempotencyKey)
+	if len(idempotencyKey) > constants.IdempotencyKeyMaxLen {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Code: http.StatusBadRequest,
+			Msg:  "Idempotency-Key is too long",
+		})
+		return
+	}
+
 	ctx := c.Request.Context()
-	// Generate proper IDs
-	msgID := "m" + uuid.New().String()
-	subSessionID := "ss" + uuid.New().String()
-
-	message := &entity.Message{
-		MsgID:       msgID,
-		SessionID:   req.SessionID,
-		Content:     req.Content,
-		Src:         "U:" + req.SenderID,
-		Dst:         "S:auto", // Default to bot
-		MsgType:     entity.MsgTypeMessage,
-		ContentType: entity.ContentTypeText,
-		Status:      entity.StatusNew,
-		Ts:          entity.StringTimestamp(time.Now().UnixNano() / int64(time.Millisecond)),
-		Ext: map[string]interface{}{
-			"subSessionId": subSessionID,
-		},
+	sender := principal(c)
+	if req.SenderID != "" && req.SenderID != sender.UserID {
+		writeError(c, http.StatusForbidden, cland_errors.ErrSenderMismatch)
+		return
 	}
+	// 发送方地址由令牌身份决定: 客户 U:xxx，员工 A:xxx
+	src := sender.Address()
 
-	if err := h.chatUC.SendMessage(ctx, message); err != nil {
+	// Generate proper IDs: an explicit msgId wins, then one derived from the idempotency key
+	msgID := req.MsgID
+	derived := msgID == "" && idempotencyKey != ""
+	if derived {
+		msgID = utils.GenerateIdempotentMessageID(src, idempotencyKey)
+	}
+	if msgID == "" {
+		msgID = utils.GenerateMessageID()
+	}
+	subSessionID := utils.GenerateSubSessionID()
+	dst := req.Dst
+	if dst == "" {
+		dst = "S:auto" // Default to bot
+	}
+
+	newMessage := func(msgID string) *entity.Message {
+		message := &entity.Message{
+			MsgID:       msgID,
+			SessionID:   req.SessionID,
+			Content:     req.Content,
+			Src:         src,
+			Dst:         dst,
+			MsgType:     entity.MsgTypeMessage,
+			ContentType: entity.ContentTypeText,
+			Status:      entity.StatusNew,
+			Ts:          entity.StringTimestamp(time.Now().UnixNano() / int64(time.Millisecond)),
+			Ext: map[string]interface{}{
+				"subSessionId": subSessionID,
+			},
+			ReplyTo: req.ReplyTo,
+		}
+		if req.CannedResponseID != "" {
+			message.Ext[usecase.ExtCannedResponseID] = req.CannedResponseID
+		}
+		if req.Internal {
+			message.MsgType = entity.MsgTypeInternalNote
+			message.Dst = ""
+		}
+		return message
+	}
+
+	message := newMessage(msgID)
+	err := h.chatUC.SendMessage(ctx, message)
+	// 幂等键超出去重窗口后按新请求处理: 由过期消息的 ID 派生下一代 ID，之后的重试沿同一条链找到新消息
+	for derived && errors.Is(err, usecase.ErrDuplicateMessage) && h.idempotencyKeyExpired(message) {
+		message = newMessage(utils.GenerateIdempotentMessageID(src, idempotencyKey+"|"+message.MsgID))
+		err = h.chatUC.SendMessage(ctx, message)
+	}
+	switch {
+	case errors.Is(err, usecase.ErrDuplicateMessage):
+		if idempotencyKey != "" {
+			content := req.Content
+			if req.CannedResponseID != "" {
+				// 快捷回复的正文由服务端展开，只比较快捷回复ID
+				content = message.Content
+				if id, _ := message.Ext[usecase.ExtCannedResponseID].(string); id != req.CannedResponseID {
+					content = req.Content
+				}
+			}
+			if e, ok := checkIdempotentReplay(message, req.SessionID, content); !ok {
+				c.JSON(http.StatusUnprocessableEntity, response.Response{Code: e.Code, Msg: e.Msg})
+				return
+			}
+		}
+		c.Header(constants.HeaderIdempotentReplay, "true")
+	case errors.Is(err, usecase.ErrInvalidReplyTo):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Code: cland_errors.ErrReplyToInvalid.Code,
+			Msg:  cland_errors.ErrReplyToInvalid.Msg,
+			Data: gin.H{"error_detail": err.Error()},
+		})
+		return
+	case errors.Is(err, usecase.ErrCannedResponseNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrCannedResponseNotFound)
+		return
+	case errors.Is(err, usecase.ErrInternalNoteForbidden):
+		writeError(c, http.StatusForbidden, cland_errors.ErrInternalNoteForbidden)
+		return
+	case errors.Is(err, usecase.ErrNotificationForbidden):
+		writeError(c, http.StatusForbidden, cland_errors.ErrNotificationForbidden)
+		return
+	case errors.Is(err, repository.ErrNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrSessionNotFound)
+		return
+	case writeAuthError(c, err):
+		return
+	case errors.Is(err, usecase.ErrMsgIDConflict):
+		c.JSON(http.StatusConflict, response.Response{
+			Code: cland_errors.ErrMsgIDConflict.Code,
+			Msg:  cland_errors.ErrMsgIDConflict.Msg,
+		})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, response.Response{
 			Code: http.StatusInternalServerError,
 			Msg:  "failed to send message",
@@ -142,6 +261,132 @@ func (h *MessageHandler) SendChatMessage(c *gin.Context) {
 			"content":      message.Content,
 			"src":          message.Src,
 			"ts":           message.Ts,
+			"status":       message.Status,
+			"replyTo":      message.ReplyTo,
+			"replyPreview": message.ReplyPreview,
+		},
+	})
+}
+
+// idempotencyKeyExpired 幂等键派生的消息是否已超出去重窗口
+func (h *MessageHandler) idempotencyKeyExpired(stored *entity.Message) bool {
+	return time.Since(time.UnixMilli(int64(stored.Ts))) > h.idempotencyWindow
+}
+
+// checkIdempotentReplay 校验幂等键重放: 请求内容必须与原消息一致
+func checkIdempotentReplay(stored *entity.Message, sessionID, content string) (cland_errors.Error, bool) {
+	if stored.SessionID != sessionID || stored.Content != content {
+		return cland_errors.ErrIdempotencyKeyReused, false
+	}
+	return cland_errors.Error{}, true
+}
+
+// GetSessionMessages retrieves the message history of a session
+// @Summary Get session history
+// @Description Retrieves all messages of a session, with quoted message previews hydrated.
+// @Description Internal notes are only returned to agents and admins.
+// @Tags messages
+// @Produce json
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sessions/{sessionId}/messages [get]
+func (h *MessageHandler) GetSessionMessages(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Code: http.StatusBadRequest,
+			Msg:  "sessionId is required",
+		})
+		return
+	}
+
+	messages, err := h.chatUC.GetSessionMessages(c.Request.Context(), sessionID)
+	if err != nil {
+		if writeAuthError(c, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(c, http.StatusNotFound, cland_errors.ErrSessionNotFound)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, response.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "failed to get session messages",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data": gin.H{
+			"messages": messages,
+		},
+	})
+}
+
+// GetCustomerHistory retrieves every session of a customer with its messages
+// @Summary Get customer history
+// @Description Lists all sessions of a customer across devices, newest first, including sessions merged in
+// @Description when the visitor identified on the main site. Agents may only view customers they have served.
+// @Tags messages
+// @Produce json
+// @Param cid path string true "Customer CID"
+// @Success 200 {object} response.Response
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/customers/{cid}/history [get]
+func (h *MessageHandler) GetCustomerHistory(c *gin.Context) {
+	history, err := h.chatUC.GetCustomerHistory(c.Request.Context(), c.Param("cid"))
+	if err != nil {
+		if writeAuthError(c, err) {
+			return
+		}
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(history))
+}
+
+// GetMessageReplies lists all replies quoting a message
+// @Summary Get message replies
+// @Description Lists all messages whose replyTo references the given message
+// @Tags messages
+// @Produce json
+// @Param msgId path string true "Message ID"
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/messages/{msgId}/replies [get]
+func (h *MessageHandler) GetMessageReplies(c *gin.Context) {
+	replies, err := h.chatUC.GetMessageReplies(c.Request.Context(), c.Param("msgId"))
+	if err != nil {
+		if writeAuthError(c, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, response.Response{
+				Code: cland_errors.ErrMessageNotFound.Code,
+				Msg:  cland_errors.ErrMessageNotFound.Msg,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, response.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "failed to get message replies",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data": gin.H{
+			"replies": replies,
 		},
 	})
 }
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/common/constants"
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// newMessageRouter 创建以客户 c1 身份发送消息的测试路由，会话 s1 属于 c1；window 为幂等键去重窗口，0 使用默认值
+func newMessageRouter(t *testing.T, window time.Duration) (*gin.Engine, *repository.MemoryMessageRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	messages := repository.NewMemoryMessageRepository()
+	sessions := repository.NewMemorySessionRepository()
+	session := &entity.Session{ID: "s1", CID: "c1", AgentId: "a1", Status: entity.SessionStatusActive, CreatedAt: time.Now()}
+	if err := sessions.Create(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	chatUC := usecase.NewChatUseCase(messages, sessions, repository.NewMemoryUserRepository(), repository.NewMemoryReactionRepository())
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx := usecase.WithPrincipal(c.Request.Context(), entity.Principal{UserID: "c1", Role: entity.RoleCustomer})
+		c.Request = c.Request.WithContext(ctx)
+	})
+	r.POST("/api/messages", NewMessageHandler(chatUC, window).SendChatMessage)
+	return r, messages
+}
+
+func sendWithKey(r *gin.Engine, key, content string) (*httptest.ResponseRecorder, string) {
+	body := `{"sessionId":"s1","content":"` + content + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/messages", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.HeaderIdempotencyKey, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp struct {
+		Data struct {
+			MsgID string `json:"msgId"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp.Data.MsgID
+}
+
+func TestSendChatMessageIdempotencyKey(t *testing.T) {
+	r, _ := newMessageRouter(t, 0)
+
+	w, first := sendWithKey(r, "k1", "hello")
+	if w.Code != http.StatusOK || first == "" {
+		t.Fatalf("first send = %d %s", w.Code, w.Body.String())
+	}
+	w, replayed := sendWithKey(r, "k1", "hello")
+	if w.Code != http.StatusOK || replayed != first || w.Header().Get(constants.HeaderIdempotentReplay) != "true" {
+		t.Fatalf("retry = %d %q replayed=%q, want replay of %s", w.Code, replayed, w.Header().Get(constants.HeaderIdempotentReplay), first)
+	}
+	if w, _ := sendWithKey(r, "k1", "different"); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reuse with different content = %d, want 422", w.Code)
+	}
+}
+
+func TestSendChatMessageExpiredIdempotencyKey(t *testing.T) {
+	r, messages := newMessageRouter(t, time.Hour)
+
+	// 写入用幂等键 k0、k1 发送过的消息，k0 仍在配置的一小时窗口内，k1 已超出
+	for _, m := range []struct {
+		key string
+		age time.Duration
+	}{{"k0", 30 * time.Minute}, {"k1", 2 * time.Hour}} {
+		stale := &entity.Message{
+			MsgID:     utils.GenerateIdempotentMessageID("U:c1", m.key),
+			SessionID: "s1",
+			Content:   "old",
+			Src:       "U:c1",
+			Dst:       "S:auto",
+			MsgType:   entity.MsgTypeMessage,
+			Status:    entity.StatusNew,
+			Ts:        entity.StringTimestamp(time.Now().Add(-m.age).UnixMilli()),
+		}
+		if err := messages.Create(context.Background(), stale); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w, _ := sendWithKey(r, "k0", "new"); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reuse within the window with different content = %d, want 422", w.Code)
+	}
+
+	// 超出窗口的键按新请求处理，新消息 ID 由键与过期消息的 ID 派生
+	staleID := utils.GenerateIdempotentMessageID("U:c1", "k1")
+
+	w, fresh := sendWithKey(r, "k1", "new")
+	if w.Code != http.StatusOK || w.Header().Get(constants.HeaderIdempotentReplay) != "" {
+		t.Fatalf("send after window = %d replayed=%q %s, want a new message", w.Code, w.Header().Get(constants.HeaderIdempotentReplay), w.Body.String())
+	}
+	if want := utils.GenerateIdempotentMessageID("U:c1", "k1|"+staleID); fresh != want {
+		t.Fatalf("msgId after window = %q, want %q derived from the expired message", fresh, want)
+	}
+	w, replayed := sendWithKey(r, "k1", "new")
+	if w.Code != http.StatusOK || replayed != fresh || w.Header().Get(constants.HeaderIdempotentReplay) != "true" {
+		t.Fatalf("retry after window = %d %q, want replay of %s", w.Code, replayed, fresh)
+	}
+}
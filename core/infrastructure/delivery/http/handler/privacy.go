@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyHandler struct {
+	privacyUC *usecase.PrivacyUseCase
+}
+
+func NewPrivacyHandler(privacyUC *usecase.PrivacyUseCase) *PrivacyHandler {
+	return &PrivacyHandler{privacyUC: privacyUC}
+}
+
+// ExportCustomerData returns everything stored about a customer as a downloadable JSON bundle
+// @Summary Export customer data
+// @Description Bundles the customer's user record, sessions, messages (including archived ones),
+// @Description attachments, reactions and ratings. The export is recorded in the audit log
+// @Tags privacy
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param cid path string true "Customer ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/privacy/customers/{cid}/export [get]
+func (h *PrivacyHandler) ExportCustomerData(c *gin.Context) {
+	cid := c.Param("cid")
+	export, err := h.privacyUC.ExportCustomer(c.Request.Context(), cid)
+	if err != nil {
+		writePrivacyError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%s.json"`, cid))
+	c.JSON(http.StatusOK, response.Success(export))
+}
+
+// EraseCustomerData erases a customer and the visitor records merged into it
+// @Summary Erase customer data
+// @Description Deletes archives, reactions and tokens, clears message content and personal fields,
+// @Description and soft-deletes the customer's sessions and messages. The CID is kept as a pseudonym
+// @Description and the audit log records only the number of affected rows
+// @Tags privacy
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param cid path string true "Customer ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/privacy/customers/{cid} [delete]
+func (h *PrivacyHandler) EraseCustomerData(c *gin.Context) {
+	result, err := h.privacyUC.EraseCustomer(c.Request.Context(), c.Param("cid"))
+	if err != nil {
+		writePrivacyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// writePrivacyError 将数据主体请求相关错误映射为HTTP响应
+func writePrivacyError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrCustomerNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrCustomerNotFound)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// defaultRatingRange 未指定统计区间时默认统计最近30天
+	defaultRatingRange = 30 * 24 * time.Hour
+)
+
+// RatingRequest represents a CSAT survey answer
+type RatingRequest struct {
+	Score   int    `json:"score"`
+	Comment string `json:"comment"`
+}
+
+type RatingHandler struct {
+	ratingUC *usecase.RatingUseCase
+}
+
+func NewRatingHandler(ratingUC *usecase.RatingUseCase) *RatingHandler {
+	return &RatingHandler{ratingUC: ratingUC}
+}
+
+// GetSurvey returns the CSAT survey of a session
+// @Summary Get CSAT survey
+// @Description REST fallback for the csat_survey socket event; includes the submitted rating if any
+// @Tags ratings
+// @Produce json
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sessions/{sessionId}/survey [get]
+func (h *RatingHandler) GetSurvey(c *gin.Context) {
+	survey, err := h.ratingUC.GetSurvey(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		writeRatingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(survey))
+}
+
+// SubmitRating stores the customer's CSAT answer for a closed session
+// @Summary Submit CSAT rating
+// @Description Stores a 1-5 star rating with an optional comment; each session can be rated once
+// @Tags ratings
+// @Accept json
+// @Produce json
+// @Param sessionId path string true "Session ID"
+// @Param Authorization header string true "Bearer token of the session customer"
+// @Param rating body handler.RatingRequest true "Rating"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/sessions/{sessionId}/rating [post]
+func (h *RatingHandler) SubmitRating(c *gin.Context) {
+	var req RatingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	rating, err := h.ratingUC.SubmitRating(c.Request.Context(), c.Param("sessionId"), principal(c).UserID, req.Score, req.Comment)
+	if err != nil {
+		writeRatingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(rating))
+}
+
+// GetAgentRatings returns CSAT aggregates per agent
+// @Summary CSAT per agent
+// @Description Aggregates ratings per agent within [from, to] (dates in yyyy-mm-dd, UTC, default last 30 days)
+// @Tags ratings
+// @Produce json
+// @Param from query string false "Start date"
+// @Param to query string false "End date (inclusive)"
+// @Param agentId query string false "Agent ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Router /api/ratings/agents [get]
+func (h *RatingHandler) GetAgentRatings(c *gin.Context) {
+	h.aggregate(c, false)
+}
+
+// GetDailyRatings returns CSAT aggregates per agent per day
+// @Summary CSAT per agent per day
+// @Description Aggregates ratings per agent and day within [from, to] (dates in yyyy-mm-dd, UTC, default last 30 days)
+// @Tags ratings
+// @Produce json
+// @Param from query string false "Start date"
+// @Param to query string false "End date (inclusive)"
+// @Param agentId query string false "Agent ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Router /api/ratings/daily [get]
+func (h *RatingHandler) GetDailyRatings(c *gin.Context) {
+	h.aggregate(c, true)
+}
+
+func (h *RatingHandler) aggregate(c *gin.Context, byDay bool) {
+	query, err := parseRatingQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Code: cland_errors.Err400.Code,
+			Msg:  "from/to must be dates in yyyy-mm-dd format",
+		})
+		return
+	}
+	query.GroupByDay = byDay
+
+	aggregates, err := h.ratingUC.AggregateRatings(c.Request.Context(), query)
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(gin.H{
+		"from":       query.From.Format(dateLayout),
+		"to":         query.To.Add(-24 * time.Hour).Format(dateLayout),
+		"aggregates": aggregates,
+	}))
+}
+
+// parseRatingQuery 解析统计区间，to 为包含当天的结束日期
+func parseRatingQuery(c *gin.Context) (entity.RatingQuery, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	query := entity.RatingQuery{
+		AgentID: c.Query("agentId"),
+		From:    today.Add(-defaultRatingRange),
+		To:      today.Add(24 * time.Hour),
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return query, err
+		}
+		query.From = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return query, err
+		}
+		query.To = t.Add(24 * time.Hour)
+	}
+	return query, nil
+}
+
+// writeRatingError 将评价相关错误映射为HTTP响应
+func writeRatingError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRating):
+		writeError(c, http.StatusBadRequest, cland_errors.ErrRatingInvalid)
+	case errors.Is(err, usecase.ErrSessionNotClosed):
+		writeError(c, http.StatusBadRequest, cland_errors.ErrSessionNotClosed)
+	case errors.Is(err, usecase.ErrNotSessionCustomer):
+		writeError(c, http.StatusForbidden, cland_errors.ErrNotSessionCustomer)
+	case errors.Is(err, usecase.ErrAlreadyRated):
+		writeError(c, http.StatusConflict, cland_errors.ErrAlreadyRated)
+	case errors.Is(err, repository.ErrNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrSessionNotFound)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// ReactionRequest represents a reaction add request; the reacting user is the authenticated caller
+type ReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// AddReaction adds an emoji reaction to a message
+// @Summary Add reaction
+// @Description Adds an emoji reaction to a message; adding the same emoji twice is a no-op
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param msgId path string true "Message ID"
+// @Param reaction body handler.ReactionRequest true "Reaction to add"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/messages/{msgId}/reactions [post]
+func (h *MessageHandler) AddReaction(c *gin.Context) {
+	var req ReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			Code: http.StatusBadRequest,
+			Msg:  "emoji is required",
+		})
+		return
+	}
+
+	update, err := h.chatUC.AddReaction(c.Request.Context(), c.Param("msgId"), principal(c).UserID, req.Emoji)
+	if err != nil {
+		writeReactionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   update,
+	})
+}
+
+// RemoveReaction removes an emoji reaction from a message
+// @Summary Remove reaction
+// @Description Removes the caller's emoji reaction from a message
+// @Tags messages
+// @Produce json
+// @Param msgId path string true "Message ID"
+// @Param emoji query string true "Emoji"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/messages/{msgId}/reactions [delete]
+func (h *MessageHandler) RemoveReaction(c *gin.Context) {
+	update, err := h.chatUC.RemoveReaction(c.Request.Context(), c.Param("msgId"), principal(c).UserID, c.Query("emoji"))
+	if err != nil {
+		writeReactionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"data":   update,
+	})
+}
+
+// writeReactionError 将表情回应相关错误映射为HTTP响应
+func writeReactionError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidEmoji):
+		c.JSON(http.StatusBadRequest, response.Response{
+			Code: cland_errors.ErrEmojiInvalid.Code,
+			Msg:  cland_errors.ErrEmojiInvalid.Msg,
+		})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, response.Response{
+			Code: cland_errors.ErrMessageNotFound.Code,
+			Msg:  cland_errors.ErrMessageNotFound.Msg,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, response.Response{
+			Code: http.StatusInternalServerError,
+			Msg:  "failed to update reaction",
+		})
+	}
+}
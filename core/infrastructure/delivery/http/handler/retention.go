@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionHandler struct {
+	retentionUC *usecase.RetentionUseCase
+}
+
+func NewRetentionHandler(retentionUC *usecase.RetentionUseCase) *RetentionHandler {
+	return &RetentionHandler{retentionUC: retentionUC}
+}
+
+// ListRetentionRuns returns the progress of recent retention runs, newest first
+// @Summary List retention runs
+// @Description A running job saves its counters after every batch of sessions
+// @Tags retention
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param limit query int false "Maximum number of runs, default 20"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/retention/runs [get]
+func (h *RetentionHandler) ListRetentionRuns(c *gin.Context) {
+	limit := 0
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeError(c, http.StatusBadRequest, cland_errors.Err400)
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.retentionUC.ListRuns(c.Request.Context(), limit)
+	if err != nil {
+		writeRetentionError(c, err)
+		return
+	}
+	if runs == nil {
+		runs = []*entity.RetentionRun{}
+	}
+	c.JSON(http.StatusOK, response.Success(runs))
+}
+
+// TriggerRetentionRun starts a retention run without waiting for the schedule
+// @Summary Trigger retention run
+// @Description The run starts in the background; follow its progress with GET /api/retention/runs
+// @Tags retention
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Success 202 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/retention/runs [post]
+func (h *RetentionHandler) TriggerRetentionRun(c *gin.Context) {
+	if err := h.retentionUC.Trigger(c.Request.Context()); err != nil {
+		writeRetentionError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, response.Success(nil))
+}
+
+// GetSessionRetention returns the archive and purge record of a session
+// @Summary Get session retention record
+// @Tags retention
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/retention/sessions/{sessionId} [get]
+func (h *RetentionHandler) GetSessionRetention(c *gin.Context) {
+	rec, err := h.retentionUC.GetSessionRetention(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		writeRetentionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(rec))
+}
+
+// RestoreSessionArchive writes the archived messages of a session back for review
+// @Summary Restore archived session
+// @Description Messages are read from the archive and written back, overwriting anonymized content.
+// @Description The session is purged again once the configured restore TTL has passed
+// @Tags retention
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/retention/sessions/{sessionId}/restore [post]
+func (h *RetentionHandler) RestoreSessionArchive(c *gin.Context) {
+	rec, err := h.retentionUC.RestoreSession(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		writeRetentionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(rec))
+}
+
+// writeRetentionError 将保留任务相关错误映射为HTTP响应
+func writeRetentionError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrRetentionRunning):
+		writeError(c, http.StatusConflict, cland_errors.ErrRetentionRunning)
+	case errors.Is(err, usecase.ErrArchiveNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrArchiveNotFound)
+	case errors.Is(err, repository.ErrNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.Err404)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// WhisperRequest represents a supervisor whisper to the assigned agent
+type WhisperRequest struct {
+	Content string `json:"content"`
+}
+
+type SupervisorHandler struct {
+	supervisorUC *usecase.SupervisorUseCase
+}
+
+func NewSupervisorHandler(supervisorUC *usecase.SupervisorUseCase) *SupervisorHandler {
+	return &SupervisorHandler{supervisorUC: supervisorUC}
+}
+
+// StartMonitor subscribes the supervisor to a session's live message stream
+// @Summary Start monitoring a session
+// @Description Silently mirrors every message of the session to the supervisor's socket connection;
+// @Description neither the customer nor the agent is notified
+// @Tags supervisor
+// @Produce json
+// @Param Authorization header string true "Bearer token of a supervisor or admin"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/supervisor/sessions/{sessionId}/monitor [post]
+func (h *SupervisorHandler) StartMonitor(c *gin.Context) {
+	session, err := h.supervisorUC.Monitor(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		writeSupervisorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(session))
+}
+
+// StopMonitor unsubscribes the supervisor from a session's message stream
+// @Summary Stop monitoring a session
+// @Tags supervisor
+// @Produce json
+// @Param Authorization header string true "Bearer token of a supervisor or admin"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/supervisor/sessions/{sessionId}/monitor [delete]
+func (h *SupervisorHandler) StopMonitor(c *gin.Context) {
+	if err := h.supervisorUC.StopMonitor(c.Request.Context(), c.Param("sessionId")); err != nil {
+		writeSupervisorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(nil))
+}
+
+// Whisper sends an agent-only message to the session's assigned agent
+// @Summary Whisper to the agent
+// @Description Sends an internal note that only the assigned agent and other staff can see
+// @Tags supervisor
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of a supervisor or admin"
+// @Param sessionId path string true "Session ID"
+// @Param request body handler.WhisperRequest true "Whisper"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/supervisor/sessions/{sessionId}/whisper [post]
+func (h *SupervisorHandler) Whisper(c *gin.Context) {
+	var req WhisperRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	msg, err := h.supervisorUC.Whisper(c.Request.Context(), c.Param("sessionId"), req.Content)
+	if err != nil {
+		writeSupervisorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(msg))
+}
+
+// BargeIn takes over a session from the assigned agent
+// @Summary Barge in
+// @Description Reassigns the session to the supervisor and notifies the customer and the previous agent
+// @Tags supervisor
+// @Produce json
+// @Param Authorization header string true "Bearer token of a supervisor or admin"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/supervisor/sessions/{sessionId}/barge-in [post]
+func (h *SupervisorHandler) BargeIn(c *gin.Context) {
+	session, err := h.supervisorUC.BargeIn(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		writeSupervisorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(session))
+}
+
+// ListAuditLogs returns supervisor audit logs, newest first
+// @Summary List audit logs
+// @Tags supervisor
+// @Produce json
+// @Param Authorization header string true "Bearer token of a supervisor or admin"
+// @Param sessionId query string false "Session ID"
+// @Param actorId query string false "Actor ID"
+// @Param action query string false "Action"
+// @Param limit query int false "Max entries (default 100)"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Router /api/supervisor/audit-logs [get]
+func (h *SupervisorHandler) ListAuditLogs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	logs, err := h.supervisorUC.ListAuditLogs(c.Request.Context(), entity.AuditQuery{
+		SessionID: c.Query("sessionId"),
+		ActorID:   c.Query("actorId"),
+		Action:    c.Query("action"),
+		Limit:     limit,
+	})
+	if err != nil {
+		writeSupervisorError(c, err)
+		return
+	}
+	if logs == nil {
+		logs = []*entity.AuditLog{}
+	}
+	c.JSON(http.StatusOK, response.Success(logs))
+}
+
+// writeSupervisorError 将主管操作相关错误映射为HTTP响应
+func writeSupervisorError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrSupervisorOnly):
+		writeError(c, http.StatusForbidden, cland_errors.ErrSupervisorOnly)
+	case errors.Is(err, usecase.ErrSessionNotActive):
+		writeError(c, http.StatusBadRequest, cland_errors.ErrSessionNotActive)
+	case errors.Is(err, usecase.ErrEmptyWhisper):
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+	case errors.Is(err, repository.ErrNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrSessionNotFound)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+var transcriptContentTypes = map[string]string{
+	entity.TranscriptText: "text/plain; charset=utf-8",
+	entity.TranscriptHTML: "text/html; charset=utf-8",
+}
+
+type TranscriptHandler struct {
+	transcriptUC *usecase.TranscriptUseCase
+}
+
+func NewTranscriptHandler(transcriptUC *usecase.TranscriptUseCase) *TranscriptHandler {
+	return &TranscriptHandler{transcriptUC: transcriptUC}
+}
+
+// GetSessionTranscript renders the transcript of a session
+// @Summary Get session transcript
+// @Description Renders the session messages with sender display names, timestamps in the requested
+// @Description time zone and attachments as links. Internal notes are never included.
+// @Description json is returned in the usual envelope, txt and html as a document
+// @Tags messages
+// @Produce json,plain,html
+// @Param sessionId path string true "Session ID"
+// @Param format query string false "json (default), txt or html"
+// @Param tz query string false "IANA time zone of the requester, e.g. Asia/Shanghai"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/sessions/{sessionId}/transcript [get]
+func (h *TranscriptHandler) GetSessionTranscript(c *gin.Context) {
+	format := c.DefaultQuery("format", entity.TranscriptJSON)
+	contentType, ok := transcriptContentTypes[format]
+	if !ok && format != entity.TranscriptJSON {
+		writeError(c, http.StatusBadRequest, cland_errors.ErrTranscriptInvalid)
+		return
+	}
+	loc, err := h.transcriptUC.LoadTimezone(c.Query("tz"))
+	if err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.ErrTranscriptInvalid)
+		return
+	}
+
+	sessionID := c.Param("sessionId")
+	transcript, err := h.transcriptUC.GetTranscript(c.Request.Context(), sessionID, loc)
+	if err != nil {
+		if writeAuthError(c, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			writeError(c, http.StatusNotFound, cland_errors.ErrSessionNotFound)
+			return
+		}
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+		return
+	}
+	if format == entity.TranscriptJSON {
+		c.JSON(http.StatusOK, response.Success(transcript))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := usecase.RenderTranscript(&buf, transcript, format); err != nil {
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="transcript-%s.%s"`, sessionID, format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
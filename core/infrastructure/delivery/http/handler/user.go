@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"cland.org/cland-chat-service/common/constants"
+	cland_errors "cland.org/cland-chat-service/common/errors"
 	"cland.org/cland-chat-service/core/domain/repository"
 	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
 	"cland.org/cland-chat-service/core/usecase"
@@ -12,9 +15,18 @@ import (
 
 // UserResponse represents the response structure for user operations
 type UserResponse struct {
-	SessionID    string `json:"sessionId"`
-	SubSessionID string `json:"subSessionId"`
-	Token        string `json:"token"`
+	SessionID        string    `json:"sessionId"`
+	SubSessionID     string    `json:"subSessionId"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// InitUserRequest optional visitor details supplied at initialization
+type InitUserRequest struct {
+	// Email lets the service email a digest of replies sent while the visitor is offline
+	Email string `json:"email"`
 }
 
 type UserHandler struct {
@@ -37,16 +49,30 @@ func NewUserHandler(
 
 // InitUser initializes a new user session
 // @Summary Initialize user session
-// @Description Creates a new user session and returns authentication tokens
+// @Description Creates a new user session and returns authentication tokens. The body is optional;
+// @Description an email left here is stored on the customer and used for offline reply digests.
 // @Tags user
 // @Accept json
 // @Produce json
+// @Param request body InitUserRequest false "Visitor details"
 // @Success 200 {object} UserResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/init [post]
 func (h *UserHandler) InitUser(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	var req InitUserRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				Code: http.StatusBadRequest,
+				Msg:  "invalid request",
+			})
+			return
+		}
+	}
+
 	// Check for existing cland-cid in headers or cookie
 	var existingCID string
 	if cid := c.GetHeader(constants.KEY_USER_ID); cid != "" {
@@ -54,7 +80,11 @@ func (h *UserHandler) InitUser(c *gin.Context) {
 	}
 
 	// Call usecase
-	res, err := h.userUC.InitUser(ctx, existingCID)
+	res, err := h.userUC.InitUser(ctx, existingCID, req.Email)
+	if errors.Is(err, usecase.ErrInvalidEmail) {
+		writeError(c, http.StatusBadRequest, cland_errors.ErrEmailInvalid)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{
 			Code: constants.ErrorCodeUserInitFailed,
@@ -82,10 +112,13 @@ func (h *UserHandler) InitUser(c *gin.Context) {
 	c.JSON(http.StatusOK, response.Response{
 		Code: constants.SuccessCode,
 		Msg:  "Initialization successful",
-		Data: gin.H{
-			"sessionId":    res.SessionID,
-			"subSessionId": res.SubSessionID,
-			"token":        res.Token,
+		Data: UserResponse{
+			SessionID:        res.SessionID,
+			SubSessionID:     res.SubSessionID,
+			Token:            res.Tokens.Token,
+			ExpiresAt:        res.Tokens.ExpiresAt,
+			RefreshToken:     res.Tokens.RefreshToken,
+			RefreshExpiresAt: res.Tokens.RefreshExpiresAt,
 		},
 	})
 }
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRequest represents a webhook subscription to create or update
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"` // message.created, session.created, session.closed, session.assigned, rating.submitted
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"` // defaults to true
+}
+
+type WebhookHandler struct {
+	webhookUC *usecase.WebhookUseCase
+}
+
+func NewWebhookHandler(webhookUC *usecase.WebhookUseCase) *WebhookHandler {
+	return &WebhookHandler{webhookUC: webhookUC}
+}
+
+func (r *WebhookRequest) toSubscription(id string) *entity.WebhookSubscription {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return &entity.WebhookSubscription{
+		ID:          id,
+		URL:         r.URL,
+		Events:      r.Events,
+		Description: r.Description,
+		Active:      active,
+	}
+}
+
+// ListWebhooks lists webhook subscriptions
+// @Summary List webhook subscriptions
+// @Description Signing secrets are not included
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} ErrorResponse
+// @Router /api/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	subs, err := h.webhookUC.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	if subs == nil {
+		subs = []*entity.WebhookSubscription{}
+	}
+	c.JSON(http.StatusOK, response.Success(subs))
+}
+
+// CreateWebhook subscribes an external URL to chat lifecycle events
+// @Summary Create webhook subscription
+// @Description Every delivery is a POST with the X-Cland-Event, X-Cland-Delivery and X-Cland-Signature headers.
+// @Description The signature is t=<unix>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>. The secret is only returned here
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param request body handler.WebhookRequest true "Webhook subscription"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	sub := req.toSubscription("")
+	if err := h.webhookUC.CreateSubscription(c.Request.Context(), sub); err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(sub))
+}
+
+// GetWebhook returns a webhook subscription
+// @Summary Get webhook subscription
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} ErrorResponse
+// @Router /api/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	sub, err := h.webhookUC.GetSubscription(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(sub))
+}
+
+// UpdateWebhook updates the URL, events and active flag of a subscription
+// @Summary Update webhook subscription
+// @Description The signing secret is kept
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "Subscription ID"
+// @Param request body handler.WebhookRequest true "Webhook subscription"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, cland_errors.Err400)
+		return
+	}
+
+	sub, err := h.webhookUC.UpdateSubscription(c.Request.Context(), req.toSubscription(c.Param("id")))
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(sub))
+}
+
+// DeleteWebhook deletes a webhook subscription
+// @Summary Delete webhook subscription
+// @Description Pending deliveries of the subscription are marked failed on their next attempt
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} ErrorResponse
+// @Router /api/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookUC.DeleteSubscription(c.Request.Context(), c.Param("id")); err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(nil))
+}
+
+// ListWebhookDeliveries returns the delivery log of a subscription, newest first
+// @Summary List webhook deliveries
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "Subscription ID"
+// @Param status query string false "pending, succeeded or failed"
+// @Param limit query int false "Maximum number of deliveries, default 100"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	query := entity.WebhookDeliveryQuery{
+		SubscriptionID: c.Param("id"),
+		Status:         c.Query("status"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			writeError(c, http.StatusBadRequest, cland_errors.Err400)
+			return
+		}
+		query.Limit = n
+	}
+
+	deliveries, err := h.webhookUC.ListDeliveries(c.Request.Context(), query)
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*entity.WebhookDelivery{}
+	}
+	c.JSON(http.StatusOK, response.Success(deliveries))
+}
+
+// GetWebhookDelivery returns a delivery with its payload and last attempt result
+// @Summary Get webhook delivery
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "Delivery ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} ErrorResponse
+// @Router /api/webhook-deliveries/{id} [get]
+func (h *WebhookHandler) GetWebhookDelivery(c *gin.Context) {
+	delivery, err := h.webhookUC.GetDelivery(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(delivery))
+}
+
+// ReplayWebhookDelivery queues the event of a delivery again
+// @Summary Replay webhook delivery
+// @Description Creates a new delivery with the same event ID and payload; receivers can deduplicate on the event ID
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer token of an admin"
+// @Param id path string true "Delivery ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/webhook-deliveries/{id}/replay [post]
+func (h *WebhookHandler) ReplayWebhookDelivery(c *gin.Context) {
+	delivery, err := h.webhookUC.ReplayDelivery(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(delivery))
+}
+
+// writeWebhookError 将 webhook 相关错误映射为HTTP响应
+func writeWebhookError(c *gin.Context, err error) {
+	if writeAuthError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, usecase.ErrInvalidWebhook):
+		writeError(c, http.StatusBadRequest, cland_errors.ErrWebhookInvalid)
+	case errors.Is(err, usecase.ErrWebhookNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrWebhookNotFound)
+	case errors.Is(err, usecase.ErrWebhookDeliveryNotFound):
+		writeError(c, http.StatusNotFound, cland_errors.ErrWebhookDeliveryNotFound)
+	default:
+		writeError(c, http.StatusInternalServerError, cland_errors.Err500)
+	}
+}
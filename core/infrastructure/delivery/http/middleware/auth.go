@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate 解析 JWT 并将身份放入请求上下文；未携带令牌的请求按匿名处理，令牌无效或已注销时直接拒绝
+func Authenticate(authUC *usecase.AuthUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := utils.BearerToken(c.GetHeader("Authorization"))
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		principal, err := authUC.Authenticate(c.Request.Context(), token)
+		switch {
+		case errors.Is(err, usecase.ErrTokenRevoked):
+			abort(c, http.StatusUnauthorized, cland_errors.ErrTokenRevoked)
+			return
+		case errors.Is(err, usecase.ErrTokenInvalid):
+			abort(c, http.StatusUnauthorized, cland_errors.ErrTokenInvalid)
+			return
+		case err != nil:
+			abort(c, http.StatusInternalServerError, cland_errors.Err500)
+			return
+		}
+
+		c.Request = c.Request.WithContext(usecase.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// RequireAuth 要求请求携带有效身份
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !usecase.PrincipalFromContext(c.Request.Context()).IsAuthenticated() {
+			abort(c, http.StatusUnauthorized, cland_errors.Err401)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRoles 要求请求身份为指定角色之一
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := usecase.PrincipalFromContext(c.Request.Context())
+		if !principal.IsAuthenticated() {
+			abort(c, http.StatusUnauthorized, cland_errors.Err401)
+			return
+		}
+		if !principal.HasRole(roles...) {
+			abort(c, http.StatusForbidden, cland_errors.ErrRoleForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+func abort(c *gin.Context, status int, e cland_errors.Error) {
+	c.AbortWithStatusJSON(status, response.Response{
+		Code: e.Code,
+		Msg:  e.Msg,
+	})
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/response"
+	"cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// newAuthRouter 创建带身份校验的测试路由: /me 要求登录并返回身份，/supervision 只允许主管与管理员
+func newAuthRouter(t *testing.T) (*gin.Engine, *usecase.AuthUseCase) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	jwt, err := utils.NewJWTManager(utils.JWTOptions{
+		Issuer:       "cland-test",
+		TTL:          time.Minute,
+		SigningKeyID: "k1",
+		Keys:         []utils.JWTKeyOptions{{ID: "k1", Algorithm: utils.JWTAlgHS256, Secret: []byte("test-secret-of-at-least-32-bytes!")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authUC := usecase.NewAuthUseCase(jwt, repository.NewMemoryTokenRepository(), repository.NewMemoryUserRepository(), time.Hour, zap.NewNop())
+
+	r := gin.New()
+	r.Use(Authenticate(authUC))
+	r.GET("/me", RequireAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, usecase.PrincipalFromContext(c.Request.Context()))
+	})
+	r.GET("/supervision", RequireRoles(entity.RoleSupervisor, entity.RoleAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r, authUC
+}
+
+func issueToken(t *testing.T, authUC *usecase.AuthUseCase, id, role string) string {
+	t.Helper()
+	tokens, err := authUC.IssueTokens(context.Background(), &entity.User{ID: id, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.Token
+}
+
+func serve(r *gin.Engine, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRoleAccess(t *testing.T) {
+	r, authUC := newAuthRouter(t)
+	customer := issueToken(t, authUC, "c1", entity.RoleCustomer)
+	agent := issueToken(t, authUC, "a1", entity.RoleAgent)
+	supervisor := issueToken(t, authUC, "s1", entity.RoleSupervisor)
+	admin := issueToken(t, authUC, "admin", entity.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+		code   int
+	}{
+		{"anonymous", "/me", "", http.StatusUnauthorized, cland_errors.Err401.Code},
+		{"malformed token", "/me", "not-a-jwt", http.StatusUnauthorized, cland_errors.ErrTokenInvalid.Code},
+		{"customer", "/me", customer, http.StatusOK, 0},
+		{"anonymous supervision", "/supervision", "", http.StatusUnauthorized, cland_errors.Err401.Code},
+		{"customer supervision", "/supervision", customer, http.StatusForbidden, cland_errors.ErrRoleForbidden.Code},
+		{"agent supervision", "/supervision", agent, http.StatusForbidden, cland_errors.ErrRoleForbidden.Code},
+		{"supervisor supervision", "/supervision", supervisor, http.StatusOK, 0},
+		{"admin supervision", "/supervision", admin, http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.path, tt.token)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.code != 0 {
+				var body response.Response
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Code != tt.code {
+					t.Errorf("code = %d, want %d", body.Code, tt.code)
+				}
+			}
+		})
+	}
+}
+
+func TestPrincipalComesFromToken(t *testing.T) {
+	r, authUC := newAuthRouter(t)
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+issueToken(t, authUC, "c1", entity.RoleCustomer))
+	// 请求中自带的身份字段不被信任
+	req.Header.Set("X-User-Id", "a1")
+	req.Header.Set("X-User-Role", entity.RoleAdmin)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var principal entity.Principal
+	if err := json.Unmarshal(w.Body.Bytes(), &principal); err != nil {
+		t.Fatal(err)
+	}
+	if principal.UserID != "c1" || principal.Role != entity.RoleCustomer {
+		t.Errorf("principal = %+v, want the token's customer", principal)
+	}
+}
+
+func TestRevokedTokenRejected(t *testing.T) {
+	r, authUC := newAuthRouter(t)
+	token := issueToken(t, authUC, "c1", entity.RoleCustomer)
+	if err := authUC.Logout(context.Background(), token, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(r, "/me", token)
+	var body response.Response
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusUnauthorized || body.Code != cland_errors.ErrTokenRevoked.Code {
+		t.Fatalf("status = %d code = %d, want 401 %d", w.Code, body.Code, cland_errors.ErrTokenRevoked.Code)
+	}
+}
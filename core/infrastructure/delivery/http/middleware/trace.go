@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"cland.org/cland-chat-service/common/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// TraceIDHeader 响应中返回的追踪 ID，便于按 ID 查找日志与 span
+const TraceIDHeader = "X-Trace-Id"
+
+// Trace 为每个请求开始一个 span；请求携带 W3C traceparent 头时延续调用方的追踪，
+// 之后用例层与仓储层的 span 都是它的子节点。需在注册路由之前添加
+func Trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if sc, ok := tracing.ParseTraceparent(c.GetHeader(tracing.TraceparentHeader)); ok {
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracing.Start(ctx, "HTTP "+c.Request.Method+" "+route)
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", c.Request.URL.Path)
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(TraceIDHeader, span.SpanContext().TraceID.String())
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if status >= http.StatusInternalServerError {
+			err := errors.New(http.StatusText(status))
+			if len(c.Errors) > 0 {
+				err = c.Errors.Last()
+			}
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
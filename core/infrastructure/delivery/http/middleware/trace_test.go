@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cland.org/cland-chat-service/common/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTraceContinuesCallerTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var got tracing.SpanContext
+	r := gin.New()
+	r.Use(Trace())
+	r.GET("/api/health", func(c *gin.Context) {
+		got = tracing.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || got.SpanID.String() == "00f067aa0ba902b7" || got.Remote {
+		t.Fatalf("handler span context = %+v, want a local child span of the caller's trace", got)
+	}
+	if w.Header().Get(TraceIDHeader) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("%s = %q, want the caller's trace id", TraceIDHeader, w.Header().Get(TraceIDHeader))
+	}
+
+	// 无效的 traceparent 被忽略，开始新的追踪
+	req = httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if !got.IsValid() || got.TraceID.String() == "00000000000000000000000000000000" || w.Header().Get(TraceIDHeader) != got.TraceID.String() {
+		t.Fatalf("invalid traceparent: span context %+v, header %q", got, w.Header().Get(TraceIDHeader))
+	}
+}
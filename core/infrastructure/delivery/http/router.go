@@ -3,8 +3,12 @@ package router
 import (
 	"net/http"
 	"sync"
+	"time"
 
+	"cland.org/cland-chat-service/core/domain/entity"
 	"cland.org/cland-chat-service/core/infrastructure/delivery/http/handler"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/middleware"
+	"cland.org/cland-chat-service/core/infrastructure/metrics"
 	"cland.org/cland-chat-service/core/usecase"
 	_ "cland.org/cland-chat-service/docs/swagger"
 	"github.com/gin-gonic/gin"
@@ -17,24 +21,77 @@ var (
 	router *gin.Engine
 )
 
-func GetRouter(chatUseCase *usecase.ChatUseCase) *gin.Engine {
+// staffRoles 客服工作台接口允许的角色
+var staffRoles = []string{entity.RoleAgent, entity.RoleSupervisor, entity.RoleAdmin}
+
+// UseCases 路由依赖的用例集合
+type UseCases struct {
+	Chat       *usecase.ChatUseCase
+	User       *usecase.UserUseCase
+	Rating     *usecase.RatingUseCase
+	Canned     *usecase.CannedResponseUseCase
+	Supervisor *usecase.SupervisorUseCase
+	Account    *usecase.AccountUseCase
+	Auth       *usecase.AuthUseCase
+	Identity   *usecase.IdentityUseCase
+	Webhook    *usecase.WebhookUseCase
+	Retention  *usecase.RetentionUseCase
+	Privacy    *usecase.PrivacyUseCase
+	Transcript *usecase.TranscriptUseCase
+	Channel    *usecase.ChannelUseCase
+	Attachment *usecase.AttachmentUseCase
+	Health     *usecase.HealthUseCase
+	SocketIO   http.Handler // Socket.IO 处理器，为空时不挂载(单独监听)
+
+	IdempotencyWindow time.Duration // Idempotency-Key 去重窗口，为 0 时使用默认值
+}
+
+// GetRouter 创建路由，middlewares(如日志与恢复)在路由注册之前添加，对 Socket.IO 以外的所有路由生效
+func GetRouter(useCases UseCases, middlewares ...gin.HandlerFunc) *gin.Engine {
 	once.Do(func() {
-		router = gin.Default()
-		setupRoutes(router, chatUseCase)
+		router = newEngine(useCases.SocketIO, middlewares...)
+		setupRoutes(router, useCases)
 	})
 	return router
 }
 
-func setupRoutes(r *gin.Engine, chatUseCase *usecase.ChatUseCase) {
+// newEngine 创建带恢复、日志、追踪与请求耗时中间件的引擎。Socket.IO 与 HTTP 接口共用端口时在这些中间件之前挂载:
+// 一个 WebSocket 连接在整个生命周期内占用一个请求，经过它们会被记为一次持续数小时的 HTTP 请求。
+// Socket.IO 也不经过 CORS 与认证中间件，握手时自行校验令牌
+func newEngine(socketIO http.Handler, middlewares ...gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	if socketIO != nil {
+		r.Any("/socket.io/*path", gin.WrapH(socketIO))
+	}
+	r.Use(gin.Logger())
+	r.Use(middlewares...)
+	// 分布式追踪与请求耗时指标，需在注册路由之前添加
+	r.Use(middleware.Trace(), metrics.GinMiddleware())
+	return r
+}
+
+func setupRoutes(r *gin.Engine, useCases UseCases) {
+	chatUseCase := useCases.Chat
+
 	// Swagger route
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Prometheus 指标
+	r.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+
+	// 存活与就绪探针，与指标一样不做认证
+	healthHandler := handler.NewHealthHandler(useCases.Health)
+	r.GET("/livez", healthHandler.Livez)
+	r.GET("/readyz", healthHandler.Readyz)
+
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		// Set CORS headers for all responses
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key, cland-cid, cland-agent-team, traceparent")
+		c.Header("Access-Control-Expose-Headers", "Idempotent-Replayed, X-Trace-Id")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		// Handle OPTIONS requests
@@ -47,25 +104,121 @@ func setupRoutes(r *gin.Engine, chatUseCase *usecase.ChatUseCase) {
 
 	// API路由分组
 	api := r.Group("/api")
+	api.Use(middleware.Authenticate(useCases.Auth))
 	{
 		api.GET("/health", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "ok"})
 		})
 
 		// User initialization
-		userUC := usecase.NewUserUseCase(
-			chatUseCase.UserRepo,
-			chatUseCase.SessionRepo,
-		)
 		userHandler := handler.NewUserHandler(
 			chatUseCase.UserRepo,
 			chatUseCase.SessionRepo,
-			userUC,
+			useCases.User,
 		)
 		api.POST("/init", userHandler.InitUser)
 
+		// 员工账号登录，令牌刷新与注销
+		accountHandler := handler.NewAccountHandler(useCases.Account)
+		authHandler := handler.NewAuthHandler(useCases.Auth)
+		api.POST("/auth/login", accountHandler.Login)
+		api.POST("/auth/refresh", authHandler.RefreshToken)
+		api.POST("/auth/logout", authHandler.Logout)
+
+		// 外部渠道服务商回调，由渠道自身校验请求签名
+		channelHandler := handler.NewChannelHandler(useCases.Channel)
+		api.POST("/channels/:channel/inbound", channelHandler.ReceiveChannelMessages)
+
+		// 以下接口需要登录，身份来自 JWT
+		authed := api.Group("", middleware.RequireAuth())
+
 		// 离线消息
-		msgHandler := handler.NewMessageHandler(chatUseCase)
-		api.GET("/messages/offline", msgHandler.GetOfflineMessages)
+		msgHandler := handler.NewMessageHandler(chatUseCase, useCases.IdempotencyWindow)
+		authed.GET("/messages/offline", msgHandler.GetOfflineMessages)
+
+		// 消息发送与引用回复
+		authed.POST("/messages", msgHandler.SendChatMessage)
+		authed.GET("/messages/:msgId/replies", msgHandler.GetMessageReplies)
+		authed.POST("/messages/:msgId/reactions", msgHandler.AddReaction)
+		authed.DELETE("/messages/:msgId/reactions", msgHandler.RemoveReaction)
+		authed.GET("/sessions/:sessionId/messages", msgHandler.GetSessionMessages)
+		authed.GET("/customers/:cid/history", msgHandler.GetCustomerHistory)
+
+		// 会话记录导出
+		transcriptHandler := handler.NewTranscriptHandler(useCases.Transcript)
+		authed.GET("/sessions/:sessionId/transcript", transcriptHandler.GetSessionTranscript)
+
+		// 渠道消息附件下载
+		attachmentHandler := handler.NewAttachmentHandler(useCases.Attachment)
+		authed.GET("/attachments/*key", middleware.RequireRoles(staffRoles...), attachmentHandler.GetAttachment)
+
+		// 访客登录主站后关联主站客户身份
+		identityHandler := handler.NewIdentityHandler(useCases.Identity)
+		authed.POST("/identify", identityHandler.Identify)
+
+		// 满意度调查
+		ratingHandler := handler.NewRatingHandler(useCases.Rating)
+		authed.GET("/sessions/:sessionId/survey", ratingHandler.GetSurvey)
+		authed.POST("/sessions/:sessionId/rating", ratingHandler.SubmitRating)
+		ratings := authed.Group("/ratings", middleware.RequireRoles(staffRoles...))
+		ratings.GET("/agents", ratingHandler.GetAgentRatings)
+		ratings.GET("/daily", ratingHandler.GetDailyRatings)
+
+		// 快捷回复
+		cannedHandler := handler.NewCannedResponseHandler(useCases.Canned)
+		canned := authed.Group("/canned-responses", middleware.RequireRoles(staffRoles...))
+		canned.GET("", cannedHandler.ListCannedResponses)
+		canned.GET("/search", cannedHandler.SearchCannedResponses)
+		canned.POST("", cannedHandler.CreateCannedResponse)
+		canned.GET("/:id", cannedHandler.GetCannedResponse)
+		canned.PUT("/:id", cannedHandler.UpdateCannedResponse)
+		canned.DELETE("/:id", cannedHandler.DeleteCannedResponse)
+
+		// 主管监听、耳语与接管
+		supervisorHandler := handler.NewSupervisorHandler(useCases.Supervisor)
+		supervisor := authed.Group("/supervisor", middleware.RequireRoles(entity.RoleSupervisor, entity.RoleAdmin))
+		supervisor.POST("/sessions/:sessionId/monitor", supervisorHandler.StartMonitor)
+		supervisor.DELETE("/sessions/:sessionId/monitor", supervisorHandler.StopMonitor)
+		supervisor.POST("/sessions/:sessionId/whisper", supervisorHandler.Whisper)
+		supervisor.POST("/sessions/:sessionId/barge-in", supervisorHandler.BargeIn)
+		supervisor.GET("/audit-logs", supervisorHandler.ListAuditLogs)
+
+		// 员工账号管理，/me 供员工查看自己的账号与修改密码，其余仅管理员可用
+		users := authed.Group("/users", middleware.RequireRoles(staffRoles...))
+		users.GET("/me", accountHandler.GetCurrentUser)
+		users.PUT("/me/password", accountHandler.ChangePassword)
+		admin := users.Group("", middleware.RequireRoles(entity.RoleAdmin))
+		admin.GET("", accountHandler.ListUsers)
+		admin.POST("", accountHandler.CreateUser)
+		admin.GET("/:id", accountHandler.GetUser)
+		admin.PUT("/:id", accountHandler.UpdateUser)
+		admin.DELETE("/:id", accountHandler.DeleteUser)
+
+		// webhook 订阅与投递日志，仅管理员可用
+		webhookHandler := handler.NewWebhookHandler(useCases.Webhook)
+		webhooks := authed.Group("/webhooks", middleware.RequireRoles(entity.RoleAdmin))
+		webhooks.GET("", webhookHandler.ListWebhooks)
+		webhooks.POST("", webhookHandler.CreateWebhook)
+		webhooks.GET("/:id", webhookHandler.GetWebhook)
+		webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+		webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", webhookHandler.ListWebhookDeliveries)
+		deliveries := authed.Group("/webhook-deliveries", middleware.RequireRoles(entity.RoleAdmin))
+		deliveries.GET("/:id", webhookHandler.GetWebhookDelivery)
+		deliveries.POST("/:id/replay", webhookHandler.ReplayWebhookDelivery)
+
+		// 消息保留任务进度与归档恢复，仅管理员可用
+		retentionHandler := handler.NewRetentionHandler(useCases.Retention)
+		retention := authed.Group("/retention", middleware.RequireRoles(entity.RoleAdmin))
+		retention.GET("/runs", retentionHandler.ListRetentionRuns)
+		retention.POST("/runs", retentionHandler.TriggerRetentionRun)
+		retention.GET("/sessions/:sessionId", retentionHandler.GetSessionRetention)
+		retention.POST("/sessions/:sessionId/restore", retentionHandler.RestoreSessionArchive)
+
+		// 客户数据导出与擦除，仅管理员可用
+		privacyHandler := handler.NewPrivacyHandler(useCases.Privacy)
+		privacy := authed.Group("/privacy", middleware.RequireRoles(entity.RoleAdmin))
+		privacy.GET("/customers/:cid/export", privacyHandler.ExportCustomerData)
+		privacy.DELETE("/customers/:cid", privacyHandler.EraseCustomerData)
 	}
 }
@@ -0,0 +1,38 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cland.org/cland-chat-service/core/infrastructure/delivery/http/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSocketIOBypassesHTTPMiddlewares(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	socketIO := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	logged := 0
+	r := newEngine(socketIO, func(c *gin.Context) {
+		logged++
+		c.Next()
+	})
+	r.GET("/api/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/socket.io/?EIO=4&transport=websocket", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("socket.io status = %d, want 200", w.Code)
+	}
+	if logged != 0 || w.Header().Get(middleware.TraceIDHeader) != "" {
+		t.Fatalf("socket.io request went through HTTP middlewares: logged=%d trace=%q", logged, w.Header().Get(middleware.TraceIDHeader))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if logged != 1 || w.Header().Get(middleware.TraceIDHeader) == "" {
+		t.Fatalf("API request skipped HTTP middlewares: logged=%d trace=%q", logged, w.Header().Get(middleware.TraceIDHeader))
+	}
+}
@@ -2,10 +2,14 @@ package connection
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/dto"
+	"cland.org/cland-chat-service/core/infrastructure/metrics"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
@@ -15,6 +19,7 @@ type Manager struct {
 	connections map[string]*websocket.Conn // userID -> connection
 	lastActive  map[string]time.Time       // userID -> last active time
 	rooms       map[string]map[string]bool // roomID -> userIDs
+	sender      dto.MessageSender          // Socket.IO 事件编码发送
 	mu          sync.RWMutex
 	log         *zap.Logger
 }
@@ -44,6 +49,27 @@ func (m *Manager) AddConnection(conn *websocket.Conn, userID string) {
 	m.log.Info("New Socket.IO connection", zap.String("userID", userID))
 }
 
+// SetMessageSender 设置 Socket.IO 事件发送器
+func (m *Manager) SetMessageSender(sender dto.MessageSender) {
+	m.mu.Lock()
+	m.sender = sender
+	m.mu.Unlock()
+}
+
+// GetConnection 获取在线用户的连接
+func (m *Manager) GetConnection(userID string) (*websocket.Conn, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	conn, ok := m.connections[userID]
+	return conn, ok
+}
+
+// IsOnline 用户当前是否有连接
+func (m *Manager) IsOnline(userID string) bool {
+	_, ok := m.GetConnection(userID)
+	return ok
+}
+
 // RemoveConnection 移除连接
 func (m *Manager) RemoveConnection(userID string) {
 	m.mu.Lock()
@@ -74,7 +100,7 @@ func (m *Manager) CheckTimeoutConnections(timeout time.Duration) []string {
 	for userID, lastActive := range m.lastActive {
 		if now.Sub(lastActive) > timeout {
 			if conn, exists := m.connections[userID]; exists {
-				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "connection timeout"))
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "connection timeout"), now.Add(time.Second))
 				conn.Close()
 				delete(m.connections, userID)
 				delete(m.lastActive, userID)
@@ -83,9 +109,17 @@ func (m *Manager) CheckTimeoutConnections(timeout time.Duration) []string {
 		}
 	}
 
+	metrics.HeartbeatTimeouts.Add(float64(len(timedOut)))
 	return timedOut
 }
 
+// ConnectionCount 当前在线连接数
+func (m *Manager) ConnectionCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.connections)
+}
+
 // SendMessage 发送消息到指定用户
 func (m *Manager) SendMessage(userID string, message interface{}) error {
 	m.mu.RLock()
@@ -105,6 +139,8 @@ func (m *Manager) SendMessage(userID string, message interface{}) error {
 	}
 
 	// 发送消息
+	unlock := LockWrite(conn)
+	defer unlock()
 	return conn.WriteMessage(websocket.TextMessage, data)
 }
 
@@ -164,10 +200,70 @@ func (m *Manager) sendToUsers(message interface{}, userIDs []string) error {
 
 	for _, userID := range userIDs {
 		if conn, ok := m.connections[userID]; ok {
-			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			unlock := LockWrite(conn)
+			err := conn.WriteMessage(websocket.TextMessage, data)
+			unlock()
+			if err != nil {
 				return err
 			}
 		}
 	}
 	return nil
 }
+
+// RoomMembers 返回房间内的用户ID
+func (m *Manager) RoomMembers(roomID string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userIDs := make([]string, 0, len(m.rooms[roomID]))
+	for userID := range m.rooms[roomID] {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
+// EmitToUsers 以 Socket.IO 事件的形式推送给多个用户，离线用户直接跳过
+func (m *Manager) EmitToUsers(eventName string, data interface{}, userIDs []string) error {
+	m.mu.RLock()
+	sender := m.sender
+	conns := make(map[string]*websocket.Conn, len(userIDs))
+	for _, userID := range userIDs {
+		if conn, ok := m.connections[userID]; ok {
+			conns[userID] = conn
+		}
+	}
+	m.mu.RUnlock()
+
+	if sender == nil {
+		return errors.New("message sender not configured")
+	}
+
+	var firstErr error
+	for userID, conn := range conns {
+		if err := sender.SendEvent(conn, "/", eventName, data); err != nil {
+			m.log.Warn("Failed to emit event",
+				zap.String("event", eventName),
+				zap.String("userID", userID),
+				zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// PushMessage 以 message 事件推送聊天消息，格式与客户端收到的普通消息一致
+func (m *Manager) PushMessage(msg entity.Message, userIDs []string) error {
+	return m.EmitToUsers("message", dto.FromEntity(msg).ToWSMessage(), userIDs)
+}
+
+// PushMessageToRoom 以 message 事件推送聊天消息给房间内的所有在线用户
+func (m *Manager) PushMessageToRoom(msg entity.Message, roomID string) error {
+	userIDs := m.RoomMembers(roomID)
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return m.PushMessage(msg, userIDs)
+}
@@ -0,0 +1,23 @@
+package connection
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeLocks 每个连接的写锁。gorilla/websocket 同一连接同时只允许一个写入方，
+// 推送、回执与关闭包都经由此锁串行写入
+var writeLocks sync.Map // *websocket.Conn -> *sync.Mutex
+
+// LockWrite 获取连接的写锁，返回解锁函数
+func LockWrite(conn *websocket.Conn) func() {
+	mu, _ := writeLocks.LoadOrStore(conn, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// ForgetConn 连接关闭后释放其写锁
+func ForgetConn(conn *websocket.Conn) {
+	writeLocks.Delete(conn)
+}
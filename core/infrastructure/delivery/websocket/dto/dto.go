@@ -21,6 +21,12 @@ type WSMessage struct {
 	Data interface{} `json:"data"`
 }
 
+// ReactionRequest 表情回应事件(reaction_add/reaction_remove)负载
+type ReactionRequest struct {
+	MsgID string `json:"msgId"`
+	Emoji string `json:"emoji"`
+}
+
 // ChatMessage 聊天消息DTO
 type ChatMessage struct {
 	entity.Message
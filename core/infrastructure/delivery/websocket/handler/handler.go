@@ -6,34 +6,115 @@ import (
 	"errors"
 	"log"
 	"sync"
+	"time"
 
 	cland_errors "cland.org/cland-chat-service/common/errors"
+	"cland.org/cland-chat-service/common/tracing"
 	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
 	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/connection"
 	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/dto"
+	"cland.org/cland-chat-service/core/infrastructure/logger"
 	"cland.org/cland-chat-service/core/usecase"
 	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
 )
 
+// Socket.IO 客户端事件名
+const (
+	EventMessage        = "message"
+	EventMessageAck     = "message_ack"
+	EventReactionAdd    = "reaction_add"
+	EventReactionRemove = "reaction_remove"
+)
+
+// errInvalidFormat 消息无法解析
+var errInvalidFormat = errors.New("invalid message format")
+
 type Handler struct {
 	ChatUseCase       *usecase.ChatUseCase
 	ConnectionManager *connection.Manager
 	MessageSender     dto.MessageSender
-	connections       sync.Map // map[string]*websocket.Conn
+	Logger            *zap.Logger      // 记录事件处理结果，可为空
+	UserID            string           // 当前连接对应的用户(cland-cid)
+	Principal         entity.Principal // 握手时由 JWT 确定的身份
+	connections       sync.Map         // map[string]*websocket.Conn
+}
+
+// eventTrace 客户端可在事件数据中携带 W3C traceparent，以延续客户端的追踪
+type eventTrace struct {
+	Traceparent string `json:"traceparent"`
+}
+
+// HandleEvent 按事件名分发客户端事件，未知事件按聊天消息处理。每个事件一个 span，
+// 处理结果连同追踪 ID 记录到日志
+func (h *Handler) HandleEvent(conn *websocket.Conn, eventName string, data string) {
+	start := time.Now()
+	ctx := h.context()
+	var trace eventTrace
+	if json.Unmarshal([]byte(data), &trace) == nil {
+		if sc, ok := tracing.ParseTraceparent(trace.Traceparent); ok {
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+		}
+	}
+	ctx, span := tracing.Start(ctx, "socket "+eventName)
+	span.SetAttribute("socket.event", eventName)
+	span.SetAttribute("user.id", h.UserID)
+
+	var err error
+	switch eventName {
+	case EventReactionAdd, EventReactionRemove:
+		err = h.handleReaction(ctx, conn, eventName, data)
+	default:
+		err = h.handleMessage(ctx, conn, data)
+	}
+	if err != nil {
+		h.sendError(conn, err)
+	}
+	span.EndWithError(err)
+	h.logEvent(ctx, eventName, time.Since(start), err)
 }
 
-func (h *Handler) HandleMessage(conn *websocket.Conn, data string) {
-	// Parse message
+// handleMessage 解析并处理聊天消息
+func (h *Handler) handleMessage(ctx context.Context, conn *websocket.Conn, data string) error {
 	var msg entity.Message
 	if err := json.Unmarshal([]byte(data), &msg); err != nil {
-		h.sendError(conn, "invalid message format")
-		return
+		return errInvalidFormat
+	}
+	return h.processMessage(ctx, conn, msg)
+}
+
+// handleReaction 处理表情回应的添加与移除，结果通过 reaction_updated 事件广播
+func (h *Handler) handleReaction(ctx context.Context, conn *websocket.Conn, eventName string, data string) error {
+	var req dto.ReactionRequest
+	if err := json.Unmarshal([]byte(data), &req); err != nil || req.MsgID == "" {
+		return errInvalidFormat
 	}
 
-	// Process message
-	if err := h.processMessage(conn, msg); err != nil {
-		h.sendError(conn, err.Error())
+	var err error
+	if eventName == EventReactionAdd {
+		_, err = h.ChatUseCase.AddReaction(ctx, req.MsgID, h.UserID, req.Emoji)
+	} else {
+		_, err = h.ChatUseCase.RemoveReaction(ctx, req.MsgID, h.UserID, req.Emoji)
+	}
+	return err
+}
+
+// logEvent 记录事件处理结果，未设置 Logger 时不记录
+func (h *Handler) logEvent(ctx context.Context, eventName string, latency time.Duration, err error) {
+	if h.Logger == nil {
+		return
+	}
+	fields := append([]zap.Field{
+		zap.String("event", eventName),
+		zap.String("userID", h.UserID),
+		zap.Duration("latency", latency),
+	}, logger.TraceFields(ctx)...)
+	if err != nil {
+		h.Logger.Warn("socket event failed", append(fields, zap.Error(err))...)
+		return
 	}
+	h.Logger.Debug("socket event handled", fields...)
 }
 
 func (h *Handler) HandleError(conn *websocket.Conn, err error) {
@@ -53,51 +134,88 @@ func (h *Handler) HandleDisconnect(conn *websocket.Conn, reason string) {
 }
 
 // processMessage 处理消息业务逻辑
-func (h *Handler) processMessage(conn *websocket.Conn, msg entity.Message) error {
-	ctx := context.Background()
-
+func (h *Handler) processMessage(ctx context.Context, conn *websocket.Conn, msg entity.Message) error {
 	switch msg.MsgType {
-	case entity.MsgTypeMessage, entity.MsgTypeNotification:
-		if err := h.ChatUseCase.SendMessage(ctx, &msg); err != nil {
+	case entity.MsgTypeMessage, entity.MsgTypeNotification, entity.MsgTypeInternalNote:
+		err := h.ChatUseCase.SendMessage(ctx, &msg)
+		if errors.Is(err, usecase.ErrDuplicateMessage) {
+			// 客户端重试了已存储的消息: 回执原消息及其当前状态，不再重复推送
+			return h.sendAck(conn, msg, "duplicate")
+		}
+		if err != nil {
 			return err
 		}
-		return h.pushMessage(msg)
+		// 启用发件箱时由分发器推送，这里只回执
+		if !h.ChatUseCase.OutboxEnabled() {
+			if err := h.pushMessage(ctx, msg); err != nil {
+				return err
+			}
+		}
+		return h.sendAck(conn, msg, "success")
 	case entity.MsgTypeAck:
-		return h.ChatUseCase.ProcessMessageStatus(ctx, msg.MsgID, entity.StatusRead)
+		return h.ChatUseCase.AcknowledgeMessage(ctx, msg.MsgID)
 	default:
 		return errors.New("unsupported message type")
 	}
 }
 
-// pushMessage 推送消息给接收方
-func (h *Handler) pushMessage(msg entity.Message) error {
-	// Handle room messages (prefix with "room:")
-	if len(msg.Dst) > 5 && msg.Dst[:5] == "room:" {
-		roomID := msg.Dst[5:]
-		msg.Status = entity.StatusDelivered
-		wsMsg := dto.FromEntity(msg).ToWSMessage()
-		return h.ConnectionManager.BroadcastToRoom(wsMsg, roomID)
-	}
-
-	// Handle direct messages
-	recipientID := msg.Dst
-	if len(msg.Dst) > 2 && msg.Dst[1] == ':' {
-		recipientID = msg.Dst[2:]
-	}
+// pushMessage 推送消息给接收方，内部备注只推送给客服
+func (h *Handler) pushMessage(ctx context.Context, msg entity.Message) error {
+	pusher := MessagePusher{ChatUseCase: h.ChatUseCase, ConnectionManager: h.ConnectionManager}
+	return pusher.Push(ctx, msg, h.UserID)
+}
 
-	msg.Status = entity.StatusDelivered
-	wsMsg := dto.FromEntity(msg).ToWSMessage()
-	if conn, ok := h.connections.Load(recipientID); ok {
-		return h.MessageSender.SendEvent(conn.(*websocket.Conn), "/", "message", wsMsg)
-	}
+// context 创建携带当前连接身份的上下文
+func (h *Handler) context() context.Context {
+	return usecase.WithPrincipal(context.Background(), h.Principal)
+}
 
-	// 接收方离线，更新为离线状态
-	return h.ChatUseCase.ProcessMessageStatus(context.Background(), msg.MsgID, entity.StatusOffline)
+// sendAck 向发送方回执消息的存储结果，便于客户端按 msgId 对账重试
+func (h *Handler) sendAck(conn *websocket.Conn, msg entity.Message, result string) error {
+	return h.MessageSender.SendEvent(conn, "/", EventMessageAck, dto.WSMessage{
+		Code: 200,
+		Msg:  result,
+		Data: msg,
+	})
 }
 
 // sendError 发送错误消息
-func (h *Handler) sendError(conn *websocket.Conn, errMsg string) {
-	h.MessageSender.SendEvent(conn, "/socket.io/", "message", cland_errors.Err500)
+func (h *Handler) sendError(conn *websocket.Conn, err error) {
+	h.MessageSender.SendEvent(conn, "/socket.io/", "message", toWSError(err))
+}
+
+// toWSError 将业务错误映射为客户端可识别的错误码
+func toWSError(err error) cland_errors.Error {
+	switch {
+	case errors.Is(err, errInvalidFormat):
+		return cland_errors.Err400
+	case errors.Is(err, usecase.ErrInvalidReplyTo):
+		return cland_errors.ErrReplyToInvalid
+	case errors.Is(err, usecase.ErrMsgIDConflict):
+		return cland_errors.ErrMsgIDConflict
+	case errors.Is(err, usecase.ErrInvalidEmoji):
+		return cland_errors.ErrEmojiInvalid
+	case errors.Is(err, usecase.ErrUnauthenticated):
+		return cland_errors.Err401
+	case errors.Is(err, usecase.ErrForbidden):
+		return cland_errors.ErrRoleForbidden
+	case errors.Is(err, usecase.ErrSenderMismatch):
+		return cland_errors.ErrSenderMismatch
+	case errors.Is(err, usecase.ErrSessionAccessDenied):
+		return cland_errors.ErrSessionAccessDenied
+	case errors.Is(err, usecase.ErrAckForbidden):
+		return cland_errors.ErrAckForbidden
+	case errors.Is(err, usecase.ErrInternalNoteForbidden):
+		return cland_errors.ErrInternalNoteForbidden
+	case errors.Is(err, usecase.ErrNotificationForbidden):
+		return cland_errors.ErrNotificationForbidden
+	case errors.Is(err, usecase.ErrCannedResponseNotFound):
+		return cland_errors.ErrCannedResponseNotFound
+	case errors.Is(err, repository.ErrNotFound):
+		return cland_errors.ErrMessageNotFound
+	default:
+		return cland_errors.Err500
+	}
 }
 
 // BroadcastMessage 广播消息给多个用户
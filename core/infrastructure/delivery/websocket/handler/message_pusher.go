@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/connection"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/dto"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+// MessagePusher 推送新消息给接收方，接收方离线时标记为离线消息。
+// 启用发件箱时作为下游处理 message.created
+type MessagePusher struct {
+	ChatUseCase       *usecase.ChatUseCase
+	ConnectionManager *connection.Manager
+}
+
+var _ usecase.OutboxSink = (*MessagePusher)(nil)
+
+// Push 推送消息给接收方，内部备注只推送给客服；senderID 为发送方，内部备注不回推给自己
+func (p *MessagePusher) Push(ctx context.Context, msg entity.Message, senderID string) error {
+	// Handle room messages (prefix with "room:")
+	if len(msg.Dst) > 5 && msg.Dst[:5] == "room:" {
+		if msg.IsInternal() {
+			// 房间成员可能包含客户，内部备注不做房间广播
+			return nil
+		}
+		roomID := msg.Dst[5:]
+		msg.Status = entity.StatusDelivered
+		wsMsg := dto.FromEntity(msg).ToWSMessage()
+		return p.ConnectionManager.BroadcastToRoom(wsMsg, roomID)
+	}
+
+	// Handle direct messages
+	recipientID := entity.UserIDFromAddress(msg.Dst)
+	if msg.IsInternal() {
+		if recipientID == senderID {
+			return nil
+		}
+		recipient, err := p.ChatUseCase.ResolvePrincipal(ctx, recipientID)
+		if err != nil {
+			return err
+		}
+		if !recipient.CanSee(&msg) {
+			return nil
+		}
+	}
+
+	msg.Status = entity.StatusDelivered
+	wsMsg := dto.FromEntity(msg).ToWSMessage()
+	if _, ok := p.ConnectionManager.GetConnection(recipientID); ok {
+		return p.ConnectionManager.EmitToUsers(EventMessage, wsMsg, []string{recipientID})
+	}
+
+	// 接收方离线，更新为离线状态
+	return p.ChatUseCase.ProcessMessageStatus(ctx, msg.MsgID, entity.StatusOffline)
+}
+
+// HandleOutboxEvent 推送发件箱中的新消息。重复分发时消息可能已被标记离线或已被确认，
+// 状态无法再变更视为已处理
+func (p *MessagePusher) HandleOutboxEvent(ctx context.Context, event *entity.OutboxEvent) error {
+	if event.EventType != entity.WebhookEventMessageCreated {
+		return nil
+	}
+	var msg entity.Message
+	if err := json.Unmarshal(event.Payload, &msg); err != nil {
+		return err
+	}
+	err := p.Push(ctx, msg, entity.UserIDFromAddress(msg.Src))
+	if errors.Is(err, usecase.ErrInvalidStatusTransition) {
+		return nil
+	}
+	return err
+}
@@ -1,13 +1,18 @@
 package sockio
 
 import (
+	"context"
 	"errors"
 	"math/rand"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
 	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/connection"
 	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/handler"
 	"cland.org/cland-chat-service/core/usecase"
@@ -19,21 +24,38 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-// WsServer 封装 WebSocket 服务器
+// WsServer 封装 WebSocket 服务器。Handler 可挂载到主服务的路由，也可由 ListenAndServe 单独监听
 type WsServer struct {
 	logger      *zap.Logger
 	chatUseCase *usecase.ChatUseCase
+	authUseCase *usecase.AuthUseCase
 	upgrader    websocket.Upgrader
 	protocol    *EngineIOProtocol
 	connManager *connection.Manager
-	once        sync.Once
+	listening   atomic.Bool
+	closing     atomic.Bool
+	mu          sync.Mutex
+	server      *http.Server                      // 单独监听时的 HTTP 服务
+	conns       map[*websocket.Conn]chan struct{} // 连接 -> 读循环退出时关闭
+	serveErr    error                             // 监听失败或服务异常退出的原因
 }
 
+var (
+	// errNotListening WebSocket 服务尚未开始监听
+	errNotListening = errors.New("websocket server is not listening yet")
+	// errShuttingDown WebSocket 服务正在关闭，不再接受新连接
+	errShuttingDown = errors.New("websocket server is shutting down")
+)
+
 // NewWsServer creates a new WebSocket server
-func NewWsServer(logger *zap.Logger, chatUseCase *usecase.ChatUseCase) *WsServer {
+func NewWsServer(logger *zap.Logger, chatUseCase *usecase.ChatUseCase, authUseCase *usecase.AuthUseCase, connManager *connection.Manager) *WsServer {
+	protocol := NewEngineIOProtocol()
+	// 连接管理器与 HTTP 层共享，事件统一按 Socket.IO 协议编码
+	connManager.SetMessageSender(NewSocketIOMessageSender(protocol, logger))
 	return &WsServer{
-		logger:      logger,
+		logger:      logger.Named("websocket"),
 		chatUseCase: chatUseCase,
+		authUseCase: authUseCase,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -41,98 +63,223 @@ func NewWsServer(logger *zap.Logger, chatUseCase *usecase.ChatUseCase) *WsServer
 				return true // Allow all origins
 			},
 		},
-		protocol: NewEngineIOProtocol(),
+		protocol:    protocol,
+		connManager: connManager,
+		conns:       make(map[*websocket.Conn]chan struct{}),
+	}
+}
+
+// Handler 返回 /socket.io/ 的处理器，挂载到主服务时由主服务负责监听
+func (s *WsServer) Handler() http.Handler {
+	s.listening.Store(true)
+	return http.HandlerFunc(s.serveSocketIO)
+}
+
+// ListenAndServe 在 addr 上单独监听，除 /socket.io/ 外还提供 ./asset 下的静态文件；
+// 阻塞直到 Shutdown 或监听失败，Shutdown 时返回 http.ErrServerClosed
+func (s *WsServer) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/socket.io/", s.serveSocketIO)
+	mux.Handle("/", http.FileServer(http.Dir("./asset")))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.stopped(err)
+		return err
+	}
+	server := &http.Server{Handler: mux}
+	s.mu.Lock()
+	s.server = server
+	s.mu.Unlock()
+	s.listening.Store(true)
+	s.logger.Info("Serving Socket.IO", zap.String("address", listener.Addr().String()))
+
+	err = server.Serve(listener)
+	if !errors.Is(err, http.ErrServerClosed) {
+		s.stopped(err)
+	}
+	return err
+}
+
+// CheckListening 服务正在监听且未在关闭时返回 nil，用于就绪检查
+func (s *WsServer) CheckListening(ctx context.Context) error {
+	if err := s.CheckServing(ctx); err != nil {
+		return err
+	}
+	if s.closing.Load() {
+		return errShuttingDown
+	}
+	if !s.listening.Load() {
+		return errNotListening
+	}
+	return nil
+}
+
+// CheckServing 监听失败或服务异常退出时返回原因，启动过程中返回 nil，用于存活检查
+func (s *WsServer) CheckServing(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.serveErr
+}
+
+// stopped 记录服务异常退出的原因
+func (s *WsServer) stopped(err error) {
+	s.listening.Store(false)
+	s.mu.Lock()
+	s.serveErr = err
+	s.mu.Unlock()
+}
+
+// Shutdown 优雅关闭: 不再接受新连接，等待各连接未完成的写入后发送 Engine.IO close 包与关闭帧，
+// 再等待客户端断开；ctx 到期时强制关闭剩余连接并返回 ctx 的错误
+func (s *WsServer) Shutdown(ctx context.Context) error {
+	s.closing.Store(true)
+
+	s.mu.Lock()
+	server := s.server
+	conns := make(map[*websocket.Conn]chan struct{}, len(s.conns))
+	for conn, done := range s.conns {
+		conns[conn] = done
+	}
+	s.mu.Unlock()
+
+	var shutdownErr error
+	if server != nil {
+		// 单独监听时先关闭监听；已升级的连接不受 http.Server 管理，下面逐个关闭
+		shutdownErr = server.Shutdown(ctx)
+	}
+
+	s.logger.Info("Closing Socket.IO connections", zap.Int("connections", len(conns)))
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	for conn := range conns {
+		go s.closeConn(conn, deadline)
+	}
+
+	for _, done := range conns {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			// 客户端未在期限内断开，强制关闭剩余连接
+			s.mu.Lock()
+			for conn := range s.conns {
+				conn.Close()
+			}
+			s.mu.Unlock()
+			return ctx.Err()
+		}
 	}
+	return shutdownErr
 }
 
-// InitWsServer 初始化 WebSocket 服务器
-func InitWsServer(logger *zap.Logger, chatUseCase *usecase.ChatUseCase) *WsServer {
-	server := NewWsServer(logger, chatUseCase)
-	server.init()
-	return server
+// closeConn 取得写锁(即等待进行中的写入完成)后发送 close 包与关闭帧，读循环在客户端回应关闭后退出
+func (s *WsServer) closeConn(conn *websocket.Conn, deadline time.Time) {
+	unlock := connection.LockWrite(conn)
+	defer unlock()
+	_ = conn.SetWriteDeadline(deadline)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(PacketTypeClose)); err != nil {
+		conn.Close()
+		return
+	}
+	if err := conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), deadline); err != nil {
+		conn.Close()
+	}
 }
 
-// init 初始化 WebSocket 配置
-func (s *WsServer) init() {
-	s.once.Do(func() {
-		s.setupWebSocket()
-	})
+// track 登记新连接，关闭期间返回 false
+func (s *WsServer) track(conn *websocket.Conn) (chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing.Load() {
+		return nil, false
+	}
+	done := make(chan struct{})
+	s.conns[conn] = done
+	return done, true
 }
 
-// setupWebSocket 配置 Socket.IO 事件
-func (s *WsServer) setupWebSocket() {
-	log := s.logger.Named("websocket")
-	log.Info("Setting up WebSocket server")
+// untrack 连接结束后注销并关闭底层连接
+func (s *WsServer) untrack(conn *websocket.Conn, done chan struct{}) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	conn.Close()
+	connection.ForgetConn(conn)
+	close(done)
+}
+
+// serveSocketIO 处理 Socket.IO 握手与 WebSocket 连接
+func (s *WsServer) serveSocketIO(w http.ResponseWriter, r *http.Request) {
+	log := s.logger
 	defer func() {
-		if r := recover(); r != nil {
-			log.Error("Recovered from panic in setupWebSocket", zap.Any("error", r))
+		if p := recover(); p != nil {
+			log.Error("Recovered from panic in Socket.IO handler", zap.Any("error", p))
 		}
 	}()
 
-	// 创建连接管理器
-	s.connManager = connection.NewManager(s.logger)
+	// 检查是否是 Socket.IO 握手请求
+	if r.URL.Query().Get("EIO") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if s.closing.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
 
-	// 创建 HTTP 路由
-	http.HandleFunc("/socket.io/", func(w http.ResponseWriter, r *http.Request) {
-		// 检查是否是 Socket.IO 握手请求
-		if r.URL.Query().Get("EIO") == "" {
-			w.WriteHeader(http.StatusBadRequest)
+	// Handle polling transport
+	if r.Method == "GET" && r.URL.Query().Get("transport") == "polling" {
+		sid := generateSessionID() // Implement this function
+		if err := s.protocol.SendHandshake(w, sid); err != nil {
+			log.Error("Failed to send handshake", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		return
+	}
 
-		// Handle polling transport
-		if r.Method == "GET" && r.URL.Query().Get("transport") == "polling" {
-			sid := generateSessionID() // Implement this function
-			if err := s.protocol.SendHandshake(w, sid); err != nil {
-				log.Error("Failed to send handshake", zap.Error(err))
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+	// Handle WebSocket transport - only upgrade if Upgrade header is present
+	if strings.ToLower(r.Header.Get("Upgrade")) == "websocket" {
+		conn, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Error("Failed to upgrade connection", zap.Error(err))
 			return
 		}
-
-		// Handle WebSocket transport - only upgrade if Upgrade header is present
-		if strings.ToLower(r.Header.Get("Upgrade")) == "websocket" {
-			conn, err := s.upgrader.Upgrade(w, r, nil)
-			if err != nil {
-				log.Error("Failed to upgrade connection", zap.Error(err))
-				return
-			}
-
-			// Send handshake ack
-			sid := generateSessionID() // Implement this function
-			if err := s.protocol.SendPacket(conn, PacketTypeOpen, map[string]interface{}{
-				"sid":          sid,
-				"upgrades":     []string{"websocket"},
-				"pingInterval": 25000,
-				"pingTimeout":  5000,
-			}); err != nil {
-				log.Error("Failed to send handshake ack", zap.Error(err))
-				conn.Close()
-				return
-			}
-
-			// Handle connection
-			if clandCid, err := s.handleConnection(conn, r); err != nil {
-				log.Error("Failed to send handshake ack", zap.Error(err))
-				conn.Close()
-				return
-			} else {
-				s.handle0(conn, clandCid)
-			}
+		done, ok := s.track(conn)
+		if !ok {
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(time.Second))
+			conn.Close()
 			return
 		}
+		defer s.untrack(conn, done)
 
-		// Continue with normal HTTP handling if not WebSocket upgrade
-		w.WriteHeader(http.StatusBadRequest)
-	})
+		// Send handshake ack
+		sid := generateSessionID() // Implement this function
+		if err := s.protocol.SendPacket(conn, PacketTypeOpen, map[string]interface{}{
+			"sid":          sid,
+			"upgrades":     []string{"websocket"},
+			"pingInterval": 25000,
+			"pingTimeout":  5000,
+		}); err != nil {
+			log.Error("Failed to send handshake ack", zap.Error(err))
+			return
+		}
 
-	http.Handle("/", http.FileServer(http.Dir("./asset")))
-	s.logger.Info("Serving at localhost:8081...")
-	err := http.ListenAndServe(":8081", nil)
-	if err != nil {
-		s.logger.Error("ws ListenAndServe", zap.Error(err))
+		// Handle connection
+		if principal, err := s.handleConnection(conn, r); err != nil {
+			log.Error("Failed to authenticate connection", zap.Error(err))
+			return
+		} else {
+			s.handle0(conn, principal)
+		}
+		return
 	}
+
+	// Continue with normal HTTP handling if not WebSocket upgrade
+	w.WriteHeader(http.StatusBadRequest)
 }
 
 // generateSessionID generates a unique session ID
@@ -150,25 +297,41 @@ func randString(n int) string {
 	return string(b)
 }
 
-// handleConnection handles WebSocket connections
-func (s *WsServer) handleConnection(conn *websocket.Conn, r *http.Request) (string, error) {
+// handleConnection handles WebSocket connections; the user is taken from the JWT passed
+// as the token query parameter or Authorization header
+func (s *WsServer) handleConnection(conn *websocket.Conn, r *http.Request) (entity.Principal, error) {
 	log := s.logger.With(zap.String("remote_addr", conn.RemoteAddr().String()))
 
 	// Get connection parameters
-	clandCID := r.URL.Query().Get("cland-cid")
-	if clandCID == "" {
-		log.Warn("Missing cland-cid, rejecting connection")
-		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4001, "missing cland-cid"))
-		return "", errors.New("miss cid")
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token, _ = utils.BearerToken(r.Header.Get("Authorization"))
+	}
+	if token == "" {
+		log.Warn("Missing token, rejecting connection")
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4001, "missing token"))
+		return entity.Principal{}, errors.New("missing token")
+	}
+	principal, err := s.authUseCase.Authenticate(r.Context(), token)
+	if err != nil {
+		log.Warn("Invalid token, rejecting connection", zap.Error(err))
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4003, "invalid token"))
+		return entity.Principal{}, err
+	}
+	if cid := r.URL.Query().Get("cland-cid"); cid != "" && cid != principal.UserID {
+		log.Warn("cland-cid does not match token, rejecting connection")
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4003, "cland-cid mismatch"))
+		return entity.Principal{}, errors.New("cland-cid mismatch")
 	}
-	// Add connection to manager
-	s.connManager.AddConnection(conn, clandCID)
 
-	return clandCID, nil
+	// Add connection to manager
+	s.connManager.AddConnection(conn, principal.UserID)
 
+	return principal, nil
 }
 
-func (s *WsServer) handle0(conn *websocket.Conn, clandCID string) {
+func (s *WsServer) handle0(conn *websocket.Conn, principal entity.Principal) {
+	clandCID := principal.UserID
 	log := s.logger.With(zap.String("remote_addr", conn.RemoteAddr().String()))
 	// Create WebSocket handler
 	messageSender := NewSocketIOMessageSender(s.protocol, s.logger)
@@ -176,6 +339,9 @@ func (s *WsServer) handle0(conn *websocket.Conn, clandCID string) {
 		ChatUseCase:       s.chatUseCase,
 		ConnectionManager: s.connManager,
 		MessageSender:     messageSender,
+		Logger:            log,
+		UserID:            clandCID,
+		Principal:         principal,
 	}
 
 	// Setup heartbeat checker
@@ -245,14 +411,14 @@ func (s *WsServer) handle0(conn *websocket.Conn, clandCID string) {
 						}
 					default:
 						// Parse event payload
-						_, eventData, err := s.protocol.ParseEventPayload(sioPayload)
+						eventName, eventData, err := s.protocol.ParseEventPayload(sioPayload)
 						if err != nil {
 							log.Error("Failed to parse event payload", zap.Error(err))
 							continue
 						}
 
-						// Process message through handler
-						wsHandler.HandleMessage(conn, string(eventData))
+						// Dispatch event through handler
+						wsHandler.HandleEvent(conn, eventName, string(eventData))
 					}
 				case PacketTypeClose:
 					s.connManager.RemoveConnection(clandCID)
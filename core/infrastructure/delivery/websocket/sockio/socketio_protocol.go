@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/connection"
 	"github.com/gorilla/websocket"
 )
 
@@ -193,6 +194,8 @@ func (p *EngineIOProtocol) SendPacket(conn *websocket.Conn, packetType string, d
 		}
 		msg = packetType + string(jsonData)
 	}
+	unlock := connection.LockWrite(conn)
+	defer unlock()
 	return conn.WriteMessage(websocket.TextMessage, []byte(msg))
 }
 
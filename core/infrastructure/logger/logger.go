@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"cland.org/cland-chat-service/common/tracing"
 	"cland.org/cland-chat-service/core/infrastructure/config"
+	"context"
 	"log"
 	"net"
 	"net/http"
@@ -158,6 +160,8 @@ func GinLogger(log *zap.Logger) gin.HandlerFunc {
 			zap.Duration("latency", time.Since(start)),
 		}
 
+		fields = append(fields, TraceFields(c.Request.Context())...)
+
 		// 添加错误信息(如果有)
 		if len(c.Errors) > 0 {
 			fields = append(fields, zap.Strings("errors", c.Errors.Errors()))
@@ -174,6 +178,18 @@ func GinLogger(log *zap.Logger) gin.HandlerFunc {
 	}
 }
 
+// TraceFields 返回 ctx 中追踪上下文的日志字段(trace_id、span_id)，没有追踪时返回 nil
+func TraceFields(ctx context.Context) []zap.Field {
+	sc := tracing.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID.String()),
+		zap.String("span_id", sc.SpanID.String()),
+	}
+}
+
 // GinRecovery 返回一个gin框架的恢复中间件
 func GinRecovery(log *zap.Logger, stack bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,95 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+const digestTimeLayout = "2006-01-02 15:04 MST"
+
+var digestFuncs = map[string]interface{}{
+	"time": func(t interface{ Format(string) string }) string { return t.Format(digestTimeLayout) },
+}
+
+var digestText = texttemplate.Must(texttemplate.New("digest").Funcs(digestFuncs).Parse(
+	`Hi {{.Name}},
+
+You have {{len .Replies}} unread {{if eq (len .Replies) 1}}reply{{else}}replies{{end}} from our support team:
+
+{{range .Replies -}}
+[{{time .SentAt}}] {{.SenderName}}: {{if .IsAttachment}}[attachment] {{.AttachmentURL}}{{else}}{{.Content}}{{end}}
+{{end}}
+{{- if .ChatURL}}
+Continue the conversation: {{.ChatURL}}
+{{end}}`))
+
+// html/template 按上下文转义回复内容与链接
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Funcs(digestFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+body { font-family: sans-serif; max-width: 720px; margin: 0 auto; }
+.reply { margin: 8px 0; }
+.meta { color: #666; font-size: 12px; }
+.content { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<p>Hi {{.Name}},</p>
+<p>You have {{len .Replies}} unread {{if eq (len .Replies) 1}}reply{{else}}replies{{end}} from our support team:</p>
+{{range .Replies}}<div class="reply">
+<div class="meta">{{.SenderName}} &middot; {{time .SentAt}}</div>
+{{if .IsAttachment}}<a href="{{.AttachmentURL}}">{{.AttachmentURL}}</a>{{else}}<div class="content">{{.Content}}</div>{{end}}
+</div>
+{{end}}{{if .ChatURL}}<p><a href="{{.ChatURL}}">Continue the conversation</a></p>
+{{end}}</body>
+</html>
+`))
+
+// OfflineNotifier 将离线回复摘要渲染为邮件，通过 MailSender 发送
+type OfflineNotifier struct {
+	sender usecase.MailSender
+	from   string
+}
+
+var _ usecase.OfflineNotifier = (*OfflineNotifier)(nil)
+
+// NewOfflineNotifier 创建邮件离线提醒，from 为空时使用发送器的缺省发件人
+func NewOfflineNotifier(sender usecase.MailSender, from string) *OfflineNotifier {
+	return &OfflineNotifier{sender: sender, from: from}
+}
+
+func (n *OfflineNotifier) NotifyOffline(ctx context.Context, digest *entity.OfflineDigest) error {
+	var text, html bytes.Buffer
+	if err := digestText.Execute(&text, digest); err != nil {
+		return err
+	}
+	if err := digestHTML.Execute(&html, digest); err != nil {
+		return err
+	}
+	msg := &entity.MailMessage{
+		From:     n.from,
+		To:       []string{digest.Email},
+		Subject:  fmt.Sprintf("You have %d unread %s", len(digest.Replies), pluralReplies(len(digest.Replies))),
+		TextBody: text.String(),
+		HTMLBody: html.String(),
+	}
+	if err := n.sender.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send offline digest: %w", err)
+	}
+	return nil
+}
+
+func pluralReplies(n int) string {
+	if n == 1 {
+		return "reply"
+	}
+	return "replies"
+}
@@ -0,0 +1,166 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+)
+
+// receivedMail SMTP 测试服务端收到的一封邮件
+type receivedMail struct {
+	from  string
+	rcpts []string
+	data  []byte
+}
+
+// smtpStub 只实现投递所需命令的 SMTP 服务端，rejectData 非 0 时以该状态码拒收正文
+type smtpStub struct {
+	ln         net.Listener
+	rejectData int
+
+	mu       sync.Mutex
+	received []receivedMail
+}
+
+func newSMTPStub(t *testing.T, rejectData int) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{ln: ln, rejectData: rejectData}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+// sender 返回指向该服务端的发送器
+func (s *smtpStub) sender(from string) *SMTPSender {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return NewSMTPSender(host, p, "", "", from)
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) { tp.PrintfLine("%d %s", code, msg) }
+
+	var current receivedMail
+	reply(220, "stub ready")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply(250, "hello")
+		case "MAIL":
+			current = receivedMail{from: strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")}
+			reply(250, "ok")
+		case "RCPT":
+			current.rcpts = append(current.rcpts, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply(250, "ok")
+		case "DATA":
+			reply(354, "go ahead")
+			if current.data, err = tp.ReadDotBytes(); err != nil {
+				return
+			}
+			if s.rejectData != 0 {
+				reply(s.rejectData, "rejected")
+				continue
+			}
+			s.mu.Lock()
+			s.received = append(s.received, current)
+			s.mu.Unlock()
+			reply(250, "queued")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(250, "ok")
+		}
+	}
+}
+
+func (s *smtpStub) messages() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.received...)
+}
+
+func TestSMTPSenderDeliversWithBcc(t *testing.T) {
+	stub := newSMTPStub(t, 0)
+	err := stub.sender("noreply@cland.org").Send(context.Background(), &entity.MailMessage{
+		To:        []string{"customer@example.com"},
+		Bcc:       []string{"archive@cland.org"},
+		Subject:   "Transcript of your conversation",
+		TextBody:  "Hello from support",
+		HTMLBody:  "<p>Hello from support</p>",
+		InReplyTo: "thread-1@cland.org",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := stub.messages()
+	if len(received) != 1 {
+		t.Fatalf("received %d messages, want 1", len(received))
+	}
+	got := received[0]
+	if got.from != "noreply@cland.org" {
+		t.Errorf("envelope sender = %q", got.from)
+	}
+	if strings.Join(got.rcpts, ",") != "customer@example.com,archive@cland.org" {
+		t.Errorf("envelope recipients = %v", got.rcpts)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(got.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Bcc") != "" || strings.Contains(string(got.data), "archive@cland.org") {
+		t.Error("bcc recipient leaked into the message")
+	}
+	if msg.Header.Get("To") != "customer@example.com" || msg.Header.Get("Subject") != "Transcript of your conversation" {
+		t.Errorf("headers To=%q Subject=%q", msg.Header.Get("To"), msg.Header.Get("Subject"))
+	}
+	if msg.Header.Get("In-Reply-To") != "<thread-1@cland.org>" || msg.Header.Get("Message-ID") == "" {
+		t.Errorf("thread headers In-Reply-To=%q Message-ID=%q", msg.Header.Get("In-Reply-To"), msg.Header.Get("Message-ID"))
+	}
+	if !strings.Contains(string(got.data), "Hello from support") {
+		t.Error("body missing from message")
+	}
+}
+
+func TestSMTPSenderReturnsRejection(t *testing.T) {
+	stub := newSMTPStub(t, 451)
+	err := stub.sender("noreply@cland.org").Send(context.Background(), &entity.MailMessage{
+		To:       []string{"customer@example.com"},
+		Subject:  "Transcript",
+		TextBody: "Hello",
+	})
+	if err == nil || !strings.Contains(err.Error(), "451") {
+		t.Fatalf("Send error = %v, want the 451 rejection", err)
+	}
+	if n := len(stub.messages()); n != 0 {
+		t.Errorf("stub accepted %d messages", n)
+	}
+}
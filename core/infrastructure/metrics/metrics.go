@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// 请求延迟的桶上界(秒)
+	latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// 数据库查询延迟的桶上界(秒)
+	queryBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+	// 发件箱等待时间的桶上界(秒)，包含下游失败后的重试退避
+	waitBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600}
+)
+
+// Default 服务导出的指标注册表，GET /metrics 输出其内容
+var Default = NewRegistry()
+
+var (
+	// HTTPRequestDuration Gin 处理 HTTP 请求的耗时，route 为路由模板
+	HTTPRequestDuration = Default.NewHistogramVec("cland_http_request_duration_seconds",
+		"HTTP request latency by method, route template and status code.", latencyBuckets, "method", "route", "status")
+	// RepositoryQueryDuration 仓储方法的耗时，method 形如 MessageRepository.Create
+	RepositoryQueryDuration = Default.NewHistogramVec("cland_repository_query_duration_seconds",
+		"Repository query latency by method.", queryBuckets, "method")
+	// Messages 消息发送、送达与失败次数
+	Messages = Default.NewCounterVec("cland_messages_total",
+		"Chat messages by event (sent, delivered, failed), message type and content type.", "event", "msg_type", "content_type")
+	// OutboxWait 发件箱记录从写入到分发成功的等待时间；SQLite 保存的写入时间精确到秒
+	OutboxWait = Default.NewHistogramVec("cland_outbox_wait_seconds",
+		"Time from an outbox event being written to being published to all sinks.", waitBuckets, "event")
+	// HeartbeatTimeouts 因心跳超时被关闭的 WebSocket 连接数
+	HeartbeatTimeouts = Default.NewCounterVec("cland_ws_heartbeat_timeouts_total",
+		"WebSocket connections closed because no heartbeat arrived in time.")
+)
+
+func init() {
+	Default.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	Default.NewGaugeFunc("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", func() float64 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return float64(stats.HeapAlloc)
+	})
+}
+
+// ObserveSince 记录从 start 到现在的耗时(秒)
+func ObserveSince(h *HistogramVec, start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// GinMiddleware 记录每个请求的耗时，需在注册路由之前添加
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched" // 未匹配的路径不作为标签，避免标签基数失控
+		}
+		ObserveSince(HTTPRequestDuration, start, c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// Recorder 将用例层的业务指标写入 Default
+type Recorder struct{}
+
+var _ usecase.Metrics = Recorder{}
+
+func (Recorder) MessageEvent(event string, msgType, contentType uint8) {
+	Messages.Inc(event, msgTypeLabel(msgType), contentTypeLabel(contentType))
+}
+
+func (Recorder) OutboxPublished(eventType string, wait time.Duration) {
+	OutboxWait.Observe(wait.Seconds(), eventType)
+}
+
+func msgTypeLabel(t uint8) string {
+	switch t {
+	case entity.MsgTypeMessage:
+		return "message"
+	case entity.MsgTypeNotification:
+		return "notification"
+	case entity.MsgTypeAck:
+		return "ack"
+	case entity.MsgTypeInternalNote:
+		return "internal_note"
+	default:
+		return "unknown"
+	}
+}
+
+func contentTypeLabel(t uint8) string {
+	switch t {
+	case entity.ContentTypeText:
+		return "text"
+	case entity.ContentTypeImage:
+		return "image"
+	case entity.ContentTypeFile:
+		return "file"
+	default:
+		return "unknown"
+	}
+}
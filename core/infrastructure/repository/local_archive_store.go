@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// LocalArchiveStore 基于本地目录实现 ArchiveStore，先写临时文件再重命名，不会留下写了一半的归档
+type LocalArchiveStore struct {
+	dir string
+}
+
+var _ repo.ArchiveStore = (*LocalArchiveStore)(nil)
+
+// NewLocalArchiveStore 在 dir 下保存归档文件，目录不存在时在首次写入时创建
+func NewLocalArchiveStore(dir string) *LocalArchiveStore {
+	return &LocalArchiveStore{dir: dir}
+}
+
+// path 将 key 转换为 dir 下的文件路径，拒绝绝对路径与 ..
+func (s *LocalArchiveStore) path(key string) (string, error) {
+	rel := filepath.FromSlash(key)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("invalid archive key %q", key)
+	}
+	return filepath.Join(s.dir, rel), nil
+}
+
+func (s *LocalArchiveStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalArchiveStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *LocalArchiveStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
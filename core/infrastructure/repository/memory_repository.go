@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
 )
 
 // MemoryMessageRepository 实现MessageRepository
@@ -48,7 +50,9 @@ func (r *MemoryUserRepository) CreateOrUpdate(ctx context.Context, user *entity.
 
 // MessageRepository implementation
 func (r *MemoryMessageRepository) Create(ctx context.Context, message *entity.Message) error {
-	r.store.Store(message.MsgID, message)
+	if _, loaded := r.store.LoadOrStore(message.MsgID, message); loaded {
+		return repo.ErrDuplicateKey
+	}
 	return nil
 }
 
@@ -72,6 +76,60 @@ func (r *MemoryMessageRepository) GetBySessionID(ctx context.Context, sessionID
 	return messages, nil
 }
 
+func (r *MemoryMessageRepository) GetReplies(ctx context.Context, msgID string) ([]*entity.Message, error) {
+	var replies []*entity.Message
+	r.store.Range(func(_, value interface{}) bool {
+		msg := value.(*entity.Message)
+		if msg.ReplyTo == msgID {
+			replies = append(replies, msg)
+		}
+		return true
+	})
+	sort.Slice(replies, func(i, j int) bool { return replies[i].Ts < replies[j].Ts })
+	return replies, nil
+}
+
+func (r *MemoryMessageRepository) GetLatestExcludingSrc(ctx context.Context, sessionID, src string) (*entity.Message, error) {
+	var latest *entity.Message
+	r.store.Range(func(_, value interface{}) bool {
+		msg := value.(*entity.Message)
+		if msg.SessionID == sessionID && msg.Src != src && (latest == nil || msg.Ts > latest.Ts) {
+			latest = msg
+		}
+		return true
+	})
+	if latest == nil {
+		return nil, repo.ErrNotFound
+	}
+	return latest, nil
+}
+
+func (r *MemoryMessageRepository) GetBySessionIDAfter(ctx context.Context, sessionID string, afterTs int64) ([]*entity.Message, error) {
+	var messages []*entity.Message
+	r.store.Range(func(_, value interface{}) bool {
+		msg := value.(*entity.Message)
+		if msg.SessionID == sessionID && int64(msg.Ts) > afterTs {
+			messages = append(messages, msg)
+		}
+		return true
+	})
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Ts < messages[j].Ts })
+	return messages, nil
+}
+
+func (r *MemoryMessageRepository) GetOfflineByDst(ctx context.Context, dst string) ([]*entity.Message, error) {
+	var messages []*entity.Message
+	r.store.Range(func(_, value interface{}) bool {
+		msg := value.(*entity.Message)
+		if msg.Dst == dst && msg.Status == entity.StatusOffline {
+			messages = append(messages, msg)
+		}
+		return true
+	})
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Ts < messages[j].Ts })
+	return messages, nil
+}
+
 func (r *MemoryMessageRepository) UpdateStatus(ctx context.Context, msgID string, status uint8) error {
 	val, ok := r.store.Load(msgID)
 	if !ok {
@@ -109,6 +167,29 @@ func (r *MemorySessionRepository) UpdateStatus(ctx context.Context, id string, s
 	return nil
 }
 
+func (r *MemorySessionRepository) Close(ctx context.Context, id string, endTime time.Time) error {
+	val, ok := r.store.Load(id)
+	if !ok {
+		return ErrNotFound
+	}
+	session := val.(*entity.Session)
+	session.Status = entity.SessionStatusClosed
+	session.EndTime = endTime
+	r.store.Store(id, session)
+	return nil
+}
+
+func (r *MemorySessionRepository) UpdateAgent(ctx context.Context, id string, agentID string) error {
+	val, ok := r.store.Load(id)
+	if !ok {
+		return ErrNotFound
+	}
+	session := val.(*entity.Session)
+	session.AgentId = agentID
+	r.store.Store(id, session)
+	return nil
+}
+
 func (r *MemorySessionRepository) ListActive(ctx context.Context) ([]*entity.Session, error) {
 	var sessions []*entity.Session
 	r.store.Range(func(_, value interface{}) bool {
@@ -121,6 +202,19 @@ func (r *MemorySessionRepository) ListActive(ctx context.Context) ([]*entity.Ses
 	return sessions, nil
 }
 
+func (r *MemorySessionRepository) ListByCID(ctx context.Context, cid string) ([]*entity.Session, error) {
+	var sessions []*entity.Session
+	r.store.Range(func(_, value interface{}) bool {
+		session := value.(*entity.Session)
+		if session.CID == cid {
+			sessions = append(sessions, session)
+		}
+		return true
+	})
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].StartTime.After(sessions[j].StartTime) })
+	return sessions, nil
+}
+
 // UserRepository implementation
 func (r *MemoryUserRepository) GetByID(ctx context.Context, id string) (*entity.User, error) {
 	val, ok := r.store.Load(id)
@@ -145,7 +239,7 @@ func (r *MemoryUserRepository) ListAgents(ctx context.Context) ([]*entity.User,
 	var agents []*entity.User
 	r.store.Range(func(_, value interface{}) bool {
 		user := value.(*entity.User)
-		if user.Role == "agent" {
+		if user.Role == entity.RoleAgent {
 			agents = append(agents, user)
 		}
 		return true
@@ -153,4 +247,1252 @@ func (r *MemoryUserRepository) ListAgents(ctx context.Context) ([]*entity.User,
 	return agents, nil
 }
 
-var ErrNotFound = errors.New("not found")
+func (r *MemoryUserRepository) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
+	var found *entity.User
+	r.store.Range(func(_, value interface{}) bool {
+		user := value.(*entity.User)
+		if user.Username == username && user.Role != entity.RoleCustomer {
+			found = user
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, user *entity.User) error {
+	if _, ok := r.store.Load(user.ID); !ok {
+		return ErrNotFound
+	}
+	r.store.Store(user.ID, user)
+	return nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id, deletedBy string) error {
+	if _, loaded := r.store.LoadAndDelete(id); !loaded {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, filter entity.UserFilter) ([]*entity.User, error) {
+	var users []*entity.User
+	r.store.Range(func(_, value interface{}) bool {
+		user := value.(*entity.User)
+		if user.Role != entity.RoleCustomer && (filter.Role == "" || user.Role == filter.Role) {
+			users = append(users, user)
+		}
+		return true
+	})
+	return users, nil
+}
+
+func (r *MemoryUserRepository) GetByExternalID(ctx context.Context, externalID string) (*entity.User, error) {
+	var found *entity.User
+	r.store.Range(func(_, value interface{}) bool {
+		user := value.(*entity.User)
+		if externalID != "" && user.ExternalID == externalID {
+			found = user
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// MemoryReactionRepository 实现ReactionRepository
+type MemoryReactionRepository struct {
+	store sync.Map // msgID|userID|emoji -> *entity.Reaction
+}
+
+func NewMemoryReactionRepository() *MemoryReactionRepository {
+	return &MemoryReactionRepository{}
+}
+
+func reactionKey(msgID, userID, emoji string) string {
+	return msgID + "|" + userID + "|" + emoji
+}
+
+func (r *MemoryReactionRepository) Add(ctx context.Context, reaction *entity.Reaction) error {
+	if reaction.CreatedAt.IsZero() {
+		reaction.CreatedAt = time.Now()
+	}
+	r.store.LoadOrStore(reactionKey(reaction.MsgID, reaction.UserID, reaction.Emoji), reaction)
+	return nil
+}
+
+func (r *MemoryReactionRepository) Remove(ctx context.Context, msgID, userID, emoji string) error {
+	r.store.Delete(reactionKey(msgID, userID, emoji))
+	return nil
+}
+
+func (r *MemoryReactionRepository) ListByMessageID(ctx context.Context, msgID string) ([]*entity.Reaction, error) {
+	return r.list(func(reaction *entity.Reaction) bool { return reaction.MsgID == msgID }), nil
+}
+
+func (r *MemoryReactionRepository) ListBySessionID(ctx context.Context, sessionID string) ([]*entity.Reaction, error) {
+	return r.list(func(reaction *entity.Reaction) bool { return reaction.SessionID == sessionID }), nil
+}
+
+func (r *MemoryReactionRepository) list(match func(*entity.Reaction) bool) []*entity.Reaction {
+	var reactions []*entity.Reaction
+	r.store.Range(func(_, value interface{}) bool {
+		reaction := value.(*entity.Reaction)
+		if match(reaction) {
+			reactions = append(reactions, reaction)
+		}
+		return true
+	})
+	sort.Slice(reactions, func(i, j int) bool { return reactions[i].CreatedAt.Before(reactions[j].CreatedAt) })
+	return reactions
+}
+
+// MemoryRatingRepository 实现RatingRepository
+type MemoryRatingRepository struct {
+	store sync.Map // sessionID -> *entity.SessionRating
+}
+
+func NewMemoryRatingRepository() *MemoryRatingRepository {
+	return &MemoryRatingRepository{}
+}
+
+func (r *MemoryRatingRepository) Create(ctx context.Context, rating *entity.SessionRating) error {
+	if rating.CreatedAt.IsZero() {
+		rating.CreatedAt = time.Now()
+	}
+	if _, loaded := r.store.LoadOrStore(rating.SessionID, rating); loaded {
+		return repo.ErrDuplicateKey
+	}
+	return nil
+}
+
+func (r *MemoryRatingRepository) GetBySessionID(ctx context.Context, sessionID string) (*entity.SessionRating, error) {
+	val, ok := r.store.Load(sessionID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return val.(*entity.SessionRating), nil
+}
+
+func (r *MemoryRatingRepository) Aggregate(ctx context.Context, q entity.RatingQuery) ([]*entity.RatingAggregate, error) {
+	type groupKey struct{ agentID, day string }
+	groups := make(map[groupKey]*entity.RatingAggregate)
+	var keys []groupKey
+	r.store.Range(func(_, value interface{}) bool {
+		rating := value.(*entity.SessionRating)
+		if rating.CreatedAt.Before(q.From) || !rating.CreatedAt.Before(q.To) {
+			return true
+		}
+		if q.AgentID != "" && rating.AgentID != q.AgentID {
+			return true
+		}
+		key := groupKey{agentID: rating.AgentID}
+		if q.GroupByDay {
+			key.day = rating.CreatedAt.UTC().Format("2006-01-02")
+		}
+		agg, ok := groups[key]
+		if !ok {
+			agg = &entity.RatingAggregate{AgentID: key.agentID, Day: key.day}
+			groups[key] = agg
+			keys = append(keys, key)
+		}
+		agg.Average = (agg.Average*float64(agg.Count) + float64(rating.Score)) / float64(agg.Count+1)
+		agg.Count++
+		if rating.Score >= entity.SatisfiedScore {
+			agg.Satisfied++
+		}
+		return true
+	})
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].day != keys[j].day {
+			return keys[i].day < keys[j].day
+		}
+		return keys[i].agentID < keys[j].agentID
+	})
+	result := make([]*entity.RatingAggregate, 0, len(keys))
+	for _, key := range keys {
+		agg := groups[key]
+		agg.CSAT = float64(agg.Satisfied) * 100 / float64(agg.Count)
+		result = append(result, agg)
+	}
+	return result, nil
+}
+
+// MemoryCannedResponseRepository 实现CannedResponseRepository
+type MemoryCannedResponseRepository struct {
+	store sync.Map // id -> *entity.CannedResponse
+}
+
+func NewMemoryCannedResponseRepository() *MemoryCannedResponseRepository {
+	return &MemoryCannedResponseRepository{}
+}
+
+func (r *MemoryCannedResponseRepository) Create(ctx context.Context, c *entity.CannedResponse) error {
+	now := time.Now()
+	c.CreatedAt, c.UpdatedAt = now, now
+	c.Variables = entity.ParseCannedVariables(c.Body)
+	if _, loaded := r.store.LoadOrStore(c.ID, c); loaded {
+		return repo.ErrDuplicateKey
+	}
+	return nil
+}
+
+func (r *MemoryCannedResponseRepository) GetByID(ctx context.Context, id string) (*entity.CannedResponse, error) {
+	val, ok := r.store.Load(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return val.(*entity.CannedResponse), nil
+}
+
+func (r *MemoryCannedResponseRepository) Update(ctx context.Context, c *entity.CannedResponse) error {
+	if _, ok := r.store.Load(c.ID); !ok {
+		return ErrNotFound
+	}
+	c.UpdatedAt = time.Now()
+	c.Variables = entity.ParseCannedVariables(c.Body)
+	r.store.Store(c.ID, c)
+	return nil
+}
+
+func (r *MemoryCannedResponseRepository) Delete(ctx context.Context, id string, deletedBy string) error {
+	if _, loaded := r.store.LoadAndDelete(id); !loaded {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MemoryCannedResponseRepository) List(ctx context.Context, filter entity.CannedResponseFilter) ([]*entity.CannedResponse, error) {
+	var result []*entity.CannedResponse
+	r.store.Range(func(_, value interface{}) bool {
+		c := value.(*entity.CannedResponse)
+		if c.VisibleTo(filter.AgentID, filter.Team) && strings.HasPrefix(c.Shortcut, filter.ShortcutPrefix) {
+			result = append(result, c)
+		}
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Shortcut < result[j].Shortcut })
+	return result, nil
+}
+
+// MemoryAuditRepository 实现AuditRepository
+type MemoryAuditRepository struct {
+	mu     sync.RWMutex
+	nextID int64
+	logs   []*entity.AuditLog
+}
+
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+func (r *MemoryAuditRepository) Create(ctx context.Context, log *entity.AuditLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	log.ID = r.nextID
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *MemoryAuditRepository) List(ctx context.Context, query entity.AuditQuery) ([]*entity.AuditLog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*entity.AuditLog
+	for i := len(r.logs) - 1; i >= 0; i-- {
+		log := r.logs[i]
+		if (query.SessionID != "" && log.SessionID != query.SessionID) ||
+			(query.ActorID != "" && log.ActorID != query.ActorID) ||
+			(query.Action != "" && log.Action != query.Action) {
+			continue
+		}
+		result = append(result, log)
+		if query.Limit > 0 && len(result) == query.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// MemoryTokenRepository 实现TokenRepository
+type MemoryTokenRepository struct {
+	mu      sync.Mutex
+	refresh map[string]*entity.RefreshToken
+	revoked map[string]*entity.RevokedToken
+}
+
+func NewMemoryTokenRepository() *MemoryTokenRepository {
+	return &MemoryTokenRepository{
+		refresh: make(map[string]*entity.RefreshToken),
+		revoked: make(map[string]*entity.RevokedToken),
+	}
+}
+
+func (r *MemoryTokenRepository) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.refresh[token.TokenHash]; ok {
+		return repo.ErrDuplicateKey
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	copied := *token
+	r.refresh[token.TokenHash] = &copied
+	return nil
+}
+
+func (r *MemoryTokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.refresh[tokenHash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (r *MemoryTokenRepository) RevokeRefreshToken(ctx context.Context, tokenHash, replacedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.refresh[tokenHash]
+	if !ok || token.Revoked() {
+		return ErrNotFound
+	}
+	token.RevokedAt = time.Now()
+	token.ReplacedBy = replacedBy
+	return nil
+}
+
+func (r *MemoryTokenRepository) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	r.revokeRefreshWhere(func(t *entity.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (r *MemoryTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	r.revokeRefreshWhere(func(t *entity.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (r *MemoryTokenRepository) revokeRefreshWhere(match func(*entity.RefreshToken) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, token := range r.refresh {
+		if !token.Revoked() && match(token) {
+			token.RevokedAt = now
+		}
+	}
+}
+
+func (r *MemoryTokenRepository) RevokeAccessToken(ctx context.Context, token *entity.RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if token.RevokedAt.IsZero() {
+		token.RevokedAt = time.Now()
+	}
+	if _, ok := r.revoked[token.JTI]; !ok {
+		copied := *token
+		r.revoked[token.JTI] = &copied
+	}
+	return nil
+}
+
+func (r *MemoryTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+func (r *MemoryTokenRepository) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for hash, token := range r.refresh {
+		if token.ExpiresAt.Before(before) {
+			delete(r.refresh, hash)
+			n++
+		}
+	}
+	for jti, token := range r.revoked {
+		if token.ExpiresAt.Before(before) {
+			delete(r.revoked, jti)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// MemoryWebhookRepository 实现WebhookRepository
+type MemoryWebhookRepository struct {
+	mu            sync.Mutex
+	subscriptions map[string]*entity.WebhookSubscription
+	deliveries    []*entity.WebhookDelivery
+}
+
+func NewMemoryWebhookRepository() *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{subscriptions: make(map[string]*entity.WebhookSubscription)}
+}
+
+func (r *MemoryWebhookRepository) CreateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subscriptions[sub.ID]; ok {
+		return repo.ErrDuplicateKey
+	}
+	now := time.Now()
+	sub.CreatedAt, sub.UpdatedAt = now, now
+	copied := *sub
+	r.subscriptions[sub.ID] = &copied
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub, ok := r.subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+func (r *MemoryWebhookRepository) UpdateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.subscriptions[sub.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	sub.Secret = stored.Secret
+	sub.UpdatedAt = time.Now()
+	copied := *sub
+	r.subscriptions[sub.ID] = &copied
+	return nil
+}
+
+func (r *MemoryWebhookRepository) DeleteSubscription(ctx context.Context, id, deletedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subscriptions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.subscriptions, id)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) ListSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subs := make([]*entity.WebhookSubscription, 0, len(r.subscriptions))
+	for _, sub := range r.subscriptions {
+		copied := *sub
+		subs = append(subs, &copied)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+	return subs, nil
+}
+
+func (r *MemoryWebhookRepository) CreateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = now
+	}
+	delivery.UpdatedAt = now
+	copied := *delivery
+	r.deliveries = append(r.deliveries, &copied)
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range r.deliveries {
+		if d.ID == id {
+			copied := *d
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryWebhookRepository) UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, d := range r.deliveries {
+		if d.ID == delivery.ID {
+			delivery.UpdatedAt = time.Now()
+			copied := *delivery
+			r.deliveries[i] = &copied
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryWebhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*entity.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == entity.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			copied := *d
+			due = append(due, &copied)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (r *MemoryWebhookRepository) ListDeliveries(ctx context.Context, query entity.WebhookDeliveryQuery) ([]*entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*entity.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		d := r.deliveries[i]
+		if (query.SubscriptionID != "" && d.SubscriptionID != query.SubscriptionID) ||
+			(query.Status != "" && d.Status != query.Status) {
+			continue
+		}
+		copied := *d
+		result = append(result, &copied)
+		if query.Limit > 0 && len(result) == query.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// ErrNotFound 与领域层保持一致，便于用例层通过 errors.Is 判断
+var ErrNotFound = repo.ErrNotFound
+
+// MemoryOutboxRepository 实现OutboxRepository，业务写入委托给内存消息与会话仓储，
+// 同一把锁内完成业务写入与事件追加
+type MemoryOutboxRepository struct {
+	mu       sync.Mutex
+	messages *MemoryMessageRepository
+	sessions *MemorySessionRepository
+	events   []*entity.OutboxEvent
+}
+
+func NewMemoryOutboxRepository(messages *MemoryMessageRepository, sessions *MemorySessionRepository) *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{messages: messages, sessions: sessions}
+}
+
+func (r *MemoryOutboxRepository) CreateMessage(ctx context.Context, message *entity.Message, events ...*entity.OutboxEvent) error {
+	return r.withEvents(events, func() error {
+		return r.messages.Create(ctx, message)
+	})
+}
+
+func (r *MemoryOutboxRepository) CreateSession(ctx context.Context, session *entity.Session, events ...*entity.OutboxEvent) error {
+	return r.withEvents(events, func() error {
+		return r.sessions.Create(ctx, session)
+	})
+}
+
+func (r *MemoryOutboxRepository) CloseSession(ctx context.Context, sessionID string, endTime time.Time, events ...*entity.OutboxEvent) error {
+	return r.withEvents(events, func() error {
+		return r.sessions.Close(ctx, sessionID, endTime)
+	})
+}
+
+func (r *MemoryOutboxRepository) AssignSession(ctx context.Context, sessionID, agentID string, events ...*entity.OutboxEvent) error {
+	return r.withEvents(events, func() error {
+		return r.sessions.UpdateAgent(ctx, sessionID, agentID)
+	})
+}
+
+func (r *MemoryOutboxRepository) withEvents(events []*entity.OutboxEvent, write func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := write(); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, event := range events {
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = now
+		}
+		event.UpdatedAt = now
+		copied := *event
+		r.events = append(r.events, &copied)
+	}
+	return nil
+}
+
+func (r *MemoryOutboxRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*entity.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*entity.OutboxEvent
+	for _, e := range r.events {
+		if e.Status == entity.OutboxPending && !e.NextAttemptAt.After(now) {
+			copied := *e
+			copied.CompletedSinks = append([]string(nil), e.CompletedSinks...)
+			due = append(due, &copied)
+			if len(due) == limit {
+				break
+			}
+		}
+	}
+	return due, nil
+}
+
+func (r *MemoryOutboxRepository) CountPending(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, e := range r.events {
+		if e.Status == entity.OutboxPending {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *MemoryOutboxRepository) Update(ctx context.Context, event *entity.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.events {
+		if e.ID == event.ID {
+			event.UpdatedAt = time.Now()
+			copied := *event
+			copied.CompletedSinks = append([]string(nil), event.CompletedSinks...)
+			r.events[i] = &copied
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// memorySnapshotter 可在事务开始时保存状态、回滚时恢复的内存仓储
+type memorySnapshotter interface {
+	snapshot() (restore func())
+}
+
+// memoryTxKey ctx 中进行中事务的键
+type memoryTxKey struct{}
+
+// MemoryTxManager 实现 TxManager，事务开始时为登记的仓储保存快照，fn 失败时恢复。
+// 事务之间串行执行，事务外的并发写入在回滚时可能被覆盖，仅用于开发与测试
+type MemoryTxManager struct {
+	mu    sync.Mutex
+	repos []memorySnapshotter
+}
+
+// NewMemoryTxManager 登记参与事务的内存仓储
+func NewMemoryTxManager(repos ...memorySnapshotter) *MemoryTxManager {
+	return &MemoryTxManager{repos: repos}
+}
+
+func (m *MemoryTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) == m {
+		return fn(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restores := make([]func(), len(m.repos))
+	for i, r := range m.repos {
+		restores[i] = r.snapshot()
+	}
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, m)); err != nil {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+		return err
+	}
+	return nil
+}
+
+// snapshotStore 复制 store 中的全部记录，返回的函数将 store 恢复为复制时的内容。
+// 记录按值复制，避免原地修改影响快照
+func snapshotStore[T any](store *sync.Map) func() {
+	saved := make(map[interface{}]T)
+	store.Range(func(key, value interface{}) bool {
+		saved[key] = *value.(*T)
+		return true
+	})
+	return func() {
+		store.Range(func(key, _ interface{}) bool {
+			store.Delete(key)
+			return true
+		})
+		for key, value := range saved {
+			value := value
+			store.Store(key, &value)
+		}
+	}
+}
+
+func (r *MemoryMessageRepository) snapshot() func() {
+	return snapshotStore[entity.Message](&r.store)
+}
+
+func (r *MemorySessionRepository) snapshot() func() {
+	return snapshotStore[entity.Session](&r.store)
+}
+
+func (r *MemoryUserRepository) snapshot() func() {
+	return snapshotStore[entity.User](&r.store)
+}
+
+func (r *MemoryOutboxRepository) snapshot() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := make([]*entity.OutboxEvent, len(r.events))
+	for i, e := range r.events {
+		copied := *e
+		saved[i] = &copied
+	}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = saved
+	}
+}
+
+// MemoryRetentionRepository 实现RetentionRepository，消息清理作用于内存消息与表情回应仓储
+type MemoryRetentionRepository struct {
+	mu        sync.Mutex
+	messages  *MemoryMessageRepository
+	sessions  *MemorySessionRepository
+	reactions *MemoryReactionRepository
+	records   map[string]*entity.SessionRetention
+	runs      []*entity.RetentionRun
+}
+
+func NewMemoryRetentionRepository(messages *MemoryMessageRepository, sessions *MemorySessionRepository, reactions *MemoryReactionRepository) *MemoryRetentionRepository {
+	return &MemoryRetentionRepository{
+		messages:  messages,
+		sessions:  sessions,
+		reactions: reactions,
+		records:   make(map[string]*entity.SessionRetention),
+	}
+}
+
+func (r *MemoryRetentionRepository) ListDueSessions(ctx context.Context, query entity.RetentionQuery) ([]*entity.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*entity.Session
+	r.sessions.store.Range(func(_, value interface{}) bool {
+		session := value.(*entity.Session)
+		channel := session.Channel
+		if channel == "" {
+			channel = entity.ChannelWeb
+		}
+		switch {
+		case session.Status != entity.SessionStatusClosed || !session.EndTime.Before(query.ClosedBefore):
+		case len(query.Channels) > 0 && !containsString(query.Channels, channel):
+		case containsString(query.ExcludeChannels, channel):
+		case query.AfterSessionID != "" && session.ID <= query.AfterSessionID:
+		default:
+			rec, ok := r.records[session.ID]
+			if !ok || rec.PurgedAt.IsZero() || (!rec.RestoredAt.IsZero() && rec.RestoredAt.Before(query.RestoredBefore)) {
+				due = append(due, session)
+			}
+		}
+		return true
+	})
+
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	if query.Limit > 0 && len(due) > query.Limit {
+		due = due[:query.Limit]
+	}
+	return due, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRetentionRepository) GetSessionRetention(ctx context.Context, sessionID string) (*entity.SessionRetention, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.records[sessionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *rec
+	return &copied, nil
+}
+
+func (r *MemoryRetentionRepository) SaveSessionRetention(ctx context.Context, record *entity.SessionRetention) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record.UpdatedAt = time.Now()
+	copied := *record
+	r.records[record.SessionID] = &copied
+	return nil
+}
+
+// sessionMessages 按消息 ID 排序返回会话中满足条件的消息，最多 limit 条
+func (r *MemoryRetentionRepository) sessionMessages(sessionID string, limit int, match func(*entity.Message) bool) []*entity.Message {
+	var messages []*entity.Message
+	r.messages.store.Range(func(_, value interface{}) bool {
+		msg := value.(*entity.Message)
+		if msg.SessionID == sessionID && match(msg) {
+			messages = append(messages, msg)
+		}
+		return true
+	})
+	sort.Slice(messages, func(i, j int) bool { return messages[i].MsgID < messages[j].MsgID })
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages
+}
+
+func (r *MemoryRetentionRepository) DeleteMessages(ctx context.Context, sessionID string, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := r.sessionMessages(sessionID, limit, func(*entity.Message) bool { return true })
+	for _, msg := range messages {
+		r.messages.store.Delete(msg.MsgID)
+		r.reactions.store.Range(func(key, value interface{}) bool {
+			if value.(*entity.Reaction).MsgID == msg.MsgID {
+				r.reactions.store.Delete(key)
+			}
+			return true
+		})
+	}
+	return int64(len(messages)), nil
+}
+
+func (r *MemoryRetentionRepository) AnonymizeMessages(ctx context.Context, sessionID string, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := r.sessionMessages(sessionID, limit, func(msg *entity.Message) bool {
+		return msg.Content != entity.AnonymizedContent || msg.Ext != nil
+	})
+	for _, msg := range messages {
+		msg.Content = entity.AnonymizedContent
+		msg.Ext = nil
+		msg.UpdatedAt = time.Now()
+	}
+	return int64(len(messages)), nil
+}
+
+func (r *MemoryRetentionRepository) RestoreMessages(ctx context.Context, messages []*entity.Message) error {
+	for _, msg := range messages {
+		copied := *msg
+		r.messages.store.Store(msg.MsgID, &copied)
+	}
+	return nil
+}
+
+func (r *MemoryRetentionRepository) CreateRun(ctx context.Context, run *entity.RetentionRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *run
+	r.runs = append(r.runs, &copied)
+	return nil
+}
+
+func (r *MemoryRetentionRepository) UpdateRun(ctx context.Context, run *entity.RetentionRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.runs {
+		if existing.ID == run.ID {
+			copied := *run
+			r.runs[i] = &copied
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemoryRetentionRepository) ListRuns(ctx context.Context, limit int) ([]*entity.RetentionRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if limit <= 0 {
+		limit = defaultRetentionRunLimit
+	}
+	var runs []*entity.RetentionRun
+	for i := len(r.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		copied := *r.runs[i]
+		runs = append(runs, &copied)
+	}
+	return runs, nil
+}
+
+// MemoryPrivacyRepository 实现PrivacyRepository。内存仓储没有软删除标记，用户、会话与消息直接移除，
+// 其余记录按 SQLite 实现的规则删除或替换
+type MemoryPrivacyRepository struct {
+	mu        sync.Mutex
+	users     *MemoryUserRepository
+	sessions  *MemorySessionRepository
+	messages  *MemoryMessageRepository
+	reactions *MemoryReactionRepository
+	ratings   *MemoryRatingRepository
+	tokens    *MemoryTokenRepository
+	outbox    *MemoryOutboxRepository
+	webhooks  *MemoryWebhookRepository
+	retention *MemoryRetentionRepository
+	offline   *MemoryOfflineNotificationRepository
+	channels  *MemoryChannelIdentityRepository
+	threads   *MemoryChannelThreadRepository
+}
+
+func NewMemoryPrivacyRepository(
+	users *MemoryUserRepository,
+	sessions *MemorySessionRepository,
+	messages *MemoryMessageRepository,
+	reactions *MemoryReactionRepository,
+	ratings *MemoryRatingRepository,
+	tokens *MemoryTokenRepository,
+	outbox *MemoryOutboxRepository,
+	webhooks *MemoryWebhookRepository,
+	retention *MemoryRetentionRepository,
+	offline *MemoryOfflineNotificationRepository,
+	channels *MemoryChannelIdentityRepository,
+	threads *MemoryChannelThreadRepository,
+) *MemoryPrivacyRepository {
+	return &MemoryPrivacyRepository{
+		users:     users,
+		sessions:  sessions,
+		messages:  messages,
+		reactions: reactions,
+		ratings:   ratings,
+		tokens:    tokens,
+		outbox:    outbox,
+		webhooks:  webhooks,
+		retention: retention,
+		offline:   offline,
+		channels:  channels,
+		threads:   threads,
+	}
+}
+
+func (r *MemoryPrivacyRepository) EraseCustomer(ctx context.Context, cid, erasedBy string) (*entity.ErasureResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	val, ok := r.users.store.Load(cid)
+	if !ok || val.(*entity.User).Role != entity.RoleCustomer {
+		return nil, ErrNotFound
+	}
+	result := &entity.ErasureResult{CID: cid, ErasedAt: time.Now()}
+
+	// 会话与其中的消息
+	sessions := make(map[string]bool)
+	r.sessions.store.Range(func(key, value interface{}) bool {
+		if value.(*entity.Session).CID == cid {
+			sessions[key.(string)] = true
+		}
+		return true
+	})
+	aggregates := make(map[string]bool, len(sessions))
+	r.messages.store.Range(func(key, value interface{}) bool {
+		if sessions[value.(*entity.Message).SessionID] {
+			aggregates[key.(string)] = true
+			r.messages.store.Delete(key)
+			result.Messages++
+		}
+		return true
+	})
+	for id := range sessions {
+		aggregates[id] = true
+		r.sessions.store.Delete(id)
+		result.Sessions++
+	}
+
+	r.reactions.store.Range(func(key, value interface{}) bool {
+		if value.(*entity.Reaction).UserID == cid {
+			r.reactions.store.Delete(key)
+			result.Reactions++
+		}
+		return true
+	})
+	r.ratings.store.Range(func(key, value interface{}) bool {
+		rating := value.(*entity.SessionRating)
+		if rating.CID == cid {
+			copied := *rating
+			copied.Comment = ""
+			r.ratings.store.Store(key, &copied)
+			result.Ratings++
+		}
+		return true
+	})
+
+	r.tokens.mu.Lock()
+	for hash, token := range r.tokens.refresh {
+		if token.UserID == cid {
+			delete(r.tokens.refresh, hash)
+			result.RefreshTokens++
+		}
+	}
+	r.tokens.mu.Unlock()
+
+	r.outbox.mu.Lock()
+	events := r.outbox.events[:0]
+	for _, e := range r.outbox.events {
+		if aggregates[e.AggregateID] {
+			result.OutboxEvents++
+			continue
+		}
+		events = append(events, e)
+	}
+	r.outbox.events = events
+	r.outbox.mu.Unlock()
+
+	r.webhooks.mu.Lock()
+	deliveries := r.webhooks.deliveries[:0]
+	for _, d := range r.webhooks.deliveries {
+		if strings.Contains(string(d.Payload), cid) {
+			result.WebhookDeliveries++
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	r.webhooks.deliveries = deliveries
+	r.webhooks.mu.Unlock()
+
+	r.retention.mu.Lock()
+	for id := range sessions {
+		delete(r.retention.records, id)
+	}
+	r.retention.mu.Unlock()
+
+	r.offline.mu.Lock()
+	delete(r.offline.states, cid)
+	r.offline.mu.Unlock()
+
+	r.channels.mu.Lock()
+	for key, identity := range r.channels.identities {
+		if identity.CID == cid {
+			delete(r.channels.identities, key)
+		}
+	}
+	r.channels.mu.Unlock()
+
+	r.threads.mu.Lock()
+	threads := r.threads.threads[:0]
+	for _, thread := range r.threads.threads {
+		if !sessions[thread.SessionID] {
+			threads = append(threads, thread)
+		}
+	}
+	r.threads.threads = threads
+	r.threads.mu.Unlock()
+
+	r.users.store.Delete(cid)
+	result.Users = 1
+	return result, nil
+}
+
+// MemoryOfflineNotificationRepository 实现OfflineNotificationRepository，待提醒的回复从内存消息仓储查询
+type MemoryOfflineNotificationRepository struct {
+	mu       sync.Mutex
+	messages *MemoryMessageRepository
+	users    *MemoryUserRepository
+	states   map[string]*entity.OfflineNotification
+}
+
+func NewMemoryOfflineNotificationRepository(messages *MemoryMessageRepository, users *MemoryUserRepository) *MemoryOfflineNotificationRepository {
+	return &MemoryOfflineNotificationRepository{
+		messages: messages,
+		users:    users,
+		states:   make(map[string]*entity.OfflineNotification),
+	}
+}
+
+// pendingReply 发给客户的普通消息仍为离线状态
+func pendingReply(msg *entity.Message) bool {
+	return msg.Status == entity.StatusOffline && msg.MsgType == entity.MsgTypeMessage && strings.HasPrefix(msg.Dst, "U:")
+}
+
+func (r *MemoryOfflineNotificationRepository) ListPendingCustomers(ctx context.Context, sentBefore, notifiedBefore time.Time, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := make(map[string]bool)
+	r.messages.store.Range(func(_, value interface{}) bool {
+		msg := value.(*entity.Message)
+		if !pendingReply(msg) || int64(msg.Ts) > sentBefore.UnixMilli() {
+			return true
+		}
+		cid := entity.UserIDFromAddress(msg.Dst)
+		if state, ok := r.states[cid]; ok && (int64(msg.Ts) <= state.LastMsgTs || state.LastSentAt.After(notifiedBefore)) {
+			return true
+		}
+		if val, ok := r.users.store.Load(cid); ok {
+			if user := val.(*entity.User); user.Role == entity.RoleCustomer && user.Email != "" {
+				pending[cid] = true
+			}
+		}
+		return true
+	})
+
+	cids := make([]string, 0, len(pending))
+	for cid := range pending {
+		cids = append(cids, cid)
+	}
+	sort.Strings(cids)
+	if limit > 0 && len(cids) > limit {
+		cids = cids[:limit]
+	}
+	return cids, nil
+}
+
+func (r *MemoryOfflineNotificationRepository) ListPendingReplies(ctx context.Context, cid string, afterTs int64) ([]*entity.Message, error) {
+	var replies []*entity.Message
+	r.messages.store.Range(func(_, value interface{}) bool {
+		msg := value.(*entity.Message)
+		if pendingReply(msg) && msg.Dst == "U:"+cid && int64(msg.Ts) > afterTs {
+			replies = append(replies, msg)
+		}
+		return true
+	})
+	sort.Slice(replies, func(i, j int) bool { return replies[i].Ts < replies[j].Ts })
+	return replies, nil
+}
+
+func (r *MemoryOfflineNotificationRepository) GetState(ctx context.Context, cid string) (*entity.OfflineNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.states[cid]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *state
+	return &copied, nil
+}
+
+func (r *MemoryOfflineNotificationRepository) SaveState(ctx context.Context, state *entity.OfflineNotification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state.UpdatedAt = time.Now()
+	copied := *state
+	r.states[state.CID] = &copied
+	return nil
+}
+
+// MemoryChannelIdentityRepository 实现ChannelIdentityRepository
+type MemoryChannelIdentityRepository struct {
+	mu         sync.Mutex
+	identities map[string]*entity.ChannelIdentity // channel + "/" + externalID -> identity
+}
+
+func NewMemoryChannelIdentityRepository() *MemoryChannelIdentityRepository {
+	return &MemoryChannelIdentityRepository{identities: make(map[string]*entity.ChannelIdentity)}
+}
+
+func (r *MemoryChannelIdentityRepository) Get(ctx context.Context, channel, externalID string) (*entity.ChannelIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identity, ok := r.identities[channel+"/"+externalID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *identity
+	return &copied, nil
+}
+
+func (r *MemoryChannelIdentityRepository) GetByCID(ctx context.Context, channel, cid string) (*entity.ChannelIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found *entity.ChannelIdentity
+	for _, identity := range r.identities {
+		if identity.Channel == channel && identity.CID == cid && (found == nil || identity.CreatedAt.After(found.CreatedAt)) {
+			found = identity
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	copied := *found
+	return &copied, nil
+}
+
+func (r *MemoryChannelIdentityRepository) Create(ctx context.Context, identity *entity.ChannelIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := identity.Channel + "/" + identity.ExternalID
+	if _, exists := r.identities[key]; exists {
+		return repo.ErrDuplicateKey
+	}
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	copied := *identity
+	r.identities[key] = &copied
+	return nil
+}
+
+// MemoryChannelThreadRepository 实现ChannelThreadRepository，按记录顺序保存
+type MemoryChannelThreadRepository struct {
+	mu      sync.Mutex
+	threads []*entity.ChannelThread
+}
+
+func NewMemoryChannelThreadRepository() *MemoryChannelThreadRepository {
+	return &MemoryChannelThreadRepository{}
+}
+
+func (r *MemoryChannelThreadRepository) Save(ctx context.Context, thread *entity.ChannelThread) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.threads {
+		if t.Channel == thread.Channel && t.ThreadID == thread.ThreadID {
+			return nil
+		}
+	}
+	if thread.CreatedAt.IsZero() {
+		thread.CreatedAt = time.Now()
+	}
+	copied := *thread
+	r.threads = append(r.threads, &copied)
+	return nil
+}
+
+func (r *MemoryChannelThreadRepository) FindSession(ctx context.Context, channel string, threadIDs []string) (*entity.ChannelThread, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.threads) - 1; i >= 0; i-- {
+		if t := r.threads[i]; t.Channel == channel && containsString(threadIDs, t.ThreadID) {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryChannelThreadRepository) ListBySession(ctx context.Context, channel, sessionID string) ([]*entity.ChannelThread, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var threads []*entity.ChannelThread
+	for _, t := range r.threads {
+		if t.Channel == channel && t.SessionID == sessionID {
+			copied := *t
+			threads = append(threads, &copied)
+		}
+	}
+	return threads, nil
+}
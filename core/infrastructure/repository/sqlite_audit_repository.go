@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// defaultAuditLimit 未指定条数时最多返回的审计日志数
+const defaultAuditLimit = 100
+
+// SQLiteAuditRepository 基于 t_audit_log 表实现 AuditRepository
+type SQLiteAuditRepository struct {
+	db *sql.DB
+}
+
+var _ repo.AuditRepository = (*SQLiteAuditRepository)(nil)
+
+// NewSQLiteAuditRepository 复用基础仓储的数据库连接
+func NewSQLiteAuditRepository(base *SQLiteRepository) *SQLiteAuditRepository {
+	return &SQLiteAuditRepository{db: base.db}
+}
+
+func (r *SQLiteAuditRepository) Create(ctx context.Context, log *entity.AuditLog) error {
+	defer observeQuery(ctx, "AuditRepository.Create")()
+	query := `INSERT INTO t_audit_log
+		(actor_id, actor_role, action, session_id, target_id, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	var detail sql.NullString
+	if len(log.Detail) > 0 {
+		data, err := json.Marshal(log.Detail)
+		if err != nil {
+			return err
+		}
+		detail = sql.NullString{String: string(data), Valid: true}
+	}
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		log.ActorID,
+		log.ActorRole,
+		log.Action,
+		nullString(log.SessionID),
+		nullString(log.TargetID),
+		detail,
+		log.CreatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	if err != nil {
+		return err
+	}
+	log.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r *SQLiteAuditRepository) List(ctx context.Context, q entity.AuditQuery) ([]*entity.AuditLog, error) {
+	defer observeQuery(ctx, "AuditRepository.List")()
+	query := `SELECT id, actor_id, actor_role, action, session_id, target_id, detail, created_at
+		FROM t_audit_log WHERE 1 = 1`
+	var args []interface{}
+	if q.SessionID != "" {
+		query += ` AND session_id = ?`
+		args = append(args, q.SessionID)
+	}
+	if q.ActorID != "" {
+		query += ` AND actor_id = ?`
+		args = append(args, q.ActorID)
+	}
+	if q.Action != "" {
+		query += ` AND action = ?`
+		args = append(args, q.Action)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*entity.AuditLog
+	for rows.Next() {
+		var log entity.AuditLog
+		var sessionID, targetID, detail sql.NullString
+		if err := rows.Scan(
+			&log.ID,
+			&log.ActorID,
+			&log.ActorRole,
+			&log.Action,
+			&sessionID,
+			&targetID,
+			&detail,
+			&log.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		log.SessionID = sessionID.String
+		log.TargetID = targetID.String
+		if detail.Valid {
+			if err := json.Unmarshal([]byte(detail.String), &log.Detail); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, &log)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLiteCannedResponseRepository 基于 t_canned_response 表实现 CannedResponseRepository
+type SQLiteCannedResponseRepository struct {
+	db *sql.DB
+}
+
+var _ repo.CannedResponseRepository = (*SQLiteCannedResponseRepository)(nil)
+
+// NewSQLiteCannedResponseRepository 复用基础仓储的数据库连接
+func NewSQLiteCannedResponseRepository(base *SQLiteRepository) *SQLiteCannedResponseRepository {
+	return &SQLiteCannedResponseRepository{db: base.db}
+}
+
+const cannedResponseColumns = `id, shortcut, title, body, scope, team, owner_id,
+		created_by, updated_by, created_at, updated_at`
+
+func scanCannedResponse(row rowScanner) (*entity.CannedResponse, error) {
+	var c entity.CannedResponse
+	var team, ownerID sql.NullString
+	err := row.Scan(
+		&c.ID,
+		&c.Shortcut,
+		&c.Title,
+		&c.Body,
+		&c.Scope,
+		&team,
+		&ownerID,
+		&c.CreatedBy,
+		&c.UpdatedBy,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.Team = team.String
+	c.OwnerID = ownerID.String
+	c.Variables = entity.ParseCannedVariables(c.Body)
+	return &c, nil
+}
+
+func (r *SQLiteCannedResponseRepository) Create(ctx context.Context, c *entity.CannedResponse) error {
+	defer observeQuery(ctx, "CannedResponseRepository.Create")()
+	query := `INSERT INTO t_canned_response
+		(id, shortcut, title, body, scope, team, owner_id, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		c.ID,
+		c.Shortcut,
+		c.Title,
+		c.Body,
+		c.Scope,
+		nullString(c.Team),
+		nullString(c.OwnerID),
+		c.CreatedBy,
+		c.UpdatedBy,
+	)
+	return translateError(err)
+}
+
+func (r *SQLiteCannedResponseRepository) GetByID(ctx context.Context, id string) (*entity.CannedResponse, error) {
+	defer observeQuery(ctx, "CannedResponseRepository.GetByID")()
+	query := `SELECT ` + cannedResponseColumns + `
+		FROM t_canned_response WHERE id = ? AND is_deleted = 0`
+
+	c, err := scanCannedResponse(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *SQLiteCannedResponseRepository) Update(ctx context.Context, c *entity.CannedResponse) error {
+	defer observeQuery(ctx, "CannedResponseRepository.Update")()
+	query := `UPDATE t_canned_response
+		SET shortcut = ?, title = ?, body = ?, scope = ?, team = ?, owner_id = ?,
+			updated_by = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND is_deleted = 0`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		c.Shortcut,
+		c.Title,
+		c.Body,
+		c.Scope,
+		nullString(c.Team),
+		nullString(c.OwnerID),
+		c.UpdatedBy,
+		c.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteCannedResponseRepository) Delete(ctx context.Context, id string, deletedBy string) error {
+	defer observeQuery(ctx, "CannedResponseRepository.Delete")()
+	query := `UPDATE t_canned_response
+		SET is_deleted = 1, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND is_deleted = 0`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, deletedBy, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteCannedResponseRepository) List(ctx context.Context, filter entity.CannedResponseFilter) ([]*entity.CannedResponse, error) {
+	defer observeQuery(ctx, "CannedResponseRepository.List")()
+	query := `SELECT ` + cannedResponseColumns + `
+		FROM t_canned_response
+		WHERE is_deleted = 0
+		AND ((scope = ? AND owner_id = ?) OR (scope = ? AND (team IS NULL OR team = ?)))`
+	args := []interface{}{
+		entity.CannedScopePersonal, filter.AgentID,
+		entity.CannedScopeTeam, filter.Team,
+	}
+	if filter.ShortcutPrefix != "" {
+		query += ` AND shortcut LIKE ? ESCAPE '\'`
+		args = append(args, escapeLike(filter.ShortcutPrefix)+"%")
+	}
+	query += ` ORDER BY shortcut ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*entity.CannedResponse
+	for rows.Next() {
+		c, err := scanCannedResponse(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// requireAffected 更新/删除未命中任何记录时返回 ErrNotFound
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' || s[i] == '_' || s[i] == '\\' {
+			b = append(b, '\\')
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
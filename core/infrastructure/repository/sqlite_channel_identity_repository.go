@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLiteChannelIdentityRepository 基于 t_channel_identity 表实现 ChannelIdentityRepository
+type SQLiteChannelIdentityRepository struct {
+	db *sql.DB
+}
+
+var _ repo.ChannelIdentityRepository = (*SQLiteChannelIdentityRepository)(nil)
+
+// NewSQLiteChannelIdentityRepository 复用基础仓储的数据库连接
+func NewSQLiteChannelIdentityRepository(base *SQLiteRepository) *SQLiteChannelIdentityRepository {
+	return &SQLiteChannelIdentityRepository{db: base.db}
+}
+
+func (r *SQLiteChannelIdentityRepository) Get(ctx context.Context, channel, externalID string) (*entity.ChannelIdentity, error) {
+	defer observeQuery(ctx, "ChannelIdentityRepository.Get")()
+	return r.get(ctx, `SELECT channel, external_id, cid, created_at
+		FROM t_channel_identity WHERE channel = ? AND external_id = ?`, channel, externalID)
+}
+
+func (r *SQLiteChannelIdentityRepository) GetByCID(ctx context.Context, channel, cid string) (*entity.ChannelIdentity, error) {
+	defer observeQuery(ctx, "ChannelIdentityRepository.GetByCID")()
+	return r.get(ctx, `SELECT channel, external_id, cid, created_at
+		FROM t_channel_identity WHERE channel = ? AND cid = ?
+		ORDER BY created_at DESC LIMIT 1`, channel, cid)
+}
+
+func (r *SQLiteChannelIdentityRepository) get(ctx context.Context, query string, args ...interface{}) (*entity.ChannelIdentity, error) {
+	var identity entity.ChannelIdentity
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(
+		&identity.Channel,
+		&identity.ExternalID,
+		&identity.CID,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *SQLiteChannelIdentityRepository) Create(ctx context.Context, identity *entity.ChannelIdentity) error {
+	defer observeQuery(ctx, "ChannelIdentityRepository.Create")()
+	query := `INSERT INTO t_channel_identity (channel, external_id, cid, created_at)
+		VALUES (?, ?, ?, ?)`
+
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		identity.Channel,
+		identity.ExternalID,
+		identity.CID,
+		identity.CreatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return translateError(err)
+}
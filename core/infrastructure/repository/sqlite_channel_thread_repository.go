@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLiteChannelThreadRepository 基于 t_channel_thread 表实现 ChannelThreadRepository
+type SQLiteChannelThreadRepository struct {
+	db *sql.DB
+}
+
+var _ repo.ChannelThreadRepository = (*SQLiteChannelThreadRepository)(nil)
+
+// NewSQLiteChannelThreadRepository 复用基础仓储的数据库连接
+func NewSQLiteChannelThreadRepository(base *SQLiteRepository) *SQLiteChannelThreadRepository {
+	return &SQLiteChannelThreadRepository{db: base.db}
+}
+
+const channelThreadColumns = `channel, thread_id, session_id, subject, created_at`
+
+func (r *SQLiteChannelThreadRepository) Save(ctx context.Context, thread *entity.ChannelThread) error {
+	defer observeQuery(ctx, "ChannelThreadRepository.Save")()
+	query := `INSERT INTO t_channel_thread (` + channelThreadColumns + `)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(channel, thread_id) DO NOTHING`
+
+	if thread.CreatedAt.IsZero() {
+		thread.CreatedAt = time.Now()
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		thread.Channel,
+		thread.ThreadID,
+		thread.SessionID,
+		nullString(thread.Subject),
+		thread.CreatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return err
+}
+
+func (r *SQLiteChannelThreadRepository) FindSession(ctx context.Context, channel string, threadIDs []string) (*entity.ChannelThread, error) {
+	defer observeQuery(ctx, "ChannelThreadRepository.FindSession")()
+	if len(threadIDs) == 0 {
+		return nil, ErrNotFound
+	}
+	query := `SELECT ` + channelThreadColumns + `
+		FROM t_channel_thread
+		WHERE channel = ? AND thread_id IN (` + placeholders(len(threadIDs)) + `)
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT 1`
+
+	args := []interface{}{channel}
+	for _, id := range threadIDs {
+		args = append(args, id)
+	}
+	threads, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(threads) == 0 {
+		return nil, ErrNotFound
+	}
+	return threads[0], nil
+}
+
+func (r *SQLiteChannelThreadRepository) ListBySession(ctx context.Context, channel, sessionID string) ([]*entity.ChannelThread, error) {
+	defer observeQuery(ctx, "ChannelThreadRepository.ListBySession")()
+	query := `SELECT ` + channelThreadColumns + `
+		FROM t_channel_thread
+		WHERE channel = ? AND session_id = ?
+		ORDER BY created_at ASC, rowid ASC`
+	return r.query(ctx, query, channel, sessionID)
+}
+
+func (r *SQLiteChannelThreadRepository) query(ctx context.Context, query string, args ...interface{}) ([]*entity.ChannelThread, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []*entity.ChannelThread
+	for rows.Next() {
+		var thread entity.ChannelThread
+		var subject sql.NullString
+		if err := rows.Scan(&thread.Channel, &thread.ThreadID, &thread.SessionID, &subject, &thread.CreatedAt); err != nil {
+			return nil, err
+		}
+		thread.Subject = subject.String
+		threads = append(threads, &thread)
+	}
+	return threads, rows.Err()
+}
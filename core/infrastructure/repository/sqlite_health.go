@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SchemaVersion 服务期望的表结构版本，对应 sql/init.sql 末尾的 PRAGMA user_version
+const SchemaVersion = 1
+
+// schemaTables 服务读写的全部表及其在建表之后新增的列；版本号引入之前创建的数据库按表与列是否齐全判断
+var schemaTables = []struct {
+	name    string
+	columns []string
+}{
+	{"t_user", []string{"username", "display_name", "email", "external_id", "merged_into", "role", "status", "skills", "max_concurrent_chats", "password_hash"}},
+	{"t_session", []string{"agent_id", "channel"}},
+	{"t_chat_message", []string{"reply_to"}},
+	{"message_reactions", nil},
+	{"session_ratings", nil},
+	{"t_canned_response", nil},
+	{"t_audit_log", nil},
+	{"t_refresh_token", nil},
+	{"t_revoked_token", nil},
+	{"t_webhook_subscription", nil},
+	{"t_webhook_delivery", nil},
+	{"t_outbox", []string{"completed_sinks"}},
+	{"t_session_retention", nil},
+	{"t_retention_run", nil},
+	{"t_offline_notification", nil},
+	{"t_channel_identity", nil},
+	{"t_channel_thread", nil},
+}
+
+// Ping 检查数据库可读，读取 sqlite_master 以确认数据库文件仍可访问
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return err
+	}
+	var n int
+	return r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&n)
+}
+
+// CheckSchema 检查表结构版本不低于 SchemaVersion；版本为 0 的旧数据库要求表与新增的列齐全
+func (r *SQLiteRepository) CheckSchema(ctx context.Context) error {
+	var version int
+	if err := r.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version >= SchemaVersion {
+		return nil
+	}
+	if version > 0 {
+		return fmt.Errorf("schema version %d is older than %d", version, SchemaVersion)
+	}
+
+	var missingTables, missingColumns []string
+	for _, table := range schemaTables {
+		existing, err := r.tableColumns(ctx, table.name)
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			missingTables = append(missingTables, table.name)
+			continue
+		}
+		for _, column := range table.columns {
+			if !existing[column] {
+				missingColumns = append(missingColumns, table.name+"."+column)
+			}
+		}
+	}
+	if len(missingTables) > 0 {
+		return fmt.Errorf("schema is missing tables: %s", strings.Join(missingTables, ", "))
+	}
+	if len(missingColumns) > 0 {
+		return fmt.Errorf("schema is missing columns: %s", strings.Join(missingColumns, ", "))
+	}
+	return nil
+}
+
+// tableColumns 返回表的全部列名，表不存在时为空
+func (r *SQLiteRepository) tableColumns(ctx context.Context, table string) (map[string]bool, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
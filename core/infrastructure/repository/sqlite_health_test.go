@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// newSchemaRepository 创建按 sql/init.sql 建表的内存数据库，再执行 migrate 模拟旧版本的表结构
+func newSchemaRepository(t *testing.T, name, migrate string) *SQLiteRepository {
+	t.Helper()
+	schema, err := os.ReadFile("../../../sql/init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _, _, _, err := NewSQLiteRepository(fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { base.Close() })
+	// 共享缓存的内存库在最后一个连接关闭时释放，固定单连接使其在测试期间保持
+	base.db.SetMaxOpenConns(1)
+	if _, err := base.db.Exec(string(schema) + migrate); err != nil {
+		t.Fatal(err)
+	}
+	return base
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		migrate string
+		wantErr string
+	}{
+		{name: "current"},
+		{
+			name:    "unversioned with all columns",
+			migrate: "PRAGMA user_version = 0;",
+		},
+		{
+			name:    "unversioned without completed_sinks",
+			migrate: "ALTER TABLE t_outbox DROP COLUMN completed_sinks; PRAGMA user_version = 0;",
+			wantErr: "schema is missing columns: t_outbox.completed_sinks",
+		},
+		{
+			name:    "unversioned without reply_to",
+			migrate: "DROP INDEX idx_t_chat_message_reply_to; ALTER TABLE t_chat_message DROP COLUMN reply_to; PRAGMA user_version = 0;",
+			wantErr: "schema is missing columns: t_chat_message.reply_to",
+		},
+		{
+			name:    "unversioned without reactions",
+			migrate: "DROP TABLE message_reactions; PRAGMA user_version = 0;",
+			wantErr: "schema is missing tables: message_reactions",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newSchemaRepository(t, fmt.Sprintf("schema%d", i), tt.migrate)
+			err := repo.CheckSchema(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("CheckSchema() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("CheckSchema() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
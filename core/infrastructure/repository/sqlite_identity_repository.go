@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLiteIdentityRepository 实现 IdentityRepository，合并涉及多张表，在同一事务内完成
+type SQLiteIdentityRepository struct {
+	db *sql.DB
+}
+
+var _ repo.IdentityRepository = (*SQLiteIdentityRepository)(nil)
+
+// NewSQLiteIdentityRepository 复用基础仓储的数据库连接
+func NewSQLiteIdentityRepository(base *SQLiteRepository) *SQLiteIdentityRepository {
+	return &SQLiteIdentityRepository{db: base.db}
+}
+
+func (r *SQLiteIdentityRepository) MergeCustomer(ctx context.Context, fromCID, intoCID, mergedBy string) (int64, error) {
+	defer observeQuery(ctx, "IdentityRepository.MergeCustomer")()
+	var sessions int64
+	err := withTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+
+		// 先软删除被合并的记录，记录不存在或已被并发合并时整体放弃
+		res, err := tx.ExecContext(ctx, `UPDATE t_user
+			SET is_deleted = 1, merged_into = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE cid = ? AND is_deleted = 0`, intoCID, mergedBy, fromCID)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(res); err != nil {
+			return err
+		}
+
+		res, err = tx.ExecContext(ctx, `UPDATE t_session
+			SET cid = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE cid = ?`, intoCID, mergedBy, fromCID)
+		if err != nil {
+			return err
+		}
+		if sessions, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		// 消息地址格式为 U:<cid>
+		fromAddr, intoAddr := "U:"+fromCID, "U:"+intoCID
+		statements := []struct {
+			query string
+			args  []interface{}
+		}{
+			{`UPDATE t_chat_message SET src = ? WHERE src = ?`, []interface{}{intoAddr, fromAddr}},
+			{`UPDATE t_chat_message SET dst = ? WHERE dst = ?`, []interface{}{intoAddr, fromAddr}},
+			// 同一条消息上两个身份都回应过同一表情时只保留一条
+			{`UPDATE OR IGNORE message_reactions SET user_id = ? WHERE user_id = ?`, []interface{}{intoCID, fromCID}},
+			{`DELETE FROM message_reactions WHERE user_id = ?`, []interface{}{fromCID}},
+			{`UPDATE session_ratings SET cid = ? WHERE cid = ?`, []interface{}{intoCID, fromCID}},
+			{`UPDATE t_channel_identity SET cid = ? WHERE cid = ?`, []interface{}{intoCID, fromCID}},
+		}
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLiteOfflineNotificationRepository 基于 t_offline_notification 表实现 OfflineNotificationRepository，
+// 待提醒的回复直接从 t_chat_message 查询
+type SQLiteOfflineNotificationRepository struct {
+	db *sql.DB
+}
+
+var _ repo.OfflineNotificationRepository = (*SQLiteOfflineNotificationRepository)(nil)
+
+// NewSQLiteOfflineNotificationRepository 复用基础仓储的数据库连接
+func NewSQLiteOfflineNotificationRepository(base *SQLiteRepository) *SQLiteOfflineNotificationRepository {
+	return &SQLiteOfflineNotificationRepository{db: base.db}
+}
+
+func (r *SQLiteOfflineNotificationRepository) ListPendingCustomers(ctx context.Context, sentBefore, notifiedBefore time.Time, limit int) ([]string, error) {
+	defer observeQuery(ctx, "OfflineNotificationRepository.ListPendingCustomers")()
+	// 发给客户的消息 dst 为 U:<cid>
+	query := `SELECT DISTINCT u.cid
+		FROM t_chat_message m
+		JOIN t_user u ON u.cid = substr(m.dst, 3)
+		LEFT JOIN t_offline_notification n ON n.cid = u.cid
+		WHERE m.status = ? AND m.msg_type = ? AND m.dst LIKE 'U:%' AND m.is_deleted = 0
+		AND m.ts <= ? AND m.ts > COALESCE(n.last_msg_ts, 0)
+		AND u.role = ? AND u.is_deleted = 0 AND u.email IS NOT NULL AND u.email <> ''
+		AND (n.last_sent_at IS NULL OR julianday(n.last_sent_at) <= julianday(?))
+		ORDER BY u.cid
+		LIMIT ?`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query,
+		entity.StatusOffline,
+		entity.MsgTypeMessage,
+		sentBefore.UnixMilli(),
+		entity.RoleCustomer,
+		notifiedBefore.UTC().Format(sqliteTimeLayout),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cids []string
+	for rows.Next() {
+		var cid string
+		if err := rows.Scan(&cid); err != nil {
+			return nil, err
+		}
+		cids = append(cids, cid)
+	}
+	return cids, rows.Err()
+}
+
+func (r *SQLiteOfflineNotificationRepository) ListPendingReplies(ctx context.Context, cid string, afterTs int64) ([]*entity.Message, error) {
+	defer observeQuery(ctx, "OfflineNotificationRepository.ListPendingReplies")()
+	query := `SELECT ` + messageColumns + `
+		FROM t_chat_message
+		WHERE dst = ? AND status = ? AND msg_type = ? AND is_deleted = 0 AND ts > ?
+		ORDER BY ts ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query,
+		"U:"+cid, entity.StatusOffline, entity.MsgTypeMessage, afterTs)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+func (r *SQLiteOfflineNotificationRepository) GetState(ctx context.Context, cid string) (*entity.OfflineNotification, error) {
+	defer observeQuery(ctx, "OfflineNotificationRepository.GetState")()
+	query := `SELECT cid, last_sent_at, last_msg_ts, updated_at
+		FROM t_offline_notification WHERE cid = ?`
+
+	var state entity.OfflineNotification
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, cid).Scan(
+		&state.CID,
+		&state.LastSentAt,
+		&state.LastMsgTs,
+		&state.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *SQLiteOfflineNotificationRepository) SaveState(ctx context.Context, state *entity.OfflineNotification) error {
+	defer observeQuery(ctx, "OfflineNotificationRepository.SaveState")()
+	query := `INSERT INTO t_offline_notification (cid, last_sent_at, last_msg_ts, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(cid) DO UPDATE SET
+			last_sent_at = excluded.last_sent_at,
+			last_msg_ts = excluded.last_msg_ts,
+			updated_at = excluded.updated_at`
+
+	state.UpdatedAt = time.Now()
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		state.CID,
+		state.LastSentAt.UTC().Format(sqliteTimeLayout),
+		state.LastMsgTs,
+		state.UpdatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return err
+}
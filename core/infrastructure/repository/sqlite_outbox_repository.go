@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLiteOutboxRepository 基于 t_outbox 表实现 OutboxRepository，业务写入与事件写入共用一个事务
+type SQLiteOutboxRepository struct {
+	db *sql.DB
+}
+
+var _ repo.OutboxRepository = (*SQLiteOutboxRepository)(nil)
+
+// NewSQLiteOutboxRepository 复用基础仓储的数据库连接
+func NewSQLiteOutboxRepository(base *SQLiteRepository) *SQLiteOutboxRepository {
+	return &SQLiteOutboxRepository{db: base.db}
+}
+
+func (r *SQLiteOutboxRepository) CreateMessage(ctx context.Context, message *entity.Message, events ...*entity.OutboxEvent) error {
+	defer observeQuery(ctx, "OutboxRepository.CreateMessage")()
+	return r.withEvents(ctx, events, func(db dbtx) error {
+		return insertMessage(ctx, db, message)
+	})
+}
+
+func (r *SQLiteOutboxRepository) CreateSession(ctx context.Context, session *entity.Session, events ...*entity.OutboxEvent) error {
+	defer observeQuery(ctx, "OutboxRepository.CreateSession")()
+	return r.withEvents(ctx, events, func(db dbtx) error {
+		return insertSession(ctx, db, session)
+	})
+}
+
+func (r *SQLiteOutboxRepository) CloseSession(ctx context.Context, sessionID string, endTime time.Time, events ...*entity.OutboxEvent) error {
+	defer observeQuery(ctx, "OutboxRepository.CloseSession")()
+	return r.withEvents(ctx, events, func(db dbtx) error {
+		return closeSession(ctx, db, sessionID, endTime)
+	})
+}
+
+func (r *SQLiteOutboxRepository) AssignSession(ctx context.Context, sessionID, agentID string, events ...*entity.OutboxEvent) error {
+	defer observeQuery(ctx, "OutboxRepository.AssignSession")()
+	return r.withEvents(ctx, events, func(db dbtx) error {
+		return updateSessionAgent(ctx, db, sessionID, agentID)
+	})
+}
+
+// withEvents 在同一事务内执行业务写入并追加事件，ctx 已处于事务中时加入该事务
+func (r *SQLiteOutboxRepository) withEvents(ctx context.Context, events []*entity.OutboxEvent, write func(db dbtx) error) error {
+	return withTx(ctx, r.db, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		if err := write(db); err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := insertOutboxEvent(ctx, db, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func insertOutboxEvent(ctx context.Context, db dbtx, event *entity.OutboxEvent) error {
+	query := `INSERT INTO t_outbox
+		(id, event_type, aggregate_id, payload, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = now
+	}
+	event.UpdatedAt = now
+	_, err := db.ExecContext(ctx, query,
+		event.ID,
+		event.EventType,
+		event.AggregateID,
+		string(event.Payload),
+		event.Status,
+		event.Attempts,
+		nullTime(event.NextAttemptAt),
+		event.CreatedAt.UTC().Format(sqliteTimeLayout),
+		event.UpdatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return translateError(err)
+}
+
+const outboxColumns = `id, event_type, aggregate_id, payload, status, attempts,
+		next_attempt_at, last_error, completed_sinks, published_at, created_at, updated_at`
+
+func scanOutboxEvent(row rowScanner) (*entity.OutboxEvent, error) {
+	var e entity.OutboxEvent
+	var payload string
+	var nextAttemptAt, publishedAt sql.NullTime
+	var lastError, completedSinks sql.NullString
+	if err := row.Scan(
+		&e.ID,
+		&e.EventType,
+		&e.AggregateID,
+		&payload,
+		&e.Status,
+		&e.Attempts,
+		&nextAttemptAt,
+		&lastError,
+		&completedSinks,
+		&publishedAt,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	e.Payload = json.RawMessage(payload)
+	e.NextAttemptAt = nextAttemptAt.Time
+	e.LastError = lastError.String
+	if completedSinks.String != "" {
+		e.CompletedSinks = strings.Split(completedSinks.String, ",")
+	}
+	e.PublishedAt = publishedAt.Time
+	return &e, nil
+}
+
+func (r *SQLiteOutboxRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*entity.OutboxEvent, error) {
+	defer observeQuery(ctx, "OutboxRepository.ListDue")()
+	query := `SELECT ` + outboxColumns + `
+		FROM t_outbox
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY seq ASC LIMIT ?`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query,
+		entity.OutboxPending, now.UTC().Format(sqliteTimeLayout), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*entity.OutboxEvent
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *SQLiteOutboxRepository) Update(ctx context.Context, event *entity.OutboxEvent) error {
+	defer observeQuery(ctx, "OutboxRepository.Update")()
+	query := `UPDATE t_outbox
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, completed_sinks = ?, published_at = ?, updated_at = ?
+		WHERE id = ?`
+
+	event.UpdatedAt = time.Now()
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		event.Status,
+		event.Attempts,
+		nullTime(event.NextAttemptAt),
+		nullString(event.LastError),
+		nullString(strings.Join(event.CompletedSinks, ",")),
+		nullTime(event.PublishedAt),
+		event.UpdatedAt.UTC().Format(sqliteTimeLayout),
+		event.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteOutboxRepository) CountPending(ctx context.Context) (int, error) {
+	defer observeQuery(ctx, "OutboxRepository.CountPending")()
+	var n int
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM t_outbox WHERE status = ?`, entity.OutboxPending).Scan(&n)
+	return n, err
+}
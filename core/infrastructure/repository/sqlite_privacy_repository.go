@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLitePrivacyRepository 实现 PrivacyRepository，擦除涉及多张表，在同一事务内完成
+type SQLitePrivacyRepository struct {
+	db *sql.DB
+}
+
+var _ repo.PrivacyRepository = (*SQLitePrivacyRepository)(nil)
+
+// NewSQLitePrivacyRepository 复用基础仓储的数据库连接
+func NewSQLitePrivacyRepository(base *SQLiteRepository) *SQLitePrivacyRepository {
+	return &SQLitePrivacyRepository{db: base.db}
+}
+
+// erasedCIDs 客户本人及已合并到该客户的访客记录，已擦除的记录同样包含在内以便重复执行
+const erasedCIDs = `SELECT cid FROM t_user WHERE (cid = ? OR merged_into = ?) AND role = 'customer'`
+
+// erasedSessions 上述客户的全部会话
+const erasedSessions = `SELECT session_id FROM t_session WHERE cid IN (` + erasedCIDs + `)`
+
+func (r *SQLitePrivacyRepository) EraseCustomer(ctx context.Context, cid, erasedBy string) (*entity.ErasureResult, error) {
+	defer observeQuery(ctx, "PrivacyRepository.EraseCustomer")()
+	result := &entity.ErasureResult{CID: cid, ErasedAt: time.Now()}
+	err := withTx(ctx, r.db, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+
+		var found int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+erasedCIDs+`)`, cid, cid).Scan(&found); err != nil {
+			return err
+		}
+		if found == 0 {
+			return ErrNotFound
+		}
+
+		// 先处理依赖会话与用户的记录，再软删除会话与用户
+		statements := []struct {
+			query    string
+			args     []interface{}
+			affected *int64
+		}{
+			{`DELETE FROM message_reactions WHERE user_id IN (` + erasedCIDs + `)`,
+				[]interface{}{cid, cid}, &result.Reactions},
+			{`UPDATE session_ratings SET comment = NULL WHERE cid IN (` + erasedCIDs + `)`,
+				[]interface{}{cid, cid}, &result.Ratings},
+			// 未发出的事件与投递记录中带有消息内容，一并删除
+			{`DELETE FROM t_outbox WHERE aggregate_id IN (` + erasedSessions + `)
+				OR aggregate_id IN (SELECT msg_id FROM t_chat_message WHERE session_id IN (` + erasedSessions + `))`,
+				[]interface{}{cid, cid, cid, cid}, &result.OutboxEvents},
+			{`DELETE FROM t_webhook_delivery WHERE EXISTS (
+				SELECT 1 FROM (` + erasedCIDs + `) u WHERE instr(t_webhook_delivery.payload, u.cid) > 0)`,
+				[]interface{}{cid, cid}, &result.WebhookDeliveries},
+			{`DELETE FROM t_session_retention WHERE session_id IN (` + erasedSessions + `)`,
+				[]interface{}{cid, cid}, nil},
+			{`UPDATE t_chat_message
+				SET content = ?, ext = NULL, is_deleted = 1, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE session_id IN (` + erasedSessions + `)`,
+				[]interface{}{entity.ErasedContent, erasedBy, cid, cid}, &result.Messages},
+			{`UPDATE t_session SET is_deleted = 1, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE cid IN (` + erasedCIDs + `)`,
+				[]interface{}{erasedBy, cid, cid}, &result.Sessions},
+			{`DELETE FROM t_refresh_token WHERE user_id IN (` + erasedCIDs + `)`,
+				[]interface{}{cid, cid}, &result.RefreshTokens},
+			{`DELETE FROM t_offline_notification WHERE cid IN (` + erasedCIDs + `)`,
+				[]interface{}{cid, cid}, nil},
+			{`DELETE FROM t_channel_identity WHERE cid IN (` + erasedCIDs + `)`,
+				[]interface{}{cid, cid}, nil},
+			{`DELETE FROM t_channel_thread WHERE session_id IN (` + erasedSessions + `)`,
+				[]interface{}{cid, cid}, nil},
+			// 保留 cid 作为假名，清除其余个人信息
+			{`UPDATE t_user
+				SET username = 'erased', display_name = NULL, email = NULL, external_id = NULL, query = NULL,
+					status = 'offline', is_deleted = 1, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE cid IN (` + erasedCIDs + `)`,
+				[]interface{}{erasedBy, cid, cid}, &result.Users},
+		}
+		for _, stmt := range statements {
+			res, err := db.ExecContext(ctx, stmt.query, stmt.args...)
+			if err != nil {
+				return err
+			}
+			if stmt.affected != nil {
+				if *stmt.affected, err = res.RowsAffected(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// sqliteTimeLayout 与 CURRENT_TIMESTAMP 一致的 UTC 时间格式，保证字符串比较有序
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// SQLiteRatingRepository 基于 session_ratings 表实现 RatingRepository
+type SQLiteRatingRepository struct {
+	db *sql.DB
+}
+
+var _ repo.RatingRepository = (*SQLiteRatingRepository)(nil)
+
+// NewSQLiteRatingRepository 复用基础仓储的数据库连接
+func NewSQLiteRatingRepository(base *SQLiteRepository) *SQLiteRatingRepository {
+	return &SQLiteRatingRepository{db: base.db}
+}
+
+func (r *SQLiteRatingRepository) Create(ctx context.Context, rating *entity.SessionRating) error {
+	defer observeQuery(ctx, "RatingRepository.Create")()
+	query := `INSERT INTO session_ratings
+		(session_id, cid, agent_id, score, comment, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	if rating.CreatedAt.IsZero() {
+		rating.CreatedAt = time.Now()
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		rating.SessionID,
+		rating.CID,
+		nullString(rating.AgentID),
+		rating.Score,
+		rating.Comment,
+		rating.CreatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return translateError(err)
+}
+
+func (r *SQLiteRatingRepository) GetBySessionID(ctx context.Context, sessionID string) (*entity.SessionRating, error) {
+	defer observeQuery(ctx, "RatingRepository.GetBySessionID")()
+	query := `SELECT session_id, cid, agent_id, score, comment, created_at
+		FROM session_ratings WHERE session_id = ?`
+
+	var rating entity.SessionRating
+	var agentID, comment sql.NullString
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, sessionID).Scan(
+		&rating.SessionID,
+		&rating.CID,
+		&agentID,
+		&rating.Score,
+		&comment,
+		&rating.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	rating.AgentID = agentID.String
+	rating.Comment = comment.String
+	return &rating, nil
+}
+
+func (r *SQLiteRatingRepository) Aggregate(ctx context.Context, q entity.RatingQuery) ([]*entity.RatingAggregate, error) {
+	defer observeQuery(ctx, "RatingRepository.Aggregate")()
+	dayExpr := `''`
+	if q.GroupByDay {
+		dayExpr = `date(created_at)`
+	}
+
+	query := `SELECT COALESCE(agent_id, ''), ` + dayExpr + ` AS day,
+		COUNT(*), AVG(score), SUM(CASE WHEN score >= ? THEN 1 ELSE 0 END)
+		FROM session_ratings
+		WHERE created_at >= ? AND created_at < ?`
+	args := []interface{}{
+		entity.SatisfiedScore,
+		q.From.UTC().Format(sqliteTimeLayout),
+		q.To.UTC().Format(sqliteTimeLayout),
+	}
+	if q.AgentID != "" {
+		query += ` AND agent_id = ?`
+		args = append(args, q.AgentID)
+	}
+	query += ` GROUP BY agent_id, day ORDER BY day, agent_id`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*entity.RatingAggregate
+	for rows.Next() {
+		var agg entity.RatingAggregate
+		if err := rows.Scan(&agg.AgentID, &agg.Day, &agg.Count, &agg.Average, &agg.Satisfied); err != nil {
+			return nil, err
+		}
+		if agg.Count > 0 {
+			agg.CSAT = float64(agg.Satisfied) * 100 / float64(agg.Count)
+		}
+		result = append(result, &agg)
+	}
+	return result, rows.Err()
+}
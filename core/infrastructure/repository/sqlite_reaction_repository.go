@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLiteReactionRepository 基于 message_reactions 表实现 ReactionRepository
+type SQLiteReactionRepository struct {
+	db *sql.DB
+}
+
+var _ repo.ReactionRepository = (*SQLiteReactionRepository)(nil)
+
+// NewSQLiteReactionRepository 复用基础仓储的数据库连接
+func NewSQLiteReactionRepository(base *SQLiteRepository) *SQLiteReactionRepository {
+	return &SQLiteReactionRepository{db: base.db}
+}
+
+func (r *SQLiteReactionRepository) Add(ctx context.Context, reaction *entity.Reaction) error {
+	defer observeQuery(ctx, "ReactionRepository.Add")()
+	// 同一用户对同一消息的同一表情只保留一条，重复添加视为成功
+	query := `INSERT OR IGNORE INTO message_reactions
+		(msg_id, user_id, emoji, session_id)
+		VALUES (?, ?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		reaction.MsgID,
+		reaction.UserID,
+		reaction.Emoji,
+		reaction.SessionID,
+	)
+	return err
+}
+
+func (r *SQLiteReactionRepository) Remove(ctx context.Context, msgID, userID, emoji string) error {
+	defer observeQuery(ctx, "ReactionRepository.Remove")()
+	query := `DELETE FROM message_reactions WHERE msg_id = ? AND user_id = ? AND emoji = ?`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, msgID, userID, emoji)
+	return err
+}
+
+func (r *SQLiteReactionRepository) ListByMessageID(ctx context.Context, msgID string) ([]*entity.Reaction, error) {
+	defer observeQuery(ctx, "ReactionRepository.ListByMessageID")()
+	query := `SELECT msg_id, session_id, user_id, emoji, created_at
+		FROM message_reactions WHERE msg_id = ?
+		ORDER BY created_at ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, msgID)
+	if err != nil {
+		return nil, err
+	}
+	return scanReactions(rows)
+}
+
+func (r *SQLiteReactionRepository) ListBySessionID(ctx context.Context, sessionID string) ([]*entity.Reaction, error) {
+	defer observeQuery(ctx, "ReactionRepository.ListBySessionID")()
+	query := `SELECT msg_id, session_id, user_id, emoji, created_at
+		FROM message_reactions WHERE session_id = ?
+		ORDER BY created_at ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return scanReactions(rows)
+}
+
+func scanReactions(rows *sql.Rows) ([]*entity.Reaction, error) {
+	defer rows.Close()
+
+	var reactions []*entity.Reaction
+	for rows.Next() {
+		var reaction entity.Reaction
+		if err := rows.Scan(
+			&reaction.MsgID,
+			&reaction.SessionID,
+			&reaction.UserID,
+			&reaction.Emoji,
+			&reaction.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, &reaction)
+	}
+	return reactions, rows.Err()
+}
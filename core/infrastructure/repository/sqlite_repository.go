@@ -5,11 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"cland.org/cland-chat-service/core/domain/entity"
 	repo "cland.org/cland-chat-service/core/domain/repository"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 // Repository interfaces
@@ -26,6 +27,7 @@ type MessageDTO struct {
 	Ts          int64
 	Status      uint8
 	Ext         []byte
+	ReplyTo     string
 	CreatedBy   string
 	UpdatedBy   string
 	CreatedAt   time.Time
@@ -35,6 +37,8 @@ type MessageDTO struct {
 type SessionDTO struct {
 	ID        string
 	CID       string
+	AgentID   string
+	Channel   string
 	StartTime time.Time
 	EndTime   time.Time
 	Status    string
@@ -45,16 +49,22 @@ type SessionDTO struct {
 }
 
 type UserDTO struct {
-	ID        string
-	UID       string
-	Username  string
-	Query     string
-	Role      string
-	Status    string
-	CreatedBy string
-	UpdatedBy string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID                 string
+	UID                string
+	Username           string
+	DisplayName        string
+	Email              string
+	ExternalID         string
+	Query              string
+	Role               string
+	Status             string
+	Skills             []byte
+	MaxConcurrentChats int
+	PasswordHash       string
+	CreatedBy          string
+	UpdatedBy          string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
 }
 
 type SQLiteRepository struct {
@@ -86,6 +96,7 @@ func toMessageDTO(msg *entity.Message) MessageDTO {
 		Ts:          int64(msg.Ts),
 		Status:      msg.Status,
 		Ext:         ext,
+		ReplyTo:     msg.ReplyTo,
 		CreatedBy:   msg.CreatedBy,
 		UpdatedBy:   msg.UpdatedBy,
 		CreatedAt:   msg.CreatedAt,
@@ -109,6 +120,7 @@ func toMessageEntity(dto MessageDTO) *entity.Message {
 		Ts:          entity.StringTimestamp(dto.Ts),
 		Status:      dto.Status,
 		Ext:         ext,
+		ReplyTo:     dto.ReplyTo,
 		CreatedBy:   dto.CreatedBy,
 		UpdatedBy:   dto.UpdatedBy,
 		CreatedAt:   dto.CreatedAt,
@@ -120,6 +132,8 @@ func toSessionDTO(session *entity.Session) SessionDTO {
 	return SessionDTO{
 		ID:        session.ID,
 		CID:       session.CID,
+		AgentID:   session.AgentId,
+		Channel:   session.Channel,
 		StartTime: session.StartTime,
 		EndTime:   session.EndTime,
 		Status:    session.Status,
@@ -134,6 +148,8 @@ func toSessionEntity(dto SessionDTO) *entity.Session {
 	return &entity.Session{
 		ID:        dto.ID,
 		CID:       dto.CID,
+		AgentId:   dto.AgentID,
+		Channel:   dto.Channel,
 		StartTime: dto.StartTime,
 		EndTime:   dto.EndTime,
 		Status:    dto.Status,
@@ -145,32 +161,52 @@ func toSessionEntity(dto SessionDTO) *entity.Session {
 }
 
 func toUserDTO(user *entity.User) UserDTO {
+	var skills []byte
+	if len(user.Skills) > 0 {
+		skills, _ = json.Marshal(user.Skills)
+	}
 	return UserDTO{
-		ID:        user.ID,
-		UID:       user.UID,
-		Username:  user.Username,
-		Query:     user.Query,
-		Role:      user.Role,
-		Status:    user.Status,
-		CreatedBy: user.CreatedBy,
-		UpdatedBy: user.UpdatedBy,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:                 user.ID,
+		UID:                user.UID,
+		Username:           user.Username,
+		DisplayName:        user.DisplayName,
+		Email:              user.Email,
+		ExternalID:         user.ExternalID,
+		Query:              user.Query,
+		Role:               user.Role,
+		Status:             user.Status,
+		Skills:             skills,
+		MaxConcurrentChats: user.MaxConcurrentChats,
+		PasswordHash:       user.PasswordHash,
+		CreatedBy:          user.CreatedBy,
+		UpdatedBy:          user.UpdatedBy,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
 	}
 }
 
 func toUserEntity(dto UserDTO) *entity.User {
+	var skills []string
+	if len(dto.Skills) > 0 {
+		json.Unmarshal(dto.Skills, &skills)
+	}
 	return &entity.User{
-		ID:        dto.ID,
-		UID:       dto.UID,
-		Username:  dto.Username,
-		Query:     dto.Query,
-		Role:      dto.Role,
-		Status:    dto.Status,
-		CreatedBy: dto.CreatedBy,
-		UpdatedBy: dto.UpdatedBy,
-		CreatedAt: dto.CreatedAt,
-		UpdatedAt: dto.UpdatedAt,
+		ID:                 dto.ID,
+		UID:                dto.UID,
+		Username:           dto.Username,
+		DisplayName:        dto.DisplayName,
+		Email:              dto.Email,
+		ExternalID:         dto.ExternalID,
+		Query:              dto.Query,
+		Role:               dto.Role,
+		Status:             dto.Status,
+		Skills:             skills,
+		MaxConcurrentChats: dto.MaxConcurrentChats,
+		PasswordHash:       dto.PasswordHash,
+		CreatedBy:          dto.CreatedBy,
+		UpdatedBy:          dto.UpdatedBy,
+		CreatedAt:          dto.CreatedAt,
+		UpdatedAt:          dto.UpdatedAt,
 	}
 }
 
@@ -204,14 +240,89 @@ var (
 	_ repo.UserRepository    = (*SQLiteUserRepository)(nil)
 )
 
+// messageColumns 消息查询的公共列
+const messageColumns = `msg_id, session_id, msg_type, src, dst, content, content_type, ts, status, ext, reply_to,
+		created_by, updated_by, created_at, updated_at`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// msTimestamp ts 列声明为 DATETIME 但存储毫秒时间戳，驱动可能将其解析为 time.Time
+type msTimestamp int64
+
+// Scan implements sql.Scanner
+func (t *msTimestamp) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*t = msTimestamp(v)
+	case time.Time:
+		*t = msTimestamp(v.UnixMilli())
+	case nil:
+		*t = 0
+	default:
+		return fmt.Errorf("unsupported ts value type %T", src)
+	}
+	return nil
+}
+
+func scanMessage(row rowScanner) (*entity.Message, error) {
+	var dto MessageDTO
+	var ts msTimestamp
+	var replyTo sql.NullString
+	err := row.Scan(
+		&dto.MsgID,
+		&dto.SessionID,
+		&dto.MsgType,
+		&dto.Src,
+		&dto.Dst,
+		&dto.Content,
+		&dto.ContentType,
+		&ts,
+		&dto.Status,
+		&dto.Ext,
+		&replyTo,
+		&dto.CreatedBy,
+		&dto.UpdatedBy,
+		&dto.CreatedAt,
+		&dto.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	dto.Ts = int64(ts)
+	dto.ReplyTo = replyTo.String
+	return toMessageEntity(dto), nil
+}
+
+func scanMessages(rows *sql.Rows) ([]*entity.Message, error) {
+	defer rows.Close()
+
+	var messages []*entity.Message
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
 // MessageRepository implementation
 func (r *SQLiteMessageRepository) Create(ctx context.Context, message *entity.Message) error {
+	defer observeQuery(ctx, "MessageRepository.Create")()
+	return insertMessage(ctx, conn(ctx, r.db), message)
+}
+
+func insertMessage(ctx context.Context, db dbtx, message *entity.Message) error {
 	query := `INSERT INTO t_chat_message 
-		(msg_id, session_id, msg_type, src, dst, content, content_type, ts, status, ext, created_by, updated_by)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		(msg_id, session_id, msg_type, src, dst, content, content_type, ts, status, ext, reply_to, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	dto := toMessageDTO(message)
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := db.ExecContext(ctx, query,
 		dto.MsgID,
 		dto.SessionID,
 		dto.MsgType,
@@ -222,103 +333,189 @@ func (r *SQLiteMessageRepository) Create(ctx context.Context, message *entity.Me
 		dto.Ts,
 		dto.Status,
 		dto.Ext,
+		nullString(dto.ReplyTo),
 		dto.CreatedBy,
 		dto.UpdatedBy,
 	)
-	return err
+	return translateError(err)
 }
 
 func (r *SQLiteMessageRepository) GetByID(ctx context.Context, msgID string) (*entity.Message, error) {
-	query := `SELECT 
-		msg_id, session_id, msg_type, src, dst, content, content_type, ts, status, ext, 
-		created_by, updated_by, created_at, updated_at
+	defer observeQuery(ctx, "MessageRepository.GetByID")()
+	query := `SELECT ` + messageColumns + `
 		FROM t_chat_message WHERE msg_id = ? AND is_deleted = 0`
 
-	row := r.db.QueryRowContext(ctx, query, msgID)
-	var dto MessageDTO
-	err := row.Scan(
-		&dto.MsgID,
-		&dto.SessionID,
-		&dto.MsgType,
-		&dto.Src,
-		&dto.Dst,
-		&dto.Content,
-		&dto.ContentType,
-		&dto.Ts,
-		&dto.Status,
-		&dto.Ext,
-		&dto.CreatedBy,
-		&dto.UpdatedBy,
-		&dto.CreatedAt,
-		&dto.UpdatedAt,
-	)
+	msg, err := scanMessage(conn(ctx, r.db).QueryRowContext(ctx, query, msgID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-	return toMessageEntity(dto), nil
+	return msg, nil
 }
 
 func (r *SQLiteMessageRepository) GetBySessionID(ctx context.Context, sessionID string) ([]*entity.Message, error) {
-	query := `SELECT 
-		msg_id, session_id, msg_type, src, dst, content, content_type, ts, status, ext, 
-		created_by, updated_by, created_at, updated_at
+	defer observeQuery(ctx, "MessageRepository.GetBySessionID")()
+	query := `SELECT ` + messageColumns + `
 		FROM t_chat_message WHERE session_id = ? AND is_deleted = 0
 		ORDER BY ts ASC`
 
-	rows, err := r.db.QueryContext(ctx, query, sessionID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, sessionID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanMessages(rows)
+}
 
-	var messages []*entity.Message
-	for rows.Next() {
-		var dto MessageDTO
-		err := rows.Scan(
-			&dto.MsgID,
-			&dto.SessionID,
-			&dto.MsgType,
-			&dto.Src,
-			&dto.Dst,
-			&dto.Content,
-			&dto.ContentType,
-			&dto.Ts,
-			&dto.Status,
-			&dto.Ext,
-			&dto.CreatedBy,
-			&dto.UpdatedBy,
-			&dto.CreatedAt,
-			&dto.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		messages = append(messages, toMessageEntity(dto))
+func (r *SQLiteMessageRepository) GetReplies(ctx context.Context, msgID string) ([]*entity.Message, error) {
+	defer observeQuery(ctx, "MessageRepository.GetReplies")()
+	query := `SELECT ` + messageColumns + `
+		FROM t_chat_message WHERE reply_to = ? AND is_deleted = 0
+		ORDER BY ts ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, msgID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+func (r *SQLiteMessageRepository) GetLatestExcludingSrc(ctx context.Context, sessionID, src string) (*entity.Message, error) {
+	defer observeQuery(ctx, "MessageRepository.GetLatestExcludingSrc")()
+	query := `SELECT ` + messageColumns + `
+		FROM t_chat_message WHERE session_id = ? AND src <> ? AND is_deleted = 0
+		ORDER BY ts DESC LIMIT 1`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, sessionID, src)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, repo.ErrNotFound
 	}
-	return messages, nil
+	return messages[0], nil
+}
+
+func (r *SQLiteMessageRepository) GetBySessionIDAfter(ctx context.Context, sessionID string, afterTs int64) ([]*entity.Message, error) {
+	defer observeQuery(ctx, "MessageRepository.GetBySessionIDAfter")()
+	query := `SELECT ` + messageColumns + `
+		FROM t_chat_message WHERE session_id = ? AND ts > ? AND is_deleted = 0
+		ORDER BY ts ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, sessionID, afterTs)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+func (r *SQLiteMessageRepository) GetOfflineByDst(ctx context.Context, dst string) ([]*entity.Message, error) {
+	defer observeQuery(ctx, "MessageRepository.GetOfflineByDst")()
+	query := `SELECT ` + messageColumns + `
+		FROM t_chat_message WHERE dst = ? AND status = ? AND is_deleted = 0
+		ORDER BY ts ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, dst, entity.StatusOffline)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
 }
 
 func (r *SQLiteMessageRepository) UpdateStatus(ctx context.Context, msgID string, status uint8) error {
+	defer observeQuery(ctx, "MessageRepository.UpdateStatus")()
 	query := `UPDATE t_chat_message 
 		SET status = ?, updated_at = CURRENT_TIMESTAMP 
 		WHERE msg_id = ?`
 
-	_, err := r.db.ExecContext(ctx, query, status, msgID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, status, msgID)
+	return err
+}
+
+// translateError 将 SQLite 约束冲突转换为领域层错误
+func translateError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique) {
+		return repo.ErrDuplicateKey
+	}
 	return err
 }
 
+// nullString 空字符串写入为 NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// sessionColumns 会话查询的公共列
+const sessionColumns = `session_id, cid, agent_id, channel, start_time, end_time, status,
+		created_by, updated_by, created_at, updated_at`
+
+func scanSession(row rowScanner) (*entity.Session, error) {
+	var dto SessionDTO
+	var agentID sql.NullString
+	err := row.Scan(
+		&dto.ID,
+		&dto.CID,
+		&agentID,
+		&dto.Channel,
+		&dto.StartTime,
+		&dto.EndTime,
+		&dto.Status,
+		&dto.CreatedBy,
+		&dto.UpdatedBy,
+		&dto.CreatedAt,
+		&dto.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	dto.AgentID = agentID.String
+	return toSessionEntity(dto), nil
+}
+
+func scanSessions(rows *sql.Rows) ([]*entity.Session, error) {
+	defer rows.Close()
+
+	var sessions []*entity.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
 func (r *SQLiteSessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	defer observeQuery(ctx, "SessionRepository.Create")()
+	return insertSession(ctx, conn(ctx, r.db), session)
+}
+
+func insertSession(ctx context.Context, db dbtx, session *entity.Session) error {
 	query := `INSERT INTO t_session 
-		(session_id, cid, start_time, end_time, status, created_by, updated_by)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`
+		(session_id, cid, agent_id, channel, start_time, end_time, status, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	dto := toSessionDTO(session)
-	_, err := r.db.ExecContext(ctx, query,
+	if dto.Channel == "" {
+		dto.Channel = entity.ChannelWeb
+	}
+	if dto.StartTime.IsZero() {
+		dto.StartTime = time.Now()
+	}
+	_, err := db.ExecContext(ctx, query,
 		dto.ID,
 		dto.CID,
+		nullString(dto.AgentID),
+		dto.Channel,
 		dto.StartTime,
 		dto.EndTime,
 		dto.Status,
@@ -329,131 +526,291 @@ func (r *SQLiteSessionRepository) Create(ctx context.Context, session *entity.Se
 }
 
 func (r *SQLiteSessionRepository) GetByID(ctx context.Context, id string) (*entity.Session, error) {
-	query := `SELECT 
-		session_id, cid, start_time, end_time, status, 
-		created_by, updated_by, created_at, updated_at
+	defer observeQuery(ctx, "SessionRepository.GetByID")()
+	query := `SELECT ` + sessionColumns + `
 		FROM t_session WHERE session_id = ? AND is_deleted = 0`
 
-	row := r.db.QueryRowContext(ctx, query, id)
-	var dto SessionDTO
-	err := row.Scan(
-		&dto.ID,
-		&dto.CID,
-		&dto.StartTime,
-		&dto.EndTime,
-		&dto.Status,
-		&dto.CreatedBy,
-		&dto.UpdatedBy,
-		&dto.CreatedAt,
-		&dto.UpdatedAt,
-	)
+	session, err := scanSession(conn(ctx, r.db).QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-	return toSessionEntity(dto), nil
+	return session, nil
 }
 
 func (r *SQLiteSessionRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+	defer observeQuery(ctx, "SessionRepository.UpdateStatus")()
 	query := `UPDATE t_session 
 		SET status = ?, updated_at = CURRENT_TIMESTAMP 
 		WHERE session_id = ?`
 
-	_, err := r.db.ExecContext(ctx, query, status, id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, status, id)
+	return err
+}
+
+func (r *SQLiteSessionRepository) Close(ctx context.Context, id string, endTime time.Time) error {
+	defer observeQuery(ctx, "SessionRepository.Close")()
+	return closeSession(ctx, conn(ctx, r.db), id, endTime)
+}
+
+func closeSession(ctx context.Context, db dbtx, id string, endTime time.Time) error {
+	query := `UPDATE t_session 
+		SET status = ?, end_time = ?, updated_at = CURRENT_TIMESTAMP 
+		WHERE session_id = ?`
+
+	_, err := db.ExecContext(ctx, query, entity.SessionStatusClosed, endTime, id)
 	return err
 }
 
+func (r *SQLiteSessionRepository) UpdateAgent(ctx context.Context, id string, agentID string) error {
+	defer observeQuery(ctx, "SessionRepository.UpdateAgent")()
+	return updateSessionAgent(ctx, conn(ctx, r.db), id, agentID)
+}
+
+func updateSessionAgent(ctx context.Context, db dbtx, id string, agentID string) error {
+	query := `UPDATE t_session 
+		SET agent_id = ?, updated_at = CURRENT_TIMESTAMP 
+		WHERE session_id = ? AND is_deleted = 0`
+
+	res, err := db.ExecContext(ctx, query, agentID, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
 func (r *SQLiteSessionRepository) ListActive(ctx context.Context) ([]*entity.Session, error) {
-	query := `SELECT 
-		session_id, cid, start_time, end_time, status, 
-		created_by, updated_by, created_at, updated_at
+	defer observeQuery(ctx, "SessionRepository.ListActive")()
+	query := `SELECT ` + sessionColumns + `
 		FROM t_session WHERE status = 'active' AND is_deleted = 0`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanSessions(rows)
+}
 
-	var sessions []*entity.Session
-	for rows.Next() {
-		var dto SessionDTO
-		err := rows.Scan(
-			&dto.ID,
-			&dto.CID,
-			&dto.StartTime,
-			&dto.EndTime,
-			&dto.Status,
-			&dto.CreatedBy,
-			&dto.UpdatedBy,
-			&dto.CreatedAt,
-			&dto.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		sessions = append(sessions, toSessionEntity(dto))
+func (r *SQLiteSessionRepository) ListByCID(ctx context.Context, cid string) ([]*entity.Session, error) {
+	defer observeQuery(ctx, "SessionRepository.ListByCID")()
+	query := `SELECT ` + sessionColumns + `
+		FROM t_session WHERE cid = ? AND is_deleted = 0
+		ORDER BY start_time DESC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, cid)
+	if err != nil {
+		return nil, err
 	}
-	return sessions, nil
+	return scanSessions(rows)
 }
 
 func (r *SQLiteUserRepository) Create(ctx context.Context, user *entity.User) error {
+	defer observeQuery(ctx, "UserRepository.Create")()
 	query := `INSERT INTO t_user 
-		(cid, uid, query, created_by, updated_by)
-		VALUES (?, ?, ?, ?, ?)`
+		(cid, uid, username, display_name, email, external_id, query, role, status,
+		 skills, max_concurrent_chats, password_hash, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	dto := toUserDTO(user)
-	_, err := r.db.ExecContext(ctx, query,
+	if dto.Role == "" {
+		dto.Role = entity.RoleCustomer
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		dto.ID,
 		dto.UID,
+		nullString(dto.Username),
+		nullString(dto.DisplayName),
+		nullString(dto.Email),
+		nullString(dto.ExternalID),
 		dto.Query,
+		dto.Role,
+		nullString(dto.Status),
+		dto.Skills,
+		dto.MaxConcurrentChats,
+		nullString(dto.PasswordHash),
 		dto.CreatedBy,
 		dto.UpdatedBy,
 	)
-	return err
+	return translateError(err)
 }
 
-func (r *SQLiteUserRepository) GetByID(ctx context.Context, id string) (*entity.User, error) {
-	query := `SELECT 
-		cid, uid, query, 
-		created_by, updated_by, created_at, updated_at
-		FROM t_user WHERE cid = ? AND is_deleted = 0`
+const userColumns = `cid, uid, username, display_name, email, external_id, query, role, status,
+		skills, max_concurrent_chats, password_hash,
+		created_by, updated_by, created_at, updated_at`
 
-	row := r.db.QueryRowContext(ctx, query, id)
+func scanUser(row rowScanner) (*entity.User, error) {
 	var dto UserDTO
+	var username, displayName, email, externalID, query, status, passwordHash sql.NullString
 	err := row.Scan(
 		&dto.ID,
 		&dto.UID,
-		&dto.Query,
+		&username,
+		&displayName,
+		&email,
+		&externalID,
+		&query,
+		&dto.Role,
+		&status,
+		&dto.Skills,
+		&dto.MaxConcurrentChats,
+		&passwordHash,
 		&dto.CreatedBy,
 		&dto.UpdatedBy,
 		&dto.CreatedAt,
 		&dto.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	dto.Username = username.String
+	dto.DisplayName = displayName.String
+	dto.Email = email.String
+	dto.ExternalID = externalID.String
+	dto.Query = query.String
+	dto.Status = status.String
+	dto.PasswordHash = passwordHash.String
+	return toUserEntity(dto), nil
+}
+
+func (r *SQLiteUserRepository) GetByID(ctx context.Context, id string) (*entity.User, error) {
+	defer observeQuery(ctx, "UserRepository.GetByID")()
+	query := `SELECT ` + userColumns + `
+		FROM t_user WHERE cid = ? AND is_deleted = 0`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-	return toUserEntity(dto), nil
+	return user, nil
 }
 
 func (r *SQLiteUserRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+	defer observeQuery(ctx, "UserRepository.UpdateStatus")()
 	query := `UPDATE t_user 
 		SET status = ?, updated_at = CURRENT_TIMESTAMP 
 		WHERE cid = ?`
 
-	_, err := r.db.ExecContext(ctx, query, status, id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, status, id)
 	return err
 }
 
 func (r *SQLiteUserRepository) ListAgents(ctx context.Context) ([]*entity.User, error) {
-	// Note: This implementation assumes agents are identified by a role field
-	// which isn't in the current schema. Would need schema modification.
-	return nil, errors.New("not implemented - requires schema changes")
+	defer observeQuery(ctx, "UserRepository.ListAgents")()
+	query := `SELECT ` + userColumns + `
+		FROM t_user WHERE role = ? AND is_deleted = 0
+		ORDER BY created_at ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, entity.RoleAgent)
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
+}
+
+func (r *SQLiteUserRepository) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
+	defer observeQuery(ctx, "UserRepository.GetByUsername")()
+	query := `SELECT ` + userColumns + `
+		FROM t_user WHERE username = ? AND role <> ? AND is_deleted = 0`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, username, entity.RoleCustomer))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *SQLiteUserRepository) Update(ctx context.Context, user *entity.User) error {
+	defer observeQuery(ctx, "UserRepository.Update")()
+	query := `UPDATE t_user
+		SET username = ?, display_name = ?, email = ?, external_id = ?, role = ?, skills = ?,
+		    max_concurrent_chats = ?, password_hash = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE cid = ? AND is_deleted = 0`
+
+	dto := toUserDTO(user)
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		nullString(dto.Username),
+		nullString(dto.DisplayName),
+		nullString(dto.Email),
+		nullString(dto.ExternalID),
+		dto.Role,
+		dto.Skills,
+		dto.MaxConcurrentChats,
+		nullString(dto.PasswordHash),
+		dto.UpdatedBy,
+		dto.ID,
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteUserRepository) Delete(ctx context.Context, id, deletedBy string) error {
+	defer observeQuery(ctx, "UserRepository.Delete")()
+	query := `UPDATE t_user
+		SET is_deleted = 1, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE cid = ? AND is_deleted = 0`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, deletedBy, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteUserRepository) List(ctx context.Context, filter entity.UserFilter) ([]*entity.User, error) {
+	defer observeQuery(ctx, "UserRepository.List")()
+	query := `SELECT ` + userColumns + `
+		FROM t_user WHERE role <> ? AND is_deleted = 0`
+	args := []interface{}{entity.RoleCustomer}
+	if filter.Role != "" {
+		query += ` AND role = ?`
+		args = append(args, filter.Role)
+	}
+	query += ` ORDER BY created_at ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
+}
+
+func (r *SQLiteUserRepository) GetByExternalID(ctx context.Context, externalID string) (*entity.User, error) {
+	defer observeQuery(ctx, "UserRepository.GetByExternalID")()
+	query := `SELECT ` + userColumns + `
+		FROM t_user WHERE external_id = ? AND is_deleted = 0`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, externalID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func scanUsers(rows *sql.Rows) ([]*entity.User, error) {
+	defer rows.Close()
+
+	var users []*entity.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
 }
 
 // Remove duplicate ErrNotFound since it's already defined in memory_repository.go
@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// defaultRetentionRunLimit 未指定条数时最多返回的任务记录数
+const defaultRetentionRunLimit = 20
+
+// SQLiteRetentionRepository 基于 t_session_retention 与 t_retention_run 表实现 RetentionRepository，
+// 消息清理直接作用于 t_chat_message
+type SQLiteRetentionRepository struct {
+	db *sql.DB
+}
+
+var _ repo.RetentionRepository = (*SQLiteRetentionRepository)(nil)
+
+// NewSQLiteRetentionRepository 复用基础仓储的数据库连接
+func NewSQLiteRetentionRepository(base *SQLiteRepository) *SQLiteRetentionRepository {
+	return &SQLiteRetentionRepository{db: base.db}
+}
+
+func (r *SQLiteRetentionRepository) ListDueSessions(ctx context.Context, query entity.RetentionQuery) ([]*entity.Session, error) {
+	defer observeQuery(ctx, "RetentionRepository.ListDueSessions")()
+	// end_time 与 restored_at 由驱动按带时区的格式写入，用 julianday 换算后比较
+	q := `SELECT ` + sessionColumns + `
+		FROM t_session
+		WHERE status = ? AND is_deleted = 0
+		AND julianday(end_time) < julianday(?)
+		AND NOT EXISTS (
+			SELECT 1 FROM t_session_retention r
+			WHERE r.session_id = t_session.session_id AND r.purged_at IS NOT NULL
+			AND (r.restored_at IS NULL OR julianday(r.restored_at) >= julianday(?))
+		)`
+	args := []interface{}{
+		entity.SessionStatusClosed,
+		query.ClosedBefore.UTC().Format(sqliteTimeLayout),
+		query.RestoredBefore.UTC().Format(sqliteTimeLayout),
+	}
+
+	if len(query.Channels) > 0 {
+		q += ` AND channel IN (` + placeholders(len(query.Channels)) + `)`
+		for _, c := range query.Channels {
+			args = append(args, c)
+		}
+	}
+	if len(query.ExcludeChannels) > 0 {
+		q += ` AND channel NOT IN (` + placeholders(len(query.ExcludeChannels)) + `)`
+		for _, c := range query.ExcludeChannels {
+			args = append(args, c)
+		}
+	}
+	if query.AfterSessionID != "" {
+		q += ` AND session_id > ?`
+		args = append(args, query.AfterSessionID)
+	}
+	q += ` ORDER BY session_id ASC LIMIT ?`
+	args = append(args, query.Limit)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanSessions(rows)
+}
+
+// placeholders 生成 n 个以逗号分隔的占位符
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func (r *SQLiteRetentionRepository) GetSessionRetention(ctx context.Context, sessionID string) (*entity.SessionRetention, error) {
+	defer observeQuery(ctx, "RetentionRepository.GetSessionRetention")()
+	query := `SELECT session_id, channel, action, archive_key, message_count,
+		archived_at, purged_at, restored_at, updated_at
+		FROM t_session_retention WHERE session_id = ?`
+
+	var rec entity.SessionRetention
+	var archiveKey sql.NullString
+	var archivedAt, purgedAt, restoredAt sql.NullTime
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, sessionID).Scan(
+		&rec.SessionID,
+		&rec.Channel,
+		&rec.Action,
+		&archiveKey,
+		&rec.MessageCount,
+		&archivedAt,
+		&purgedAt,
+		&restoredAt,
+		&rec.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	rec.ArchiveKey = archiveKey.String
+	rec.ArchivedAt = archivedAt.Time
+	rec.PurgedAt = purgedAt.Time
+	rec.RestoredAt = restoredAt.Time
+	return &rec, nil
+}
+
+func (r *SQLiteRetentionRepository) SaveSessionRetention(ctx context.Context, record *entity.SessionRetention) error {
+	defer observeQuery(ctx, "RetentionRepository.SaveSessionRetention")()
+	query := `INSERT INTO t_session_retention
+		(session_id, channel, action, archive_key, message_count, archived_at, purged_at, restored_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET
+			channel = excluded.channel,
+			action = excluded.action,
+			archive_key = excluded.archive_key,
+			message_count = excluded.message_count,
+			archived_at = excluded.archived_at,
+			purged_at = excluded.purged_at,
+			restored_at = excluded.restored_at,
+			updated_at = excluded.updated_at`
+
+	record.UpdatedAt = time.Now()
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		record.SessionID,
+		record.Channel,
+		record.Action,
+		nullString(record.ArchiveKey),
+		record.MessageCount,
+		nullTime(record.ArchivedAt),
+		nullTime(record.PurgedAt),
+		nullTime(record.RestoredAt),
+		record.UpdatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return err
+}
+
+func (r *SQLiteRetentionRepository) DeleteMessages(ctx context.Context, sessionID string, limit int) (int64, error) {
+	defer observeQuery(ctx, "RetentionRepository.DeleteMessages")()
+	var deleted int64
+	err := withTx(ctx, r.db, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		batch := `SELECT msg_id FROM t_chat_message WHERE session_id = ? ORDER BY msg_id LIMIT ?`
+
+		// 未开启外键约束，表情回应需要显式删除
+		if _, err := db.ExecContext(ctx, `DELETE FROM message_reactions
+			WHERE msg_id IN (`+batch+`)`, sessionID, limit); err != nil {
+			return err
+		}
+		res, err := db.ExecContext(ctx, `DELETE FROM t_chat_message
+			WHERE msg_id IN (`+batch+`)`, sessionID, limit)
+		if err != nil {
+			return err
+		}
+		deleted, err = res.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
+
+func (r *SQLiteRetentionRepository) AnonymizeMessages(ctx context.Context, sessionID string, limit int) (int64, error) {
+	defer observeQuery(ctx, "RetentionRepository.AnonymizeMessages")()
+	query := `UPDATE t_chat_message
+		SET content = ?, ext = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE msg_id IN (
+			SELECT msg_id FROM t_chat_message
+			WHERE session_id = ? AND (content != ? OR ext IS NOT NULL)
+			LIMIT ?
+		)`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		entity.AnonymizedContent, sessionID, entity.AnonymizedContent, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *SQLiteRetentionRepository) RestoreMessages(ctx context.Context, messages []*entity.Message) error {
+	defer observeQuery(ctx, "RetentionRepository.RestoreMessages")()
+	query := `INSERT INTO t_chat_message
+		(msg_id, session_id, msg_type, src, dst, content, content_type, ts, status, ext, reply_to, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(msg_id) DO UPDATE SET
+			content = excluded.content,
+			ext = excluded.ext,
+			is_deleted = 0,
+			updated_at = CURRENT_TIMESTAMP`
+
+	return withTx(ctx, r.db, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+		for _, message := range messages {
+			dto := toMessageDTO(message)
+			if _, err := db.ExecContext(ctx, query,
+				dto.MsgID,
+				dto.SessionID,
+				dto.MsgType,
+				dto.Src,
+				dto.Dst,
+				dto.Content,
+				dto.ContentType,
+				dto.Ts,
+				dto.Status,
+				dto.Ext,
+				nullString(dto.ReplyTo),
+				dto.CreatedBy,
+				dto.UpdatedBy,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *SQLiteRetentionRepository) CreateRun(ctx context.Context, run *entity.RetentionRun) error {
+	defer observeQuery(ctx, "RetentionRepository.CreateRun")()
+	query := `INSERT INTO t_retention_run (run_id, status, started_at) VALUES (?, ?, ?)`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		run.ID,
+		run.Status,
+		run.StartedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return translateError(err)
+}
+
+func (r *SQLiteRetentionRepository) UpdateRun(ctx context.Context, run *entity.RetentionRun) error {
+	defer observeQuery(ctx, "RetentionRepository.UpdateRun")()
+	query := `UPDATE t_retention_run
+		SET status = ?, finished_at = ?, sessions_scanned = ?, sessions_archived = ?, sessions_purged = ?,
+			sessions_failed = ?, messages_deleted = ?, messages_anonymized = ?, last_error = ?
+		WHERE run_id = ?`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		run.Status,
+		nullTime(run.FinishedAt),
+		run.SessionsScanned,
+		run.SessionsArchived,
+		run.SessionsPurged,
+		run.SessionsFailed,
+		run.MessagesDeleted,
+		run.MessagesAnonymized,
+		nullString(run.LastError),
+		run.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteRetentionRepository) ListRuns(ctx context.Context, limit int) ([]*entity.RetentionRun, error) {
+	defer observeQuery(ctx, "RetentionRepository.ListRuns")()
+	if limit <= 0 {
+		limit = defaultRetentionRunLimit
+	}
+	query := `SELECT run_id, status, started_at, finished_at, sessions_scanned, sessions_archived,
+		sessions_purged, sessions_failed, messages_deleted, messages_anonymized, last_error
+		FROM t_retention_run
+		ORDER BY started_at DESC, run_id DESC LIMIT ?`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*entity.RetentionRun
+	for rows.Next() {
+		var run entity.RetentionRun
+		var finishedAt sql.NullTime
+		var lastError sql.NullString
+		if err := rows.Scan(
+			&run.ID,
+			&run.Status,
+			&run.StartedAt,
+			&finishedAt,
+			&run.SessionsScanned,
+			&run.SessionsArchived,
+			&run.SessionsPurged,
+			&run.SessionsFailed,
+			&run.MessagesDeleted,
+			&run.MessagesAnonymized,
+			&lastError,
+		); err != nil {
+			return nil, err
+		}
+		run.FinishedAt = finishedAt.Time
+		run.LastError = lastError.String
+		runs = append(runs, &run)
+	}
+	return runs, rows.Err()
+}
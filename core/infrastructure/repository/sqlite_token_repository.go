@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// SQLiteTokenRepository 基于 t_refresh_token 与 t_revoked_token 表实现 TokenRepository
+type SQLiteTokenRepository struct {
+	db *sql.DB
+}
+
+var _ repo.TokenRepository = (*SQLiteTokenRepository)(nil)
+
+// NewSQLiteTokenRepository 复用基础仓储的数据库连接
+func NewSQLiteTokenRepository(base *SQLiteRepository) *SQLiteTokenRepository {
+	return &SQLiteTokenRepository{db: base.db}
+}
+
+func (r *SQLiteTokenRepository) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	defer observeQuery(ctx, "TokenRepository.CreateRefreshToken")()
+	query := `INSERT INTO t_refresh_token
+		(token_hash, user_id, family_id, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`
+
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		token.TokenHash,
+		token.UserID,
+		token.FamilyID,
+		token.ExpiresAt.UTC().Format(sqliteTimeLayout),
+		token.CreatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return translateError(err)
+}
+
+func (r *SQLiteTokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	defer observeQuery(ctx, "TokenRepository.GetRefreshToken")()
+	query := `SELECT token_hash, user_id, family_id, expires_at, revoked_at, replaced_by, created_at
+		FROM t_refresh_token WHERE token_hash = ?`
+
+	var token entity.RefreshToken
+	var revokedAt sql.NullTime
+	var replacedBy sql.NullString
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.TokenHash,
+		&token.UserID,
+		&token.FamilyID,
+		&token.ExpiresAt,
+		&revokedAt,
+		&replacedBy,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	token.RevokedAt = revokedAt.Time
+	token.ReplacedBy = replacedBy.String
+	return &token, nil
+}
+
+func (r *SQLiteTokenRepository) RevokeRefreshToken(ctx context.Context, tokenHash, replacedBy string) error {
+	defer observeQuery(ctx, "TokenRepository.RevokeRefreshToken")()
+	query := `UPDATE t_refresh_token
+		SET revoked_at = CURRENT_TIMESTAMP, replaced_by = ?
+		WHERE token_hash = ? AND revoked_at IS NULL`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, nullString(replacedBy), tokenHash)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteTokenRepository) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	defer observeQuery(ctx, "TokenRepository.RevokeRefreshFamily")()
+	query := `UPDATE t_refresh_token
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = ? AND revoked_at IS NULL`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (r *SQLiteTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	defer observeQuery(ctx, "TokenRepository.RevokeUserRefreshTokens")()
+	query := `UPDATE t_refresh_token
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND revoked_at IS NULL`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID)
+	return err
+}
+
+func (r *SQLiteTokenRepository) RevokeAccessToken(ctx context.Context, token *entity.RevokedToken) error {
+	defer observeQuery(ctx, "TokenRepository.RevokeAccessToken")()
+	query := `INSERT OR IGNORE INTO t_revoked_token (jti, user_id, expires_at, revoked_at)
+		VALUES (?, ?, ?, ?)`
+
+	if token.RevokedAt.IsZero() {
+		token.RevokedAt = time.Now()
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		token.JTI,
+		token.UserID,
+		token.ExpiresAt.UTC().Format(sqliteTimeLayout),
+		token.RevokedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return err
+}
+
+func (r *SQLiteTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	defer observeQuery(ctx, "TokenRepository.IsAccessTokenRevoked")()
+	query := `SELECT 1 FROM t_revoked_token WHERE jti = ?`
+
+	var one int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, jti).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *SQLiteTokenRepository) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer observeQuery(ctx, "TokenRepository.PurgeExpired")()
+	cutoff := before.UTC().Format(sqliteTimeLayout)
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM t_refresh_token WHERE expires_at < ?`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	refresh, _ := res.RowsAffected()
+
+	res, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM t_revoked_token WHERE expires_at < ?`, cutoff)
+	if err != nil {
+		return refresh, err
+	}
+	revoked, _ := res.RowsAffected()
+	return refresh + revoked, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cland.org/cland-chat-service/common/tracing"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+	"cland.org/cland-chat-service/core/infrastructure/metrics"
+)
+
+// dbtx 由 *sql.DB 与 *sql.Tx 共同实现，语句可在事务内外复用
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txKey ctx 中进行中事务的键
+type txKey struct{}
+
+// conn 返回 ctx 中进行中的事务，不在事务中时返回 db
+func conn(ctx context.Context, db *sql.DB) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// withTx 在事务内执行 fn；ctx 已处于事务中时加入该事务，由最外层负责提交或回滚
+func withTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SQLiteTxManager 实现 TxManager，事务通过 ctx 传递给各 SQLite 仓储
+type SQLiteTxManager struct {
+	db *sql.DB
+}
+
+var _ repo.TxManager = (*SQLiteTxManager)(nil)
+
+// NewSQLiteTxManager 复用基础仓储的数据库连接
+func NewSQLiteTxManager(base *SQLiteRepository) *SQLiteTxManager {
+	return &SQLiteTxManager{db: base.db}
+}
+
+func (m *SQLiteTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTx(ctx, m.db, fn)
+}
+
+// observeQuery 为仓储方法生成追踪 span 并记录耗时，在方法开头 defer observeQuery(ctx, method)() 调用
+func observeQuery(ctx context.Context, method string) func() {
+	start := time.Now()
+	_, span := tracing.Start(ctx, method)
+	span.SetAttribute("db.system", "sqlite")
+	return func() {
+		span.End()
+		metrics.ObserveSince(metrics.RepositoryQueryDuration, start, method)
+	}
+}
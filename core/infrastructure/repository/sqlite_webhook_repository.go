@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	repo "cland.org/cland-chat-service/core/domain/repository"
+)
+
+// defaultDeliveryLimit 未指定条数时最多返回的投递日志数
+const defaultDeliveryLimit = 100
+
+// SQLiteWebhookRepository 基于 t_webhook_subscription 与 t_webhook_delivery 表实现 WebhookRepository
+type SQLiteWebhookRepository struct {
+	db *sql.DB
+}
+
+var _ repo.WebhookRepository = (*SQLiteWebhookRepository)(nil)
+
+// NewSQLiteWebhookRepository 复用基础仓储的数据库连接
+func NewSQLiteWebhookRepository(base *SQLiteRepository) *SQLiteWebhookRepository {
+	return &SQLiteWebhookRepository{db: base.db}
+}
+
+func (r *SQLiteWebhookRepository) CreateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error {
+	defer observeQuery(ctx, "WebhookRepository.CreateSubscription")()
+	query := `INSERT INTO t_webhook_subscription
+		(id, url, events, secret, description, active, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	events, err := json.Marshal(sub.Events)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, query,
+		sub.ID,
+		sub.URL,
+		string(events),
+		sub.Secret,
+		nullString(sub.Description),
+		sub.Active,
+		sub.CreatedBy,
+		sub.UpdatedBy,
+	)
+	return translateError(err)
+}
+
+const subscriptionColumns = `id, url, events, secret, description, active,
+		created_by, updated_by, created_at, updated_at`
+
+func scanSubscription(row rowScanner) (*entity.WebhookSubscription, error) {
+	var sub entity.WebhookSubscription
+	var events string
+	var description sql.NullString
+	if err := row.Scan(
+		&sub.ID,
+		&sub.URL,
+		&events,
+		&sub.Secret,
+		&description,
+		&sub.Active,
+		&sub.CreatedBy,
+		&sub.UpdatedBy,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	sub.Description = description.String
+	if err := json.Unmarshal([]byte(events), &sub.Events); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *SQLiteWebhookRepository) GetSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	defer observeQuery(ctx, "WebhookRepository.GetSubscription")()
+	query := `SELECT ` + subscriptionColumns + `
+		FROM t_webhook_subscription WHERE id = ? AND is_deleted = 0`
+
+	sub, err := scanSubscription(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (r *SQLiteWebhookRepository) UpdateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error {
+	defer observeQuery(ctx, "WebhookRepository.UpdateSubscription")()
+	query := `UPDATE t_webhook_subscription
+		SET url = ?, events = ?, description = ?, active = ?, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND is_deleted = 0`
+
+	events, err := json.Marshal(sub.Events)
+	if err != nil {
+		return err
+	}
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		sub.URL,
+		string(events),
+		nullString(sub.Description),
+		sub.Active,
+		sub.UpdatedBy,
+		sub.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteWebhookRepository) DeleteSubscription(ctx context.Context, id, deletedBy string) error {
+	defer observeQuery(ctx, "WebhookRepository.DeleteSubscription")()
+	query := `UPDATE t_webhook_subscription
+		SET is_deleted = 1, active = 0, updated_by = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND is_deleted = 0`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, deletedBy, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteWebhookRepository) ListSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	defer observeQuery(ctx, "WebhookRepository.ListSubscriptions")()
+	query := `SELECT ` + subscriptionColumns + `
+		FROM t_webhook_subscription WHERE is_deleted = 0
+		ORDER BY created_at ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*entity.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (r *SQLiteWebhookRepository) CreateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	defer observeQuery(ctx, "WebhookRepository.CreateDelivery")()
+	query := `INSERT INTO t_webhook_delivery
+		(id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	now := time.Now()
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = now
+	}
+	delivery.UpdatedAt = now
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		delivery.ID,
+		delivery.SubscriptionID,
+		delivery.EventID,
+		delivery.EventType,
+		string(delivery.Payload),
+		delivery.Status,
+		delivery.Attempts,
+		nullTime(delivery.NextAttemptAt),
+		delivery.CreatedAt.UTC().Format(sqliteTimeLayout),
+		delivery.UpdatedAt.UTC().Format(sqliteTimeLayout),
+	)
+	return translateError(err)
+}
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+		next_attempt_at, last_error, response_status, delivered_at, created_at, updated_at`
+
+func scanDelivery(row rowScanner) (*entity.WebhookDelivery, error) {
+	var d entity.WebhookDelivery
+	var payload string
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var lastError sql.NullString
+	var responseStatus sql.NullInt64
+	if err := row.Scan(
+		&d.ID,
+		&d.SubscriptionID,
+		&d.EventID,
+		&d.EventType,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&nextAttemptAt,
+		&lastError,
+		&responseStatus,
+		&deliveredAt,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	d.Payload = json.RawMessage(payload)
+	d.NextAttemptAt = nextAttemptAt.Time
+	d.LastError = lastError.String
+	d.ResponseStatus = int(responseStatus.Int64)
+	d.DeliveredAt = deliveredAt.Time
+	return &d, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]*entity.WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []*entity.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *SQLiteWebhookRepository) GetDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	defer observeQuery(ctx, "WebhookRepository.GetDelivery")()
+	query := `SELECT ` + deliveryColumns + ` FROM t_webhook_delivery WHERE id = ?`
+
+	d, err := scanDelivery(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *SQLiteWebhookRepository) UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	defer observeQuery(ctx, "WebhookRepository.UpdateDelivery")()
+	query := `UPDATE t_webhook_delivery
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, response_status = ?,
+		    delivered_at = ?, updated_at = ?
+		WHERE id = ?`
+
+	delivery.UpdatedAt = time.Now()
+	var responseStatus sql.NullInt64
+	if delivery.ResponseStatus != 0 {
+		responseStatus = sql.NullInt64{Int64: int64(delivery.ResponseStatus), Valid: true}
+	}
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		delivery.Status,
+		delivery.Attempts,
+		nullTime(delivery.NextAttemptAt),
+		nullString(delivery.LastError),
+		responseStatus,
+		nullTime(delivery.DeliveredAt),
+		delivery.UpdatedAt.UTC().Format(sqliteTimeLayout),
+		delivery.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *SQLiteWebhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	defer observeQuery(ctx, "WebhookRepository.ListDueDeliveries")()
+	query := `SELECT ` + deliveryColumns + `
+		FROM t_webhook_delivery
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at ASC LIMIT ?`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query,
+		entity.WebhookDeliveryPending, now.UTC().Format(sqliteTimeLayout), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+func (r *SQLiteWebhookRepository) ListDeliveries(ctx context.Context, q entity.WebhookDeliveryQuery) ([]*entity.WebhookDelivery, error) {
+	defer observeQuery(ctx, "WebhookRepository.ListDeliveries")()
+	query := `SELECT ` + deliveryColumns + ` FROM t_webhook_delivery WHERE 1 = 1`
+	var args []interface{}
+	if q.SubscriptionID != "" {
+		query += ` AND subscription_id = ?`
+		args = append(args, q.SubscriptionID)
+	}
+	if q.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, q.Status)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+// nullTime 零值时间写入 NULL，其余按 UTC 格式化以便与 CURRENT_TIMESTAMP 比较
+func nullTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(sqliteTimeLayout), Valid: true}
+}
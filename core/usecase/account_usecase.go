@@ -0,0 +1,284 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+)
+
+const (
+	minPasswordLen       = 8
+	maxPasswordLen       = 72 // bcrypt 只使用前 72 字节
+	maxDisplayNameLen    = 100
+	maxSkills            = 20
+	maxSkillLen          = 50
+	maxConcurrentChatCap = 100
+)
+
+var (
+	// ErrInvalidAccount 账号字段不合法
+	ErrInvalidAccount = errors.New("invalid account")
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrUsernameTaken 登录名已被其他员工账号使用
+	ErrUsernameTaken = errors.New("username already taken")
+	// ErrAccountNotFound 员工账号不存在
+	ErrAccountNotFound = errors.New("account not found")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{3,50}$`)
+
+// dummyPasswordHash 用户不存在时仍执行一次哈希比较，避免通过响应耗时探测用户名
+var dummyPasswordHash, _ = utils.HashPassword("cland-dummy-password")
+
+// LoginResult 登录结果
+type LoginResult struct {
+	TokenPair
+	User *entity.User `json:"user"`
+}
+
+// AccountUseCase 客服、主管与管理员账号管理用例；访客由 InitUser 创建，不在此管理
+type AccountUseCase struct {
+	userRepo repository.UserRepository
+	authUC   *AuthUseCase
+}
+
+// NewAccountUseCase 创建账号管理用例
+func NewAccountUseCase(userRepo repository.UserRepository, authUC *AuthUseCase) *AccountUseCase {
+	return &AccountUseCase{userRepo: userRepo, authUC: authUC}
+}
+
+// Login 校验用户名与密码，签发携带角色的 JWT
+func (uc *AccountUseCase) Login(ctx context.Context, username, password string) (*LoginResult, error) {
+	user, err := uc.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.CheckPassword(dummyPasswordHash, password)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if user.PasswordHash == "" || !utils.CheckPassword(user.PasswordHash, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	tokens, err := uc.authUC.IssueTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{TokenPair: *tokens, User: user}, nil
+}
+
+// Create 新建员工账号，仅管理员可用
+func (uc *AccountUseCase) Create(ctx context.Context, user *entity.User, password string) error {
+	principal, err := requireRole(ctx, entity.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	normalizeAccount(user)
+	if err := validateAccount(user); err != nil {
+		return err
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.ID = utils.GenerateAccountID()
+	user.UID = user.ID
+	user.PasswordHash = hash
+	user.Status = "offline"
+	user.CreatedBy = principal.UserID
+	user.UpdatedBy = principal.UserID
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return ErrUsernameTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// Get 获取员工账号，员工可查看自己的账号，管理员可查看全部
+func (uc *AccountUseCase) Get(ctx context.Context, id string) (*entity.User, error) {
+	if err := authorizeSelf(ctx, id); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if !entity.IsStaffRole(user.Role) {
+		return nil, ErrAccountNotFound
+	}
+	return user, nil
+}
+
+// Update 修改员工账号资料与角色，password 非空时同时重置密码；仅管理员可用
+func (uc *AccountUseCase) Update(ctx context.Context, update *entity.User, password string) (*entity.User, error) {
+	principal, err := requireRole(ctx, entity.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.Get(ctx, update.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 角色变更或重置密码后，已有登录需重新认证
+	revoke := password != "" || user.Role != update.Role
+	user.Username = update.Username
+	user.DisplayName = update.DisplayName
+	user.Email = update.Email
+	user.Role = update.Role
+	user.Skills = update.Skills
+	user.MaxConcurrentChats = update.MaxConcurrentChats
+	normalizeAccount(user)
+	if err := validateAccount(user); err != nil {
+		return nil, err
+	}
+	if password != "" {
+		if err := validatePassword(password); err != nil {
+			return nil, err
+		}
+		if user.PasswordHash, err = utils.HashPassword(password); err != nil {
+			return nil, err
+		}
+	}
+
+	user.UpdatedBy = principal.UserID
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	if revoke {
+		if err := uc.authUC.RevokeUser(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// ChangePassword 员工修改自己的密码，需要校验原密码；其他设备上的登录随之失效
+func (uc *AccountUseCase) ChangePassword(ctx context.Context, oldPassword, newPassword string) error {
+	principal, err := requireStaff(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := uc.Get(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPassword(user.PasswordHash, oldPassword) {
+		return ErrInvalidCredentials
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	if user.PasswordHash, err = utils.HashPassword(newPassword); err != nil {
+		return err
+	}
+	user.UpdatedBy = principal.UserID
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	return uc.authUC.RevokeUser(ctx, user.ID)
+}
+
+// Delete 删除员工账号，仅管理员可用；不能删除自己，避免误操作后无人可管理
+func (uc *AccountUseCase) Delete(ctx context.Context, id string) error {
+	principal, err := requireRole(ctx, entity.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if id == principal.UserID {
+		return ErrForbidden
+	}
+	if _, err := uc.Get(ctx, id); err != nil {
+		return err
+	}
+	if err := uc.userRepo.Delete(ctx, id, principal.UserID); err != nil {
+		return err
+	}
+	return uc.authUC.RevokeUser(ctx, id)
+}
+
+// List 按角色列出员工账号，仅管理员可用
+func (uc *AccountUseCase) List(ctx context.Context, role string) ([]*entity.User, error) {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if role != "" && !entity.IsStaffRole(role) {
+		return nil, ErrInvalidAccount
+	}
+	return uc.userRepo.List(ctx, entity.UserFilter{Role: role})
+}
+
+// normalizeAccount 去除首尾空白并去重技能标签
+func normalizeAccount(user *entity.User) {
+	user.Username = strings.TrimSpace(user.Username)
+	user.DisplayName = strings.TrimSpace(user.DisplayName)
+	user.Email = strings.TrimSpace(user.Email)
+
+	seen := make(map[string]bool, len(user.Skills))
+	skills := user.Skills[:0:0]
+	for _, skill := range user.Skills {
+		skill = strings.ToLower(strings.TrimSpace(skill))
+		if skill == "" || seen[skill] {
+			continue
+		}
+		seen[skill] = true
+		skills = append(skills, skill)
+	}
+	user.Skills = skills
+}
+
+func validateAccount(user *entity.User) error {
+	if !usernamePattern.MatchString(user.Username) || !entity.IsStaffRole(user.Role) {
+		return ErrInvalidAccount
+	}
+	if len(user.DisplayName) > maxDisplayNameLen {
+		return ErrInvalidAccount
+	}
+	if user.Email != "" {
+		if _, err := mail.ParseAddress(user.Email); err != nil {
+			return ErrInvalidAccount
+		}
+	}
+	if user.MaxConcurrentChats < 0 || user.MaxConcurrentChats > maxConcurrentChatCap {
+		return ErrInvalidAccount
+	}
+	if len(user.Skills) > maxSkills {
+		return ErrInvalidAccount
+	}
+	for _, skill := range user.Skills {
+		if len(skill) > maxSkillLen {
+			return ErrInvalidAccount
+		}
+	}
+	return nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+		return ErrInvalidAccount
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// newTestAuthUseCase 创建使用 HS256 测试密钥的认证用例
+func newTestAuthUseCase(t *testing.T, users repository.UserRepository) *usecase.AuthUseCase {
+	t.Helper()
+	jwt, err := utils.NewJWTManager(utils.JWTOptions{
+		Issuer:       "cland-test",
+		TTL:          time.Minute,
+		SigningKeyID: "k1",
+		Keys:         []utils.JWTKeyOptions{{ID: "k1", Algorithm: utils.JWTAlgHS256, Secret: []byte("test-secret-of-at-least-32-bytes!")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return usecase.NewAuthUseCase(jwt, infrarepo.NewMemoryTokenRepository(), users, time.Hour, zap.NewNop())
+}
+
+func TestAccountCreateValidation(t *testing.T) {
+	users := newSQLiteRepository(t).users
+	uc := usecase.NewAccountUseCase(users, newTestAuthUseCase(t, users))
+	admin := principalContext("admin1", entity.RoleAdmin)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		user     entity.User
+		password string
+		wantErr  error
+	}{
+		{name: "not admin", ctx: principalContext("s1", entity.RoleSupervisor), user: entity.User{Username: "bob", Role: entity.RoleAgent}, password: "password1", wantErr: usecase.ErrForbidden},
+		{name: "short username", ctx: admin, user: entity.User{Username: "ab", Role: entity.RoleAgent}, password: "password1", wantErr: usecase.ErrInvalidAccount},
+		{name: "username with spaces", ctx: admin, user: entity.User{Username: "bob smith", Role: entity.RoleAgent}, password: "password1", wantErr: usecase.ErrInvalidAccount},
+		{name: "customer role", ctx: admin, user: entity.User{Username: "bob", Role: entity.RoleCustomer}, password: "password1", wantErr: usecase.ErrInvalidAccount},
+		{name: "invalid email", ctx: admin, user: entity.User{Username: "bob", Role: entity.RoleAgent, Email: "bob@"}, password: "password1", wantErr: usecase.ErrInvalidAccount},
+		{name: "too many chats", ctx: admin, user: entity.User{Username: "bob", Role: entity.RoleAgent, MaxConcurrentChats: 101}, password: "password1", wantErr: usecase.ErrInvalidAccount},
+		{name: "short password", ctx: admin, user: entity.User{Username: "bob", Role: entity.RoleAgent}, password: "short", wantErr: usecase.ErrInvalidAccount},
+		{name: "password over 72 bytes", ctx: admin, user: entity.User{Username: "bob", Role: entity.RoleAgent}, password: strings.Repeat("p", 73), wantErr: usecase.ErrInvalidAccount},
+		{name: "valid", ctx: admin, user: entity.User{Username: " bob ", Role: entity.RoleAgent, Skills: []string{"Billing", " billing", "refunds", ""}}, password: "password1"},
+		{name: "username taken", ctx: admin, user: entity.User{Username: "bob", Role: entity.RoleSupervisor}, password: "password2", wantErr: usecase.ErrUsernameTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := tt.user
+			err := uc.Create(tt.ctx, &user, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			stored, err := uc.Get(admin, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Username != "bob" || !reflect.DeepEqual(stored.Skills, []string{"billing", "refunds"}) || stored.PasswordHash == "" {
+				t.Errorf("stored account = %+v, want trimmed username and deduplicated skills", stored)
+			}
+		})
+	}
+}
+
+func TestAccountChangesRevokeTokens(t *testing.T) {
+	ctx := context.Background()
+	users := newSQLiteRepository(t).users
+	authUC := newTestAuthUseCase(t, users)
+	uc := usecase.NewAccountUseCase(users, authUC)
+	admin := principalContext("admin1", entity.RoleAdmin)
+
+	account := &entity.User{Username: "bob", DisplayName: "Bob", Role: entity.RoleAgent}
+	if err := uc.Create(admin, account, "password1"); err != nil {
+		t.Fatal(err)
+	}
+	login := func(password string) *usecase.LoginResult {
+		t.Helper()
+		res, err := uc.Login(ctx, "bob", password)
+		if err != nil {
+			t.Fatalf("Login(%q) error = %v", password, err)
+		}
+		return res
+	}
+	res := login("password1")
+
+	// 只修改资料不影响已有登录
+	update := *account
+	update.DisplayName = "Bobby"
+	if _, err := uc.Update(admin, &update, ""); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := authUC.Refresh(ctx, res.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh after a profile update error = %v, want the session kept", err)
+	}
+
+	// 角色变更后已有登录需重新认证
+	update.Role = entity.RoleSupervisor
+	if _, err := uc.Update(admin, &update, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authUC.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, usecase.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh after a role change error = %v, want ErrInvalidRefreshToken", err)
+	}
+	if res = login("password1"); res.User.Role != entity.RoleSupervisor {
+		t.Fatalf("role after update = %s, want supervisor", res.User.Role)
+	}
+
+	// 修改密码需要原密码，新密码同样校验长度
+	self := principalContext(account.ID, entity.RoleSupervisor)
+	if err := uc.ChangePassword(self, "wrong-password", "password2"); !errors.Is(err, usecase.ErrInvalidCredentials) {
+		t.Fatalf("ChangePassword with a wrong old password error = %v, want ErrInvalidCredentials", err)
+	}
+	if err := uc.ChangePassword(self, "password1", "short"); !errors.Is(err, usecase.ErrInvalidAccount) {
+		t.Fatalf("ChangePassword to a short password error = %v, want ErrInvalidAccount", err)
+	}
+	if err := uc.ChangePassword(principalContext("c1", entity.RoleCustomer), "password1", "password2"); !errors.Is(err, usecase.ErrForbidden) {
+		t.Fatalf("customer ChangePassword error = %v, want ErrForbidden", err)
+	}
+	if err := uc.ChangePassword(self, "password1", "password2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authUC.Refresh(ctx, res.RefreshToken); !errors.Is(err, usecase.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh after a password change error = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := uc.Login(ctx, "bob", "password1"); !errors.Is(err, usecase.ErrInvalidCredentials) {
+		t.Fatalf("Login with the old password error = %v, want ErrInvalidCredentials", err)
+	}
+	res = login("password2")
+
+	// 管理员重置密码同样撤销登录
+	if _, err := uc.Update(admin, &update, "password3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authUC.Refresh(ctx, res.RefreshToken); !errors.Is(err, usecase.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh after a password reset error = %v, want ErrInvalidRefreshToken", err)
+	}
+	login("password3")
+}
@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"cland.org/cland-chat-service/core/domain/repository"
+)
+
+// AttachmentURLPrefix 附件下载地址前缀，附件消息的 Content 为该前缀加存储键
+const AttachmentURLPrefix = "/api/attachments/"
+
+// AttachmentUseCase 保存渠道消息中的附件(如邮件附件)，并供员工下载
+type AttachmentUseCase struct {
+	store repository.ArchiveStore
+}
+
+// NewAttachmentUseCase 创建附件用例，附件与归档使用相同的存储接口
+func NewAttachmentUseCase(store repository.ArchiveStore) *AttachmentUseCase {
+	return &AttachmentUseCase{store: store}
+}
+
+// Save 保存附件并返回下载地址，相同的键覆盖原文件
+func (uc *AttachmentUseCase) Save(ctx context.Context, key string, r io.Reader) (string, error) {
+	if err := uc.store.Put(ctx, key, r); err != nil {
+		return "", err
+	}
+	return AttachmentURLPrefix + key, nil
+}
+
+// attachmentKey 从附件消息的 Content 中取出存储键，不是本服务保存的附件时返回 false
+func attachmentKey(content string) (string, bool) {
+	key, ok := strings.CutPrefix(content, AttachmentURLPrefix)
+	key = strings.TrimPrefix(key, "/")
+	if !ok || key == "" || strings.Contains(key, "..") {
+		return "", false
+	}
+	return key, true
+}
+
+// Open 读取附件，仅员工可用；不存在时返回 ErrNotFound
+func (uc *AttachmentUseCase) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if _, err := requireStaff(ctx); err != nil {
+		return nil, err
+	}
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || strings.Contains(key, "..") {
+		return nil, repository.ErrNotFound
+	}
+	return uc.store.Get(ctx, key)
+}
@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DefaultRefreshTokenTTL 未配置时刷新令牌的有效期
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	// ErrTokenInvalid 访问令牌签名、有效期或签发方校验失败
+	ErrTokenInvalid = errors.New("token is invalid or expired")
+	// ErrTokenRevoked 访问令牌已注销
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrInvalidRefreshToken 刷新令牌不存在、已过期或已撤销
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid, expired or revoked")
+)
+
+// TokenPair 登录或刷新后签发的令牌
+type TokenPair struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// AuthUseCase 访问令牌与刷新令牌的签发、轮换与撤销
+type AuthUseCase struct {
+	jwt        *utils.JWTManager
+	tokenRepo  repository.TokenRepository
+	userRepo   repository.UserRepository
+	refreshTTL time.Duration
+	log        *zap.Logger
+}
+
+// NewAuthUseCase 创建令牌用例
+func NewAuthUseCase(
+	jwt *utils.JWTManager,
+	tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository,
+	refreshTTL time.Duration,
+	log *zap.Logger,
+) *AuthUseCase {
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTokenTTL
+	}
+	return &AuthUseCase{
+		jwt:        jwt,
+		tokenRepo:  tokenRepo,
+		userRepo:   userRepo,
+		refreshTTL: refreshTTL,
+		log:        log.Named("auth"),
+	}
+}
+
+// IssueTokens 为一次新的登录签发访问令牌与刷新令牌
+func (uc *AuthUseCase) IssueTokens(ctx context.Context, user *entity.User) (*TokenPair, error) {
+	return uc.issue(ctx, user, uuid.New().String(), nil)
+}
+
+// issue 签发令牌对；previous 非空时表示轮换，旧刷新令牌在新令牌写入前撤销
+func (uc *AuthUseCase) issue(ctx context.Context, user *entity.User, familyID string, previous *entity.RefreshToken) (*TokenPair, error) {
+	token, claims, err := uc.jwt.Generate(user.ID, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &entity.RefreshToken{
+		TokenHash: utils.HashToken(refresh),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(uc.refreshTTL),
+	}
+	if previous != nil {
+		if err := uc.tokenRepo.RevokeRefreshToken(ctx, previous.TokenHash, record.TokenHash); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				// 并发请求已抢先轮换了同一个令牌，按重复使用处理
+				return nil, uc.reuseDetected(ctx, previous)
+			}
+			return nil, err
+		}
+	}
+	if err := uc.tokenRepo.CreateRefreshToken(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		Token:            token,
+		ExpiresAt:        claims.ExpiresAt.Time,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// Refresh 用刷新令牌换取新的令牌对，旧刷新令牌随即失效。
+// 已轮换过的令牌再次出现说明可能被盗用，此时撤销整个 family，用户需重新登录。
+func (uc *AuthUseCase) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	record, err := uc.tokenRepo.GetRefreshToken(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if record.Revoked() {
+		if record.ReplacedBy != "" {
+			return nil, uc.reuseDetected(ctx, record)
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if record.Expired(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 角色以当前账号为准，账号删除后无法再刷新
+	user, err := uc.userRepo.GetByID(ctx, record.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	return uc.issue(ctx, user, record.FamilyID, record)
+}
+
+func (uc *AuthUseCase) reuseDetected(ctx context.Context, record *entity.RefreshToken) error {
+	uc.log.Warn("Refresh token reuse detected, revoking token family",
+		zap.String("userId", record.UserID), zap.String("familyId", record.FamilyID))
+	if err := uc.tokenRepo.RevokeRefreshFamily(ctx, record.FamilyID); err != nil {
+		return err
+	}
+	return ErrInvalidRefreshToken
+}
+
+// Logout 注销当前访问令牌及其所属登录的刷新令牌，两者均可为空；已失效的访问令牌无需再撤销
+func (uc *AuthUseCase) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	var record *entity.RefreshToken
+	if refreshToken != "" {
+		var err error
+		record, err = uc.tokenRepo.GetRefreshToken(ctx, utils.HashToken(refreshToken))
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		// 只能注销自己的登录
+		if principal := PrincipalFromContext(ctx); record != nil && principal.UserID != "" && principal.UserID != record.UserID {
+			return ErrForbidden
+		}
+	}
+
+	if accessToken != "" {
+		if claims, err := uc.jwt.Validate(accessToken); err == nil {
+			if err := uc.tokenRepo.RevokeAccessToken(ctx, &entity.RevokedToken{
+				JTI:       claims.ID,
+				UserID:    claims.UserID,
+				ExpiresAt: claims.ExpiresAt.Time,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if record != nil {
+		return uc.tokenRepo.RevokeRefreshFamily(ctx, record.FamilyID)
+	}
+	return nil
+}
+
+// RevokeUser 撤销用户全部刷新令牌，用于改密、改角色或删除账号；已签发的访问令牌在短有效期后自然失效
+func (uc *AuthUseCase) RevokeUser(ctx context.Context, userID string) error {
+	return uc.tokenRepo.RevokeUserRefreshTokens(ctx, userID)
+}
+
+// Authenticate 校验访问令牌并检查撤销列表，返回令牌对应的身份
+func (uc *AuthUseCase) Authenticate(ctx context.Context, token string) (entity.Principal, error) {
+	claims, err := uc.jwt.Validate(token)
+	if err != nil || claims.ID == "" {
+		return entity.Principal{}, ErrTokenInvalid
+	}
+	revoked, err := uc.tokenRepo.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return entity.Principal{}, err
+	}
+	if revoked {
+		return entity.Principal{}, ErrTokenRevoked
+	}
+	return entity.Principal{UserID: claims.UserID, Role: claims.Role}, nil
+}
+
+// Run 定期清理已过期的刷新令牌与撤销记录，直到 ctx 取消
+func (uc *AuthUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := uc.tokenRepo.PurgeExpired(ctx, now)
+			if err != nil {
+				uc.log.Error("Failed to purge expired tokens", zap.Error(err))
+				continue
+			}
+			if n > 0 {
+				uc.log.Info("Purged expired tokens", zap.Int64("count", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+)
+
+var (
+	// ErrUnauthenticated 请求未携带有效身份
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden 当前角色无权执行该操作
+	ErrForbidden = errors.New("permission denied")
+	// ErrSenderMismatch 只能以自己的身份发送消息
+	ErrSenderMismatch = errors.New("messages can only be sent as yourself")
+	// ErrSessionAccessDenied 会话不属于当前用户
+	ErrSessionAccessDenied = errors.New("session belongs to another user")
+	// ErrAckForbidden 只有消息接收方可以确认消息
+	ErrAckForbidden = errors.New("only the recipient can acknowledge a message")
+)
+
+// WithSystemPrincipal 为后台任务设置服务内部身份
+func WithSystemPrincipal(ctx context.Context) context.Context {
+	return WithPrincipal(ctx, entity.Principal{UserID: SystemAddress, Role: entity.RoleSystem})
+}
+
+// authenticated 返回已认证的请求身份
+func authenticated(ctx context.Context) (entity.Principal, error) {
+	principal := PrincipalFromContext(ctx)
+	if !principal.IsAuthenticated() {
+		return principal, ErrUnauthenticated
+	}
+	return principal, nil
+}
+
+// requireRole 要求请求身份为指定角色之一，服务内部身份不受限制
+func requireRole(ctx context.Context, roles ...string) (entity.Principal, error) {
+	principal, err := authenticated(ctx)
+	if err != nil {
+		return principal, err
+	}
+	if !principal.IsSystem() && !principal.HasRole(roles...) {
+		return principal, ErrForbidden
+	}
+	return principal, nil
+}
+
+// requireStaff 要求请求身份为客服、主管或管理员
+func requireStaff(ctx context.Context) (entity.Principal, error) {
+	return requireRole(ctx, entity.RoleAgent, entity.RoleSupervisor, entity.RoleAdmin)
+}
+
+// authorizeSender 消息的 Src 必须是请求身份自己的地址
+func authorizeSender(ctx context.Context, message *entity.Message) (entity.Principal, error) {
+	principal, err := authenticated(ctx)
+	if err != nil {
+		return principal, err
+	}
+	if !principal.IsSystem() && message.Src != principal.Address() {
+		return principal, ErrSenderMismatch
+	}
+	return principal, nil
+}
+
+// authorizeSession 客户只能访问自己的会话，客服只能访问分配给自己的会话，主管与管理员不受限制
+func authorizeSession(ctx context.Context, session *entity.Session) error {
+	principal, err := authenticated(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case principal.IsSupervisor():
+		return nil
+	case principal.Role == entity.RoleAgent && session.AgentId == principal.UserID:
+		return nil
+	case principal.Role == entity.RoleCustomer && session.CID == principal.UserID:
+		return nil
+	default:
+		return ErrSessionAccessDenied
+	}
+}
+
+// authorizeSelf 只能访问自己的数据，管理员不受限制
+func authorizeSelf(ctx context.Context, userID string) error {
+	principal, err := authenticated(ctx)
+	if err != nil {
+		return err
+	}
+	if principal.UserID != userID && !principal.HasRole(entity.RoleAdmin) && !principal.IsSystem() {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+)
+
+const (
+	// ExtCannedResponseID 客服通过快捷回复发送消息时，消息 Ext 中携带的快捷回复ID
+	ExtCannedResponseID = "cannedResponseId"
+
+	maxCannedShortcutLen = 50
+	maxCannedTitleLen    = 200
+	maxCannedBodyLen     = 4000
+)
+
+var (
+	// ErrInvalidCannedResponse 快捷回复字段不合法
+	ErrInvalidCannedResponse = errors.New("shortcut must start with '/' and title/body are required")
+	// ErrCannedShortcutTaken 同一可见范围内快捷指令重复
+	ErrCannedShortcutTaken = errors.New("shortcut already used in this scope")
+	// ErrCannedResponseNotFound 快捷回复不存在或对该客服不可见
+	ErrCannedResponseNotFound = errors.New("canned response not found")
+)
+
+// CannedResponseUseCase 快捷回复用例
+type CannedResponseUseCase struct {
+	cannedRepo  repository.CannedResponseRepository
+	sessionRepo repository.SessionRepository
+	userRepo    repository.UserRepository
+}
+
+// NewCannedResponseUseCase 创建快捷回复用例
+func NewCannedResponseUseCase(
+	cannedRepo repository.CannedResponseRepository,
+	sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository,
+) *CannedResponseUseCase {
+	return &CannedResponseUseCase{
+		cannedRepo:  cannedRepo,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// Create 新建快捷回复，personal 快捷回复归属于创建者
+func (uc *CannedResponseUseCase) Create(ctx context.Context, canned *entity.CannedResponse) error {
+	principal, err := requireStaff(ctx)
+	if err != nil {
+		return err
+	}
+	agentID := principal.UserID
+
+	if canned.Scope == entity.CannedScopePersonal {
+		canned.OwnerID = agentID
+		canned.Team = ""
+	} else {
+		canned.OwnerID = ""
+	}
+	if err := uc.validate(ctx, canned); err != nil {
+		return err
+	}
+
+	canned.ID = utils.GenerateCannedResponseID()
+	canned.CreatedBy = agentID
+	canned.UpdatedBy = agentID
+	canned.Variables = entity.ParseCannedVariables(canned.Body)
+	return uc.cannedRepo.Create(ctx, canned)
+}
+
+// Get 获取对客服可见的快捷回复
+func (uc *CannedResponseUseCase) Get(ctx context.Context, id, team string) (*entity.CannedResponse, error) {
+	principal, err := requireStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	canned, err := uc.cannedRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrCannedResponseNotFound
+		}
+		return nil, err
+	}
+	if !canned.VisibleTo(principal.UserID, team) {
+		return nil, ErrCannedResponseNotFound
+	}
+	return canned, nil
+}
+
+// Update 修改快捷回复的指令、标题、正文；范围与归属不可修改
+func (uc *CannedResponseUseCase) Update(ctx context.Context, update *entity.CannedResponse, team string) (*entity.CannedResponse, error) {
+	canned, err := uc.Get(ctx, update.ID, team)
+	if err != nil {
+		return nil, err
+	}
+
+	canned.Shortcut = update.Shortcut
+	canned.Title = update.Title
+	canned.Body = update.Body
+	if err := uc.validate(ctx, canned); err != nil {
+		return nil, err
+	}
+
+	canned.UpdatedBy = PrincipalFromContext(ctx).UserID
+	canned.Variables = entity.ParseCannedVariables(canned.Body)
+	if err := uc.cannedRepo.Update(ctx, canned); err != nil {
+		return nil, err
+	}
+	return canned, nil
+}
+
+// Delete 删除快捷回复
+func (uc *CannedResponseUseCase) Delete(ctx context.Context, id, team string) error {
+	if _, err := uc.Get(ctx, id, team); err != nil {
+		return err
+	}
+	return uc.cannedRepo.Delete(ctx, id, PrincipalFromContext(ctx).UserID)
+}
+
+// Search 按快捷指令前缀查询对客服可见的快捷回复，前缀为空时返回全部
+func (uc *CannedResponseUseCase) Search(ctx context.Context, team, shortcutPrefix string) ([]*entity.CannedResponse, error) {
+	principal, err := requireStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return uc.cannedRepo.List(ctx, entity.CannedResponseFilter{
+		AgentID:        principal.UserID,
+		Team:           team,
+		ShortcutPrefix: shortcutPrefix,
+	})
+}
+
+// Expand 用会话与用户数据替换快捷回复正文中的变量
+func (uc *CannedResponseUseCase) Expand(ctx context.Context, id, agentID, sessionID string) (string, error) {
+	canned, err := uc.cannedRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrCannedResponseNotFound
+		}
+		return "", err
+	}
+	// 团队信息尚未落库，这里只拦截他人的个人快捷回复
+	if canned.Scope == entity.CannedScopePersonal && canned.OwnerID != agentID {
+		return "", ErrCannedResponseNotFound
+	}
+
+	return entity.ExpandCannedBody(canned.Body, uc.variables(ctx, agentID, sessionID)), nil
+}
+
+// variables 收集可用的变量取值，查询失败的数据留空由默认值兜底
+func (uc *CannedResponseUseCase) variables(ctx context.Context, agentID, sessionID string) map[string]string {
+	values := map[string]string{
+		"agent.id":         agentID,
+		"agent.name":       "",
+		"customer.id":      "",
+		"customer.name":    "",
+		"session.id":       sessionID,
+		"session.channel":  "",
+		"session.start":    "",
+		"session.duration": "",
+	}
+
+	if agent, err := uc.userRepo.GetByID(ctx, agentID); err == nil {
+		values["agent.name"] = agent.Name()
+	}
+
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return values
+	}
+	values["customer.id"] = session.CID
+	values["session.channel"] = session.Channel
+	if !session.StartTime.IsZero() {
+		values["session.start"] = session.StartTime.Format("2006-01-02 15:04")
+		values["session.duration"] = strconv.Itoa(int(time.Since(session.StartTime).Minutes())) + "m"
+	}
+	if customer, err := uc.userRepo.GetByID(ctx, session.CID); err == nil {
+		values["customer.name"] = customer.Name()
+	}
+	return values
+}
+
+// validate 校验字段并确保同一可见范围内快捷指令唯一
+func (uc *CannedResponseUseCase) validate(ctx context.Context, canned *entity.CannedResponse) error {
+	canned.Shortcut = strings.TrimSpace(canned.Shortcut)
+	if canned.Scope == "" {
+		canned.Scope = entity.CannedScopeTeam
+	}
+	if !strings.HasPrefix(canned.Shortcut, "/") || len(canned.Shortcut) < 2 ||
+		len(canned.Shortcut) > maxCannedShortcutLen || strings.ContainsAny(canned.Shortcut, " \t\n") ||
+		canned.Title == "" || len(canned.Title) > maxCannedTitleLen ||
+		canned.Body == "" || len(canned.Body) > maxCannedBodyLen ||
+		(canned.Scope != entity.CannedScopeTeam && canned.Scope != entity.CannedScopePersonal) {
+		return ErrInvalidCannedResponse
+	}
+
+	existing, err := uc.cannedRepo.List(ctx, entity.CannedResponseFilter{
+		AgentID:        canned.OwnerID,
+		Team:           canned.Team,
+		ShortcutPrefix: canned.Shortcut,
+	})
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.ID != canned.ID && other.Shortcut == canned.Shortcut &&
+			other.Scope == canned.Scope && other.Team == canned.Team && other.OwnerID == canned.OwnerID {
+			return ErrCannedShortcutTaken
+		}
+	}
+	return nil
+}
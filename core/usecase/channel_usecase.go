@@ -0,0 +1,343 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrChannelNotFound 渠道未注册
+	ErrChannelNotFound = errors.New("channel not found")
+	// ErrInvalidChannelPayload 服务商回调无法解析，渠道实现返回时包装此错误
+	ErrInvalidChannelPayload = errors.New("invalid channel payload")
+	// ErrChannelUnauthorized 服务商回调签名校验失败，渠道实现返回时包装此错误
+	ErrChannelUnauthorized = errors.New("channel request is not authenticated")
+)
+
+// Channel 外部接入渠道(邮件、即时通讯、短信等)适配器，由基础设施层实现
+type Channel interface {
+	// Name 渠道名，记录在会话上，并用于回调地址与按渠道的超时、保留策略
+	Name() string
+	// ParseInbound 校验并解析服务商回调，将其中的客户消息规范化为 entity.Message
+	ParseInbound(ctx context.Context, header http.Header, body []byte) ([]*entity.InboundMessage, error)
+	// Send 将客服回复经服务商发给客户，失败时返回错误由发件箱重试
+	Send(ctx context.Context, msg *entity.OutboundMessage) error
+}
+
+// ChannelUseCase 管理已注册的渠道：入站消息归到渠道用户对应的客户与会话后按客户身份发送，
+// 作为发件箱下游将渠道会话中的客服回复发回服务商
+type ChannelUseCase struct {
+	channels     map[string]Channel
+	chatUC       *ChatUseCase
+	userRepo     repository.UserRepository
+	messageRepo  repository.MessageRepository
+	identityRepo repository.ChannelIdentityRepository
+	threadRepo   repository.ChannelThreadRepository
+	tx           repository.TxManager
+	log          *zap.Logger
+}
+
+var _ OutboxSink = (*ChannelUseCase)(nil)
+
+// NewChannelUseCase 创建渠道用例，渠道通过 Register 注册
+func NewChannelUseCase(chatUC *ChatUseCase, userRepo repository.UserRepository, messageRepo repository.MessageRepository,
+	identityRepo repository.ChannelIdentityRepository, log *zap.Logger) *ChannelUseCase {
+	return &ChannelUseCase{
+		channels:     make(map[string]Channel),
+		chatUC:       chatUC,
+		userRepo:     userRepo,
+		messageRepo:  messageRepo,
+		identityRepo: identityRepo,
+		log:          log.Named("channel"),
+	}
+}
+
+// SetTxManager 设置工作单元，新渠道用户的客户记录与对应关系在同一事务内创建
+func (uc *ChannelUseCase) SetTxManager(tx repository.TxManager) {
+	uc.tx = tx
+}
+
+// SetThreadRepository 启用按线索归属会话，未设置时线索信息被忽略
+func (uc *ChannelUseCase) SetThreadRepository(threadRepo repository.ChannelThreadRepository) {
+	uc.threadRepo = threadRepo
+}
+
+// Register 注册渠道，网页渠道由 Socket.IO 接入，不能注册
+func (uc *ChannelUseCase) Register(channel Channel) error {
+	name := channel.Name()
+	if name == "" || name == entity.ChannelWeb {
+		return fmt.Errorf("invalid channel name %q", name)
+	}
+	if _, exists := uc.channels[name]; exists {
+		return fmt.Errorf("channel %q already registered", name)
+	}
+	uc.channels[name] = channel
+	return nil
+}
+
+// Channels 已注册的渠道名
+func (uc *ChannelUseCase) Channels() []string {
+	names := make([]string, 0, len(uc.channels))
+	for name := range uc.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HandleInbound 处理服务商回调，返回接收的消息数。服务商重复回调的消息不会重复写入
+func (uc *ChannelUseCase) HandleInbound(ctx context.Context, name string, header http.Header, body []byte) (int, error) {
+	channel, ok := uc.channels[name]
+	if !ok {
+		return 0, ErrChannelNotFound
+	}
+	inbound, err := channel.ParseInbound(ctx, header, body)
+	if err != nil {
+		return 0, err
+	}
+	return uc.Receive(ctx, name, inbound)
+}
+
+// Receive 接收已解析的入站消息，供不经 HTTP 回调的来源(如本地 maildir)使用
+func (uc *ChannelUseCase) Receive(ctx context.Context, name string, inbound []*entity.InboundMessage) (int, error) {
+	if _, ok := uc.channels[name]; !ok {
+		return 0, ErrChannelNotFound
+	}
+	for _, in := range inbound {
+		if in.ExternalID == "" || in.Message == nil {
+			return 0, fmt.Errorf("%w: sender and message are required", ErrInvalidChannelPayload)
+		}
+		if err := uc.receive(ctx, name, in); err != nil {
+			return 0, err
+		}
+	}
+	return len(inbound), nil
+}
+
+// receive 以渠道用户对应的客户身份发送一条入站消息，找不到可归入的进行中会话时新建会话
+func (uc *ChannelUseCase) receive(ctx context.Context, name string, in *entity.InboundMessage) error {
+	cid, err := uc.resolveCustomer(ctx, name, in)
+	if err != nil {
+		return err
+	}
+	customerCtx := WithPrincipal(ctx, entity.Principal{UserID: cid, Role: entity.RoleCustomer})
+
+	var session *entity.Session
+	if in.Threaded && uc.threadRepo != nil {
+		session, err = uc.threadSession(ctx, cid, name, in)
+	} else {
+		session, err = uc.activeSession(ctx, cid, name)
+	}
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		if session, err = uc.chatUC.CreateChannelSession(customerCtx, cid, name); err != nil {
+			return err
+		}
+	}
+
+	msg := in.Message
+	msg.Src = "U:" + cid
+	msg.Dst = "S:auto"
+	if session.AgentId != "" {
+		msg.Dst = "A:" + session.AgentId
+	}
+	msg.SessionID = session.ID
+	msg.MsgType = entity.MsgTypeMessage
+	if msg.ContentType == 0 {
+		msg.ContentType = entity.ContentTypeText
+	}
+	msg.MsgID = utils.GenerateMessageID()
+	if in.ProviderMsgID != "" {
+		msg.MsgID = utils.GenerateIdempotentMessageID(msg.Src, name+":"+in.ProviderMsgID)
+	}
+	if msg.Ext == nil {
+		msg.Ext = map[string]interface{}{}
+	}
+	msg.Ext["channel"] = name
+
+	err = uc.chatUC.SendMessage(customerCtx, msg)
+	if err != nil && !errors.Is(err, ErrDuplicateMessage) {
+		return err
+	}
+	if in.Threaded && in.ThreadID != "" && uc.threadRepo != nil {
+		return uc.threadRepo.Save(ctx, &entity.ChannelThread{
+			Channel:   name,
+			ThreadID:  in.ThreadID,
+			SessionID: session.ID,
+			Subject:   in.Subject,
+		})
+	}
+	return nil
+}
+
+// threadSession 按线索查找客户进行中的会话；线索属于其他客户或会话已关闭时返回 nil 以新建会话。
+// 本条消息自身的线索标识也参与查找，同一封邮件拆出的多条消息与重复投递归入同一会话
+func (uc *ChannelUseCase) threadSession(ctx context.Context, cid, name string, in *entity.InboundMessage) (*entity.Session, error) {
+	refs := in.ThreadRefs
+	if in.ThreadID != "" {
+		refs = append([]string{in.ThreadID}, refs...)
+	}
+	thread, err := uc.threadRepo.FindSession(ctx, name, refs)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	session, err := uc.chatUC.SessionRepo.GetByID(ctx, thread.SessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if session.CID != cid || session.Status != entity.SessionStatusActive {
+		return nil, nil
+	}
+	return session, nil
+}
+
+// resolveCustomer 返回渠道用户对应的客户，首次来信时创建客户记录
+func (uc *ChannelUseCase) resolveCustomer(ctx context.Context, name string, in *entity.InboundMessage) (string, error) {
+	identity, err := uc.identityRepo.Get(ctx, name, in.ExternalID)
+	if err == nil {
+		return identity.CID, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return "", err
+	}
+
+	cid := utils.GenerateClandCID()
+	err = uc.withinTx(ctx, func(ctx context.Context) error {
+		customer := newCustomer(cid, "")
+		customer.DisplayName = in.Name
+		customer.Email = in.Email
+		customer.Status = "offline"
+		if err := uc.userRepo.Create(ctx, customer); err != nil {
+			return err
+		}
+		return uc.identityRepo.Create(ctx, &entity.ChannelIdentity{Channel: name, ExternalID: in.ExternalID, CID: cid})
+	})
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		// 并发回调已为该渠道用户创建了客户
+		identity, err = uc.identityRepo.Get(ctx, name, in.ExternalID)
+		if err != nil {
+			return "", err
+		}
+		return identity.CID, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	uc.log.Info("Channel customer created", zap.String("channel", name), zap.String("cid", cid))
+	return cid, nil
+}
+
+// activeSession 客户在该渠道最近一个进行中的会话，没有时返回 nil
+func (uc *ChannelUseCase) activeSession(ctx context.Context, cid, name string) (*entity.Session, error) {
+	sessions, err := uc.chatUC.SessionRepo.ListByCID(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.Channel == name && session.Status == entity.SessionStatusActive {
+			return session, nil
+		}
+	}
+	return nil, nil
+}
+
+// withinTx 在工作单元内执行 fn，未设置工作单元时直接执行
+func (uc *ChannelUseCase) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if uc.tx == nil {
+		return fn(ctx)
+	}
+	return uc.tx.WithinTx(ctx, fn)
+}
+
+// HandleOutboxEvent 将渠道会话中的客服回复经服务商发给客户，发送成功后标记为已送达。
+// 重复分发时已送达的消息不再发送
+func (uc *ChannelUseCase) HandleOutboxEvent(ctx context.Context, event *entity.OutboxEvent) error {
+	if event.EventType != entity.WebhookEventMessageCreated {
+		return nil
+	}
+	var msg entity.Message
+	if err := json.Unmarshal(event.Payload, &msg); err != nil {
+		return err
+	}
+	if msg.MsgType != entity.MsgTypeMessage || !strings.HasPrefix(msg.Src, "A:") || msg.SessionID == "" {
+		return nil
+	}
+
+	session, err := uc.chatUC.SessionRepo.GetByID(ctx, msg.SessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	channel, ok := uc.channels[session.Channel]
+	if !ok {
+		return nil
+	}
+
+	stored, err := uc.messageRepo.GetByID(ctx, msg.MsgID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if stored.Status == entity.StatusDelivered || stored.Status == entity.StatusRead || stored.Status == entity.StatusRecall {
+		return nil
+	}
+
+	identity, err := uc.identityRepo.GetByCID(ctx, session.Channel, session.CID)
+	if errors.Is(err, repository.ErrNotFound) {
+		uc.log.Warn("No channel identity for customer, reply not sent",
+			zap.String("channel", session.Channel), zap.String("cid", session.CID), zap.String("msgId", msg.MsgID))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	out := &entity.OutboundMessage{ExternalID: identity.ExternalID, Message: stored}
+	if uc.threadRepo != nil {
+		threads, err := uc.threadRepo.ListBySession(ctx, session.Channel, session.ID)
+		if err != nil {
+			return err
+		}
+		for _, thread := range threads {
+			out.ThreadRefs = append(out.ThreadRefs, thread.ThreadID)
+			if out.Subject == "" {
+				out.Subject = thread.Subject
+			}
+		}
+	}
+	if err := channel.Send(ctx, out); err != nil {
+		return fmt.Errorf("send via channel %s: %w", session.Channel, err)
+	}
+	if out.ThreadID != "" && uc.threadRepo != nil {
+		if err := uc.threadRepo.Save(ctx, &entity.ChannelThread{
+			Channel:   session.Channel,
+			ThreadID:  out.ThreadID,
+			SessionID: session.ID,
+			Subject:   out.Subject,
+		}); err != nil {
+			return err
+		}
+	}
+	err = uc.chatUC.ProcessMessageStatus(ctx, msg.MsgID, entity.StatusDelivered)
+	if errors.Is(err, ErrInvalidStatusTransition) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,186 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/infrastructure/channel"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// channelProvider 模拟渠道服务商，校验签名并记录收到的客服回复，前 failures 次返回 503
+type channelProvider struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	attempts int
+	replies  []channel.WebhookMessage
+}
+
+func (p *channelProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	if err := utils.VerifyWebhookSignature(p.secret, r.Header.Get(utils.WebhookSignatureHeader), body, time.Minute); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	p.attempts++
+	if p.attempts <= p.failures {
+		http.Error(w, "simulated outage", http.StatusServiceUnavailable)
+		return
+	}
+	var msg channel.WebhookMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.replies = append(p.replies, msg)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestChannelInboundAndReplyRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	messages := infrarepo.NewMemoryMessageRepository()
+	sessions := infrarepo.NewMemorySessionRepository()
+	users := infrarepo.NewMemoryUserRepository()
+	identities := infrarepo.NewMemoryChannelIdentityRepository()
+	outbox := infrarepo.NewMemoryOutboxRepository(messages, sessions)
+	dispatcher := usecase.NewOutboxDispatcher(outbox, usecase.OutboxOptions{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}, zap.NewNop())
+	chatUC := usecase.NewChatUseCase(messages, sessions, users, infrarepo.NewMemoryReactionRepository())
+	chatUC.SetOutbox(outbox, dispatcher)
+
+	provider := &channelProvider{secret: "chsec", failures: 1}
+	server := httptest.NewServer(provider)
+	defer server.Close()
+
+	channelUC := usecase.NewChannelUseCase(chatUC, users, messages, identities, zap.NewNop())
+	if err := channelUC.Register(channel.NewWebhookChannel(channel.WebhookOptions{
+		Name: "sms", OutboundURL: server.URL, Secret: "chsec",
+	}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.AddSink("channel", channelUC)
+
+	// 服务商回调客户消息；重复回调同一条消息不重复写入
+	body, _ := json.Marshal(channel.WebhookInbound{Messages: []channel.WebhookMessage{{
+		ID: "sms-1", From: &channel.WebhookSender{ID: "+15550100", Name: "Ann"}, Text: "Where is my order?", Timestamp: time.Now().UnixMilli(),
+	}}})
+	for i := 0; i < 2; i++ {
+		header := http.Header{}
+		header.Set(utils.WebhookSignatureHeader, utils.SignWebhook("chsec", time.Now(), body))
+		if n, err := channelUC.HandleInbound(ctx, "sms", header, body); err != nil || n != 1 {
+			t.Fatalf("HandleInbound = %d, %v", n, err)
+		}
+	}
+
+	identity, err := identities.Get(ctx, "sms", "+15550100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	customerSessions, err := sessions.ListByCID(ctx, identity.CID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customerSessions) != 1 || customerSessions[0].Channel != "sms" {
+		t.Fatalf("customer sessions %+v, want one sms session", customerSessions)
+	}
+	session := customerSessions[0]
+	stored, err := messages.GetBySessionID(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].Src != "U:"+identity.CID || stored[0].Content != "Where is my order?" {
+		t.Fatalf("session messages %+v, want the customer message once", stored)
+	}
+
+	// 客服回复经发件箱发回服务商，服务商故障时重试
+	if err := sessions.UpdateAgent(ctx, session.ID, "a1"); err != nil {
+		t.Fatal(err)
+	}
+	reply := &entity.Message{
+		MsgID: "reply-1", SessionID: session.ID, MsgType: entity.MsgTypeMessage, Src: "A:a1", Dst: "U:" + identity.CID,
+		ContentType: entity.ContentTypeText, Content: "Your parcel ships today.",
+	}
+	if err := chatUC.SendMessage(principalContext("a1", entity.RoleAgent), reply); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.Dispatch(ctx, time.Now().Add(time.Hour))
+	dispatcher.Dispatch(ctx, time.Now().Add(2*time.Hour))
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if provider.attempts != 2 || len(provider.replies) != 1 {
+		t.Fatalf("provider attempts=%d replies=%d, want 2 attempts and 1 reply", provider.attempts, len(provider.replies))
+	}
+	if got := provider.replies[0]; got.ID != "reply-1" || got.To != "+15550100" || got.Text != "Your parcel ships today." {
+		t.Errorf("provider received %+v", got)
+	}
+	delivered, err := messages.GetByID(ctx, "reply-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered.Status != entity.StatusDelivered {
+		t.Errorf("reply status = %d, want delivered", delivered.Status)
+	}
+}
+
+func TestChannelDoesNotSendInternalNotes(t *testing.T) {
+	ctx := context.Background()
+	messages := infrarepo.NewMemoryMessageRepository()
+	sessions := infrarepo.NewMemorySessionRepository()
+	users := infrarepo.NewMemoryUserRepository()
+	outbox := infrarepo.NewMemoryOutboxRepository(messages, sessions)
+	dispatcher := usecase.NewOutboxDispatcher(outbox, usecase.OutboxOptions{}, zap.NewNop())
+	chatUC := usecase.NewChatUseCase(messages, sessions, users, infrarepo.NewMemoryReactionRepository())
+	chatUC.SetOutbox(outbox, dispatcher)
+
+	provider := &channelProvider{secret: "chsec"}
+	server := httptest.NewServer(provider)
+	defer server.Close()
+	identities := infrarepo.NewMemoryChannelIdentityRepository()
+	channelUC := usecase.NewChannelUseCase(chatUC, users, messages, identities, zap.NewNop())
+	if err := channelUC.Register(channel.NewWebhookChannel(channel.WebhookOptions{
+		Name: "sms", OutboundURL: server.URL, Secret: "chsec",
+	}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.AddSink("channel", channelUC)
+
+	if err := identities.Create(ctx, &entity.ChannelIdentity{Channel: "sms", ExternalID: "+15550100", CID: "c1", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	session := &entity.Session{ID: "s1", CID: "c1", AgentId: "a1", Channel: "sms", Status: entity.SessionStatusActive, CreatedAt: time.Now()}
+	if err := sessions.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	agent := principalContext("a1", entity.RoleAgent)
+	for _, msg := range []*entity.Message{
+		{MsgID: "n1", SessionID: "s1", MsgType: entity.MsgTypeInternalNote, Src: "A:a1", Dst: "U:c1", ContentType: entity.ContentTypeText, Content: "Customer is angry"},
+		{MsgID: "m1", SessionID: "s1", MsgType: entity.MsgTypeMessage, Src: "A:a1", Dst: "U:c1", ContentType: entity.ContentTypeText, Content: "Sorry for the delay."},
+	} {
+		if err := chatUC.SendMessage(agent, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dispatcher.Dispatch(ctx, time.Now().Add(time.Minute))
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if len(provider.replies) != 1 || provider.replies[0].ID != "m1" {
+		t.Fatalf("provider received %+v, want only the customer-facing reply m1", provider.replies)
+	}
+}
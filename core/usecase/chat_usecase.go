@@ -3,17 +3,56 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"cland.org/cland-chat-service/common/tracing"
+	"cland.org/cland-chat-service/common/utils"
 	"cland.org/cland-chat-service/core/domain/entity"
 	"cland.org/cland-chat-service/core/domain/repository"
 )
 
+var (
+	// ErrInvalidReplyTo 引用的消息不存在或不属于同一会话
+	ErrInvalidReplyTo = errors.New("replyTo must reference a message in the same session")
+	// ErrDuplicateMessage 同一发送方重复提交了已存储的消息，message 已被替换为原消息
+	ErrDuplicateMessage = errors.New("message already persisted")
+	// ErrMsgIDConflict msgId 已被其他发送方占用
+	ErrMsgIDConflict = errors.New("msgId already used by another sender")
+	// ErrInternalNoteForbidden 只有客服可以发送内部备注
+	ErrInternalNoteForbidden = errors.New("only agents can send internal notes")
+	// ErrNotificationForbidden 只有员工或服务内部身份可以发送通知
+	ErrNotificationForbidden = errors.New("only staff can send notifications")
+	// ErrInvalidStatusTransition 消息状态不能从当前状态变更为目标状态
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+)
+
+// EventEmitter 向在线用户推送实时事件
+type EventEmitter interface {
+	EmitToUsers(eventName string, data interface{}, userIDs []string) error
+	PushMessage(msg entity.Message, userIDs []string) error
+	PushMessageToRoom(msg entity.Message, roomID string) error
+}
+
+// CannedResponseExpander 将快捷回复展开为消息正文
+type CannedResponseExpander interface {
+	Expand(ctx context.Context, id, agentID, sessionID string) (string, error)
+}
+
 // ChatUseCase 聊天用例
 type ChatUseCase struct {
-	messageRepo repository.MessageRepository
-	SessionRepo repository.SessionRepository
-	UserRepo    repository.UserRepository
+	messageRepo  repository.MessageRepository
+	reactionRepo repository.ReactionRepository
+	SessionRepo  repository.SessionRepository
+	UserRepo     repository.UserRepository
+	emitter      EventEmitter
+	canned       CannedResponseExpander
+	publisher    EventPublisher
+	outbox       repository.OutboxRepository
+	dispatcher   *OutboxDispatcher
+	tx           repository.TxManager
+	metrics      Metrics
 }
 
 // NewChatUseCase 创建聊天用例
@@ -21,21 +60,143 @@ func NewChatUseCase(
 	messageRepo repository.MessageRepository,
 	sessionRepo repository.SessionRepository,
 	userRepo repository.UserRepository,
+	reactionRepo repository.ReactionRepository,
 ) *ChatUseCase {
 	return &ChatUseCase{
-		messageRepo: messageRepo,
-		SessionRepo: sessionRepo,
-		UserRepo:    userRepo,
+		messageRepo:  messageRepo,
+		reactionRepo: reactionRepo,
+		SessionRepo:  sessionRepo,
+		UserRepo:     userRepo,
 	}
 }
 
-// SendMessage 发送消息
+// SetEventEmitter 设置实时事件推送通道(由 WebSocket 层提供)
+func (uc *ChatUseCase) SetEventEmitter(emitter EventEmitter) {
+	uc.emitter = emitter
+}
+
+// SetCannedResponseExpander 设置快捷回复展开器
+func (uc *ChatUseCase) SetCannedResponseExpander(canned CannedResponseExpander) {
+	uc.canned = canned
+}
+
+// SetEventPublisher 设置业务事件发布器(webhook)
+func (uc *ChatUseCase) SetEventPublisher(publisher EventPublisher) {
+	uc.publisher = publisher
+}
+
+// SetOutbox 启用事务性发件箱：消息与会话变更连同事件在同一事务内写入，
+// 由分发器负责实时推送与 webhook 发布
+func (uc *ChatUseCase) SetOutbox(outbox repository.OutboxRepository, dispatcher *OutboxDispatcher) {
+	uc.outbox = outbox
+	uc.dispatcher = dispatcher
+}
+
+// SetTxManager 设置工作单元，会话关闭、改派与随之产生的系统通知在同一事务内提交；未设置时逐条写入
+func (uc *ChatUseCase) SetTxManager(tx repository.TxManager) {
+	uc.tx = tx
+}
+
+// withinTx 在工作单元内执行 fn，未设置工作单元时直接执行
+func (uc *ChatUseCase) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if uc.tx == nil {
+		return fn(ctx)
+	}
+	return uc.tx.WithinTx(ctx, fn)
+}
+
+// SetMetrics 设置指标记录器
+func (uc *ChatUseCase) SetMetrics(metrics Metrics) {
+	uc.metrics = metrics
+}
+
+// record 记录消息指标，未设置记录器时忽略
+func (uc *ChatUseCase) record(event string, message *entity.Message) {
+	if uc.metrics != nil {
+		uc.metrics.MessageEvent(event, message.MsgType, message.ContentType)
+	}
+}
+
+// OutboxEnabled 是否通过发件箱分发消息；启用时投递层不再直接推送新消息
+func (uc *ChatUseCase) OutboxEnabled() bool {
+	return uc.outbox != nil
+}
+
+// publish 发布业务事件，未配置发布器时忽略
+func (uc *ChatUseCase) publish(ctx context.Context, eventType string, data interface{}) {
+	if uc.publisher != nil {
+		uc.publisher.Publish(ctx, eventType, data)
+	}
+}
+
+// outboxEvent 生成一条立即可分发的发件箱记录
+func outboxEvent(eventType, aggregateID string, data interface{}) (*entity.OutboxEvent, error) {
+	return entity.NewOutboxEvent(utils.GenerateEventID(), eventType, aggregateID, data)
+}
+
+// emit 推送实时事件，未配置推送通道时忽略
+func (uc *ChatUseCase) emit(eventName string, data interface{}, userIDs []string) {
+	if uc.emitter == nil || len(userIDs) == 0 {
+		return
+	}
+	_ = uc.emitter.EmitToUsers(eventName, data, userIDs)
+}
+
+// SendMessage 发送消息，只能以自己的身份发送到自己有权访问的会话
 func (uc *ChatUseCase) SendMessage(ctx context.Context, message *entity.Message) error {
+	ctx, span := tracing.Start(ctx, "ChatUseCase.SendMessage")
+	span.SetAttribute("message.id", message.MsgID)
+	span.SetAttribute("session.id", message.SessionID)
+	span.SetAttribute("message.type", message.MsgType)
+	err := uc.sendMessage(ctx, message)
+	if !errors.Is(err, ErrDuplicateMessage) {
+		span.EndWithError(err)
+	} else {
+		span.SetAttribute("message.duplicate", true)
+		span.End()
+	}
+	if message.MsgType != entity.MsgTypeAck {
+		switch {
+		case err == nil:
+			uc.record(MessageEventSent, message)
+		case !errors.Is(err, ErrDuplicateMessage):
+			uc.record(MessageEventFailed, message)
+		}
+	}
+	return err
+}
+
+func (uc *ChatUseCase) sendMessage(ctx context.Context, message *entity.Message) error {
+	if err := uc.authorizeSend(ctx, message); err != nil {
+		return err
+	}
+
 	// 初始化消息时间戳
 	if message.Ts == 0 {
 		message.Ts = entity.StringTimestamp(time.Now().UnixNano() / int64(time.Millisecond))
 	}
 
+	// 幂等: (src, msgId) 相同的重试直接返回已存储的原消息
+	if message.MsgID != "" && message.MsgType != entity.MsgTypeAck {
+		if err := uc.resolveDuplicate(ctx, message); err != nil {
+			return err
+		}
+	}
+
+	// 客服发送快捷回复时由服务端展开变量
+	if message.MsgType == entity.MsgTypeMessage {
+		if err := uc.expandCannedResponse(ctx, message); err != nil {
+			return err
+		}
+	}
+
+	// 校验引用消息
+	if message.ReplyTo != "" && message.MsgType != entity.MsgTypeAck {
+		if err := uc.attachReplyPreview(ctx, message); err != nil {
+			return err
+		}
+	}
+
 	// 处理不同类型的消息
 	switch message.MsgType {
 	case entity.MsgTypeMessage:
@@ -44,24 +205,164 @@ func (uc *ChatUseCase) SendMessage(ctx context.Context, message *entity.Message)
 		return uc.handleNotification(ctx, message)
 	case entity.MsgTypeAck:
 		return uc.handleAck(ctx, message)
+	case entity.MsgTypeInternalNote:
+		return uc.handleInternalNote(ctx, message)
 	default:
 		return errors.New("invalid message type")
 	}
 }
 
+// authorizeSend 校验发送方身份；通知只能由员工或服务内部身份发送，属于会话的消息还要求对会话有访问权
+func (uc *ChatUseCase) authorizeSend(ctx context.Context, message *entity.Message) error {
+	if message.MsgType == entity.MsgTypeAck {
+		return nil // handleAck 按接收方校验
+	}
+	principal, err := authorizeSender(ctx, message)
+	if err != nil {
+		return err
+	}
+	if message.MsgType == entity.MsgTypeNotification && !principal.IsStaff() {
+		return ErrNotificationForbidden
+	}
+	if message.SessionID == "" {
+		return nil
+	}
+
+	session, err := uc.SessionRepo.GetByID(ctx, message.SessionID)
+	if err != nil {
+		return err
+	}
+	if err := authorizeSession(ctx, session); err != nil {
+		return err
+	}
+	// 会话内发给客户的消息只能发给该会话的客户
+	if strings.HasPrefix(message.Dst, "U:") && entity.UserIDFromAddress(message.Dst) != session.CID {
+		return ErrSessionAccessDenied
+	}
+	return nil
+}
+
+// expandCannedResponse Ext 携带快捷回复ID时，用展开后的正文替换消息内容，仅对客服发送的消息生效
+func (uc *ChatUseCase) expandCannedResponse(ctx context.Context, message *entity.Message) error {
+	id, _ := message.Ext[ExtCannedResponseID].(string)
+	if id == "" || uc.canned == nil || !strings.HasPrefix(message.Src, "A:") {
+		return nil
+	}
+
+	content, err := uc.canned.Expand(ctx, id, entity.UserIDFromAddress(message.Src), message.SessionID)
+	if err != nil {
+		return err
+	}
+	message.Content = content
+	return nil
+}
+
+// attachReplyPreview 校验 replyTo 指向同一会话内的消息，并填充引用摘要
+func (uc *ChatUseCase) attachReplyPreview(ctx context.Context, message *entity.Message) error {
+	if message.ReplyTo == message.MsgID {
+		return fmt.Errorf("%w: message cannot reply to itself", ErrInvalidReplyTo)
+	}
+
+	target, err := uc.messageRepo.GetByID(ctx, message.ReplyTo)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: message %s not found", ErrInvalidReplyTo, message.ReplyTo)
+		}
+		return err
+	}
+	if target.SessionID != message.SessionID {
+		return fmt.Errorf("%w: message %s belongs to another session", ErrInvalidReplyTo, message.ReplyTo)
+	}
+	if target.IsInternal() && !message.IsInternal() {
+		// 客户可见的消息不能引用内部备注，否则摘要会泄露给客户
+		return fmt.Errorf("%w: internal notes can only be quoted by internal notes", ErrInvalidReplyTo)
+	}
+
+	message.ReplyPreview = target.Preview()
+	return nil
+}
+
+// resolveDuplicate 检查 msgId 是否已存在：同一发送方的重试用原消息替换 message
+// 并返回 ErrDuplicateMessage，其他发送方占用则返回 ErrMsgIDConflict
+func (uc *ChatUseCase) resolveDuplicate(ctx context.Context, message *entity.Message) error {
+	stored, err := uc.messageRepo.GetByID(ctx, message.MsgID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if stored.Src != message.Src {
+		return ErrMsgIDConflict
+	}
+
+	if stored.ReplyTo != "" {
+		if target, err := uc.messageRepo.GetByID(ctx, stored.ReplyTo); err == nil {
+			stored.ReplyPreview = target.Preview()
+		}
+	}
+	*message = *stored
+	return ErrDuplicateMessage
+}
+
+// createMessage 保存消息，并发重试导致的主键冲突按幂等处理
+func (uc *ChatUseCase) createMessage(ctx context.Context, message *entity.Message) error {
+	err := uc.insertMessage(ctx, message)
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		if dupErr := uc.resolveDuplicate(ctx, message); dupErr != nil {
+			return dupErr
+		}
+	}
+	if err == nil {
+		uc.mirrorToMonitors(*message)
+		uc.dispatcher.Notify()
+	}
+	return err
+}
+
+// insertMessage 写入消息，启用发件箱时同一事务内写入 message.created
+func (uc *ChatUseCase) insertMessage(ctx context.Context, message *entity.Message) error {
+	if uc.outbox == nil {
+		return uc.messageRepo.Create(ctx, message)
+	}
+	event, err := outboxEvent(entity.WebhookEventMessageCreated, message.MsgID, message)
+	if err != nil {
+		return err
+	}
+	return uc.outbox.CreateMessage(ctx, message, event)
+}
+
+// mirrorToMonitors 将会话消息同步给正在监听该会话的主管
+func (uc *ChatUseCase) mirrorToMonitors(message entity.Message) {
+	if uc.emitter == nil || message.SessionID == "" {
+		return
+	}
+	_ = uc.emitter.PushMessageToRoom(message, MonitorRoom(message.SessionID))
+}
+
 // handleChatMessage 处理普通聊天消息
 func (uc *ChatUseCase) handleChatMessage(ctx context.Context, message *entity.Message) error {
+	if uc.outbox != nil {
+		// 直接以已发送状态写入，推送与 webhook 由发件箱分发
+		message.Status = entity.StatusSent
+		return uc.createMessage(ctx, message)
+	}
+
 	// 设置初始状态
 	message.Status = entity.StatusNew
 
 	// 保存消息
-	if err := uc.messageRepo.Create(ctx, message); err != nil {
+	if err := uc.createMessage(ctx, message); err != nil {
 		return err
 	}
 
 	// 更新为已发送状态
 	message.Status = entity.StatusSent
-	return uc.messageRepo.UpdateStatus(ctx, message.MsgID, message.Status)
+	if err := uc.messageRepo.UpdateStatus(ctx, message.MsgID, message.Status); err != nil {
+		return err
+	}
+	uc.publish(ctx, entity.WebhookEventMessageCreated, message)
+	return nil
 }
 
 // handleNotification 处理通知消息
@@ -69,7 +370,7 @@ func (uc *ChatUseCase) handleNotification(ctx context.Context, message *entity.M
 	// 初始化消息不需要会话检查
 	if message.Content == "init" {
 		message.Status = entity.StatusNew
-		return uc.messageRepo.Create(ctx, message)
+		return uc.createMessage(ctx, message)
 	}
 
 	// 其他通知需要会话检查
@@ -79,7 +380,25 @@ func (uc *ChatUseCase) handleNotification(ctx context.Context, message *entity.M
 	}
 
 	message.Status = entity.StatusNew
-	return uc.messageRepo.Create(ctx, message)
+	return uc.createMessage(ctx, message)
+}
+
+// handleInternalNote 处理客服内部备注，接收方只能是客服，默认发给会话的负责客服
+func (uc *ChatUseCase) handleInternalNote(ctx context.Context, message *entity.Message) error {
+	if !strings.HasPrefix(message.Src, "A:") || !PrincipalFromContext(ctx).IsStaff() {
+		return ErrInternalNoteForbidden
+	}
+
+	session, err := uc.SessionRepo.GetByID(ctx, message.SessionID)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(message.Dst, "A:") {
+		message.Dst = "A:" + session.AgentId
+	}
+
+	message.Status = entity.StatusNew
+	return uc.createMessage(ctx, message)
 }
 
 // handleAck 处理确认消息
@@ -89,6 +408,9 @@ func (uc *ChatUseCase) handleAck(ctx context.Context, message *entity.Message) e
 	if err != nil {
 		return err
 	}
+	if err := authorizeRecipient(ctx, original); err != nil {
+		return err
+	}
 
 	// 验证状态转换是否有效
 	switch original.Status {
@@ -97,19 +419,95 @@ func (uc *ChatUseCase) handleAck(ctx context.Context, message *entity.Message) e
 	case entity.StatusNew, entity.StatusSent:
 		original.Status = entity.StatusDelivered
 	default:
-		return errors.New("invalid status transition")
+		return ErrInvalidStatusTransition
 	}
 
 	// 更新消息状态
-	return uc.messageRepo.UpdateStatus(ctx, original.MsgID, original.Status)
+	if err := uc.messageRepo.UpdateStatus(ctx, original.MsgID, original.Status); err != nil {
+		return err
+	}
+	if original.Status == entity.StatusDelivered {
+		uc.record(MessageEventDelivered, original)
+	}
+	return nil
 }
 
-// GetSessionMessages 获取会话消息
-func (uc *ChatUseCase) GetSessionMessages(ctx context.Context, sessionID string) ([]*entity.Message, error) {
+// GetSessionMessages 获取会话消息，只能读取自己有权访问的会话
+func (uc *ChatUseCase) GetSessionMessages(ctx context.Context, sessionID string) (_ []*entity.Message, err error) {
+	ctx, span := tracing.Start(ctx, "ChatUseCase.GetSessionMessages")
+	span.SetAttribute("session.id", sessionID)
+	defer func() { span.EndWithError(err) }()
+
+	session, err := uc.SessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return uc.sessionMessages(ctx, sessionID)
+}
+
+// SessionHistory 客户的一次会话及其消息
+type SessionHistory struct {
+	Session  *entity.Session   `json:"session"`
+	Messages []*entity.Message `json:"messages"`
+}
+
+// GetCustomerHistory 获取客户跨设备的全部会话与消息，按会话开始时间倒序。
+// 客户只能查看自己的历史，客服需接待过该客户的某个会话，主管与管理员不受限制
+func (uc *ChatUseCase) GetCustomerHistory(ctx context.Context, cid string) ([]*SessionHistory, error) {
+	principal, err := authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := uc.SessionRepo.ListByCID(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	if !canViewCustomer(principal, cid, sessions) {
+		return nil, ErrForbidden
+	}
+
+	history := make([]*SessionHistory, 0, len(sessions))
+	for _, session := range sessions {
+		messages, err := uc.sessionMessages(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, &SessionHistory{Session: session, Messages: messages})
+	}
+	return history, nil
+}
+
+func canViewCustomer(principal entity.Principal, cid string, sessions []*entity.Session) bool {
+	switch {
+	case principal.IsSupervisor() || principal.IsSystem():
+		return true
+	case principal.Role == entity.RoleCustomer:
+		return principal.UserID == cid
+	case principal.Role == entity.RoleAgent:
+		for _, session := range sessions {
+			if session.AgentId == principal.UserID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sessionMessages 查询会话消息并填充引用摘要与表情回应，内部备注按请求身份过滤
+func (uc *ChatUseCase) sessionMessages(ctx context.Context, sessionID string) ([]*entity.Message, error) {
 	messages, err := uc.messageRepo.GetBySessionID(ctx, sessionID)
 	if err != nil {
 		return nil, err
 	}
+	messages = PrincipalFromContext(ctx).FilterVisible(messages)
+
+	uc.hydrateReplyPreviews(ctx, messages)
+	if err := uc.hydrateReactions(ctx, sessionID, messages); err != nil {
+		return nil, err
+	}
 
 	// 将历史消息标记为已读状态
 	for _, msg := range messages {
@@ -123,8 +521,65 @@ func (uc *ChatUseCase) GetSessionMessages(ctx context.Context, sessionID string)
 	return messages, nil
 }
 
-// CreateSession 创建会话
+// hydrateReplyPreviews 为引用了其他消息的历史消息填充引用摘要
+func (uc *ChatUseCase) hydrateReplyPreviews(ctx context.Context, messages []*entity.Message) {
+	byID := make(map[string]*entity.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.MsgID] = msg
+	}
+
+	for _, msg := range messages {
+		if msg.ReplyTo == "" {
+			continue
+		}
+		target, ok := byID[msg.ReplyTo]
+		if !ok {
+			// 被引用消息不在当前结果集中(如已删除)，单独查询一次
+			var err error
+			if target, err = uc.messageRepo.GetByID(ctx, msg.ReplyTo); err != nil {
+				continue
+			}
+		}
+		msg.ReplyPreview = target.Preview()
+	}
+}
+
+// GetMessageReplies 获取引用了指定消息的所有回复
+func (uc *ChatUseCase) GetMessageReplies(ctx context.Context, msgID string) ([]*entity.Message, error) {
+	original, err := uc.messageRepo.GetByID(ctx, msgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.authorizeMessage(ctx, original); err != nil {
+		return nil, err
+	}
+	principal := PrincipalFromContext(ctx)
+
+	replies, err := uc.messageRepo.GetReplies(ctx, msgID)
+	if err != nil {
+		return nil, err
+	}
+	replies = principal.FilterVisible(replies)
+
+	preview := original.Preview()
+	for _, reply := range replies {
+		reply.ReplyPreview = preview
+	}
+	return replies, nil
+}
+
+// CreateSession 创建网页渠道的会话
 func (uc *ChatUseCase) CreateSession(ctx context.Context, userID string) (*entity.Session, error) {
+	return uc.CreateChannelSession(ctx, userID, entity.ChannelWeb)
+}
+
+// CreateChannelSession 为客户在指定接入渠道创建会话
+func (uc *ChatUseCase) CreateChannelSession(ctx context.Context, userID, channel string) (_ *entity.Session, err error) {
+	ctx, span := tracing.Start(ctx, "ChatUseCase.CreateChannelSession")
+	span.SetAttribute("session.channel", channel)
+	defer func() { span.EndWithError(err) }()
+
 	// 获取可用客服
 	agents, err := uc.UserRepo.ListAgents(ctx)
 	if err != nil {
@@ -138,46 +593,301 @@ func (uc *ChatUseCase) CreateSession(ctx context.Context, userID string) (*entit
 	}
 
 	session := &entity.Session{
+		ID:      utils.GenerateSessionID(),
 		CID:     userID,
 		AgentId: agentID,
+		Channel: channel,
 		Status:  "active",
 	}
 
-	if err := uc.SessionRepo.Create(ctx, session); err != nil {
+	events := []sessionEvent{{entity.WebhookEventSessionCreated, session}}
+	if agentID != "" {
+		events = append(events, sessionEvent{entity.WebhookEventSessionAssigned, SessionAssignedData{
+			SessionID: session.ID,
+			CID:       session.CID,
+			AgentID:   agentID,
+		}})
+	}
+	err = uc.writeSession(ctx, session.ID, events, func() error {
+		return uc.SessionRepo.Create(ctx, session)
+	}, func(outboxEvents []*entity.OutboxEvent) error {
+		return uc.outbox.CreateSession(ctx, session, outboxEvents...)
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return session, nil
 }
 
+// sessionEvent 会话变更产生的业务事件
+type sessionEvent struct {
+	eventType string
+	data      interface{}
+}
+
+// writeSession 执行会话写入并发布事件。启用发件箱时事件随写入在同一事务内提交，
+// 否则写入成功后直接发布
+func (uc *ChatUseCase) writeSession(ctx context.Context, sessionID string, events []sessionEvent,
+	write func() error, writeWithOutbox func([]*entity.OutboxEvent) error) error {
+	if uc.outbox == nil {
+		if err := write(); err != nil {
+			return err
+		}
+		for _, e := range events {
+			uc.publish(ctx, e.eventType, e.data)
+		}
+		return nil
+	}
+
+	outboxEvents := make([]*entity.OutboxEvent, 0, len(events))
+	for _, e := range events {
+		event, err := outboxEvent(e.eventType, sessionID, e.data)
+		if err != nil {
+			return err
+		}
+		outboxEvents = append(outboxEvents, event)
+	}
+	if err := writeWithOutbox(outboxEvents); err != nil {
+		return err
+	}
+	uc.dispatcher.Notify()
+	return nil
+}
+
+// assignSession 将会话改派给 agentID 并发布 session.assigned
+func (uc *ChatUseCase) assignSession(ctx context.Context, session *entity.Session, agentID string) error {
+	events := []sessionEvent{{entity.WebhookEventSessionAssigned, SessionAssignedData{
+		SessionID:       session.ID,
+		CID:             session.CID,
+		AgentID:         agentID,
+		PreviousAgentID: session.AgentId,
+	}}}
+	err := uc.writeSession(ctx, session.ID, events, func() error {
+		return uc.SessionRepo.UpdateAgent(ctx, session.ID, agentID)
+	}, func(outboxEvents []*entity.OutboxEvent) error {
+		return uc.outbox.AssignSession(ctx, session.ID, agentID, outboxEvents...)
+	})
+	if err != nil {
+		return err
+	}
+	session.AgentId = agentID
+	return nil
+}
+
 // CloseSession 关闭会话
-func (uc *ChatUseCase) CloseSession(ctx context.Context, sessionID string) error {
-	return uc.SessionRepo.UpdateStatus(ctx, sessionID, "closed")
+func (uc *ChatUseCase) CloseSession(ctx context.Context, sessionID string) (err error) {
+	ctx, span := tracing.Start(ctx, "ChatUseCase.CloseSession")
+	span.SetAttribute("session.id", sessionID)
+	defer func() { span.EndWithError(err) }()
+
+	session, err := uc.SessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	return uc.closeSession(ctx, session, CloseReasonManual)
+}
+
+// closeSession 记录结束时间并关闭会话，向双方发送系统通知
+func (uc *ChatUseCase) closeSession(ctx context.Context, session *entity.Session, reason string) error {
+	if session.Status == entity.SessionStatusClosed {
+		return nil
+	}
+
+	session.EndTime = time.Now()
+	closed := *session
+	closed.Status = entity.SessionStatusClosed
+	events := []sessionEvent{{entity.WebhookEventSessionClosed, SessionClosedData{Session: &closed, Reason: reason}}}
+	participants := nonEmpty(session.CID, session.AgentId)
+	notices := systemNotices(session, closeNotice(reason), SystemEventSessionClosed, participants)
+	// 关闭通知与 session.closed 一起提交，进程在提交后崩溃时由发件箱继续投递
+	err := uc.withinTx(ctx, func(ctx context.Context) error {
+		err := uc.writeSession(ctx, session.ID, events, func() error {
+			return uc.SessionRepo.Close(ctx, session.ID, session.EndTime)
+		}, func(outboxEvents []*entity.OutboxEvent) error {
+			return uc.outbox.CloseSession(ctx, session.ID, session.EndTime, outboxEvents...)
+		})
+		if err != nil {
+			return err
+		}
+		return uc.saveNotices(ctx, notices)
+	})
+	if err != nil {
+		return err
+	}
+	session.Status = entity.SessionStatusClosed
+	uc.deliverNotices(notices)
+	uc.emit(EventSessionClosed, SessionClosedEvent{
+		SessionID: session.ID,
+		Reason:    reason,
+		EndTime:   session.EndTime,
+	}, participants)
+
+	// 推送满意度调查，客户离线时可通过 REST 接口获取
+	uc.emit(EventCSATSurvey, newCSATSurvey(session), nonEmpty(session.CID))
+	return nil
+}
+
+// notifySystem 保存一条系统通知并推送给指定用户
+func (uc *ChatUseCase) notifySystem(ctx context.Context, session *entity.Session, content, event string, userIDs []string) error {
+	notices := systemNotices(session, content, event, userIDs)
+	if err := uc.withinTx(ctx, func(ctx context.Context) error {
+		return uc.saveNotices(ctx, notices)
+	}); err != nil {
+		return err
+	}
+	uc.deliverNotices(notices)
+	return nil
+}
+
+// systemNotices 为每个用户构造一条系统通知
+func systemNotices(session *entity.Session, content, event string, userIDs []string) []*entity.Message {
+	notices := make([]*entity.Message, 0, len(userIDs))
+	for _, userID := range userIDs {
+		notices = append(notices, &entity.Message{
+			MsgType:     entity.MsgTypeNotification,
+			SessionID:   session.ID,
+			MsgID:       utils.GenerateMessageID(),
+			Src:         SystemAddress,
+			Dst:         participantAddress(session, userID),
+			Content:     content,
+			ContentType: entity.ContentTypeText,
+			Ts:          entity.StringTimestamp(time.Now().UnixMilli()),
+			Status:      entity.StatusNew,
+			Ext:         map[string]interface{}{SystemEventKey: event},
+			CreatedBy:   SystemAddress,
+			UpdatedBy:   SystemAddress,
+		})
+	}
+	return notices
+}
+
+// saveNotices 保存系统通知，启用发件箱时同时写入 message.created，由分发器推送
+func (uc *ChatUseCase) saveNotices(ctx context.Context, notices []*entity.Message) error {
+	for _, notice := range notices {
+		if err := uc.insertMessage(ctx, notice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliverNotices 在通知提交后推送: 启用发件箱时唤醒分发器，否则直接推送给接收方
+func (uc *ChatUseCase) deliverNotices(notices []*entity.Message) {
+	if len(notices) == 0 {
+		return
+	}
+	// 每个参与者各存一条通知，主管只需看到一次
+	uc.mirrorToMonitors(*notices[0])
+	if uc.outbox != nil {
+		uc.dispatcher.Notify()
+		return
+	}
+	if uc.emitter != nil {
+		for _, notice := range notices {
+			_ = uc.emitter.PushMessage(*notice, []string{entity.UserIDFromAddress(notice.Dst)})
+		}
+	}
+}
+
+// participantAddress 返回会话参与者的消息地址(客户 U:xxx，客服 A:xxx)
+func participantAddress(session *entity.Session, userID string) string {
+	if userID == session.CID {
+		return "U:" + userID
+	}
+	return "A:" + userID
+}
+
+// nonEmpty 过滤空用户ID
+func nonEmpty(ids ...string) []string {
+	var result []string
+	for _, id := range ids {
+		if id != "" {
+			result = append(result, id)
+		}
+	}
+	return result
 }
 
-// GetOfflineMessages 获取离线消息并更新状态
+// GetOfflineMessages 获取发给该用户的离线消息并更新为已送达，只能读取自己的离线消息
 func (uc *ChatUseCase) GetOfflineMessages(ctx context.Context, userID string) ([]*entity.Message, error) {
-	// Get all messages for user
-	allMessages, err := uc.GetSessionMessages(ctx, userID)
+	if err := authorizeSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// 离线消息按接收方地址查询，地址前缀取决于接收方角色
+	recipient := PrincipalFromContext(ctx)
+	if recipient.UserID != userID {
+		user, err := uc.UserRepo.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		recipient = entity.Principal{UserID: user.ID, Role: user.Role}
+	}
+	offline, err := uc.messageRepo.GetOfflineByDst(ctx, recipient.Address())
 	if err != nil {
 		return nil, err
 	}
+	offline = recipient.FilterVisible(offline)
+	uc.hydrateReplyPreviews(ctx, offline)
 
 	var messages []*entity.Message
-	for _, msg := range allMessages {
-		if msg.Status == entity.StatusOffline {
-			// Update status to delivered
-			if err := uc.ProcessMessageStatus(ctx, msg.MsgID, entity.StatusDelivered); err == nil {
-				messages = append(messages, msg)
-			}
+	for _, msg := range offline {
+		if err := uc.ProcessMessageStatus(ctx, msg.MsgID, entity.StatusDelivered); err == nil {
+			msg.Status = entity.StatusDelivered
+			messages = append(messages, msg)
 		}
 	}
 
 	return messages, nil
 }
 
+// AcknowledgeMessage 接收方确认已读消息
+func (uc *ChatUseCase) AcknowledgeMessage(ctx context.Context, msgID string) (err error) {
+	ctx, span := tracing.Start(ctx, "ChatUseCase.AcknowledgeMessage")
+	span.SetAttribute("message.id", msgID)
+	defer func() { span.EndWithError(err) }()
+
+	message, err := uc.messageRepo.GetByID(ctx, msgID)
+	if err != nil {
+		return err
+	}
+	if err := authorizeRecipient(ctx, message); err != nil {
+		return err
+	}
+	return uc.ProcessMessageStatus(ctx, msgID, entity.StatusRead)
+}
+
+// authorizeMessage 消息所在会话对请求身份可访问，且内部备注只对员工可见
+func (uc *ChatUseCase) authorizeMessage(ctx context.Context, message *entity.Message) error {
+	if !PrincipalFromContext(ctx).CanSee(message) {
+		return repository.ErrNotFound
+	}
+	session, err := uc.SessionRepo.GetByID(ctx, message.SessionID)
+	if err != nil {
+		return err
+	}
+	return authorizeSession(ctx, session)
+}
+
+// authorizeRecipient 只有消息接收方可以确认消息
+func authorizeRecipient(ctx context.Context, message *entity.Message) error {
+	principal, err := authenticated(ctx)
+	if err != nil {
+		return err
+	}
+	if !principal.IsSystem() && entity.UserIDFromAddress(message.Dst) != principal.UserID {
+		return ErrAckForbidden
+	}
+	return nil
+}
+
 // ProcessMessageStatus 处理消息状态更新
-func (uc *ChatUseCase) ProcessMessageStatus(ctx context.Context, msgID string, newStatus uint8) error {
+func (uc *ChatUseCase) ProcessMessageStatus(ctx context.Context, msgID string, newStatus uint8) (err error) {
+	ctx, span := tracing.Start(ctx, "ChatUseCase.ProcessMessageStatus")
+	span.SetAttribute("message.id", msgID)
+	span.SetAttribute("message.status", newStatus)
+	defer func() { span.EndWithError(err) }()
+
 	// 获取消息
 	message, err := uc.messageRepo.GetByID(ctx, msgID)
 	if err != nil {
@@ -192,9 +902,15 @@ func (uc *ChatUseCase) ProcessMessageStatus(ctx context.Context, msgID string, n
 	case message.Status == entity.StatusOffline && newStatus == entity.StatusDelivered:
 	case newStatus == entity.StatusRecall: // 允许从多个状态撤回
 	default:
-		return errors.New("invalid status transition")
+		return ErrInvalidStatusTransition
 	}
 
 	// 更新状态
-	return uc.messageRepo.UpdateStatus(ctx, msgID, newStatus)
+	if err := uc.messageRepo.UpdateStatus(ctx, msgID, newStatus); err != nil {
+		return err
+	}
+	if newStatus == entity.StatusDelivered {
+		uc.record(MessageEventDelivered, message)
+	}
+	return nil
 }
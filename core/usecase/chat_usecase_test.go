@@ -0,0 +1,243 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+// newMemoryChatUseCase 创建基于内存仓储的聊天用例，并写入客户 c1 与客服 a1 的会话 s1
+func newMemoryChatUseCase(t *testing.T) (*usecase.ChatUseCase, *infrarepo.MemoryMessageRepository) {
+	t.Helper()
+	messages := infrarepo.NewMemoryMessageRepository()
+	sessions := infrarepo.NewMemorySessionRepository()
+	uc := usecase.NewChatUseCase(messages, sessions, infrarepo.NewMemoryUserRepository(), infrarepo.NewMemoryReactionRepository())
+	session := &entity.Session{ID: "s1", CID: "c1", AgentId: "a1", Status: entity.SessionStatusActive, CreatedAt: time.Now()}
+	if err := sessions.Create(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	return uc, messages
+}
+
+func principalContext(userID, role string) context.Context {
+	return usecase.WithPrincipal(context.Background(), entity.Principal{UserID: userID, Role: role})
+}
+
+func TestSendMessageAuthorization(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		message entity.Message
+		want    error
+	}{
+		{
+			name:    "customer message in own session",
+			ctx:     principalContext("c1", entity.RoleCustomer),
+			message: entity.Message{MsgType: entity.MsgTypeMessage, SessionID: "s1", Src: "U:c1", Dst: "A:a1"},
+		},
+		{
+			name:    "customer message in another session",
+			ctx:     principalContext("c2", entity.RoleCustomer),
+			message: entity.Message{MsgType: entity.MsgTypeMessage, SessionID: "s1", Src: "U:c2", Dst: "A:a1"},
+			want:    usecase.ErrSessionAccessDenied,
+		},
+		{
+			name:    "customer notification",
+			ctx:     principalContext("c1", entity.RoleCustomer),
+			message: entity.Message{MsgType: entity.MsgTypeNotification, SessionID: "s1", Src: "U:c1", Dst: "A:a1"},
+			want:    usecase.ErrNotificationForbidden,
+		},
+		{
+			name:    "agent notification in another agent's session",
+			ctx:     principalContext("a2", entity.RoleAgent),
+			message: entity.Message{MsgType: entity.MsgTypeNotification, SessionID: "s1", Src: "A:a2", Dst: "U:c1"},
+			want:    usecase.ErrSessionAccessDenied,
+		},
+		{
+			name:    "agent message to a customer outside the session",
+			ctx:     principalContext("a1", entity.RoleAgent),
+			message: entity.Message{MsgType: entity.MsgTypeMessage, SessionID: "s1", Src: "A:a1", Dst: "U:c2"},
+			want:    usecase.ErrSessionAccessDenied,
+		},
+		{
+			name:    "agent internal note in own session",
+			ctx:     principalContext("a1", entity.RoleAgent),
+			message: entity.Message{MsgType: entity.MsgTypeInternalNote, SessionID: "s1", Src: "A:a1"},
+		},
+		{
+			name:    "agent internal note in another agent's session",
+			ctx:     principalContext("a2", entity.RoleAgent),
+			message: entity.Message{MsgType: entity.MsgTypeInternalNote, SessionID: "s1", Src: "A:a2"},
+			want:    usecase.ErrSessionAccessDenied,
+		},
+		{
+			name:    "supervisor internal note in any session",
+			ctx:     principalContext("sup", entity.RoleSupervisor),
+			message: entity.Message{MsgType: entity.MsgTypeInternalNote, SessionID: "s1", Src: "A:sup"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newMemoryChatUseCase(t)
+			message := tt.message
+			message.Content = "hello"
+			message.ContentType = entity.ContentTypeText
+			err := uc.SendMessage(tt.ctx, &message)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("SendMessage error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOfflineMessagesByRecipient(t *testing.T) {
+	uc, messages := newMemoryChatUseCase(t)
+	ctx := context.Background()
+	for _, m := range []*entity.Message{
+		{MsgID: "m1", SessionID: "s1", Src: "A:a1", Dst: "U:c1", MsgType: entity.MsgTypeMessage, Ts: 1, Status: entity.StatusOffline},
+		{MsgID: "m2", SessionID: "s1", Src: "A:a1", Dst: "U:c1", MsgType: entity.MsgTypeMessage, Ts: 2, Status: entity.StatusRead},
+		{MsgID: "m3", SessionID: "s1", Src: "U:c1", Dst: "A:a1", MsgType: entity.MsgTypeMessage, Ts: 3, Status: entity.StatusOffline},
+	} {
+		if err := messages.Create(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := uc.GetOfflineMessages(principalContext("c1", entity.RoleCustomer), "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].MsgID != "m1" {
+		t.Fatalf("GetOfflineMessages returned %v, want only m1", got)
+	}
+	stored, _ := messages.GetByID(ctx, "m1")
+	if stored.Status != entity.StatusDelivered {
+		t.Errorf("m1 status = %d, want delivered", stored.Status)
+	}
+
+	got, err = uc.GetOfflineMessages(principalContext("a1", entity.RoleAgent), "a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].MsgID != "m3" {
+		t.Fatalf("GetOfflineMessages returned %v, want only m3", got)
+	}
+}
+
+func TestReadAuthorization(t *testing.T) {
+	uc, messages := newMemoryChatUseCase(t)
+	ctx := context.Background()
+	for _, m := range []*entity.Message{
+		{MsgID: "m1", SessionID: "s1", Src: "U:c1", Dst: "A:a1", MsgType: entity.MsgTypeMessage, Content: "hi", Ts: 1},
+		{MsgID: "m2", SessionID: "s1", Src: "A:a1", Dst: "A:a1", MsgType: entity.MsgTypeInternalNote, Content: "note", Ts: 2},
+	} {
+		if err := messages.Create(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := uc.GetSessionMessages(principalContext("c2", entity.RoleCustomer), "s1"); !errors.Is(err, usecase.ErrSessionAccessDenied) {
+		t.Errorf("other customer: error = %v, want ErrSessionAccessDenied", err)
+	}
+	if _, err := uc.GetSessionMessages(principalContext("a2", entity.RoleAgent), "s1"); !errors.Is(err, usecase.ErrSessionAccessDenied) {
+		t.Errorf("unassigned agent: error = %v, want ErrSessionAccessDenied", err)
+	}
+	if _, err := uc.GetSessionMessages(context.Background(), "s1"); !errors.Is(err, usecase.ErrUnauthenticated) {
+		t.Errorf("anonymous: error = %v, want ErrUnauthenticated", err)
+	}
+	if _, err := uc.GetOfflineMessages(principalContext("c2", entity.RoleCustomer), "c1"); !errors.Is(err, usecase.ErrForbidden) {
+		t.Errorf("offline messages of another user: error = %v, want ErrForbidden", err)
+	}
+
+	// 客户看不到内部备注，主管可以查看任意会话
+	visible, err := uc.GetSessionMessages(principalContext("c1", entity.RoleCustomer), "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visible) != 1 || visible[0].MsgID != "m1" {
+		t.Errorf("customer sees %d messages, want only m1", len(visible))
+	}
+	all, err := uc.GetSessionMessages(principalContext("sup", entity.RoleSupervisor), "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("supervisor sees %d messages, want 2", len(all))
+	}
+}
+
+func TestInternalNotesHiddenFromCustomer(t *testing.T) {
+	uc, messages := newMemoryChatUseCase(t)
+	ctx := context.Background()
+	customer := principalContext("c1", entity.RoleCustomer)
+	agent := principalContext("a1", entity.RoleAgent)
+
+	question := &entity.Message{MsgID: "m1", SessionID: "s1", Src: "U:c1", Dst: "A:a1", MsgType: entity.MsgTypeMessage, Content: "Where is my order?"}
+	if err := uc.SendMessage(customer, question); err != nil {
+		t.Fatal(err)
+	}
+	spoofed := &entity.Message{MsgID: "n0", SessionID: "s1", Src: "U:c1", Dst: "A:a1", MsgType: entity.MsgTypeInternalNote, Content: "fake note"}
+	if err := uc.SendMessage(customer, spoofed); !errors.Is(err, usecase.ErrInternalNoteForbidden) {
+		t.Fatalf("customer note error = %v, want ErrInternalNoteForbidden", err)
+	}
+
+	// 发给客户地址的备注改为发给负责客服
+	note := &entity.Message{MsgID: "n1", SessionID: "s1", Src: "A:a1", Dst: "U:c1", MsgType: entity.MsgTypeInternalNote,
+		Content: "VIP, refund without asking", ReplyTo: "m1"}
+	if err := uc.SendMessage(agent, note); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := messages.GetByID(ctx, "n1"); stored.Dst != "A:a1" {
+		t.Fatalf("note dst = %s, want A:a1", stored.Dst)
+	}
+	// 即使存储中有发给客户的备注，离线消息也不会交给客户
+	leaked := &entity.Message{MsgID: "n2", SessionID: "s1", Src: "A:a1", Dst: "U:c1", MsgType: entity.MsgTypeInternalNote, Content: "leaked", Status: entity.StatusOffline, Ts: 2}
+	if err := messages.Create(ctx, leaked); err != nil {
+		t.Fatal(err)
+	}
+
+	visible, err := uc.GetSessionMessages(customer, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range visible {
+		if msg.IsInternal() {
+			t.Errorf("customer sees internal note %s", msg.MsgID)
+		}
+	}
+	if all, err := uc.GetSessionMessages(agent, "s1"); err != nil || len(all) != len(visible)+2 {
+		t.Errorf("agent sees %d messages, %v, want the customer's %d plus 2 notes", len(all), err, len(visible))
+	}
+
+	replies, err := uc.GetMessageReplies(customer, "m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 0 {
+		t.Errorf("customer sees %d replies to m1, want the note hidden", len(replies))
+	}
+	if replies, err := uc.GetMessageReplies(agent, "m1"); err != nil || len(replies) != 1 {
+		t.Errorf("agent sees %d replies to m1, %v, want the note", len(replies), err)
+	}
+
+	offline, err := uc.GetOfflineMessages(customer, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range offline {
+		if msg.IsInternal() {
+			t.Errorf("offline messages for the customer include note %s", msg.MsgID)
+		}
+	}
+
+	// 普通回复不能引用内部备注，避免在摘要中泄露备注内容
+	quote := &entity.Message{MsgID: "m2", SessionID: "s1", Src: "A:a1", Dst: "U:c1", MsgType: entity.MsgTypeMessage, Content: "Done", ReplyTo: "n1"}
+	if err := uc.SendMessage(agent, quote); !errors.Is(err, usecase.ErrInvalidReplyTo) {
+		t.Fatalf("reply quoting a note error = %v, want ErrInvalidReplyTo", err)
+	}
+}
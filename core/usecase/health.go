@@ -1 +1,159 @@
 package usecase
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHealthCheckTimeout 未配置时单个组件检查的超时时间
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// 健康状态
+const (
+	HealthStatusUp           = "up"
+	HealthStatusDown         = "down"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+// HealthCheck 检查一个依赖组件，返回 nil 表示可用；需遵守 ctx 的超时
+type HealthCheck func(ctx context.Context) error
+
+// ComponentHealth 单个组件的检查结果
+type ComponentHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport 存活或就绪检查的汇总结果，Status 为 up 时所有组件都可用
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// Up 服务存活或已就绪
+func (r HealthReport) Up() bool {
+	return r.Status == HealthStatusUp
+}
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthUseCase 组件检查的注册表。存活检查只包含进程自身无法恢复的故障，失败时应重启进程；
+// 就绪检查包含存活检查与全部依赖，失败时负载均衡应暂停转发流量
+type HealthUseCase struct {
+	mu           sync.RWMutex
+	liveness     []namedCheck
+	readiness    []namedCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewHealthUseCase 创建健康检查注册表，timeout 为单个组件检查的超时时间
+func NewHealthUseCase(timeout time.Duration) *HealthUseCase {
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	return &HealthUseCase{timeout: timeout}
+}
+
+// AddLivenessCheck 注册存活检查，同时计入就绪检查
+func (uc *HealthUseCase) AddLivenessCheck(name string, check HealthCheck) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.liveness = append(uc.liveness, namedCheck{name: name, check: check})
+}
+
+// AddReadinessCheck 注册就绪检查；与存活检查同名时在就绪检查中替代它，用于更严格的检查
+func (uc *HealthUseCase) AddReadinessCheck(name string, check HealthCheck) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.readiness = append(uc.readiness, namedCheck{name: name, check: check})
+}
+
+// SetShuttingDown 进入优雅关闭，此后就绪检查始终返回 shutting_down
+func (uc *HealthUseCase) SetShuttingDown() {
+	uc.shuttingDown.Store(true)
+}
+
+// Liveness 执行存活检查
+func (uc *HealthUseCase) Liveness(ctx context.Context) HealthReport {
+	uc.mu.RLock()
+	checks := append([]namedCheck(nil), uc.liveness...)
+	uc.mu.RUnlock()
+	return uc.run(ctx, checks)
+}
+
+// Readiness 执行存活与就绪检查；关闭期间仍执行检查以便排查，但状态为 shutting_down
+func (uc *HealthUseCase) Readiness(ctx context.Context) HealthReport {
+	uc.mu.RLock()
+	replaced := make(map[string]bool, len(uc.readiness))
+	for _, c := range uc.readiness {
+		replaced[c.name] = true
+	}
+	var checks []namedCheck
+	for _, c := range uc.liveness {
+		if !replaced[c.name] {
+			checks = append(checks, c)
+		}
+	}
+	checks = append(checks, uc.readiness...)
+	uc.mu.RUnlock()
+	report := uc.run(ctx, checks)
+	if uc.shuttingDown.Load() {
+		report.Status = HealthStatusShuttingDown
+	}
+	return report
+}
+
+// run 并发执行检查，每个检查单独计时与超时
+func (uc *HealthUseCase) run(ctx context.Context, checks []namedCheck) HealthReport {
+	report := HealthReport{Status: HealthStatusUp, Components: make([]ComponentHealth, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Components[i] = uc.check(ctx, c)
+		}()
+	}
+	wg.Wait()
+	for _, component := range report.Components {
+		if component.Status != HealthStatusUp {
+			report.Status = HealthStatusDown
+		}
+	}
+	return report
+}
+
+// check 执行单个检查；检查未在超时内返回时按超时失败处理，不等待其结束
+func (uc *HealthUseCase) check(ctx context.Context, c namedCheck) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, uc.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := ComponentHealth{
+		Name:      c.name,
+		Status:    HealthStatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
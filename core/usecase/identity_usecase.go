@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultIdentityTokenMaxAge 未配置时主站身份令牌签发后的最长可用时间
+	DefaultIdentityTokenMaxAge = 5 * time.Minute
+	maxExternalIDLen           = 100
+)
+
+var (
+	// ErrIdentityDisabled 未配置主站身份令牌的验证密钥
+	ErrIdentityDisabled = errors.New("identity linking is not configured")
+	// ErrInvalidIdentityToken 主站身份令牌签名、有效期或内容校验失败
+	ErrInvalidIdentityToken = errors.New("identity token is invalid or expired")
+)
+
+// IdentifyResult 身份关联结果；ClandCID 为关联后的客户 CID，客户端需改用新令牌重新建立连接
+type IdentifyResult struct {
+	TokenPair
+	ClandCID       string `json:"clandCid"`
+	ExternalID     string `json:"externalId"`
+	Merged         bool   `json:"merged"`         // 当前访客记录是否已并入已有客户
+	MergedSessions int64  `json:"mergedSessions"` // 并入的会话数
+}
+
+// IdentityUseCase 将匿名访客 CID 关联到主站客户 ID，同一客户在不同设备上的会话与历史合并到同一条记录
+type IdentityUseCase struct {
+	verifier     *utils.JWTManager
+	maxAge       time.Duration
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	authUC       *AuthUseCase
+	log          *zap.Logger
+}
+
+// NewIdentityUseCase 创建身份关联用例；verifier 为 nil 表示未启用
+func NewIdentityUseCase(
+	verifier *utils.JWTManager,
+	maxAge time.Duration,
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	authUC *AuthUseCase,
+	log *zap.Logger,
+) *IdentityUseCase {
+	if maxAge <= 0 {
+		maxAge = DefaultIdentityTokenMaxAge
+	}
+	return &IdentityUseCase{
+		verifier:     verifier,
+		maxAge:       maxAge,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		authUC:       authUC,
+		log:          log.Named("identity"),
+	}
+}
+
+// Identify 校验主站签发的身份令牌并关联当前访客：
+// 该主站客户尚无记录时直接关联当前 CID；已有记录时把当前匿名访客的会话与消息并入已有记录；
+// 当前 CID 已关联其他主站客户（共用设备）时不合并，切换到该客户自己的记录。
+func (uc *IdentityUseCase) Identify(ctx context.Context, identityToken string) (*IdentifyResult, error) {
+	principal, err := requireRole(ctx, entity.RoleCustomer)
+	if err != nil {
+		return nil, err
+	}
+	if uc.verifier == nil {
+		return nil, ErrIdentityDisabled
+	}
+	claims, err := uc.verify(identityToken)
+	if err != nil {
+		return nil, err
+	}
+	externalID := strings.TrimSpace(claims.Subject)
+
+	current, err := uc.userRepo.GetByID(ctx, principal.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, err
+	}
+
+	result := &IdentifyResult{ExternalID: externalID}
+	customer, err := uc.resolve(ctx, current, externalID, result)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.updateProfile(ctx, customer, claims); err != nil {
+		return nil, err
+	}
+
+	if result.Merged {
+		// 匿名记录已不存在，其刷新令牌一并作废
+		if err := uc.authUC.RevokeUser(ctx, current.ID); err != nil {
+			return nil, err
+		}
+		uc.log.Info("Merged visitor into customer",
+			zap.String("from", current.ID), zap.String("into", customer.ID),
+			zap.Int64("sessions", result.MergedSessions))
+	}
+
+	tokens, err := uc.authUC.IssueTokens(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	result.TokenPair = *tokens
+	result.ClandCID = customer.ID
+	return result, nil
+}
+
+// verify 校验签名、签发方、受众与签发时间
+func (uc *IdentityUseCase) verify(identityToken string) (*utils.IdentityClaims, error) {
+	claims := &utils.IdentityClaims{}
+	if err := uc.verifier.ParseWithClaims(identityToken, claims); err != nil {
+		return nil, ErrInvalidIdentityToken
+	}
+	externalID := strings.TrimSpace(claims.Subject)
+	if externalID == "" || len(externalID) > maxExternalIDLen {
+		return nil, ErrInvalidIdentityToken
+	}
+	if claims.IssuedAt == nil || time.Since(claims.IssuedAt.Time) > uc.maxAge {
+		return nil, ErrInvalidIdentityToken
+	}
+	return claims, nil
+}
+
+// resolve 找到或建立 externalID 对应的客户记录
+func (uc *IdentityUseCase) resolve(ctx context.Context, current *entity.User, externalID string, result *IdentifyResult) (*entity.User, error) {
+	if current.ExternalID == externalID {
+		return current, nil
+	}
+
+	customer, err := uc.userRepo.GetByExternalID(ctx, externalID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	switch {
+	case customer == nil && current.ExternalID == "":
+		current.ExternalID = externalID
+		current.UpdatedBy = current.ID
+		err := uc.userRepo.Update(ctx, current)
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			// 另一台设备同时完成了关联，改为并入它
+			current.ExternalID = ""
+			return uc.resolve(ctx, current, externalID, result)
+		}
+		return current, err
+
+	case customer == nil:
+		customer = newCustomer(utils.GenerateClandCID(), "")
+		customer.ExternalID = externalID
+		err := uc.userRepo.Create(ctx, customer)
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return uc.resolve(ctx, current, externalID, result)
+		}
+		return customer, err
+
+	case current.ExternalID == "":
+		sessions, err := uc.identityRepo.MergeCustomer(ctx, current.ID, customer.ID, customer.ID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrTokenInvalid
+			}
+			return nil, err
+		}
+		result.Merged = true
+		result.MergedSessions = sessions
+		return customer, nil
+
+	default:
+		return customer, nil
+	}
+}
+
+// updateProfile 用主站提供的姓名与邮箱补全客户资料，不合法的值忽略
+func (uc *IdentityUseCase) updateProfile(ctx context.Context, customer *entity.User, claims *utils.IdentityClaims) error {
+	name := strings.TrimSpace(claims.Name)
+	if len(name) > maxDisplayNameLen {
+		name = ""
+	}
+	email := strings.TrimSpace(claims.Email)
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			email = ""
+		}
+	}
+
+	changed := false
+	if name != "" && name != customer.DisplayName {
+		customer.DisplayName = name
+		changed = true
+	}
+	if email != "" && email != customer.Email {
+		customer.Email = email
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	customer.UpdatedBy = customer.ID
+	return uc.userRepo.Update(ctx, customer)
+}
@@ -0,0 +1,18 @@
+package usecase
+
+import "time"
+
+// 消息指标事件
+const (
+	MessageEventSent      = "sent"      // 消息已保存并进入推送流程
+	MessageEventDelivered = "delivered" // 消息状态变为已送达
+	MessageEventFailed    = "failed"    // 发送请求未能保存消息
+)
+
+// Metrics 业务指标记录器，由基础设施层实现(如 Prometheus)，未设置时不记录
+type Metrics interface {
+	// MessageEvent 按消息类型与内容类型记录一次消息事件
+	MessageEvent(event string, msgType, contentType uint8)
+	// OutboxPublished 记录发件箱记录从写入到分发成功的等待时间
+	OutboxPublished(eventType string, wait time.Duration)
+}
@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// 未配置时的离线提醒参数
+	DefaultOfflineNotifyDelay       = 15 * time.Minute
+	DefaultOfflineNotifyMinInterval = time.Hour
+	DefaultOfflineNotifyBatch       = 100
+
+	// ChatURLSessionPlaceholder 会话链接模板中替换为会话 ID 的占位符
+	ChatURLSessionPlaceholder = "{sessionId}"
+)
+
+// OfflineNotifier 向离线客户发送未读回复摘要，由基础设施层实现(邮件等)
+type OfflineNotifier interface {
+	NotifyOffline(ctx context.Context, digest *entity.OfflineDigest) error
+}
+
+// PresenceChecker 查询用户当前是否有在线连接
+type PresenceChecker interface {
+	IsOnline(userID string) bool
+}
+
+// OfflineNotifyOptions 离线提醒配置
+type OfflineNotifyOptions struct {
+	Delay       time.Duration  // 客服回复后客户持续离线多久才提醒
+	MinInterval time.Duration  // 同一客户两次提醒的最短间隔
+	Batch       int            // 每轮最多提醒的客户数
+	ChatURL     string         // 返回会话的链接模板，{sessionId} 替换为最近的会话 ID
+	Timezone    *time.Location // 摘要中时间使用的时区，nil 为 UTC
+}
+
+// OfflineNotifyUseCase 客服回复推送时客户离线，持续离线超过 Delay 后通过 OfflineNotifier 发送未读回复摘要。
+// 每轮扫描从消息状态推导待提醒的回复，客户重新连接并读取消息后回复不再是离线状态，提醒随之取消
+type OfflineNotifyUseCase struct {
+	repo     repository.OfflineNotificationRepository
+	userRepo repository.UserRepository
+	notifier OfflineNotifier
+	presence PresenceChecker
+	opts     OfflineNotifyOptions
+	log      *zap.Logger
+}
+
+// NewOfflineNotifyUseCase 创建离线提醒用例；presence 为 nil 时只按消息状态判断
+func NewOfflineNotifyUseCase(repo repository.OfflineNotificationRepository, userRepo repository.UserRepository,
+	notifier OfflineNotifier, presence PresenceChecker, opts OfflineNotifyOptions, log *zap.Logger) *OfflineNotifyUseCase {
+	if opts.Delay <= 0 {
+		opts.Delay = DefaultOfflineNotifyDelay
+	}
+	if opts.MinInterval <= 0 {
+		opts.MinInterval = DefaultOfflineNotifyMinInterval
+	}
+	if opts.Batch <= 0 {
+		opts.Batch = DefaultOfflineNotifyBatch
+	}
+	if opts.Timezone == nil {
+		opts.Timezone = time.UTC
+	}
+	return &OfflineNotifyUseCase{
+		repo:     repo,
+		userRepo: userRepo,
+		notifier: notifier,
+		presence: presence,
+		opts:     opts,
+		log:      log.Named("offline-notify"),
+	}
+}
+
+// Run 按固定间隔扫描，直到 ctx 取消
+func (uc *OfflineNotifyUseCase) Run(ctx context.Context, interval time.Duration) {
+	ctx = WithSystemPrincipal(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	uc.log.Info("Offline notifier started", zap.Duration("interval", interval), zap.Duration("delay", uc.opts.Delay))
+	for {
+		select {
+		case <-ctx.Done():
+			uc.log.Info("Offline notifier stopped")
+			return
+		case <-ticker.C:
+			if _, err := uc.RunOnce(ctx, time.Now()); err != nil {
+				uc.log.Error("Offline notification scan failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RunOnce 向待提醒的客户发送摘要，返回发送的数量。单个客户失败只记录错误，下一轮重试
+func (uc *OfflineNotifyUseCase) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	cids, err := uc.repo.ListPendingCustomers(ctx, now.Add(-uc.opts.Delay), now.Add(-uc.opts.MinInterval), uc.opts.Batch)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, cid := range cids {
+		ok, err := uc.notify(ctx, cid, now)
+		if err != nil {
+			uc.log.Error("Failed to notify offline customer", zap.String("cid", cid), zap.Error(err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// notify 在发送前重新检查在线状态、发送频率与未读回复，返回是否已发送
+func (uc *OfflineNotifyUseCase) notify(ctx context.Context, cid string, now time.Time) (bool, error) {
+	if uc.presence != nil && uc.presence.IsOnline(cid) {
+		return false, nil
+	}
+
+	state, err := uc.repo.GetState(ctx, cid)
+	if errors.Is(err, repository.ErrNotFound) {
+		state = &entity.OfflineNotification{CID: cid}
+	} else if err != nil {
+		return false, err
+	}
+	if !state.LastSentAt.IsZero() && now.Sub(state.LastSentAt) < uc.opts.MinInterval {
+		return false, nil
+	}
+
+	replies, err := uc.repo.ListPendingReplies(ctx, cid, state.LastMsgTs)
+	if err != nil || len(replies) == 0 {
+		return false, err
+	}
+	// 最早一条未读回复离线时间未达到 Delay 时等待下一轮，届时一并提醒后续回复
+	if now.Sub(time.UnixMilli(int64(replies[0].Ts))) < uc.opts.Delay {
+		return false, nil
+	}
+
+	customer, err := uc.userRepo.GetByID(ctx, cid)
+	if err != nil {
+		return false, err
+	}
+	if customer.Email == "" {
+		return false, nil
+	}
+
+	digest := &entity.OfflineDigest{
+		CID:      cid,
+		Name:     customer.Name(),
+		Email:    customer.Email,
+		Timezone: uc.opts.Timezone.String(),
+		Replies:  make([]entity.OfflineReply, 0, len(replies)),
+	}
+	names := make(map[string]string)
+	for _, msg := range replies {
+		reply := entity.OfflineReply{
+			MsgID:      msg.MsgID,
+			SessionID:  msg.SessionID,
+			SenderName: uc.senderName(ctx, msg.Src, names),
+			SentAt:     time.UnixMilli(int64(msg.Ts)).In(uc.opts.Timezone),
+			Content:    msg.Content,
+		}
+		if msg.ContentType == entity.ContentTypeImage || msg.ContentType == entity.ContentTypeFile {
+			reply.AttachmentURL = msg.Content
+		}
+		digest.Replies = append(digest.Replies, reply)
+	}
+	last := replies[len(replies)-1]
+	if uc.opts.ChatURL != "" {
+		digest.ChatURL = strings.ReplaceAll(uc.opts.ChatURL, ChatURLSessionPlaceholder, last.SessionID)
+	}
+
+	if err := uc.notifier.NotifyOffline(ctx, digest); err != nil {
+		return false, err
+	}
+	state.LastSentAt = now
+	state.LastMsgTs = int64(last.Ts)
+	if err := uc.repo.SaveState(ctx, state); err != nil {
+		return true, err
+	}
+	uc.log.Info("Offline digest sent", zap.String("cid", cid), zap.Int("replies", len(replies)))
+	return true, nil
+}
+
+// senderName 发送方展示名称，用户不存在时使用 ID
+func (uc *OfflineNotifyUseCase) senderName(ctx context.Context, src string, cache map[string]string) string {
+	userID := entity.UserIDFromAddress(src)
+	if name, ok := cache[userID]; ok {
+		return name
+	}
+	name := userID
+	if user, err := uc.userRepo.GetByID(ctx, userID); err == nil {
+		name = user.Name()
+	}
+	cache[userID] = name
+	return name
+}
@@ -0,0 +1,217 @@
+package usecase_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// recordingNotifier 记录发出的离线摘要
+type recordingNotifier struct {
+	mu      sync.Mutex
+	digests []*entity.OfflineDigest
+}
+
+func (n *recordingNotifier) NotifyOffline(ctx context.Context, digest *entity.OfflineDigest) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.digests = append(n.digests, digest)
+	return nil
+}
+
+func (n *recordingNotifier) replies(i int) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var ids []string
+	for _, reply := range n.digests[i].Replies {
+		ids = append(ids, reply.MsgID)
+	}
+	return ids
+}
+
+// onlineSet 在线用户集合
+type onlineSet map[string]bool
+
+func (s onlineSet) IsOnline(userID string) bool { return s[userID] }
+
+// offlineFixture 同一存储上的消息、用户与离线提醒仓储
+type offlineFixture struct {
+	messages repository.MessageRepository
+	sessions repository.SessionRepository
+	users    repository.UserRepository
+	offline  repository.OfflineNotificationRepository
+}
+
+func offlineFixtures() map[string]func(t *testing.T) offlineFixture {
+	return map[string]func(t *testing.T) offlineFixture{
+		"memory": func(t *testing.T) offlineFixture {
+			messages := infrarepo.NewMemoryMessageRepository()
+			users := infrarepo.NewMemoryUserRepository()
+			return offlineFixture{
+				messages: messages,
+				sessions: infrarepo.NewMemorySessionRepository(),
+				users:    users,
+				offline:  infrarepo.NewMemoryOfflineNotificationRepository(messages, users),
+			}
+		},
+		"sqlite": func(t *testing.T) offlineFixture {
+			repos := newSQLiteRepository(t)
+			return offlineFixture{
+				messages: repos.messages,
+				sessions: repos.sessions,
+				users:    repos.users,
+				offline:  infrarepo.NewSQLiteOfflineNotificationRepository(repos.base),
+			}
+		},
+	}
+}
+
+// seed 写入客户 c1(留有邮箱)、c2 与客服 a1，以及各自的会话
+func (f offlineFixture) seed(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	for _, user := range []*entity.User{
+		{ID: "c1", UID: "c1", Username: "ann", DisplayName: "Ann", Email: "ann@example.com", Role: entity.RoleCustomer, CreatedBy: "c1", UpdatedBy: "c1"},
+		{ID: "c2", UID: "c2", Username: "cid", DisplayName: "Cid", Email: "cid@example.com", Role: entity.RoleCustomer, CreatedBy: "c2", UpdatedBy: "c2"},
+		{ID: "c3", UID: "c3", Username: "guest_c3", Role: entity.RoleCustomer, CreatedBy: "c3", UpdatedBy: "c3"},
+		{ID: "a1", UID: "a1", Username: "bob", DisplayName: "Bob", Role: entity.RoleAgent, CreatedBy: "admin", UpdatedBy: "admin"},
+	} {
+		if err := f.users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, session := range []*entity.Session{
+		{ID: "s1", CID: "c1", AgentId: "a1", Status: entity.SessionStatusActive, CreatedAt: time.Now()},
+		{ID: "s2", CID: "c2", AgentId: "a1", Status: entity.SessionStatusActive, CreatedAt: time.Now()},
+		{ID: "s3", CID: "c3", AgentId: "a1", Status: entity.SessionStatusActive, CreatedAt: time.Now()},
+	} {
+		if err := f.sessions.Create(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// reply 写入一条客服发给 cid、推送时客户离线的回复
+func (f offlineFixture) reply(t *testing.T, msgID, sessionID, cid string, at time.Time) {
+	t.Helper()
+	msg := &entity.Message{
+		MsgID:       msgID,
+		SessionID:   sessionID,
+		MsgType:     entity.MsgTypeMessage,
+		ContentType: entity.ContentTypeText,
+		Src:         "A:a1",
+		Dst:         "U:" + cid,
+		Content:     "reply " + msgID,
+		Status:      entity.StatusOffline,
+		Ts:          entity.StringTimestamp(at.UnixMilli()),
+	}
+	if err := f.messages.Create(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOfflineNotifyRunOnce(t *testing.T) {
+	for name, newFixture := range offlineFixtures() {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed(t)
+			ctx := usecase.WithSystemPrincipal(context.Background())
+			notifier := &recordingNotifier{}
+			uc := usecase.NewOfflineNotifyUseCase(f.offline, f.users, notifier, nil, usecase.OfflineNotifyOptions{
+				Delay:       15 * time.Minute,
+				MinInterval: time.Hour,
+				ChatURL:     "https://chat.example.com/s/{sessionId}",
+			}, zap.NewNop())
+
+			t0 := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+			f.reply(t, "m1", "s1", "c1", t0)
+			// 没有邮箱的客户不提醒
+			f.reply(t, "m9", "s3", "c3", t0)
+
+			run := func(at time.Duration, want int) {
+				t.Helper()
+				sent, err := uc.RunOnce(ctx, t0.Add(at))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if sent != want {
+					t.Fatalf("RunOnce(t0+%s) sent %d, want %d", at, sent, want)
+				}
+			}
+
+			// 未达到 delay
+			run(5*time.Minute, 0)
+			run(16*time.Minute, 1)
+			if got := notifier.replies(0); len(got) != 1 || got[0] != "m1" {
+				t.Fatalf("first digest replies = %v, want [m1]", got)
+			}
+			if digest := notifier.digests[0]; digest.Email != "ann@example.com" || digest.ChatURL != "https://chat.example.com/s/s1" {
+				t.Fatalf("digest = %+v", digest)
+			}
+
+			// 已提醒过的回复不再发送
+			run(30*time.Minute, 0)
+
+			// 新回复超过 delay，但距上次提醒不足 min_interval
+			f.reply(t, "m2", "s1", "c1", t0.Add(20*time.Minute))
+			run(40*time.Minute, 0)
+			run(80*time.Minute, 1)
+			if got := notifier.replies(1); len(got) != 1 || got[0] != "m2" {
+				t.Fatalf("second digest replies = %v, want only the new reply [m2]", got)
+			}
+			run(200*time.Minute, 0)
+		})
+	}
+}
+
+func TestOfflineNotifyCancelledOnReconnect(t *testing.T) {
+	for name, newFixture := range offlineFixtures() {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed(t)
+			ctx := usecase.WithSystemPrincipal(context.Background())
+			notifier := &recordingNotifier{}
+			online := onlineSet{}
+			uc := usecase.NewOfflineNotifyUseCase(f.offline, f.users, notifier, online, usecase.OfflineNotifyOptions{
+				Delay:       15 * time.Minute,
+				MinInterval: time.Hour,
+			}, zap.NewNop())
+			chatUC := usecase.NewChatUseCase(f.messages, f.sessions, f.users, infrarepo.NewMemoryReactionRepository())
+
+			t0 := time.Now().Add(-24 * time.Hour)
+			f.reply(t, "m1", "s1", "c1", t0)
+			f.reply(t, "m2", "s2", "c2", t0)
+
+			// c1 在线时不提醒
+			online["c1"] = true
+			// c2 重新连接后拉取离线消息，回复转为已送达
+			if _, err := chatUC.GetOfflineMessages(principalContext("c2", entity.RoleCustomer), "c2"); err != nil {
+				t.Fatal(err)
+			}
+
+			sent, err := uc.RunOnce(ctx, t0.Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sent != 0 {
+				t.Fatalf("RunOnce sent %d digests to reconnected customers, want 0", sent)
+			}
+
+			// c1 断开后仍有未读回复，恢复提醒
+			online["c1"] = false
+			if sent, err := uc.RunOnce(ctx, t0.Add(time.Hour)); err != nil || sent != 1 {
+				t.Fatalf("RunOnce after disconnect = %d, %v, want 1", sent, err)
+			}
+			if notifier.digests[0].CID != "c1" {
+				t.Fatalf("digest sent to %s, want c1", notifier.digests[0].CID)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"cland.org/cland-chat-service/common/tracing"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// 未配置时的分发参数
+	DefaultOutboxMaxAttempts    = 20
+	DefaultOutboxInitialBackoff = time.Second
+	DefaultOutboxMaxBackoff     = 5 * time.Minute
+
+	outboxBatchSize = 100
+)
+
+// ErrOutboxNotRunning 分发循环未启动或已退出
+var ErrOutboxNotRunning = errors.New("outbox dispatcher is not running")
+
+// OutboxSink 发件箱事件的下游，如实时推送、webhook 或消息总线。成功处理的下游记录在事件上，
+// 返回错误时记录稍后只重新分发给失败的下游；处理成功但状态未能保存时仍可能重复，下游宜按事件 ID 去重
+type OutboxSink interface {
+	HandleOutboxEvent(ctx context.Context, event *entity.OutboxEvent) error
+}
+
+// OutboxOptions 分发重试策略
+type OutboxOptions struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+type namedSink struct {
+	name string
+	sink OutboxSink
+}
+
+// OutboxDispatcher 将发件箱记录按写入顺序分发给各下游，至少投递一次。业务写入提交后
+// 调用 Notify 立即分发，定时轮询兜底进程崩溃或下游失败后遗留的记录
+type OutboxDispatcher struct {
+	repo    repository.OutboxRepository
+	sinks   []namedSink
+	opts    OutboxOptions
+	wake    chan struct{}
+	metrics Metrics
+	running atomic.Bool
+	log     *zap.Logger
+}
+
+// NewOutboxDispatcher 创建发件箱分发器
+func NewOutboxDispatcher(repo repository.OutboxRepository, opts OutboxOptions, log *zap.Logger) *OutboxDispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultOutboxMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultOutboxInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultOutboxMaxBackoff
+	}
+	return &OutboxDispatcher{
+		repo: repo,
+		opts: opts,
+		wake: make(chan struct{}, 1),
+		log:  log.Named("outbox"),
+	}
+}
+
+// AddSink 注册下游，需在 Run 之前调用
+func (d *OutboxDispatcher) AddSink(name string, sink OutboxSink) {
+	d.sinks = append(d.sinks, namedSink{name: name, sink: sink})
+}
+
+// SetMetrics 设置指标记录器，记录每条记录从写入到分发成功的等待时间
+func (d *OutboxDispatcher) SetMetrics(metrics Metrics) {
+	d.metrics = metrics
+}
+
+// Notify 唤醒分发循环，不阻塞调用方；nil 分发器上调用无效
+func (d *OutboxDispatcher) Notify() {
+	if d == nil {
+		return
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 在被唤醒或定时到期时分发到期记录，直到 ctx 取消
+func (d *OutboxDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	d.running.Store(true)
+	defer d.running.Store(false)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		d.Dispatch(ctx, time.Now())
+	}
+}
+
+// CheckHealth 检查分发循环在运行且发件箱可读，用于就绪检查
+func (d *OutboxDispatcher) CheckHealth(ctx context.Context) error {
+	if !d.running.Load() {
+		return ErrOutboxNotRunning
+	}
+	_, err := d.repo.CountPending(ctx)
+	return err
+}
+
+// Dispatch 依次分发所有到期记录，返回本轮处理的条数
+func (d *OutboxDispatcher) Dispatch(ctx context.Context, now time.Time) int {
+	total := 0
+	for ctx.Err() == nil {
+		events, err := d.repo.ListDue(ctx, now, outboxBatchSize)
+		if err != nil {
+			d.log.Error("Failed to list due outbox events", zap.Error(err))
+			return total
+		}
+		for _, event := range events {
+			if err := d.dispatch(ctx, event); err != nil {
+				// 状态未保存的记录仍然到期，留到下一轮，避免本轮反复分发
+				d.log.Error("Failed to update outbox event", zap.String("eventId", event.ID), zap.Error(err))
+				return total
+			}
+			total++
+		}
+		if len(events) < outboxBatchSize {
+			return total
+		}
+	}
+	return total
+}
+
+// dispatch 将一条记录交给所有下游并保存结果
+func (d *OutboxDispatcher) dispatch(ctx context.Context, event *entity.OutboxEvent) (err error) {
+	// 分发在后台进行，与写入事件的请求不在同一追踪内，按事件开始新的追踪
+	ctx, span := tracing.Start(ctx, "OutboxDispatcher.dispatch")
+	span.SetAttribute("outbox.event_id", event.ID)
+	span.SetAttribute("outbox.event_type", event.EventType)
+	span.SetAttribute("outbox.aggregate_id", event.AggregateID)
+	span.SetAttribute("outbox.attempt", event.Attempts+1)
+	defer func() { span.EndWithError(err) }()
+
+	log := d.log.With(zap.String("eventId", event.ID), zap.String("event", event.EventType))
+
+	var failed error
+	for _, s := range d.sinks {
+		if event.SinkCompleted(s.name) {
+			continue
+		}
+		if err := s.sink.HandleOutboxEvent(ctx, event); err != nil {
+			log.Warn("Outbox sink failed", zap.String("sink", s.name), zap.Error(err))
+			if failed == nil {
+				failed = fmt.Errorf("%s: %w", s.name, err)
+			}
+			continue
+		}
+		event.MarkSinkCompleted(s.name)
+	}
+
+	event.Attempts++
+	switch {
+	case failed == nil:
+		event.Status = entity.OutboxPublished
+		event.PublishedAt = time.Now()
+		event.NextAttemptAt = time.Time{}
+		event.LastError = ""
+		if d.metrics != nil {
+			d.metrics.OutboxPublished(event.EventType, event.PublishedAt.Sub(event.CreatedAt))
+		}
+	case event.Attempts >= d.opts.MaxAttempts:
+		event.Status = entity.OutboxFailed
+		event.NextAttemptAt = time.Time{}
+		event.LastError = failed.Error()
+		log.Error("Outbox event failed permanently", zap.Int("attempts", event.Attempts), zap.Error(failed))
+	default:
+		event.NextAttemptAt = time.Now().Add(backoffDelay(d.opts.InitialBackoff, d.opts.MaxBackoff, event.Attempts))
+		event.LastError = failed.Error()
+	}
+	span.RecordError(failed)
+	return d.repo.Update(ctx, event)
+}
@@ -0,0 +1,155 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// countingSink 记录被调用次数，前 failures 次返回错误
+type countingSink struct {
+	calls    int
+	failures int
+}
+
+func (s *countingSink) HandleOutboxEvent(ctx context.Context, event *entity.OutboxEvent) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("sink unavailable")
+	}
+	return nil
+}
+
+func TestOutboxDispatcherRetriesOnlyFailedSinks(t *testing.T) {
+	for name, newRepo := range map[string]func(t *testing.T) repository.OutboxRepository{
+		"memory": func(t *testing.T) repository.OutboxRepository {
+			return infrarepo.NewMemoryOutboxRepository(infrarepo.NewMemoryMessageRepository(), infrarepo.NewMemorySessionRepository())
+		},
+		"sqlite": func(t *testing.T) repository.OutboxRepository {
+			return infrarepo.NewSQLiteOutboxRepository(newSQLiteRepository(t).base)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepo(t)
+			event, err := entity.NewOutboxEvent("evt-1", entity.WebhookEventMessageCreated, "msg-1", map[string]string{"msgId": "msg-1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			message := &entity.Message{MsgID: "msg-1", SessionID: "s-1", Src: "U:c1", Dst: "A:a1",
+				MsgType: entity.MsgTypeMessage, ContentType: entity.ContentTypeText, Content: "hi"}
+			if err := repo.CreateMessage(ctx, message, event); err != nil {
+				t.Fatal(err)
+			}
+
+			transcript := &countingSink{}
+			webhook := &countingSink{failures: 2}
+			dispatcher := usecase.NewOutboxDispatcher(repo, usecase.OutboxOptions{
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     time.Millisecond,
+			}, zap.NewNop())
+			dispatcher.AddSink("transcript", transcript)
+			dispatcher.AddSink("webhook", webhook)
+
+			// 每轮都取一个足够晚的时间，使退避后的记录立即到期
+			for i := 1; i <= 3; i++ {
+				dispatcher.Dispatch(ctx, time.Now().Add(time.Duration(i)*time.Hour))
+			}
+
+			if transcript.calls != 1 {
+				t.Errorf("transcript sink called %d times, want 1", transcript.calls)
+			}
+			if webhook.calls != 3 {
+				t.Errorf("webhook sink called %d times, want 3", webhook.calls)
+			}
+			pending, err := repo.CountPending(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pending != 0 {
+				t.Errorf("%d events still pending, want 0", pending)
+			}
+		})
+	}
+}
+
+// recordingSink 记录分发到的事件
+type recordingSink struct {
+	events []*entity.OutboxEvent
+}
+
+func (s *recordingSink) HandleOutboxEvent(ctx context.Context, event *entity.OutboxEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// failingNoticeOutbox 写入系统通知时返回错误，模拟关闭会话的事务中途失败
+type failingNoticeOutbox struct {
+	repository.OutboxRepository
+}
+
+func (o failingNoticeOutbox) CreateMessage(ctx context.Context, message *entity.Message, events ...*entity.OutboxEvent) error {
+	if message.Src == usecase.SystemAddress {
+		return errors.New("notice insert failed")
+	}
+	return o.OutboxRepository.CreateMessage(ctx, message, events...)
+}
+
+func TestCloseSessionWritesNoticesToOutbox(t *testing.T) {
+	repos := newSQLiteRepository(t)
+	outbox := infrarepo.NewSQLiteOutboxRepository(repos.base)
+	dispatcher := usecase.NewOutboxDispatcher(outbox, usecase.OutboxOptions{}, zap.NewNop())
+	sink := &recordingSink{}
+	dispatcher.AddSink("socket", sink)
+	uc := usecase.NewChatUseCase(repos.messages, repos.sessions, repos.users, infrarepo.NewMemoryReactionRepository())
+	uc.SetOutbox(outbox, dispatcher)
+	uc.SetTxManager(infrarepo.NewSQLiteTxManager(repos.base))
+
+	ctx := context.Background()
+	for _, id := range []string{"s1", "s2"} {
+		session := &entity.Session{ID: id, CID: "c1", AgentId: "a1", Status: entity.SessionStatusActive, CreatedAt: time.Now()}
+		if err := repos.sessions.Create(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	agent := principalContext("a1", entity.RoleAgent)
+
+	// 通知写入失败时关闭一并回滚
+	failing := usecase.NewChatUseCase(repos.messages, repos.sessions, repos.users, infrarepo.NewMemoryReactionRepository())
+	failing.SetOutbox(failingNoticeOutbox{outbox}, dispatcher)
+	failing.SetTxManager(infrarepo.NewSQLiteTxManager(repos.base))
+	if err := failing.CloseSession(agent, "s2"); err == nil {
+		t.Fatal("CloseSession succeeded although the notice insert failed")
+	}
+	if session, err := repos.sessions.GetByID(ctx, "s2"); err != nil || session.Status == entity.SessionStatusClosed {
+		t.Fatalf("session s2 = %+v, %v; want it still open after rollback", session, err)
+	}
+
+	// 关闭提交后即使进程未推送，通知也随 session.closed 留在发件箱中
+	if err := uc.CloseSession(agent, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	due, err := outbox.ListDue(ctx, time.Now().Add(time.Minute), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]int{}
+	for _, event := range due {
+		types[event.EventType]++
+	}
+	if types[entity.WebhookEventSessionClosed] != 1 || types[entity.WebhookEventMessageCreated] != 2 {
+		t.Fatalf("outbox events = %v, want 1 session.closed and a message.created per participant", types)
+	}
+
+	dispatcher.Dispatch(ctx, time.Now().Add(time.Minute))
+	if len(sink.events) != 3 {
+		t.Fatalf("dispatched %d events, want 3", len(sink.events))
+	}
+}
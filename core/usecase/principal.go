@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+)
+
+type principalKey struct{}
+
+// WithPrincipal 将请求身份放入上下文
+func WithPrincipal(ctx context.Context, principal entity.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext 读取请求身份，未设置时按匿名客户处理
+func PrincipalFromContext(ctx context.Context) entity.Principal {
+	if principal, ok := ctx.Value(principalKey{}).(entity.Principal); ok {
+		return principal
+	}
+	return entity.Principal{Role: entity.RoleCustomer}
+}
+
+// ResolvePrincipal 按用户档案中的角色确定身份，未知用户按客户处理
+func (uc *ChatUseCase) ResolvePrincipal(ctx context.Context, userID string) (entity.Principal, error) {
+	principal := entity.Principal{UserID: userID, Role: entity.RoleCustomer}
+	if userID == "" {
+		return principal, nil
+	}
+
+	user, err := uc.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return principal, nil
+		}
+		return principal, err
+	}
+	if user.Role != "" {
+		principal.Role = user.Role
+	}
+	return principal, nil
+}
@@ -0,0 +1,340 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+// ErrCustomerNotFound CID 不存在、不是客户或已被擦除
+var ErrCustomerNotFound = errors.New("customer not found")
+
+// PrivacyUseCase 处理客户的数据访问(导出)与擦除请求，均需要管理员权限并写入审计日志
+type PrivacyUseCase struct {
+	userRepo     repository.UserRepository
+	sessionRepo  repository.SessionRepository
+	messageRepo  repository.MessageRepository
+	reactionRepo repository.ReactionRepository
+	ratingRepo   repository.RatingRepository
+	privacyRepo  repository.PrivacyRepository
+	auditRepo    repository.AuditRepository
+	tx           repository.TxManager
+	retention    repository.RetentionRepository
+	store        repository.ArchiveStore
+	attachments  repository.ArchiveStore
+	log          *zap.Logger
+}
+
+// NewPrivacyUseCase 创建数据主体请求用例
+func NewPrivacyUseCase(
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	messageRepo repository.MessageRepository,
+	reactionRepo repository.ReactionRepository,
+	ratingRepo repository.RatingRepository,
+	privacyRepo repository.PrivacyRepository,
+	auditRepo repository.AuditRepository,
+	log *zap.Logger,
+) *PrivacyUseCase {
+	return &PrivacyUseCase{
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		messageRepo:  messageRepo,
+		reactionRepo: reactionRepo,
+		ratingRepo:   ratingRepo,
+		privacyRepo:  privacyRepo,
+		auditRepo:    auditRepo,
+		log:          log.Named("privacy"),
+	}
+}
+
+// SetTxManager 设置后擦除与审计日志在同一事务内提交
+func (uc *PrivacyUseCase) SetTxManager(tx repository.TxManager) {
+	uc.tx = tx
+}
+
+// SetArchive 设置后导出包含已归档的消息，擦除时一并删除归档文件
+func (uc *PrivacyUseCase) SetArchive(retention repository.RetentionRepository, store repository.ArchiveStore) {
+	uc.retention = retention
+	uc.store = store
+}
+
+// SetAttachmentStore 设置附件存储，擦除时一并删除客户会话中的附件文件
+func (uc *PrivacyUseCase) SetAttachmentStore(store repository.ArchiveStore) {
+	uc.attachments = store
+}
+
+func (uc *PrivacyUseCase) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if uc.tx == nil {
+		return fn(ctx)
+	}
+	return uc.tx.WithinTx(ctx, fn)
+}
+
+// getCustomer 查找未擦除的客户
+func (uc *PrivacyUseCase) getCustomer(ctx context.Context, cid string) (*entity.User, error) {
+	user, err := uc.userRepo.GetByID(ctx, cid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Role != entity.RoleCustomer {
+		return nil, ErrCustomerNotFound
+	}
+	return user, nil
+}
+
+// ExportCustomer 导出客户的用户信息、会话、消息(含已归档的消息)、附件、表情回应与评价
+func (uc *PrivacyUseCase) ExportCustomer(ctx context.Context, cid string) (*entity.DataExport, error) {
+	principal, err := requireRole(ctx, entity.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uc.getCustomer(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := uc.sessionRepo.ListByCID(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	// 空列表导出为 []，便于下游按格式解析
+	export := &entity.DataExport{
+		Format:           entity.DataExportFormat,
+		CID:              cid,
+		ExportedAt:       time.Now(),
+		User:             user,
+		Sessions:         append([]*entity.Session{}, sessions...),
+		Messages:         []*entity.Message{},
+		ArchivedMessages: []*entity.Message{},
+		Attachments:      []entity.Attachment{},
+		Reactions:        []*entity.Reaction{},
+		Ratings:          []*entity.SessionRating{},
+	}
+	for _, session := range sessions {
+		messages, err := uc.messageRepo.GetBySessionID(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.Messages = append(export.Messages, messages...)
+
+		archived, err := uc.archivedMessages(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.ArchivedMessages = append(export.ArchivedMessages, archived...)
+
+		reactions, err := uc.reactionRepo.ListBySessionID(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, reaction := range reactions {
+			if reaction.UserID == cid {
+				export.Reactions = append(export.Reactions, reaction)
+			}
+		}
+
+		rating, err := uc.ratingRepo.GetBySessionID(ctx, session.ID)
+		if err == nil {
+			export.Ratings = append(export.Ratings, rating)
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+	for _, messages := range [][]*entity.Message{export.Messages, export.ArchivedMessages} {
+		for _, msg := range messages {
+			if msg.ContentType == entity.ContentTypeImage || msg.ContentType == entity.ContentTypeFile {
+				export.Attachments = append(export.Attachments, entity.Attachment{
+					MsgID:       msg.MsgID,
+					SessionID:   msg.SessionID,
+					ContentType: msg.ContentType,
+					URL:         msg.Content,
+					Ext:         msg.Ext,
+				})
+			}
+		}
+	}
+
+	if err := uc.audit(ctx, principal, entity.AuditActionDataExport, cid, map[string]interface{}{
+		"sessions":         len(export.Sessions),
+		"messages":         len(export.Messages),
+		"archivedMessages": len(export.ArchivedMessages),
+	}); err != nil {
+		return nil, err
+	}
+	uc.log.Info("Customer data exported", zap.String("cid", cid), zap.String("actorId", principal.UserID))
+	return export, nil
+}
+
+// archivedMessages 读取已清理会话的归档消息；已恢复的会话消息已在消息表中，不重复导出
+func (uc *PrivacyUseCase) archivedMessages(ctx context.Context, sessionID string) ([]*entity.Message, error) {
+	if uc.retention == nil || uc.store == nil {
+		return nil, nil
+	}
+	rec, err := uc.retention.GetSessionRetention(ctx, sessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rec.ArchiveKey == "" || rec.PurgedAt.IsZero() || !rec.RestoredAt.IsZero() {
+		return nil, nil
+	}
+	messages, err := readArchive(ctx, uc.store, rec.ArchiveKey)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return messages, err
+}
+
+// EraseCustomer 擦除客户及已合并到该客户的访客数据：删除附件与归档文件，清除消息内容与个人信息并软删除，
+// 保留 CID 作为假名，审计日志只记录各表受影响的记录数
+func (uc *PrivacyUseCase) EraseCustomer(ctx context.Context, cid string) (*entity.ErasureResult, error) {
+	principal, err := requireRole(ctx, entity.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.getCustomer(ctx, cid); err != nil {
+		return nil, err
+	}
+
+	// 附件与归档文件不在事务内，先删除；删除后擦除失败可重试，会话仍能查到。
+	// 附件地址可能只记录在归档消息中，需在删除归档前处理
+	attachments, err := uc.deleteAttachments(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	archives, err := uc.deleteArchives(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *entity.ErasureResult
+	err = uc.withinTx(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = uc.privacyRepo.EraseCustomer(ctx, cid, principal.UserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrCustomerNotFound
+		}
+		if err != nil {
+			return err
+		}
+		result.Attachments = attachments
+		result.Archives = archives
+		return uc.audit(ctx, principal, entity.AuditActionDataErase, cid, map[string]interface{}{
+			"users":             result.Users,
+			"sessions":          result.Sessions,
+			"messages":          result.Messages,
+			"reactions":         result.Reactions,
+			"ratings":           result.Ratings,
+			"refreshTokens":     result.RefreshTokens,
+			"outboxEvents":      result.OutboxEvents,
+			"webhookDeliveries": result.WebhookDeliveries,
+			"attachments":       result.Attachments,
+			"archives":          result.Archives,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	uc.log.Info("Customer data erased",
+		zap.String("cid", cid),
+		zap.String("actorId", principal.UserID),
+		zap.Int64("sessions", result.Sessions),
+		zap.Int64("messages", result.Messages))
+	return result, nil
+}
+
+// deleteAttachments 删除客户各会话消息(含已归档的消息)引用的附件文件，返回删除的数量
+func (uc *PrivacyUseCase) deleteAttachments(ctx context.Context, cid string) (int64, error) {
+	if uc.attachments == nil {
+		return 0, nil
+	}
+	sessions, err := uc.sessionRepo.ListByCID(ctx, cid)
+	if err != nil {
+		return 0, err
+	}
+	keys := make(map[string]bool)
+	for _, session := range sessions {
+		messages, err := uc.messageRepo.GetBySessionID(ctx, session.ID)
+		if err != nil {
+			return 0, err
+		}
+		archived, err := uc.archivedMessages(ctx, session.ID)
+		if err != nil {
+			return 0, err
+		}
+		for _, msg := range append(messages, archived...) {
+			if msg.ContentType != entity.ContentTypeImage && msg.ContentType != entity.ContentTypeFile {
+				continue
+			}
+			if key, ok := attachmentKey(msg.Content); ok {
+				keys[key] = true
+			}
+		}
+	}
+
+	var deleted int64
+	for key := range keys {
+		if err := uc.attachments.Delete(ctx, key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// deleteArchives 删除客户各会话的归档文件，返回删除的数量
+func (uc *PrivacyUseCase) deleteArchives(ctx context.Context, cid string) (int64, error) {
+	if uc.retention == nil || uc.store == nil {
+		return 0, nil
+	}
+	sessions, err := uc.sessionRepo.ListByCID(ctx, cid)
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, session := range sessions {
+		rec, err := uc.retention.GetSessionRetention(ctx, session.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		if rec.ArchiveKey == "" {
+			continue
+		}
+		if err := uc.store.Delete(ctx, rec.ArchiveKey); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (uc *PrivacyUseCase) audit(ctx context.Context, principal entity.Principal, action, cid string, detail map[string]interface{}) error {
+	err := uc.auditRepo.Create(ctx, &entity.AuditLog{
+		ActorID:   principal.UserID,
+		ActorRole: principal.Role,
+		Action:    action,
+		TargetID:  cid,
+		Detail:    detail,
+	})
+	if err != nil {
+		uc.log.Error("Failed to write audit log",
+			zap.String("action", action),
+			zap.String("actorID", principal.UserID),
+			zap.String("cid", cid),
+			zap.Error(err))
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+func TestEraseCustomerDeletesAttachments(t *testing.T) {
+	ctx := context.Background()
+	users := infrarepo.NewMemoryUserRepository()
+	sessions := infrarepo.NewMemorySessionRepository()
+	messages := infrarepo.NewMemoryMessageRepository()
+	reactions := infrarepo.NewMemoryReactionRepository()
+	ratings := infrarepo.NewMemoryRatingRepository()
+	privacy := infrarepo.NewMemoryPrivacyRepository(users, sessions, messages, reactions, ratings,
+		infrarepo.NewMemoryTokenRepository(),
+		infrarepo.NewMemoryOutboxRepository(messages, sessions),
+		infrarepo.NewMemoryWebhookRepository(),
+		infrarepo.NewMemoryRetentionRepository(messages, sessions, reactions),
+		infrarepo.NewMemoryOfflineNotificationRepository(messages, users),
+		infrarepo.NewMemoryChannelIdentityRepository(),
+		infrarepo.NewMemoryChannelThreadRepository())
+	uc := usecase.NewPrivacyUseCase(users, sessions, messages, reactions, ratings, privacy,
+		infrarepo.NewMemoryAuditRepository(), zap.NewNop())
+
+	dir := t.TempDir()
+	store := infrarepo.NewLocalArchiveStore(dir)
+	uc.SetAttachmentStore(store)
+	for _, key := range []string{"email/c1/photo.png", "email/c2/other.png"} {
+		if err := store.Put(ctx, key, strings.NewReader("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := users.Create(ctx, &entity.User{ID: "c1", Role: entity.RoleCustomer}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.Create(ctx, &entity.Session{ID: "s1", CID: "c1", Status: entity.SessionStatusActive, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*entity.Message{
+		{MsgID: "m1", SessionID: "s1", Src: "U:c1", ContentType: entity.ContentTypeImage, Content: usecase.AttachmentURLPrefix + "email/c1/photo.png"},
+		{MsgID: "m2", SessionID: "s1", Src: "U:c1", ContentType: entity.ContentTypeFile, Content: "https://cdn.example.com/report.pdf"},
+		{MsgID: "m3", SessionID: "s1", Src: "U:c1", ContentType: entity.ContentTypeText, Content: usecase.AttachmentURLPrefix + "email/c2/other.png"},
+	} {
+		if err := messages.Create(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	admin := usecase.WithPrincipal(ctx, entity.Principal{UserID: "admin", Role: entity.RoleAdmin})
+	result, err := uc.EraseCustomer(admin, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Attachments != 1 {
+		t.Errorf("result.Attachments = %d, want 1", result.Attachments)
+	}
+	if _, err := store.Get(ctx, "email/c1/photo.png"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("erased attachment still readable: %v", err)
+	}
+	// 文本消息中的地址不是附件，其他客户的文件保留
+	if _, err := os.Stat(filepath.Join(dir, "email", "c2", "other.png")); err != nil {
+		t.Errorf("unrelated attachment was removed: %v", err)
+	}
+}
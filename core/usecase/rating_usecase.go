@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+)
+
+const (
+	// EventCSATSurvey 会话结束后推送给客户的满意度调查事件
+	EventCSATSurvey = "csat_survey"
+
+	minRatingScore      = 1
+	maxRatingScore      = 5
+	maxRatingCommentLen = 1000
+)
+
+var (
+	// ErrInvalidRating 评分不在 1-5 之间或评论过长
+	ErrInvalidRating = errors.New("score must be between 1 and 5 and comment at most 1000 characters")
+	// ErrAlreadyRated 会话已评价过
+	ErrAlreadyRated = errors.New("session already rated")
+	// ErrSessionNotClosed 会话尚未结束，不能评价
+	ErrSessionNotClosed = errors.New("session is not closed")
+	// ErrNotSessionCustomer 评价人不是会话的客户
+	ErrNotSessionCustomer = errors.New("only the session customer can rate it")
+)
+
+// CSATSurvey 满意度调查，socket 推送与 REST 查询共用
+type CSATSurvey struct {
+	SessionID        string                `json:"sessionId"`
+	AgentID          string                `json:"agentId"`
+	MinScore         int                   `json:"minScore"`
+	MaxScore         int                   `json:"maxScore"`
+	CommentMaxLength int                   `json:"commentMaxLength"`
+	Rating           *entity.SessionRating `json:"rating,omitempty"` // 已提交的评价
+}
+
+// newCSATSurvey 根据会话生成满意度调查
+func newCSATSurvey(session *entity.Session) CSATSurvey {
+	return CSATSurvey{
+		SessionID:        session.ID,
+		AgentID:          session.AgentId,
+		MinScore:         minRatingScore,
+		MaxScore:         maxRatingScore,
+		CommentMaxLength: maxRatingCommentLen,
+	}
+}
+
+// RatingUseCase 满意度评价用例
+type RatingUseCase struct {
+	ratingRepo  repository.RatingRepository
+	sessionRepo repository.SessionRepository
+	publisher   EventPublisher
+}
+
+// NewRatingUseCase 创建满意度评价用例
+func NewRatingUseCase(
+	ratingRepo repository.RatingRepository,
+	sessionRepo repository.SessionRepository,
+) *RatingUseCase {
+	return &RatingUseCase{
+		ratingRepo:  ratingRepo,
+		sessionRepo: sessionRepo,
+	}
+}
+
+// GetSurvey 获取会话的满意度调查(socket 推送的 REST 兜底)，已评价时附带评价结果
+func (uc *RatingUseCase) GetSurvey(ctx context.Context, sessionID string) (*CSATSurvey, error) {
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	survey := newCSATSurvey(session)
+	rating, err := uc.ratingRepo.GetBySessionID(ctx, sessionID)
+	switch {
+	case err == nil:
+		survey.Rating = rating
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+	return &survey, nil
+}
+
+// SubmitRating 提交会话评价，每个会话只能评价一次
+func (uc *RatingUseCase) SubmitRating(ctx context.Context, sessionID, cid string, score int, comment string) (*entity.SessionRating, error) {
+	if score < minRatingScore || score > maxRatingScore || utf8.RuneCountInString(comment) > maxRatingCommentLen {
+		return nil, ErrInvalidRating
+	}
+	if err := authorizeSelf(ctx, cid); err != nil {
+		return nil, err
+	}
+
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.CID != cid {
+		return nil, ErrNotSessionCustomer
+	}
+	if session.Status != entity.SessionStatusClosed {
+		return nil, ErrSessionNotClosed
+	}
+
+	rating := &entity.SessionRating{
+		SessionID: session.ID,
+		CID:       session.CID,
+		AgentID:   session.AgentId,
+		Score:     score,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	}
+	if err := uc.ratingRepo.Create(ctx, rating); err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, ErrAlreadyRated
+		}
+		return nil, err
+	}
+	if uc.publisher != nil {
+		uc.publisher.Publish(ctx, entity.WebhookEventRatingSubmitted, rating)
+	}
+	return rating, nil
+}
+
+// SetEventPublisher 设置业务事件发布器(webhook)
+func (uc *RatingUseCase) SetEventPublisher(publisher EventPublisher) {
+	uc.publisher = publisher
+}
+
+// AggregateRatings 按客服(可选按天)统计满意度
+func (uc *RatingUseCase) AggregateRatings(ctx context.Context, query entity.RatingQuery) ([]*entity.RatingAggregate, error) {
+	principal, err := requireRole(ctx, entity.RoleAgent, entity.RoleSupervisor, entity.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if principal.Role == entity.RoleAgent {
+		// 客服只能查看自己的满意度
+		query.AgentID = principal.UserID
+	}
+	return uc.ratingRepo.Aggregate(ctx, query)
+}
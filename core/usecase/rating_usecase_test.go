@@ -0,0 +1,153 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+// ratingFixture 同一存储上的会话与评价仓储
+type ratingFixture struct {
+	sessions repository.SessionRepository
+	ratings  repository.RatingRepository
+}
+
+func ratingFixtures() map[string]func(t *testing.T) ratingFixture {
+	return map[string]func(t *testing.T) ratingFixture{
+		"memory": func(t *testing.T) ratingFixture {
+			return ratingFixture{sessions: infrarepo.NewMemorySessionRepository(), ratings: infrarepo.NewMemoryRatingRepository()}
+		},
+		"sqlite": func(t *testing.T) ratingFixture {
+			repos := newSQLiteRepository(t)
+			return ratingFixture{sessions: repos.sessions, ratings: infrarepo.NewSQLiteRatingRepository(repos.base)}
+		},
+	}
+}
+
+func (f ratingFixture) session(t *testing.T, id, cid, agentID string, closed bool) {
+	t.Helper()
+	ctx := context.Background()
+	session := &entity.Session{ID: id, CID: cid, AgentId: agentID, Status: entity.SessionStatusActive, StartTime: time.Now(), CreatedAt: time.Now()}
+	if err := f.sessions.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	if closed {
+		if err := f.sessions.Close(ctx, id, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSubmitRating(t *testing.T) {
+	for name, newFixture := range ratingFixtures() {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			uc := usecase.NewRatingUseCase(f.ratings, f.sessions)
+			f.session(t, "s1", "c1", "a1", true)
+			f.session(t, "s2", "c1", "a1", false)
+			customer := principalContext("c1", entity.RoleCustomer)
+
+			tests := []struct {
+				name      string
+				ctx       context.Context
+				sessionID string
+				cid       string
+				score     int
+				comment   string
+				wantErr   error
+			}{
+				{name: "score too low", ctx: customer, sessionID: "s1", cid: "c1", score: 0, wantErr: usecase.ErrInvalidRating},
+				{name: "score too high", ctx: customer, sessionID: "s1", cid: "c1", score: 6, wantErr: usecase.ErrInvalidRating},
+				{name: "comment too long", ctx: customer, sessionID: "s1", cid: "c1", score: 5, comment: strings.Repeat("好", 1001), wantErr: usecase.ErrInvalidRating},
+				{name: "other customer", ctx: principalContext("c2", entity.RoleCustomer), sessionID: "s1", cid: "c2", score: 5, wantErr: usecase.ErrNotSessionCustomer},
+				{name: "rating for someone else", ctx: principalContext("c2", entity.RoleCustomer), sessionID: "s1", cid: "c1", score: 5, wantErr: usecase.ErrForbidden},
+				{name: "session still active", ctx: customer, sessionID: "s2", cid: "c1", score: 5, wantErr: usecase.ErrSessionNotClosed},
+				{name: "closed session", ctx: customer, sessionID: "s1", cid: "c1", score: 4, comment: strings.Repeat("好", 1000)},
+				{name: "second rating", ctx: customer, sessionID: "s1", cid: "c1", score: 1, wantErr: usecase.ErrAlreadyRated},
+			}
+			for _, tt := range tests {
+				rating, err := uc.SubmitRating(tt.ctx, tt.sessionID, tt.cid, tt.score, tt.comment)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("%s: SubmitRating() error = %v, want %v", tt.name, err, tt.wantErr)
+				}
+				if err == nil && (rating.AgentID != "a1" || rating.Score != tt.score) {
+					t.Fatalf("%s: rating = %+v", tt.name, rating)
+				}
+			}
+
+			// 重复提交不覆盖第一次的评价
+			survey, err := uc.GetSurvey(customer, "s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if survey.Rating == nil || survey.Rating.Score != 4 {
+				t.Fatalf("survey rating = %+v, want the first score 4", survey.Rating)
+			}
+		})
+	}
+}
+
+func TestAggregateRatings(t *testing.T) {
+	for name, newFixture := range ratingFixtures() {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			uc := usecase.NewRatingUseCase(f.ratings, f.sessions)
+			ctx := context.Background()
+
+			day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+			for _, rating := range []*entity.SessionRating{
+				{SessionID: "s1", CID: "c1", AgentID: "a1", Score: 5, CreatedAt: day.Add(9 * time.Hour)},
+				{SessionID: "s2", CID: "c2", AgentID: "a1", Score: 2, CreatedAt: day.Add(10 * time.Hour)},
+				{SessionID: "s3", CID: "c3", AgentID: "a1", Score: 4, CreatedAt: day.Add(33 * time.Hour)},
+				{SessionID: "s4", CID: "c4", AgentID: "a2", Score: 3, CreatedAt: day.Add(9 * time.Hour)},
+				// 统计区间为 [From, To)，恰好在 To 的评价不计入
+				{SessionID: "s5", CID: "c5", AgentID: "a1", Score: 1, CreatedAt: day.Add(48 * time.Hour)},
+				{SessionID: "s6", CID: "c6", AgentID: "a1", Score: 1, CreatedAt: day.Add(-time.Second)},
+			} {
+				if err := f.ratings.Create(ctx, rating); err != nil {
+					t.Fatal(err)
+				}
+			}
+			query := entity.RatingQuery{From: day, To: day.Add(48 * time.Hour), GroupByDay: true}
+
+			got, err := uc.AggregateRatings(principalContext("sup1", entity.RoleSupervisor), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []entity.RatingAggregate{
+				{AgentID: "a1", Day: "2026-10-01", Count: 2, Average: 3.5, Satisfied: 1, CSAT: 50},
+				{AgentID: "a2", Day: "2026-10-01", Count: 1, Average: 3, Satisfied: 0, CSAT: 0},
+				{AgentID: "a1", Day: "2026-10-02", Count: 1, Average: 4, Satisfied: 1, CSAT: 100},
+			}
+			if len(got) != len(want) {
+				t.Fatalf("aggregates = %d rows, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if *got[i] != want[i] {
+					t.Errorf("aggregate[%d] = %+v, want %+v", i, *got[i], want[i])
+				}
+			}
+
+			// 客服只能看到自己的统计
+			query.GroupByDay = false
+			query.AgentID = "a1"
+			own, err := uc.AggregateRatings(principalContext("a2", entity.RoleAgent), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(own) != 1 || own[0].AgentID != "a2" || own[0].Count != 1 {
+				t.Fatalf("agent aggregates = %+v, want only a2", own)
+			}
+			if _, err := uc.AggregateRatings(principalContext("c1", entity.RoleCustomer), query); !errors.Is(err, usecase.ErrForbidden) {
+				t.Fatalf("customer AggregateRatings error = %v, want ErrForbidden", err)
+			}
+		})
+	}
+}
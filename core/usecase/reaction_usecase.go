@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+)
+
+const (
+	// EventReactionUpdated 表情回应变化事件
+	EventReactionUpdated = "reaction_updated"
+
+	ReactionActionAdd    = "add"
+	ReactionActionRemove = "remove"
+
+	maxEmojiLength = 32
+)
+
+// ErrInvalidEmoji 表情为空或过长
+var ErrInvalidEmoji = errors.New("emoji must be between 1 and 32 bytes")
+
+// ReactionUpdate reaction_updated 事件负载，同时作为接口返回值
+type ReactionUpdate struct {
+	MsgID     string                   `json:"msgId"`
+	SessionID string                   `json:"sessionId"`
+	UserID    string                   `json:"userId"`
+	Emoji     string                   `json:"emoji"`
+	Action    string                   `json:"action"` // add, remove
+	Reactions []entity.ReactionSummary `json:"reactions"`
+}
+
+// AddReaction 为消息添加表情回应
+func (uc *ChatUseCase) AddReaction(ctx context.Context, msgID, userID, emoji string) (*ReactionUpdate, error) {
+	return uc.updateReaction(ctx, msgID, userID, emoji, ReactionActionAdd)
+}
+
+// RemoveReaction 移除消息的表情回应
+func (uc *ChatUseCase) RemoveReaction(ctx context.Context, msgID, userID, emoji string) (*ReactionUpdate, error) {
+	return uc.updateReaction(ctx, msgID, userID, emoji, ReactionActionRemove)
+}
+
+// updateReaction 增删回应后重新聚合，并通知会话参与者
+func (uc *ChatUseCase) updateReaction(ctx context.Context, msgID, userID, emoji, action string) (*ReactionUpdate, error) {
+	if emoji == "" || len(emoji) > maxEmojiLength {
+		return nil, ErrInvalidEmoji
+	}
+	if err := authorizeSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	msg, err := uc.messageRepo.GetByID(ctx, msgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.authorizeMessage(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	if action == ReactionActionAdd {
+		err = uc.reactionRepo.Add(ctx, &entity.Reaction{
+			MsgID:     msg.MsgID,
+			SessionID: msg.SessionID,
+			UserID:    userID,
+			Emoji:     emoji,
+		})
+	} else {
+		err = uc.reactionRepo.Remove(ctx, msg.MsgID, userID, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := uc.reactionRepo.ListByMessageID(ctx, msg.MsgID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &ReactionUpdate{
+		MsgID:     msg.MsgID,
+		SessionID: msg.SessionID,
+		UserID:    userID,
+		Emoji:     emoji,
+		Action:    action,
+		Reactions: entity.SummarizeReactions(reactions),
+	}
+	uc.emit(EventReactionUpdated, update, uc.sessionParticipants(ctx, msg))
+	return update, nil
+}
+
+// hydrateReactions 为历史消息填充表情回应统计
+func (uc *ChatUseCase) hydrateReactions(ctx context.Context, sessionID string, messages []*entity.Message) error {
+	reactions, err := uc.reactionRepo.ListBySessionID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	byMsg := make(map[string][]*entity.Reaction)
+	for _, r := range reactions {
+		byMsg[r.MsgID] = append(byMsg[r.MsgID], r)
+	}
+	for _, msg := range messages {
+		if rs, ok := byMsg[msg.MsgID]; ok {
+			msg.Reactions = entity.SummarizeReactions(rs)
+		}
+	}
+	return nil
+}
+
+// sessionParticipants 返回会话参与者(客户、客服及消息收发双方)的用户ID，内部备注不含客户
+func (uc *ChatUseCase) sessionParticipants(ctx context.Context, msg *entity.Message) []string {
+	candidates := []string{entity.UserIDFromAddress(msg.Src), entity.UserIDFromAddress(msg.Dst)}
+	session, err := uc.SessionRepo.GetByID(ctx, msg.SessionID)
+	if err == nil {
+		candidates = append(candidates, session.CID, session.AgentId)
+	}
+
+	seen := make(map[string]bool, len(candidates))
+	if msg.IsInternal() && session != nil {
+		// 内部备注的回应不通知客户
+		seen[session.CID] = true
+	}
+	var userIDs []string
+	for _, id := range candidates {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		userIDs = append(userIDs, id)
+	}
+	return userIDs
+}
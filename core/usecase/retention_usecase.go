@@ -0,0 +1,407 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultRetentionChannel 未单独配置保留策略的渠道使用的策略键
+	DefaultRetentionChannel = "default"
+
+	// 未配置时的任务参数
+	DefaultRetentionSessionBatch = 50
+	DefaultRetentionDeleteBatch  = 500
+	DefaultRetentionRestoreTTL   = 7 * 24 * time.Hour
+)
+
+var (
+	ErrRetentionRunning = errors.New("retention run already in progress")
+	ErrArchiveNotFound  = errors.New("session archive not found")
+)
+
+// RetentionOptions 保留任务配置
+type RetentionOptions struct {
+	Policies     map[string]entity.RetentionPolicy // channel -> policy，default 为缺省策略
+	SessionBatch int                               // 每次查询的到期会话数
+	DeleteBatch  int                               // 每批删除或匿名化的消息数
+	BatchPause   time.Duration                     // 每批之间暂停，限制删除速度
+	RestoreTTL   time.Duration                     // 恢复供查看的会话保留多久后重新清理
+}
+
+// RetentionUseCase 按渠道策略归档并清理已关闭会话的消息。每个会话先归档再清理，进度保存在
+// 会话的保留记录中，任务中断或失败的会话在下一轮从未完成的步骤继续
+type RetentionUseCase struct {
+	repo     repository.RetentionRepository
+	messages repository.MessageRepository
+	store    repository.ArchiveStore
+	opts     RetentionOptions
+	running  sync.Mutex
+	wake     chan struct{}
+	log      *zap.Logger
+}
+
+// NewRetentionUseCase 创建保留任务，After 不大于 0 或动作未知的策略被忽略；store 为 nil 时不归档
+func NewRetentionUseCase(repo repository.RetentionRepository, messages repository.MessageRepository,
+	store repository.ArchiveStore, opts RetentionOptions, log *zap.Logger) *RetentionUseCase {
+	log = log.Named("retention")
+	if opts.SessionBatch <= 0 {
+		opts.SessionBatch = DefaultRetentionSessionBatch
+	}
+	if opts.DeleteBatch <= 0 {
+		opts.DeleteBatch = DefaultRetentionDeleteBatch
+	}
+	if opts.RestoreTTL <= 0 {
+		opts.RestoreTTL = DefaultRetentionRestoreTTL
+	}
+
+	policies := make(map[string]entity.RetentionPolicy, len(opts.Policies))
+	for channel, policy := range opts.Policies {
+		if policy.After <= 0 {
+			continue
+		}
+		if policy.Action != entity.RetentionDelete && policy.Action != entity.RetentionAnonymize {
+			log.Error("Ignoring retention policy with unknown action",
+				zap.String("channel", channel), zap.String("action", policy.Action))
+			continue
+		}
+		if policy.Archive && store == nil {
+			log.Warn("No archive store configured, purging without archive", zap.String("channel", channel))
+			policy.Archive = false
+		}
+		policies[channel] = policy
+	}
+	opts.Policies = policies
+
+	return &RetentionUseCase{
+		repo:     repo,
+		messages: messages,
+		store:    store,
+		opts:     opts,
+		wake:     make(chan struct{}, 1),
+		log:      log,
+	}
+}
+
+// Enabled 是否配置了有效的保留策略
+func (uc *RetentionUseCase) Enabled() bool {
+	return len(uc.opts.Policies) > 0
+}
+
+// Run 按固定间隔或被 Trigger 唤醒时执行一轮任务，直到 ctx 取消
+func (uc *RetentionUseCase) Run(ctx context.Context, interval time.Duration) {
+	ctx = WithSystemPrincipal(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	uc.log.Info("Retention job started", zap.Duration("interval", interval))
+	for {
+		select {
+		case <-ctx.Done():
+			uc.log.Info("Retention job stopped")
+			return
+		case <-ticker.C:
+		case <-uc.wake:
+		}
+		if _, err := uc.RunOnce(ctx); err != nil && !errors.Is(err, ErrRetentionRunning) {
+			uc.log.Error("Retention run failed", zap.Error(err))
+		}
+	}
+}
+
+// Trigger 请求立即执行一轮任务，需要管理员权限
+func (uc *RetentionUseCase) Trigger(ctx context.Context) error {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return err
+	}
+	if !uc.running.TryLock() {
+		return ErrRetentionRunning
+	}
+	uc.running.Unlock()
+
+	select {
+	case uc.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// RunOnce 依次按各渠道策略处理到期会话，每批处理后保存任务进度
+func (uc *RetentionUseCase) RunOnce(ctx context.Context) (*entity.RetentionRun, error) {
+	if !uc.running.TryLock() {
+		return nil, ErrRetentionRunning
+	}
+	defer uc.running.Unlock()
+
+	now := time.Now()
+	run := &entity.RetentionRun{
+		ID:        utils.GenerateRetentionRunID(),
+		Status:    entity.RetentionRunRunning,
+		StartedAt: now,
+	}
+	if err := uc.repo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	// 单独配置了策略的渠道不使用缺省策略
+	var channels []string
+	for channel := range uc.opts.Policies {
+		if channel != DefaultRetentionChannel {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+
+	err := func() error {
+		for _, channel := range channels {
+			query := entity.RetentionQuery{Channels: []string{channel}}
+			if err := uc.applyPolicy(ctx, run, now, uc.opts.Policies[channel], query); err != nil {
+				return err
+			}
+		}
+		if policy, ok := uc.opts.Policies[DefaultRetentionChannel]; ok {
+			query := entity.RetentionQuery{ExcludeChannels: channels}
+			if err := uc.applyPolicy(ctx, run, now, policy, query); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+
+	run.FinishedAt = time.Now()
+	run.Status = entity.RetentionRunCompleted
+	if err != nil {
+		run.Status = entity.RetentionRunFailed
+		run.LastError = err.Error()
+	}
+	// 任务被取消时仍需记录结果
+	if err := uc.repo.UpdateRun(context.WithoutCancel(ctx), run); err != nil {
+		uc.log.Error("Failed to save retention run", zap.String("runId", run.ID), zap.Error(err))
+	}
+	uc.log.Info("Retention run finished",
+		zap.String("runId", run.ID),
+		zap.String("status", run.Status),
+		zap.Int("sessionsPurged", run.SessionsPurged),
+		zap.Int("sessionsFailed", run.SessionsFailed),
+		zap.Int64("messagesDeleted", run.MessagesDeleted),
+		zap.Int64("messagesAnonymized", run.MessagesAnonymized))
+	return run, err
+}
+
+// applyPolicy 分页处理策略覆盖的到期会话。单个会话失败只记录错误，留到下一轮重试
+func (uc *RetentionUseCase) applyPolicy(ctx context.Context, run *entity.RetentionRun, now time.Time,
+	policy entity.RetentionPolicy, query entity.RetentionQuery) error {
+	query.ClosedBefore = now.Add(-policy.After)
+	query.RestoredBefore = now.Add(-uc.opts.RestoreTTL)
+	query.Limit = uc.opts.SessionBatch
+
+	for {
+		sessions, err := uc.repo.ListDueSessions(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, session := range sessions {
+			run.SessionsScanned++
+			if err := uc.processSession(ctx, run, session, policy); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				run.SessionsFailed++
+				run.LastError = fmt.Sprintf("session %s: %v", session.ID, err)
+				uc.log.Error("Failed to apply retention", zap.String("sessionId", session.ID), zap.Error(err))
+			}
+			query.AfterSessionID = session.ID
+		}
+		if err := uc.repo.UpdateRun(ctx, run); err != nil {
+			return err
+		}
+		if len(sessions) < query.Limit {
+			return nil
+		}
+	}
+}
+
+// processSession 归档(如需要)后清理会话消息，每一步完成后保存记录
+func (uc *RetentionUseCase) processSession(ctx context.Context, run *entity.RetentionRun,
+	session *entity.Session, policy entity.RetentionPolicy) error {
+	rec, err := uc.repo.GetSessionRetention(ctx, session.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		rec = &entity.SessionRetention{SessionID: session.ID}
+	} else if err != nil {
+		return err
+	}
+	rec.Channel = session.Channel
+	rec.Action = policy.Action
+
+	// 已有归档的会话(上次中断或恢复后再次到期)不重复归档
+	if policy.Archive && rec.ArchiveKey == "" {
+		key, count, err := uc.archive(ctx, session)
+		if err != nil {
+			return fmt.Errorf("archive: %w", err)
+		}
+		rec.ArchiveKey = key
+		rec.MessageCount = count
+		rec.ArchivedAt = time.Now()
+		if err := uc.repo.SaveSessionRetention(ctx, rec); err != nil {
+			return err
+		}
+		run.SessionsArchived++
+	}
+
+	purge := uc.repo.DeleteMessages
+	counter := &run.MessagesDeleted
+	if policy.Action == entity.RetentionAnonymize {
+		purge = uc.repo.AnonymizeMessages
+		counter = &run.MessagesAnonymized
+	}
+	for {
+		n, err := purge(ctx, session.ID, uc.opts.DeleteBatch)
+		if err != nil {
+			return err
+		}
+		*counter += n
+		if n > 0 && uc.opts.BatchPause > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(uc.opts.BatchPause):
+			}
+		}
+		if n < int64(uc.opts.DeleteBatch) {
+			break
+		}
+	}
+
+	rec.PurgedAt = time.Now()
+	rec.RestoredAt = time.Time{}
+	if err := uc.repo.SaveSessionRetention(ctx, rec); err != nil {
+		return err
+	}
+	run.SessionsPurged++
+	return nil
+}
+
+// archiveKey 归档文件按会话结束日期分目录
+func archiveKey(session *entity.Session) string {
+	return fmt.Sprintf("sessions/%s/%s.jsonl.gz", session.EndTime.UTC().Format("2006/01/02"), session.ID)
+}
+
+// archive 将会话全部消息按每行一条 JSON 写入 gzip 文件并保存到冷存储
+func (uc *RetentionUseCase) archive(ctx context.Context, session *entity.Session) (string, int, error) {
+	messages, err := uc.messages.GetBySessionID(ctx, session.ID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, msg := range messages {
+		if err := enc.Encode(msg); err != nil {
+			return "", 0, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return "", 0, err
+	}
+
+	key := archiveKey(session)
+	if err := uc.store.Put(ctx, key, &buf); err != nil {
+		return "", 0, err
+	}
+	return key, len(messages), nil
+}
+
+// ListRuns 查看最近的任务进度与结果，需要管理员权限
+func (uc *RetentionUseCase) ListRuns(ctx context.Context, limit int) ([]*entity.RetentionRun, error) {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListRuns(ctx, limit)
+}
+
+// GetSessionRetention 查看会话的归档与清理记录，需要管理员权限
+func (uc *RetentionUseCase) GetSessionRetention(ctx context.Context, sessionID string) (*entity.SessionRetention, error) {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return uc.repo.GetSessionRetention(ctx, sessionID)
+}
+
+// RestoreSession 将归档的会话消息写回供查看，RestoreTTL 后由任务重新清理；需要管理员权限
+func (uc *RetentionUseCase) RestoreSession(ctx context.Context, sessionID string) (*entity.SessionRetention, error) {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	rec, err := uc.repo.GetSessionRetention(ctx, sessionID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && rec.ArchiveKey == "") {
+		return nil, ErrArchiveNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if uc.store == nil {
+		return nil, ErrArchiveNotFound
+	}
+
+	messages, err := readArchive(ctx, uc.store, rec.ArchiveKey)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrArchiveNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.repo.RestoreMessages(ctx, messages); err != nil {
+		return nil, err
+	}
+
+	rec.RestoredAt = time.Now()
+	if err := uc.repo.SaveSessionRetention(ctx, rec); err != nil {
+		return nil, err
+	}
+	uc.log.Info("Restored archived session",
+		zap.String("sessionId", sessionID),
+		zap.String("by", PrincipalFromContext(ctx).UserID),
+		zap.Int("messages", len(messages)))
+	return rec, nil
+}
+
+// readArchive 读取并解码归档文件
+func readArchive(ctx context.Context, store repository.ArchiveStore, key string) ([]*entity.Message, error) {
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var messages []*entity.Message
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg entity.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &msg)
+	}
+	return messages, scanner.Err()
+}
@@ -0,0 +1,161 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// retentionFixture 同一存储上的消息、会话与保留仓储
+type retentionFixture struct {
+	messages  repository.MessageRepository
+	sessions  repository.SessionRepository
+	retention repository.RetentionRepository
+}
+
+func retentionFixtures() map[string]func(t *testing.T) retentionFixture {
+	return map[string]func(t *testing.T) retentionFixture{
+		"memory": func(t *testing.T) retentionFixture {
+			messages := infrarepo.NewMemoryMessageRepository()
+			sessions := infrarepo.NewMemorySessionRepository()
+			return retentionFixture{
+				messages:  messages,
+				sessions:  sessions,
+				retention: infrarepo.NewMemoryRetentionRepository(messages, sessions, infrarepo.NewMemoryReactionRepository()),
+			}
+		},
+		"sqlite": func(t *testing.T) retentionFixture {
+			repos := newSQLiteRepository(t)
+			return retentionFixture{
+				messages:  repos.messages,
+				sessions:  repos.sessions,
+				retention: infrarepo.NewSQLiteRetentionRepository(repos.base),
+			}
+		},
+	}
+}
+
+// closedSession 写入一个在 endTime 关闭的会话及其消息
+func (f retentionFixture) closedSession(t *testing.T, id, channel string, endTime time.Time, msgIDs ...string) {
+	t.Helper()
+	ctx := context.Background()
+	session := &entity.Session{ID: id, CID: "c1", AgentId: "a1", Channel: channel, Status: entity.SessionStatusActive,
+		StartTime: endTime.Add(-time.Hour), CreatedAt: endTime.Add(-time.Hour)}
+	if err := f.sessions.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	for i, msgID := range msgIDs {
+		msg := &entity.Message{MsgID: msgID, SessionID: id, MsgType: entity.MsgTypeMessage, ContentType: entity.ContentTypeText,
+			Src: "U:c1", Dst: "A:a1", Content: "content " + msgID, Status: entity.StatusSent,
+			Ts: entity.StringTimestamp(endTime.Add(-time.Duration(len(msgIDs)-i) * time.Minute).UnixMilli())}
+		if err := f.messages.Create(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.sessions.Close(ctx, id, endTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// contents 返回会话当前的消息内容，按内容排序
+func (f retentionFixture) contents(t *testing.T, sessionID string) []string {
+	t.Helper()
+	messages, err := f.messages.GetBySessionID(context.Background(), sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, msg := range messages {
+		contents = append(contents, msg.Content)
+	}
+	sort.Strings(contents)
+	return contents
+}
+
+func TestRetentionArchiveAndRestore(t *testing.T) {
+	for name, newFixture := range retentionFixtures() {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			ctx := usecase.WithSystemPrincipal(context.Background())
+			admin := principalContext("admin1", entity.RoleAdmin)
+			uc := usecase.NewRetentionUseCase(f.retention, f.messages, infrarepo.NewLocalArchiveStore(t.TempDir()), usecase.RetentionOptions{
+				Policies: map[string]entity.RetentionPolicy{
+					usecase.DefaultRetentionChannel: {After: 24 * time.Hour, Action: entity.RetentionDelete, Archive: true},
+					"sms":                           {After: 24 * time.Hour, Action: entity.RetentionAnonymize},
+				},
+				DeleteBatch: 1,
+				RestoreTTL:  time.Hour,
+			}, zap.NewNop())
+
+			now := time.Now()
+			f.closedSession(t, "s-old", entity.ChannelWeb, now.Add(-48*time.Hour), "m1", "m2")
+			f.closedSession(t, "s-recent", entity.ChannelWeb, now.Add(-time.Hour), "m3")
+			f.closedSession(t, "s-sms", "sms", now.Add(-48*time.Hour), "m4")
+
+			run, err := uc.RunOnce(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != entity.RetentionRunCompleted || run.SessionsPurged != 2 || run.SessionsArchived != 1 ||
+				run.MessagesDeleted != 2 || run.MessagesAnonymized != 1 {
+				t.Fatalf("run = %+v, want 2 sessions purged, 1 archived, 2 deleted and 1 anonymized", run)
+			}
+			if got := f.contents(t, "s-old"); len(got) != 0 {
+				t.Errorf("expired web session still has %v", got)
+			}
+			if got := f.contents(t, "s-recent"); len(got) != 1 {
+				t.Errorf("recent session has %v, want it untouched", got)
+			}
+			if got := f.contents(t, "s-sms"); len(got) != 1 || got[0] != entity.AnonymizedContent {
+				t.Errorf("sms session has %v, want the message anonymized", got)
+			}
+
+			// 已清理的会话不再处理
+			if run, err := uc.RunOnce(ctx); err != nil || run.SessionsScanned != 0 {
+				t.Fatalf("second run = %+v, %v, want nothing scanned", run, err)
+			}
+
+			// 只有管理员可以恢复，没有归档的会话无法恢复
+			if _, err := uc.RestoreSession(principalContext("a1", entity.RoleAgent), "s-old"); !errors.Is(err, usecase.ErrForbidden) {
+				t.Errorf("agent restore error = %v, want ErrForbidden", err)
+			}
+			if _, err := uc.RestoreSession(admin, "s-sms"); !errors.Is(err, usecase.ErrArchiveNotFound) {
+				t.Errorf("restore without archive error = %v, want ErrArchiveNotFound", err)
+			}
+			rec, err := uc.RestoreSession(admin, "s-old")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rec.RestoredAt.IsZero() || rec.MessageCount != 2 {
+				t.Errorf("restored record = %+v", rec)
+			}
+			if got := f.contents(t, "s-old"); len(got) != 2 || got[0] != "content m1" || got[1] != "content m2" {
+				t.Fatalf("restored messages = %v, want the archived content", got)
+			}
+
+			// 恢复未超过 RestoreTTL 时保留，之后重新清理且不重复归档
+			if run, err := uc.RunOnce(ctx); err != nil || run.SessionsPurged != 0 {
+				t.Fatalf("run within restore TTL = %+v, %v, want nothing purged", run, err)
+			}
+			rec.RestoredAt = now.Add(-2 * time.Hour)
+			if err := f.retention.SaveSessionRetention(ctx, rec); err != nil {
+				t.Fatal(err)
+			}
+			run, err = uc.RunOnce(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.SessionsPurged != 1 || run.SessionsArchived != 0 || run.MessagesDeleted != 2 {
+				t.Fatalf("run after restore TTL = %+v, want the restored session purged without a new archive", run)
+			}
+		})
+	}
+}
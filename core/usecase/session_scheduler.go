@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// EventSessionClosed 会话关闭事件
+	EventSessionClosed = "session_closed"
+
+	// SystemAddress 系统消息的发送方地址
+	SystemAddress = "S:system"
+	// SystemEventKey 系统通知在 Ext 中记录事件类型的键
+	SystemEventKey = "event"
+
+	SystemEventInactivityWarning = "inactivity_warning"
+	SystemEventSessionClosed     = "session_closed"
+
+	CloseReasonManual     = "manual"
+	CloseReasonInactivity = "inactivity"
+
+	// DefaultTimeoutChannel 未单独配置超时的渠道使用的策略键
+	DefaultTimeoutChannel = "default"
+)
+
+// SessionClosedEvent session_closed 事件负载
+type SessionClosedEvent struct {
+	SessionID string    `json:"sessionId"`
+	Reason    string    `json:"reason"`
+	EndTime   time.Time `json:"endTime"`
+}
+
+// SessionTimeoutPolicy 会话不活跃超时策略，零值表示不启用对应动作
+type SessionTimeoutPolicy struct {
+	WarnAfter  time.Duration // 不活跃多久后提醒客户
+	CloseAfter time.Duration // 不活跃多久后自动关闭
+}
+
+// SessionScheduler 定时扫描进行中的会话，提醒并关闭长时间不活跃的会话。
+// 每轮扫描的状态均从数据库推导(最后一条非系统消息与其后的提醒通知)，服务重启后可直接继续。
+type SessionScheduler struct {
+	chatUC   *ChatUseCase
+	policies map[string]SessionTimeoutPolicy // channel -> policy
+	interval time.Duration
+	log      *zap.Logger
+}
+
+// NewSessionScheduler 创建会话超时调度器
+func NewSessionScheduler(chatUC *ChatUseCase, policies map[string]SessionTimeoutPolicy, interval time.Duration, log *zap.Logger) *SessionScheduler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &SessionScheduler{
+		chatUC:   chatUC,
+		policies: policies,
+		interval: interval,
+		log:      log.Named("session-scheduler"),
+	}
+}
+
+// Run 按固定间隔扫描，直到 ctx 取消
+func (s *SessionScheduler) Run(ctx context.Context) {
+	ctx = WithSystemPrincipal(ctx)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.log.Info("Session inactivity scheduler started", zap.Duration("interval", s.interval))
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("Session inactivity scheduler stopped")
+			return
+		case <-ticker.C:
+			if err := s.Tick(ctx, time.Now()); err != nil {
+				s.log.Error("Session inactivity scan failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Tick 执行一轮扫描
+func (s *SessionScheduler) Tick(ctx context.Context, now time.Time) error {
+	sessions, err := s.chatUC.SessionRepo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if err := s.check(ctx, session, now); err != nil {
+			s.log.Warn("Failed to apply inactivity policy",
+				zap.String("sessionId", session.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// check 对单个会话应用超时策略
+func (s *SessionScheduler) check(ctx context.Context, session *entity.Session, now time.Time) error {
+	policy := s.policyFor(session.Channel)
+	if policy.WarnAfter <= 0 && policy.CloseAfter <= 0 {
+		return nil
+	}
+
+	lastActive, warned, err := s.inactivityState(ctx, session)
+	if err != nil {
+		return err
+	}
+	idle := now.Sub(lastActive)
+
+	switch {
+	case policy.CloseAfter > 0 && idle >= policy.CloseAfter:
+		s.log.Info("Closing inactive session",
+			zap.String("sessionId", session.ID), zap.Duration("idle", idle))
+		return s.chatUC.closeSession(ctx, session, CloseReasonInactivity)
+	case policy.WarnAfter > 0 && idle >= policy.WarnAfter && !warned && session.CID != "":
+		return s.chatUC.notifySystem(ctx, session, warningNotice(policy, idle),
+			SystemEventInactivityWarning, []string{session.CID})
+	}
+	return nil
+}
+
+// inactivityState 推导会话最后活跃时间(最后一条非系统消息)，以及此后是否已发送过提醒
+func (s *SessionScheduler) inactivityState(ctx context.Context, session *entity.Session) (time.Time, bool, error) {
+	lastActive := session.StartTime
+	if lastActive.IsZero() {
+		lastActive = session.CreatedAt
+	}
+
+	latest, err := s.chatUC.messageRepo.GetLatestExcludingSrc(ctx, session.ID, SystemAddress)
+	switch {
+	case err == nil:
+		if ts := time.UnixMilli(int64(latest.Ts)); ts.After(lastActive) {
+			lastActive = ts
+		}
+	case !errors.Is(err, repository.ErrNotFound):
+		return time.Time{}, false, err
+	}
+
+	// 最后活跃之后只有系统消息，其中出现过提醒说明已提醒过
+	notices, err := s.chatUC.messageRepo.GetBySessionIDAfter(ctx, session.ID, lastActive.UnixMilli())
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	for _, msg := range notices {
+		if msg.Src == SystemAddress && msg.Ext[SystemEventKey] == SystemEventInactivityWarning {
+			return lastActive, true, nil
+		}
+	}
+	return lastActive, false, nil
+}
+
+// policyFor 获取渠道对应的超时策略，未配置时使用默认策略
+func (s *SessionScheduler) policyFor(channel string) SessionTimeoutPolicy {
+	if channel == "" {
+		channel = entity.ChannelWeb
+	}
+	if policy, ok := s.policies[channel]; ok {
+		return policy
+	}
+	return s.policies[DefaultTimeoutChannel]
+}
+
+func warningNotice(policy SessionTimeoutPolicy, idle time.Duration) string {
+	if policy.CloseAfter > 0 {
+		remaining := (policy.CloseAfter - idle).Round(time.Minute)
+		if remaining < time.Minute {
+			remaining = time.Minute
+		}
+		return fmt.Sprintf("您已有一段时间未回复，会话将在 %d 分钟后自动结束。", int(remaining.Minutes()))
+	}
+	return "您已有一段时间未回复，如仍需帮助请继续留言。"
+}
+
+func closeNotice(reason string) string {
+	if reason == CloseReasonInactivity {
+		return "由于长时间未活动，本次会话已自动结束。"
+	}
+	return "本次会话已结束。"
+}
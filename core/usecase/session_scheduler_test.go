@@ -0,0 +1,209 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// schedulerFixture 调度器测试使用的消息与会话仓储
+type schedulerFixture struct {
+	messages repository.MessageRepository
+	sessions repository.SessionRepository
+	users    repository.UserRepository
+}
+
+func schedulerFixtures() map[string]func(t *testing.T) schedulerFixture {
+	return map[string]func(t *testing.T) schedulerFixture{
+		"memory": func(t *testing.T) schedulerFixture {
+			return schedulerFixture{
+				messages: infrarepo.NewMemoryMessageRepository(),
+				sessions: infrarepo.NewMemorySessionRepository(),
+				users:    infrarepo.NewMemoryUserRepository(),
+			}
+		},
+		"sqlite": func(t *testing.T) schedulerFixture {
+			repos := newSQLiteRepository(t)
+			return schedulerFixture{messages: repos.messages, sessions: repos.sessions, users: repos.users}
+		},
+	}
+}
+
+func (f schedulerFixture) session(t *testing.T, id, channel string, start time.Time) {
+	t.Helper()
+	session := &entity.Session{ID: id, CID: "c-" + id, AgentId: "a1", Channel: channel,
+		Status: entity.SessionStatusActive, StartTime: start, CreatedAt: start, CreatedBy: "c-" + id, UpdatedBy: "c-" + id}
+	if err := f.sessions.Create(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f schedulerFixture) message(t *testing.T, msgID, sessionID, src string, at time.Time, ext map[string]interface{}) {
+	t.Helper()
+	msg := &entity.Message{MsgID: msgID, SessionID: sessionID, MsgType: entity.MsgTypeMessage, ContentType: entity.ContentTypeText,
+		Src: src, Dst: "A:a1", Content: msgID, Status: entity.StatusSent, Ts: entity.StringTimestamp(at.UnixMilli()), Ext: ext}
+	if src == usecase.SystemAddress {
+		msg.MsgType = entity.MsgTypeNotification
+	}
+	if err := f.messages.Create(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// warnings 统计会话中的不活跃提醒
+func (f schedulerFixture) warnings(t *testing.T, sessionID string) int {
+	t.Helper()
+	messages, err := f.messages.GetBySessionID(context.Background(), sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, msg := range messages {
+		if msg.Src == usecase.SystemAddress && msg.Ext[usecase.SystemEventKey] == usecase.SystemEventInactivityWarning {
+			n++
+		}
+	}
+	return n
+}
+
+func (f schedulerFixture) status(t *testing.T, sessionID string) string {
+	t.Helper()
+	session, err := f.sessions.GetByID(context.Background(), sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session.Status
+}
+
+func TestSessionSchedulerTick(t *testing.T) {
+	for name, newFixture := range schedulerFixtures() {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			chatUC := usecase.NewChatUseCase(f.messages, f.sessions, f.users, infrarepo.NewMemoryReactionRepository())
+			scheduler := usecase.NewSessionScheduler(chatUC, map[string]usecase.SessionTimeoutPolicy{
+				usecase.DefaultTimeoutChannel: {WarnAfter: 5 * time.Minute, CloseAfter: 10 * time.Minute},
+				"email":                       {CloseAfter: time.Hour},
+			}, time.Minute, zap.NewNop())
+			ctx := usecase.WithSystemPrincipal(context.Background())
+
+			t0 := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+			// web 未单独配置，使用 default 策略；email 只在一小时后关闭，不提醒
+			f.session(t, "web", entity.ChannelWeb, t0)
+			f.session(t, "mail", "email", t0)
+			f.message(t, "m1", "web", "U:c-web", t0.Add(time.Minute), nil)
+
+			tick := func(now time.Time) {
+				t.Helper()
+				if err := scheduler.Tick(ctx, now); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tick(t0.Add(5 * time.Minute))
+			if n := f.warnings(t, "web"); n != 0 {
+				t.Fatalf("warnings after 4m idle = %d, want 0", n)
+			}
+			tick(t0.Add(7 * time.Minute))
+			tick(t0.Add(8 * time.Minute))
+			if n := f.warnings(t, "web"); n != 1 {
+				t.Fatalf("warnings after 7m idle = %d, want exactly 1", n)
+			}
+			tick(t0.Add(20 * time.Minute))
+			if n, status := f.warnings(t, "mail"), f.status(t, "mail"); n != 0 || status != entity.SessionStatusActive {
+				t.Fatalf("email session after 20m: warnings=%d status=%s, want 0 and active", n, status)
+			}
+			if status := f.status(t, "web"); status != entity.SessionStatusClosed {
+				t.Fatalf("web session after 19m idle = %s, want closed", status)
+			}
+			tick(t0.Add(61 * time.Minute))
+			if status := f.status(t, "mail"); status != entity.SessionStatusClosed {
+				t.Fatalf("email session after 61m idle = %s, want closed", status)
+			}
+		})
+	}
+}
+
+func TestSessionSchedulerRestartsOnCustomerActivity(t *testing.T) {
+	for name, newFixture := range schedulerFixtures() {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			chatUC := usecase.NewChatUseCase(f.messages, f.sessions, f.users, infrarepo.NewMemoryReactionRepository())
+			scheduler := usecase.NewSessionScheduler(chatUC, map[string]usecase.SessionTimeoutPolicy{
+				usecase.DefaultTimeoutChannel: {WarnAfter: 5 * time.Minute, CloseAfter: 10 * time.Minute},
+			}, time.Minute, zap.NewNop())
+			ctx := usecase.WithSystemPrincipal(context.Background())
+
+			t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+			f.session(t, "s1", entity.ChannelWeb, t0)
+			if err := scheduler.Tick(ctx, t0.Add(6*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if n := f.warnings(t, "s1"); n != 1 {
+				t.Fatalf("warnings after 6m idle = %d, want 1", n)
+			}
+
+			// 系统提醒按实际时间写入，客户在提醒之后回复，重新计时
+			t1 := time.Now().Add(time.Second).Truncate(time.Millisecond)
+			f.message(t, "m1", "s1", "U:c-s1", t1, nil)
+			if err := scheduler.Tick(ctx, t1.Add(4*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if n, status := f.warnings(t, "s1"), f.status(t, "s1"); n != 1 || status != entity.SessionStatusActive {
+				t.Fatalf("4m after the reply: warnings=%d status=%s, want 1 and active", n, status)
+			}
+			if err := scheduler.Tick(ctx, t1.Add(6*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if n := f.warnings(t, "s1"); n != 2 {
+				t.Fatalf("warnings 6m after the reply = %d, want a new warning", n)
+			}
+			if err := scheduler.Tick(ctx, t1.Add(11*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if status := f.status(t, "s1"); status != entity.SessionStatusClosed {
+				t.Fatalf("session 11m after the reply = %s, want closed", status)
+			}
+		})
+	}
+}
+
+func TestSessionSchedulerIgnoresSystemMessages(t *testing.T) {
+	for name, newFixture := range schedulerFixtures() {
+		t.Run(name, func(t *testing.T) {
+			f := newFixture(t)
+			chatUC := usecase.NewChatUseCase(f.messages, f.sessions, f.users, infrarepo.NewMemoryReactionRepository())
+			scheduler := usecase.NewSessionScheduler(chatUC, map[string]usecase.SessionTimeoutPolicy{
+				usecase.DefaultTimeoutChannel: {WarnAfter: 5 * time.Minute, CloseAfter: 10 * time.Minute},
+			}, time.Minute, zap.NewNop())
+			ctx := usecase.WithSystemPrincipal(context.Background())
+
+			t0 := time.Now().Truncate(time.Second)
+			f.session(t, "s1", entity.ChannelWeb, t0)
+			f.message(t, "m1", "s1", "U:c-s1", t0.Add(8*time.Minute), nil)
+			// 最后活跃之后的系统消息多于以往的回看条数
+			for i := 0; i < 30; i++ {
+				f.message(t, "sys"+string(rune('a'+i)), "s1", usecase.SystemAddress,
+					t0.Add(8*time.Minute+time.Duration(i+1)*time.Second), map[string]interface{}{usecase.SystemEventKey: "barge_in"})
+			}
+
+			if err := scheduler.Tick(ctx, t0.Add(11*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if n, status := f.warnings(t, "s1"), f.status(t, "s1"); n != 0 || status != entity.SessionStatusActive {
+				t.Fatalf("3m after the last customer message: warnings=%d status=%s, want 0 and active", n, status)
+			}
+			if err := scheduler.Tick(ctx, t0.Add(14*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			if n := f.warnings(t, "s1"); n != 1 {
+				t.Fatalf("warnings after 6m idle = %d, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package usecase_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+)
+
+var sqliteSeq atomic.Int64
+
+// sqliteRepos 同一内存数据库上的基础仓储
+type sqliteRepos struct {
+	base     *infrarepo.SQLiteRepository
+	messages *infrarepo.SQLiteMessageRepository
+	sessions *infrarepo.SQLiteSessionRepository
+	users    *infrarepo.SQLiteUserRepository
+}
+
+// newSQLiteRepository 创建按 sql/init.sql 建表的内存数据库，测试结束时关闭
+func newSQLiteRepository(t *testing.T) sqliteRepos {
+	t.Helper()
+	schema, err := os.ReadFile("../../sql/init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 共享缓存使连接池中的连接看到同一个内存库；holder 保持库在测试期间不被释放
+	dsn := fmt.Sprintf("file:test%d?mode=memory&cache=shared", sqliteSeq.Add(1))
+	holder, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := holder.Exec(string(schema)); err != nil {
+		holder.Close()
+		t.Fatal(err)
+	}
+	base, messages, sessions, users, err := infrarepo.NewSQLiteRepository(dsn)
+	if err != nil {
+		holder.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		base.Close()
+		holder.Close()
+	})
+	return sqliteRepos{base: base, messages: messages, sessions: sessions, users: users}
+}
@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// EventSessionReassigned 会话被主管接管后通知客户与原客服
+	EventSessionReassigned = "session_reassigned"
+	// SystemEventBargeIn 主管接管会话的系统通知
+	SystemEventBargeIn = "barge_in"
+	// ExtWhisper 主管耳语消息在 Ext 中的标记
+	ExtWhisper = "whisper"
+)
+
+var (
+	// ErrSupervisorOnly 只有主管或管理员可以执行该操作
+	ErrSupervisorOnly = errors.New("only supervisors can perform this action")
+	// ErrSessionNotActive 会话已结束
+	ErrSessionNotActive = errors.New("session is not active")
+	// ErrEmptyWhisper 耳语内容为空
+	ErrEmptyWhisper = errors.New("whisper content is required")
+)
+
+// MonitorRoom 返回监听会话消息流的房间ID
+func MonitorRoom(sessionID string) string {
+	return "monitor:" + sessionID
+}
+
+// RoomSubscriber 管理实时连接的房间订阅(由 WebSocket 层提供)
+type RoomSubscriber interface {
+	JoinRoom(userID, roomID string)
+	LeaveRoom(userID, roomID string)
+}
+
+// SessionReassigned 会话被接管事件
+type SessionReassigned struct {
+	SessionID     string `json:"sessionId"`
+	PreviousAgent string `json:"previousAgentId"`
+	AgentID       string `json:"agentId"`
+}
+
+// SupervisorUseCase 主管监听、耳语与接管用例，所有操作均写入审计日志
+type SupervisorUseCase struct {
+	chatUC    *ChatUseCase
+	auditRepo repository.AuditRepository
+	rooms     RoomSubscriber
+	log       *zap.Logger
+}
+
+// NewSupervisorUseCase 创建主管用例
+func NewSupervisorUseCase(chatUC *ChatUseCase, auditRepo repository.AuditRepository, rooms RoomSubscriber, log *zap.Logger) *SupervisorUseCase {
+	return &SupervisorUseCase{
+		chatUC:    chatUC,
+		auditRepo: auditRepo,
+		rooms:     rooms,
+		log:       log,
+	}
+}
+
+// Monitor 静默订阅会话消息流，客户与客服均无感知
+func (uc *SupervisorUseCase) Monitor(ctx context.Context, sessionID string) (*entity.Session, error) {
+	principal, session, err := uc.authorize(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先留痕再订阅，审计失败时不开始监听
+	if err := uc.audit(ctx, principal, entity.AuditActionMonitorStart, session, session.AgentId, nil); err != nil {
+		return nil, err
+	}
+	uc.rooms.JoinRoom(principal.UserID, MonitorRoom(session.ID))
+	return session, nil
+}
+
+// StopMonitor 取消订阅会话消息流
+func (uc *SupervisorUseCase) StopMonitor(ctx context.Context, sessionID string) error {
+	principal := PrincipalFromContext(ctx)
+	if !principal.IsSupervisor() {
+		return ErrSupervisorOnly
+	}
+	session, err := uc.chatUC.SessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	uc.rooms.LeaveRoom(principal.UserID, MonitorRoom(session.ID))
+	return uc.audit(ctx, principal, entity.AuditActionMonitorStop, session, session.AgentId, nil)
+}
+
+// Whisper 向会话的负责客服发送仅客服可见的耳语
+func (uc *SupervisorUseCase) Whisper(ctx context.Context, sessionID, content string) (*entity.Message, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyWhisper
+	}
+	principal, session, err := uc.authorize(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	whisper := &entity.Message{
+		MsgID:       utils.GenerateMessageID(),
+		SessionID:   session.ID,
+		Src:         "A:" + principal.UserID,
+		Dst:         "A:" + session.AgentId,
+		Content:     content,
+		MsgType:     entity.MsgTypeInternalNote,
+		ContentType: entity.ContentTypeText,
+		Ts:          entity.StringTimestamp(now.UnixMilli()),
+		Ext:         map[string]interface{}{ExtWhisper: true},
+		CreatedBy:   principal.UserID,
+		UpdatedBy:   principal.UserID,
+	}
+	if err := uc.chatUC.SendMessage(ctx, whisper); err != nil {
+		return nil, err
+	}
+	// 启用发件箱时由分发器推送给负责客服，这里不再重复推送
+	if !uc.chatUC.OutboxEnabled() && uc.chatUC.emitter != nil && session.AgentId != "" {
+		_ = uc.chatUC.emitter.PushMessage(*whisper, []string{session.AgentId})
+	}
+
+	err = uc.audit(ctx, principal, entity.AuditActionWhisper, session, session.AgentId, map[string]interface{}{
+		"msgId": whisper.MsgID,
+	})
+	return whisper, err
+}
+
+// BargeIn 主管接管会话：会话改派给主管，并通知客户与原客服
+func (uc *SupervisorUseCase) BargeIn(ctx context.Context, sessionID string) (*entity.Session, error) {
+	principal, session, err := uc.authorize(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	previous := session.AgentId
+	if previous == principal.UserID {
+		return session, nil
+	}
+
+	// 改派与接入通知在同一事务内提交，由发件箱投递
+	notices := systemNotices(session, "主管已接入本次会话。", SystemEventBargeIn, nonEmpty(session.CID, previous))
+	err = uc.chatUC.withinTx(ctx, func(ctx context.Context) error {
+		if err := uc.chatUC.assignSession(ctx, session, principal.UserID); err != nil {
+			return err
+		}
+		return uc.chatUC.saveNotices(ctx, notices)
+	})
+	if err != nil {
+		return nil, err
+	}
+	uc.chatUC.deliverNotices(notices)
+	uc.chatUC.emit(EventSessionReassigned, SessionReassigned{
+		SessionID:     session.ID,
+		PreviousAgent: previous,
+		AgentID:       principal.UserID,
+	}, nonEmpty(session.CID, previous, principal.UserID))
+
+	// 接管后直接参与会话，不再需要静默监听
+	uc.rooms.LeaveRoom(principal.UserID, MonitorRoom(session.ID))
+	err = uc.audit(ctx, principal, entity.AuditActionBargeIn, session, previous, nil)
+	return session, err
+}
+
+// ListAuditLogs 查询审计日志
+func (uc *SupervisorUseCase) ListAuditLogs(ctx context.Context, query entity.AuditQuery) ([]*entity.AuditLog, error) {
+	if !PrincipalFromContext(ctx).IsSupervisor() {
+		return nil, ErrSupervisorOnly
+	}
+	return uc.auditRepo.List(ctx, query)
+}
+
+// authorize 校验主管身份并返回进行中的会话
+func (uc *SupervisorUseCase) authorize(ctx context.Context, sessionID string) (entity.Principal, *entity.Session, error) {
+	principal := PrincipalFromContext(ctx)
+	if !principal.IsSupervisor() {
+		return principal, nil, ErrSupervisorOnly
+	}
+
+	session, err := uc.chatUC.SessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return principal, nil, err
+	}
+	if session.Status == entity.SessionStatusClosed {
+		return principal, nil, ErrSessionNotActive
+	}
+	return principal, session, nil
+}
+
+// audit 写入审计日志
+func (uc *SupervisorUseCase) audit(ctx context.Context, principal entity.Principal, action string, session *entity.Session, targetID string, detail map[string]interface{}) error {
+	err := uc.auditRepo.Create(ctx, &entity.AuditLog{
+		ActorID:   principal.UserID,
+		ActorRole: principal.Role,
+		Action:    action,
+		SessionID: session.ID,
+		TargetID:  targetID,
+		Detail:    detail,
+	})
+	if err != nil {
+		uc.log.Error("Failed to write audit log",
+			zap.String("action", action),
+			zap.String("actorID", principal.UserID),
+			zap.String("sessionID", session.ID),
+			zap.Error(err))
+	}
+	return err
+}
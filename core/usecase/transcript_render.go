@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	texttemplate "text/template"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+)
+
+const transcriptTimeLayout = "2006-01-02 15:04:05 MST"
+
+var transcriptFuncs = map[string]interface{}{
+	"time": func(t interface{ Format(string) string }) string { return t.Format(transcriptTimeLayout) },
+}
+
+var transcriptText = texttemplate.Must(texttemplate.New("transcript").Funcs(transcriptFuncs).Parse(
+	`Conversation {{.SessionID}}
+Started: {{time .StartTime}}
+{{- if not .EndTime.IsZero}}
+Ended:   {{time .EndTime}}
+{{- end}}
+
+{{range .Entries -}}
+[{{time .SentAt}}] {{.SenderName}}: {{if .IsAttachment}}[attachment] {{.AttachmentURL}}{{else}}{{.Content}}{{end}}
+{{end}}`))
+
+// html/template 按上下文转义消息内容，非 http(s) 的附件地址不会输出为可点击的链接
+var transcriptHTML = htmltemplate.Must(htmltemplate.New("transcript").Funcs(transcriptFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Conversation {{.SessionID}}</title>
+<style>
+body { font-family: sans-serif; max-width: 720px; margin: 0 auto; }
+.entry { margin: 8px 0; }
+.meta { color: #666; font-size: 12px; }
+.system { color: #888; font-style: italic; }
+.content { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h2>Conversation {{.SessionID}}</h2>
+<p class="meta">Started {{time .StartTime}}{{if not .EndTime.IsZero}}, ended {{time .EndTime}}{{end}}</p>
+{{range .Entries}}<div class="entry{{if eq .SenderRole "system"}} system{{end}}">
+<div class="meta">{{.SenderName}} &middot; {{time .SentAt}}</div>
+{{if .IsAttachment}}<a href="{{.AttachmentURL}}">{{.AttachmentURL}}</a>{{else}}<div class="content">{{.Content}}</div>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
+
+// RenderTranscript 按 format 输出会话记录
+func RenderTranscript(w io.Writer, transcript *entity.Transcript, format string) error {
+	switch format {
+	case entity.TranscriptJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(transcript)
+	case entity.TranscriptText:
+		return transcriptText.Execute(w, transcript)
+	case entity.TranscriptHTML:
+		return transcriptHTML.Execute(w, transcript)
+	default:
+		return ErrInvalidTranscriptFormat
+	}
+}
@@ -0,0 +1,231 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidTranscriptFormat 不支持的导出格式
+	ErrInvalidTranscriptFormat = errors.New("transcript format must be json, txt or html")
+	// ErrInvalidTimezone 无法识别的 IANA 时区名
+	ErrInvalidTimezone = errors.New("unknown time zone")
+)
+
+// MailSender 发送邮件，由基础设施层实现(SMTP 等)
+type MailSender interface {
+	Send(ctx context.Context, msg *entity.MailMessage) error
+}
+
+// TranscriptOptions 会话记录配置
+type TranscriptOptions struct {
+	Timezone     *time.Location // 请求未指定时区及邮件使用的缺省时区，nil 为 UTC
+	EmailOnClose bool           // 会话关闭后将记录发送到客户邮箱
+	From         string
+	Bcc          []string // 合规存档邮箱，每封会话记录邮件都密送
+}
+
+// TranscriptUseCase 生成面向客户的会话记录，并可在会话关闭后通过邮件发送
+type TranscriptUseCase struct {
+	chatUC   *ChatUseCase
+	userRepo repository.UserRepository
+	mailer   MailSender
+	opts     TranscriptOptions
+	log      *zap.Logger
+}
+
+var _ OutboxSink = (*TranscriptUseCase)(nil)
+
+// NewTranscriptUseCase 创建会话记录用例；mailer 为 nil 时不发送邮件
+func NewTranscriptUseCase(chatUC *ChatUseCase, userRepo repository.UserRepository, mailer MailSender,
+	opts TranscriptOptions, log *zap.Logger) *TranscriptUseCase {
+	if opts.Timezone == nil {
+		opts.Timezone = time.UTC
+	}
+	return &TranscriptUseCase{
+		chatUC:   chatUC,
+		userRepo: userRepo,
+		mailer:   mailer,
+		opts:     opts,
+		log:      log.Named("transcript"),
+	}
+}
+
+// LoadTimezone 解析 IANA 时区名，空字符串返回缺省时区
+func (uc *TranscriptUseCase) LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return uc.opts.Timezone, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// GetTranscript 生成会话记录，权限与查看会话消息相同；内部备注对任何请求方都不输出
+func (uc *TranscriptUseCase) GetTranscript(ctx context.Context, sessionID string, loc *time.Location) (*entity.Transcript, error) {
+	session, err := uc.chatUC.SessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := uc.chatUC.GetSessionMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = uc.opts.Timezone
+	}
+
+	transcript := &entity.Transcript{
+		SessionID:   session.ID,
+		CID:         session.CID,
+		Channel:     session.Channel,
+		StartTime:   session.StartTime.In(loc),
+		Timezone:    loc.String(),
+		GeneratedAt: time.Now().In(loc),
+		Entries:     []entity.TranscriptEntry{},
+	}
+	if !session.EndTime.IsZero() {
+		transcript.EndTime = session.EndTime.In(loc)
+	}
+
+	names := make(map[string]*entity.User)
+	for _, msg := range messages {
+		if !transcriptVisible(session, msg) {
+			continue
+		}
+		entry := entity.TranscriptEntry{
+			MsgID:       msg.MsgID,
+			SentAt:      time.UnixMilli(int64(msg.Ts)).In(loc),
+			ContentType: msg.ContentType,
+			Content:     msg.Content,
+		}
+		if msg.ContentType == entity.ContentTypeImage || msg.ContentType == entity.ContentTypeFile {
+			entry.AttachmentURL = msg.Content
+		}
+		uc.resolveSender(ctx, &entry, msg.Src, names)
+		transcript.Entries = append(transcript.Entries, entry)
+	}
+	sort.SliceStable(transcript.Entries, func(i, j int) bool {
+		return transcript.Entries[i].SentAt.Before(transcript.Entries[j].SentAt)
+	})
+	return transcript, nil
+}
+
+// transcriptVisible 会话记录只包含普通消息与发给客户的系统通知(每位参与者各存一条，只取一条)
+func transcriptVisible(session *entity.Session, msg *entity.Message) bool {
+	switch msg.MsgType {
+	case entity.MsgTypeMessage:
+		return true
+	case entity.MsgTypeNotification:
+		return entity.UserIDFromAddress(msg.Dst) == session.CID
+	default:
+		return false
+	}
+}
+
+// resolveSender 填充发送方名称与角色，已删除的用户使用 ID 作为名称
+func (uc *TranscriptUseCase) resolveSender(ctx context.Context, entry *entity.TranscriptEntry, src string, cache map[string]*entity.User) {
+	if src == SystemAddress {
+		entry.SenderID = src
+		entry.SenderName = "System"
+		entry.SenderRole = entity.TranscriptSenderSystem
+		return
+	}
+
+	userID := entity.UserIDFromAddress(src)
+	entry.SenderID = userID
+	entry.SenderName = userID
+	entry.SenderRole = entity.RoleAgent
+	if strings.HasPrefix(src, "U:") {
+		entry.SenderRole = entity.RoleCustomer
+	}
+
+	user, ok := cache[userID]
+	if !ok {
+		var err error
+		user, err = uc.userRepo.GetByID(ctx, userID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			uc.log.Warn("Failed to resolve transcript sender", zap.String("userId", userID), zap.Error(err))
+		}
+		cache[userID] = user
+	}
+	if user != nil {
+		entry.SenderName = user.Name()
+		entry.SenderRole = user.Role
+	}
+}
+
+// HandleOutboxEvent 会话关闭后发送会话记录邮件。发送失败时返回错误由发件箱重试
+func (uc *TranscriptUseCase) HandleOutboxEvent(ctx context.Context, event *entity.OutboxEvent) error {
+	if event.EventType != entity.WebhookEventSessionClosed || !uc.opts.EmailOnClose || uc.mailer == nil {
+		return nil
+	}
+	var data SessionClosedData
+	if err := json.Unmarshal(event.Payload, &data); err != nil {
+		return err
+	}
+	if data.Session == nil {
+		return nil
+	}
+	return uc.EmailTranscript(WithSystemPrincipal(ctx), data.Session.ID)
+}
+
+// EmailTranscript 将会话记录发送到客户邮箱并密送合规存档邮箱，均未配置时跳过
+func (uc *TranscriptUseCase) EmailTranscript(ctx context.Context, sessionID string) error {
+	if uc.mailer == nil {
+		return nil
+	}
+	transcript, err := uc.GetTranscript(ctx, sessionID, uc.opts.Timezone)
+	if errors.Is(err, repository.ErrNotFound) {
+		// 会话已被擦除
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var to []string
+	customer, err := uc.userRepo.GetByID(ctx, transcript.CID)
+	if err == nil && customer.Email != "" {
+		to = append(to, customer.Email)
+	} else if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if len(to) == 0 && len(uc.opts.Bcc) == 0 {
+		uc.log.Debug("No recipient for transcript", zap.String("sessionId", sessionID))
+		return nil
+	}
+
+	var text, html bytes.Buffer
+	if err := RenderTranscript(&text, transcript, entity.TranscriptText); err != nil {
+		return err
+	}
+	if err := RenderTranscript(&html, transcript, entity.TranscriptHTML); err != nil {
+		return err
+	}
+	msg := &entity.MailMessage{
+		From:     uc.opts.From,
+		To:       to,
+		Bcc:      uc.opts.Bcc,
+		Subject:  fmt.Sprintf("Transcript of your conversation on %s", transcript.StartTime.Format("2006-01-02")),
+		TextBody: text.String(),
+		HTMLBody: html.String(),
+	}
+	if err := uc.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send transcript: %w", err)
+	}
+	uc.log.Info("Transcript emailed", zap.String("sessionId", sessionID), zap.Int("recipients", len(to)+len(uc.opts.Bcc)))
+	return nil
+}
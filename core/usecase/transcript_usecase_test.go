@@ -0,0 +1,115 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// recordingMailer 记录发出的邮件，前 failures 次返回错误
+type recordingMailer struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []*entity.MailMessage
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg *entity.MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+	if m.attempts <= m.failures {
+		return errors.New("451 mailbox busy")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestTranscriptEmailedAfterSessionClose(t *testing.T) {
+	ctx := context.Background()
+	messages := infrarepo.NewMemoryMessageRepository()
+	sessions := infrarepo.NewMemorySessionRepository()
+	users := infrarepo.NewMemoryUserRepository()
+	outbox := infrarepo.NewMemoryOutboxRepository(messages, sessions)
+	dispatcher := usecase.NewOutboxDispatcher(outbox, usecase.OutboxOptions{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}, zap.NewNop())
+	chatUC := usecase.NewChatUseCase(messages, sessions, users, infrarepo.NewMemoryReactionRepository())
+	chatUC.SetOutbox(outbox, dispatcher)
+
+	mailer := &recordingMailer{failures: 1}
+	transcripts := usecase.NewTranscriptUseCase(chatUC, users, mailer, usecase.TranscriptOptions{
+		EmailOnClose: true,
+		From:         "support@cland.org",
+		Bcc:          []string{"archive@cland.org"},
+	}, zap.NewNop())
+	dispatcher.AddSink("transcript", transcripts)
+
+	for _, user := range []*entity.User{
+		{ID: "c1", Username: "ann", DisplayName: "Ann", Email: "ann@example.com", Role: entity.RoleCustomer},
+		{ID: "a1", Username: "bob", DisplayName: "Bob", Role: entity.RoleAgent},
+	} {
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	session := &entity.Session{ID: "s1", CID: "c1", AgentId: "a1", Status: entity.SessionStatusActive, StartTime: time.Now(), CreatedAt: time.Now()}
+	if err := sessions.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	customer := principalContext("c1", entity.RoleCustomer)
+	agent := principalContext("a1", entity.RoleAgent)
+	for _, send := range []struct {
+		ctx     context.Context
+		message *entity.Message
+	}{
+		{customer, &entity.Message{MsgID: "m1", MsgType: entity.MsgTypeMessage, Src: "U:c1", Dst: "A:a1", Content: "Where is my order?"}},
+		{agent, &entity.Message{MsgID: "m2", MsgType: entity.MsgTypeInternalNote, Src: "A:a1", Content: "VIP customer"}},
+		{agent, &entity.Message{MsgID: "m3", MsgType: entity.MsgTypeMessage, Src: "A:a1", Dst: "U:c1", Content: "It ships today."}},
+	} {
+		send.message.SessionID = "s1"
+		send.message.ContentType = entity.ContentTypeText
+		if err := chatUC.SendMessage(send.ctx, send.message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := chatUC.CloseSession(agent, "s1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次发送失败，由发件箱重试
+	dispatcher.Dispatch(ctx, time.Now().Add(time.Hour))
+	if len(mailer.sent) != 0 {
+		t.Fatalf("transcript sent despite the mail failure")
+	}
+	dispatcher.Dispatch(ctx, time.Now().Add(2*time.Hour))
+
+	if mailer.attempts != 2 || len(mailer.sent) != 1 {
+		t.Fatalf("mail attempts=%d sent=%d, want 2 attempts and 1 mail", mailer.attempts, len(mailer.sent))
+	}
+	mail := mailer.sent[0]
+	if strings.Join(mail.To, ",") != "ann@example.com" || strings.Join(mail.Bcc, ",") != "archive@cland.org" || mail.From != "support@cland.org" {
+		t.Errorf("mail envelope From=%q To=%v Bcc=%v", mail.From, mail.To, mail.Bcc)
+	}
+	for _, want := range []string{"Ann: Where is my order?", "Bob: It ships today."} {
+		if !strings.Contains(mail.TextBody, want) {
+			t.Errorf("transcript missing %q:\n%s", want, mail.TextBody)
+		}
+	}
+	if strings.Contains(mail.TextBody, "VIP customer") || strings.Contains(mail.HTMLBody, "VIP customer") {
+		t.Error("transcript contains an internal note")
+	}
+	if pending, _ := outbox.CountPending(ctx); pending != 0 {
+		t.Errorf("%d outbox events still pending", pending)
+	}
+}
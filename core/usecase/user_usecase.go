@@ -2,6 +2,9 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"net/mail"
+	"strings"
 	"time"
 
 	"cland.org/cland-chat-service/common/utils"
@@ -9,76 +12,177 @@ import (
 	"cland.org/cland-chat-service/core/domain/repository"
 )
 
+// maxEmailLen 邮箱最大长度，与 t_user.email 列一致
+const maxEmailLen = 255
+
+// ErrInvalidEmail 访客留下的邮箱格式不合法
+var ErrInvalidEmail = errors.New("invalid email")
+
 type UserUseCase struct {
 	userRepo    repository.UserRepository
 	sessionRepo repository.SessionRepository
+	authUC      *AuthUseCase
+	publisher   EventPublisher
+	tx          repository.TxManager
+	outbox      repository.OutboxRepository
+	dispatcher  *OutboxDispatcher
 }
 
 func NewUserUseCase(
 	userRepo repository.UserRepository,
 	sessionRepo repository.SessionRepository,
+	authUC *AuthUseCase,
 ) *UserUseCase {
 	return &UserUseCase{
 		userRepo:    userRepo,
 		sessionRepo: sessionRepo,
+		authUC:      authUC,
+	}
+}
+
+// SetEventPublisher 设置业务事件发布器(webhook)
+func (uc *UserUseCase) SetEventPublisher(publisher EventPublisher) {
+	uc.publisher = publisher
+}
+
+// SetTxManager 设置工作单元，访客与会话在同一事务内创建；未设置时逐条写入
+func (uc *UserUseCase) SetTxManager(tx repository.TxManager) {
+	uc.tx = tx
+}
+
+// SetOutbox 启用事务性发件箱，session.created 随会话写入并由分发器投递
+func (uc *UserUseCase) SetOutbox(outbox repository.OutboxRepository, dispatcher *OutboxDispatcher) {
+	uc.outbox = outbox
+	uc.dispatcher = dispatcher
+}
+
+// withinTx 在工作单元内执行 fn，未设置工作单元时直接执行
+func (uc *UserUseCase) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if uc.tx == nil {
+		return fn(ctx)
 	}
+	return uc.tx.WithinTx(ctx, fn)
 }
 
 type InitUserResponse struct {
 	SessionID    string
 	SubSessionID string
-	Token        string
+	Tokens       *TokenPair
 	ClandCID     string
 }
 
-func (uc *UserUseCase) InitUser(ctx context.Context, existingCID string) (*InitUserResponse, error) {
+// InitUser 初始化访客；只有持有该 CID 有效令牌的请求才能沿用已有 CID，否则签发新的访客身份。
+// email 为访客留下的联系邮箱，可为空，非空时保存到客户记录，用于离线提醒
+func (uc *UserUseCase) InitUser(ctx context.Context, existingCID, email string) (*InitUserResponse, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate or validate CID
 	var clandCID string
-	if utils.IsValidClandCID(existingCID) {
+	principal := PrincipalFromContext(ctx)
+	if utils.IsValidClandCID(existingCID) && principal.UserID == existingCID {
 		clandCID = existingCID
 	} else {
 		clandCID = utils.GenerateClandCID()
 	}
 
-	// Create or update user
-	user := &entity.User{
-		ID:         clandCID,
-		Username:   "guest_" + clandCID[1:7],
-		Role:       "customer",
-		Status:     "online",
-		LastActive: time.Now(),
-	}
+	// 访客与会话一起提交，会话写入失败时不留下孤立的访客记录
+	var user *entity.User
+	var session *entity.Session
+	err = uc.withinTx(ctx, func(ctx context.Context) error {
+		// Reuse the stored user or create a guest
+		var err error
+		user, err = uc.userRepo.GetByID(ctx, clandCID)
+		if errors.Is(err, repository.ErrNotFound) {
+			user = newCustomer(clandCID, email)
+			err = uc.userRepo.Create(ctx, user)
+			if errors.Is(err, repository.ErrDuplicateKey) {
+				// 该 CID 已合并到其他客户记录，改用新的访客身份
+				clandCID = utils.GenerateClandCID()
+				user = newCustomer(clandCID, email)
+				err = uc.userRepo.Create(ctx, user)
+			}
+		} else if err == nil && email != "" && email != user.Email {
+			user.Email = email
+			user.UpdatedBy = clandCID
+			err = uc.userRepo.Update(ctx, user)
+		}
+		if err != nil {
+			return err
+		}
 
-	if err := uc.userRepo.Create(ctx, user); err != nil {
+		// Create new session
+		session = &entity.Session{
+			ID:           utils.GenerateSessionID(),
+			CID:          clandCID,
+			Channel:      entity.ChannelWeb,
+			SubSessionID: utils.GenerateSubSessionID(),
+			Status:       "active",
+			CreatedAt:    time.Now(),
+		}
+		return uc.createSession(ctx, session)
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	// Create new session
-	sessionID := utils.GenerateSessionID()
-	subSessionID := utils.GenerateSubSessionID()
-
-	session := &entity.Session{
-		ID:           sessionID,
-		CID:          clandCID,
-		SubSessionID: subSessionID,
-		Status:       "active",
-		CreatedAt:    time.Now(),
-	}
-
-	if err := uc.sessionRepo.Create(ctx, session); err != nil {
-		return nil, err
+	if uc.outbox != nil {
+		uc.dispatcher.Notify()
+	} else if uc.publisher != nil {
+		uc.publisher.Publish(ctx, entity.WebhookEventSessionCreated, session)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(clandCID)
+	// Issue access and refresh tokens
+	tokens, err := uc.authUC.IssueTokens(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
 	return &InitUserResponse{
-		SessionID:    sessionID,
-		SubSessionID: subSessionID,
-		Token:        token,
+		SessionID:    session.ID,
+		SubSessionID: session.SubSessionID,
+		Tokens:       tokens,
 		ClandCID:     clandCID,
 	}, nil
 }
+
+// createSession 保存新会话，启用发件箱时同时写入 session.created
+func (uc *UserUseCase) createSession(ctx context.Context, session *entity.Session) error {
+	if uc.outbox == nil {
+		return uc.sessionRepo.Create(ctx, session)
+	}
+	event, err := outboxEvent(entity.WebhookEventSessionCreated, session.ID, session)
+	if err != nil {
+		return err
+	}
+	return uc.outbox.CreateSession(ctx, session, event)
+}
+
+// newCustomer 构造访客记录
+func newCustomer(clandCID, email string) *entity.User {
+	return &entity.User{
+		ID:         clandCID,
+		UID:        clandCID,
+		Username:   "guest_" + clandCID[1:7],
+		Email:      email,
+		Role:       entity.RoleCustomer,
+		Status:     "online",
+		CreatedBy:  clandCID,
+		UpdatedBy:  clandCID,
+		LastActive: time.Now(),
+	}
+}
+
+// normalizeEmail 去除首尾空白并校验邮箱，"名字 <地址>" 形式只保留地址
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || len(addr.Address) > maxEmailLen {
+		return "", ErrInvalidEmail
+	}
+	return addr.Address, nil
+}
@@ -0,0 +1,107 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+var errSessionInsert = errors.New("session insert failed")
+
+// failingSessionRepository 写入会话后返回错误，模拟事务中途失败
+type failingSessionRepository struct {
+	repository.SessionRepository
+	cid string
+}
+
+func (r *failingSessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	r.cid = session.CID
+	if err := r.SessionRepository.Create(ctx, session); err != nil {
+		return err
+	}
+	return errSessionInsert
+}
+
+func TestInitUserRollsBackWhenSessionInsertFails(t *testing.T) {
+	type fixture struct {
+		users    repository.UserRepository
+		sessions repository.SessionRepository
+		tx       repository.TxManager
+	}
+	for name, newFixture := range map[string]func(t *testing.T) fixture{
+		"memory": func(t *testing.T) fixture {
+			users := infrarepo.NewMemoryUserRepository()
+			sessions := infrarepo.NewMemorySessionRepository()
+			return fixture{users: users, sessions: sessions, tx: infrarepo.NewMemoryTxManager(users, sessions)}
+		},
+		"sqlite": func(t *testing.T) fixture {
+			repos := newSQLiteRepository(t)
+			return fixture{users: repos.users, sessions: repos.sessions, tx: infrarepo.NewSQLiteTxManager(repos.base)}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFixture(t)
+			sessions := &failingSessionRepository{SessionRepository: f.sessions}
+			uc := usecase.NewUserUseCase(f.users, sessions, nil)
+			uc.SetTxManager(f.tx)
+
+			if _, err := uc.InitUser(ctx, "", ""); !errors.Is(err, errSessionInsert) {
+				t.Fatalf("InitUser error = %v, want %v", err, errSessionInsert)
+			}
+			if sessions.cid == "" {
+				t.Fatal("session insert was not attempted")
+			}
+
+			if _, err := f.users.GetByID(ctx, sessions.cid); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("GetByID error = %v, want ErrNotFound", err)
+			}
+			remaining, err := f.sessions.ListByCID(ctx, sessions.cid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(remaining) != 0 {
+				t.Errorf("%d sessions remain after rollback, want 0", len(remaining))
+			}
+		})
+	}
+}
+
+func TestInitUserStoresEmail(t *testing.T) {
+	ctx := context.Background()
+	users := infrarepo.NewMemoryUserRepository()
+	uc := usecase.NewUserUseCase(users, infrarepo.NewMemorySessionRepository(), newTestAuthUseCase(t, users))
+
+	if _, err := uc.InitUser(ctx, "", "not-an-email"); !errors.Is(err, usecase.ErrInvalidEmail) {
+		t.Fatalf("InitUser with invalid email error = %v, want ErrInvalidEmail", err)
+	}
+
+	res, err := uc.InitUser(ctx, "", " Ann <ann@example.com> ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := users.GetByID(ctx, res.ClandCID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "ann@example.com" {
+		t.Fatalf("stored email = %q, want ann@example.com", user.Email)
+	}
+
+	// 持有令牌的访客再次初始化时可更新邮箱，空邮箱不覆盖已有的
+	visitor := principalContext(res.ClandCID, entity.RoleCustomer)
+	if _, err := uc.InitUser(visitor, res.ClandCID, "ann@work.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.InitUser(visitor, res.ClandCID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := users.GetByID(ctx, res.ClandCID); user.Email != "ann@work.example.com" {
+		t.Fatalf("email after update = %q, want ann@work.example.com", user.Email)
+	}
+}
@@ -0,0 +1,484 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/domain/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// 未配置时的投递参数
+	DefaultWebhookTimeout        = 10 * time.Second
+	DefaultWebhookMaxAttempts    = 8
+	DefaultWebhookInitialBackoff = 30 * time.Second
+	DefaultWebhookMaxBackoff     = time.Hour
+
+	webhookBatchSize       = 50
+	webhookConcurrency     = 8
+	maxWebhookURLLen       = 2048
+	maxWebhookDescription  = 200
+	maxWebhookResponseRead = 64 << 10
+)
+
+var (
+	// ErrInvalidWebhook 订阅地址或事件类型不合法
+	ErrInvalidWebhook = errors.New("invalid webhook subscription")
+	// ErrWebhookNotFound 订阅不存在
+	ErrWebhookNotFound = errors.New("webhook subscription not found")
+	// ErrWebhookDeliveryNotFound 投递记录不存在
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// EventPublisher 业务事件发布接口；发布失败不影响业务操作本身
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType string, data interface{})
+}
+
+// WebhookEvent 投递给订阅方的事件信封
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// SessionAssignedData session.assigned 事件数据
+type SessionAssignedData struct {
+	SessionID       string `json:"sessionId"`
+	CID             string `json:"cid"`
+	AgentID         string `json:"agentId"`
+	PreviousAgentID string `json:"previousAgentId,omitempty"`
+}
+
+// SessionClosedData session.closed 事件数据
+type SessionClosedData struct {
+	*entity.Session
+	Reason string `json:"reason"`
+}
+
+// WebhookOptions 投递超时与重试策略
+type WebhookOptions struct {
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration // 第 n 次失败后等待 InitialBackoff * 2^(n-1)，不超过 MaxBackoff
+	MaxBackoff     time.Duration
+}
+
+// WebhookUseCase webhook 订阅管理与事件投递。事件发布时按订阅写入投递日志，
+// 由 Run 后台轮询到期记录发送，失败按指数退避重试
+type WebhookUseCase struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	opts   WebhookOptions
+	log    *zap.Logger
+}
+
+// NewWebhookUseCase 创建 webhook 用例，client 为 nil 时使用按 Timeout 配置的默认客户端
+func NewWebhookUseCase(repo repository.WebhookRepository, client *http.Client, opts WebhookOptions, log *zap.Logger) *WebhookUseCase {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultWebhookTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultWebhookMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultWebhookInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultWebhookMaxBackoff
+	}
+	if client == nil {
+		client = &http.Client{Timeout: opts.Timeout}
+	}
+	return &WebhookUseCase{
+		repo:   repo,
+		client: client,
+		opts:   opts,
+		log:    log.Named("webhook"),
+	}
+}
+
+// Publish 为订阅了该事件的每个启用中的订阅写入一条待投递记录
+func (uc *WebhookUseCase) Publish(ctx context.Context, eventType string, data interface{}) {
+	event := WebhookEvent{
+		ID:        utils.GenerateEventID(),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	if err := uc.enqueue(ctx, event); err != nil {
+		uc.log.Error("Failed to publish webhook event", zap.String("event", eventType), zap.Error(err))
+	}
+}
+
+// HandleOutboxEvent 作为发件箱下游写入投递记录，沿用发件箱记录 ID 作为事件 ID，
+// 发件箱重试产生的重复投递可由接收方去重
+func (uc *WebhookUseCase) HandleOutboxEvent(ctx context.Context, event *entity.OutboxEvent) error {
+	if event.EventType == entity.WebhookEventMessageCreated {
+		// 与直接发布时一致，只有普通聊天消息触发 message.created，通知与内部备注不对外发布
+		var msg struct {
+			MsgType uint8 `json:"msgType"`
+		}
+		if err := json.Unmarshal(event.Payload, &msg); err != nil {
+			return err
+		}
+		if msg.MsgType != entity.MsgTypeMessage {
+			return nil
+		}
+	}
+	return uc.enqueue(ctx, WebhookEvent{
+		ID:        event.ID,
+		Type:      event.EventType,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+}
+
+// enqueue 为订阅了该事件的每个启用中的订阅写入一条待投递记录
+func (uc *WebhookUseCase) enqueue(ctx context.Context, event WebhookEvent) error {
+	subs, err := uc.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, sub := range subs {
+		if !sub.Active || !sub.Subscribes(event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+		delivery := &entity.WebhookDelivery{
+			ID:             utils.GenerateWebhookDeliveryID(),
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         entity.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		}
+		if err := uc.repo.CreateDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run 定期投递到期的记录，直到 ctx 取消
+func (uc *WebhookUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			uc.DeliverDue(ctx, now)
+		}
+	}
+}
+
+// DeliverDue 并发投递一批到期记录，全部完成后返回，避免同一记录被下一轮重复投递
+func (uc *WebhookUseCase) DeliverDue(ctx context.Context, now time.Time) {
+	deliveries, err := uc.repo.ListDueDeliveries(ctx, now, webhookBatchSize)
+	if err != nil {
+		uc.log.Error("Failed to list due webhook deliveries", zap.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webhookConcurrency)
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(delivery *entity.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			uc.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+// deliver 发送一次并记录结果
+func (uc *WebhookUseCase) deliver(ctx context.Context, delivery *entity.WebhookDelivery) {
+	log := uc.log.With(zap.String("deliveryId", delivery.ID), zap.String("event", delivery.EventType))
+
+	sub, err := uc.repo.GetSubscription(ctx, delivery.SubscriptionID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound) || (err == nil && !sub.Active):
+		// 订阅已删除或停用，不再重试；重新启用后可手动重放
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.NextAttemptAt = time.Time{}
+		delivery.LastError = "subscription deleted or disabled"
+	case err != nil:
+		log.Error("Failed to load webhook subscription", zap.Error(err))
+		return
+	default:
+		status, sendErr := uc.send(ctx, sub, delivery)
+		delivery.Attempts++
+		delivery.ResponseStatus = status
+		if sendErr == nil {
+			delivery.Status = entity.WebhookDeliverySucceeded
+			delivery.DeliveredAt = time.Now()
+			delivery.NextAttemptAt = time.Time{}
+			delivery.LastError = ""
+			break
+		}
+		delivery.LastError = sendErr.Error()
+		if delivery.Attempts >= uc.opts.MaxAttempts {
+			delivery.Status = entity.WebhookDeliveryFailed
+			delivery.NextAttemptAt = time.Time{}
+			log.Warn("Webhook delivery failed permanently", zap.Int("attempts", delivery.Attempts), zap.Error(sendErr))
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(uc.backoff(delivery.Attempts))
+			log.Info("Webhook delivery failed, will retry", zap.Int("attempts", delivery.Attempts),
+				zap.Time("nextAttemptAt", delivery.NextAttemptAt), zap.Error(sendErr))
+		}
+	}
+
+	if err := uc.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Error("Failed to update webhook delivery", zap.Error(err))
+	}
+}
+
+// send POST 事件到订阅地址，2xx 视为成功
+func (uc *WebhookUseCase) send(ctx context.Context, sub *entity.WebhookSubscription, delivery *entity.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cland-webhook/1.0")
+	req.Header.Set(utils.WebhookEventHeader, delivery.EventType)
+	req.Header.Set(utils.WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(utils.WebhookSignatureHeader, utils.SignWebhook(sub.Secret, time.Now(), delivery.Payload))
+
+	resp, err := uc.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponseRead))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (uc *WebhookUseCase) backoff(attempts int) time.Duration {
+	return backoffDelay(uc.opts.InitialBackoff, uc.opts.MaxBackoff, attempts)
+}
+
+// backoffDelay 指数退避: 第 n 次失败后等待 initial * 2^(n-1)，不超过 max，
+// 附加至多 10% 的随机抖动，避免大量重试同时到期
+func backoffDelay(initial, max time.Duration, attempts int) time.Duration {
+	delay := initial
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
+
+// CreateSubscription 新建订阅并生成签名密钥，仅管理员可用；密钥只在创建时返回
+func (uc *WebhookUseCase) CreateSubscription(ctx context.Context, sub *entity.WebhookSubscription) error {
+	principal, err := requireRole(ctx, entity.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if err := normalizeSubscription(sub); err != nil {
+		return err
+	}
+
+	if sub.Secret, err = utils.GenerateWebhookSecret(); err != nil {
+		return err
+	}
+	sub.ID = utils.GenerateWebhookID()
+	sub.CreatedBy = principal.UserID
+	sub.UpdatedBy = principal.UserID
+	return uc.repo.CreateSubscription(ctx, sub)
+}
+
+// GetSubscription 获取订阅，仅管理员可用
+func (uc *WebhookUseCase) GetSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	sub, err := uc.getSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+func (uc *WebhookUseCase) getSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	sub, err := uc.repo.GetSubscription(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	return sub, err
+}
+
+// UpdateSubscription 修改订阅地址、事件与启用状态，仅管理员可用
+func (uc *WebhookUseCase) UpdateSubscription(ctx context.Context, update *entity.WebhookSubscription) (*entity.WebhookSubscription, error) {
+	principal, err := requireRole(ctx, entity.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := uc.getSubscription(ctx, update.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.URL = update.URL
+	sub.Events = update.Events
+	sub.Description = update.Description
+	sub.Active = update.Active
+	if err := normalizeSubscription(sub); err != nil {
+		return nil, err
+	}
+	sub.UpdatedBy = principal.UserID
+	if err := uc.repo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+// DeleteSubscription 删除订阅，尚未投递的记录随之失败，仅管理员可用
+func (uc *WebhookUseCase) DeleteSubscription(ctx context.Context, id string) error {
+	principal, err := requireRole(ctx, entity.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	err = uc.repo.DeleteSubscription(ctx, id, principal.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+// ListSubscriptions 列出全部订阅，仅管理员可用
+func (uc *WebhookUseCase) ListSubscriptions(ctx context.Context) ([]*entity.WebhookSubscription, error) {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	subs, err := uc.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+	return subs, nil
+}
+
+// ListDeliveries 查询订阅的投递日志，仅管理员可用
+func (uc *WebhookUseCase) ListDeliveries(ctx context.Context, query entity.WebhookDeliveryQuery) ([]*entity.WebhookDelivery, error) {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if _, err := uc.getSubscription(ctx, query.SubscriptionID); err != nil {
+		return nil, err
+	}
+	return uc.repo.ListDeliveries(ctx, query)
+}
+
+// GetDelivery 获取单条投递记录，仅管理员可用
+func (uc *WebhookUseCase) GetDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	if _, err := requireRole(ctx, entity.RoleAdmin); err != nil {
+		return nil, err
+	}
+	delivery, err := uc.repo.GetDelivery(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// ReplayDelivery 以原事件 ID 与内容新建一条待投递记录，原记录保留在日志中；仅管理员可用
+func (uc *WebhookUseCase) ReplayDelivery(ctx context.Context, id string) (*entity.WebhookDelivery, error) {
+	original, err := uc.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := uc.getSubscription(ctx, original.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.Active {
+		return nil, ErrInvalidWebhook
+	}
+
+	replay := &entity.WebhookDelivery{
+		ID:             utils.GenerateWebhookDeliveryID(),
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         entity.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now(),
+	}
+	if err := uc.repo.CreateDelivery(ctx, replay); err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// normalizeSubscription 校验地址并去重事件类型
+func normalizeSubscription(sub *entity.WebhookSubscription) error {
+	sub.URL = strings.TrimSpace(sub.URL)
+	sub.Description = strings.TrimSpace(sub.Description)
+	if len(sub.URL) > maxWebhookURLLen || len(sub.Description) > maxWebhookDescription {
+		return ErrInvalidWebhook
+	}
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhook
+	}
+
+	seen := make(map[string]bool, len(sub.Events))
+	events := sub.Events[:0:0]
+	for _, event := range sub.Events {
+		event = strings.TrimSpace(event)
+		if !entity.IsWebhookEventType(event) {
+			return ErrInvalidWebhook
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return ErrInvalidWebhook
+	}
+	sub.Events = events
+	return nil
+}
@@ -0,0 +1,143 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	infrarepo "cland.org/cland-chat-service/core/infrastructure/repository"
+	"cland.org/cland-chat-service/core/usecase"
+	"go.uber.org/zap"
+)
+
+// webhookReceiver 校验签名并记录收到的投递，前 failures 次返回 500
+type webhookReceiver struct {
+	mu         sync.Mutex
+	secret     string
+	failures   int
+	attempts   int
+	deliveries []string
+	events     []usecase.WebhookEvent
+	rejected   int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	if err := utils.VerifyWebhookSignature(r.secret, req.Header.Get(utils.WebhookSignatureHeader), body, time.Minute); err != nil {
+		r.rejected++
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	r.attempts++
+	r.deliveries = append(r.deliveries, req.Header.Get(utils.WebhookDeliveryHeader))
+	if r.attempts <= r.failures {
+		http.Error(w, "simulated failure", http.StatusInternalServerError)
+		return
+	}
+	var event usecase.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.events = append(r.events, event)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestWebhookDeliverySignedAndRetried(t *testing.T) {
+	ctx := context.Background()
+	admin := usecase.WithPrincipal(ctx, entity.Principal{UserID: "admin", Role: entity.RoleAdmin})
+	uc := usecase.NewWebhookUseCase(infrarepo.NewMemoryWebhookRepository(), nil, usecase.WebhookOptions{
+		InitialBackoff: time.Minute,
+		MaxBackoff:     time.Minute,
+	}, zap.NewNop())
+
+	receiver := &webhookReceiver{failures: 1}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub := &entity.WebhookSubscription{URL: server.URL, Events: []string{entity.WebhookEventSessionCreated}, Active: true}
+	if err := uc.CreateSubscription(admin, sub); err != nil {
+		t.Fatal(err)
+	}
+	receiver.secret = sub.Secret
+	uc.Publish(ctx, entity.WebhookEventSessionCreated, map[string]string{"sessionId": "s1"})
+	uc.Publish(ctx, entity.WebhookEventSessionClosed, map[string]string{"sessionId": "s1"})
+
+	// 第一次投递失败后按退避等待，未到期时不重试
+	now := time.Now()
+	uc.DeliverDue(ctx, now)
+	uc.DeliverDue(ctx, now)
+	deliveries, err := uc.ListDeliveries(admin, entity.WebhookDeliveryQuery{SubscriptionID: sub.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries, want 1 for the subscribed event", len(deliveries))
+	}
+	first := deliveries[0]
+	if first.Status != entity.WebhookDeliveryPending || first.Attempts != 1 || first.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("after failure: status=%s attempts=%d response=%d", first.Status, first.Attempts, first.ResponseStatus)
+	}
+	if !first.NextAttemptAt.After(now) {
+		t.Errorf("next attempt %v is not after the failed attempt", first.NextAttemptAt)
+	}
+
+	uc.DeliverDue(ctx, now.Add(2*time.Minute))
+	delivered, err := uc.GetDelivery(admin, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered.Status != entity.WebhookDeliverySucceeded || delivered.Attempts != 2 {
+		t.Fatalf("after retry: status=%s attempts=%d", delivered.Status, delivered.Attempts)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if receiver.rejected != 0 {
+		t.Errorf("receiver rejected %d signatures", receiver.rejected)
+	}
+	if len(receiver.deliveries) != 2 || receiver.deliveries[0] != first.ID || receiver.deliveries[1] != first.ID {
+		t.Errorf("delivery ids %v, want the same id on retry", receiver.deliveries)
+	}
+	if len(receiver.events) != 1 || receiver.events[0].Type != entity.WebhookEventSessionCreated || receiver.events[0].ID != first.EventID {
+		t.Errorf("received events %+v", receiver.events)
+	}
+}
+
+func TestWebhookDeliveryFailsWhenSignatureRejected(t *testing.T) {
+	ctx := context.Background()
+	admin := usecase.WithPrincipal(ctx, entity.Principal{UserID: "admin", Role: entity.RoleAdmin})
+	uc := usecase.NewWebhookUseCase(infrarepo.NewMemoryWebhookRepository(), nil, usecase.WebhookOptions{MaxAttempts: 1}, zap.NewNop())
+
+	// 接收方持有的密钥与订阅不一致，签名校验失败
+	receiver := &webhookReceiver{secret: "whsec_stale"}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	sub := &entity.WebhookSubscription{URL: server.URL, Events: []string{entity.WebhookEventSessionCreated}, Active: true}
+	if err := uc.CreateSubscription(admin, sub); err != nil {
+		t.Fatal(err)
+	}
+	uc.Publish(ctx, entity.WebhookEventSessionCreated, map[string]string{"sessionId": "s1"})
+	uc.DeliverDue(ctx, time.Now())
+
+	deliveries, err := uc.ListDeliveries(admin, entity.WebhookDeliveryQuery{SubscriptionID: sub.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != entity.WebhookDeliveryFailed || deliveries[0].ResponseStatus != http.StatusUnauthorized {
+		t.Fatalf("deliveries %+v, want one failed with 401", deliveries)
+	}
+	if receiver.rejected != 1 {
+		t.Errorf("receiver rejected %d requests, want 1", receiver.rejected)
+	}
+}
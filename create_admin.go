@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"cland.org/cland-chat-service/core/domain/entity"
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+// envAdminPassword 未通过 -password 指定时读取的环境变量，避免密码留在 shell 历史中
+const envAdminPassword = "CLAND_ADMIN_PASSWORD"
+
+// runCreateAdmin 创建首个管理员账号:
+//
+//	cland-chat-service create-admin -username admin [-password ...] [-display-name ...] [-email ...]
+func runCreateAdmin(ctx context.Context, accountUC *usecase.AccountUseCase, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := fs.String("username", "", "admin login name")
+	password := fs.String("password", "", "admin password (default $"+envAdminPassword+")")
+	displayName := fs.String("display-name", "", "display name")
+	email := fs.String("email", "", "email address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *password == "" {
+		*password = os.Getenv(envAdminPassword)
+	}
+	if *username == "" || *password == "" {
+		fs.Usage()
+		return errors.New("username and password are required")
+	}
+
+	admin := &entity.User{
+		Username:    *username,
+		DisplayName: *displayName,
+		Email:       *email,
+		Role:        entity.RoleAdmin,
+	}
+	if err := accountUC.Create(usecase.WithSystemPrincipal(ctx), admin, *password); err != nil {
+		return err
+	}
+	fmt.Printf("admin account %q created with id %s\n", admin.Username, admin.ID)
+	return nil
+}
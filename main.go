@@ -3,21 +3,33 @@ package main
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // transcript time zones must resolve on hosts without a zoneinfo database
 
+	"cland.org/cland-chat-service/common/tracing"
+	"cland.org/cland-chat-service/common/utils"
+	"cland.org/cland-chat-service/core/domain/entity"
+	corerepo "cland.org/cland-chat-service/core/domain/repository"
+	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/connection"
+	wshandler "cland.org/cland-chat-service/core/infrastructure/delivery/websocket/handler"
 	"cland.org/cland-chat-service/core/infrastructure/delivery/websocket/sockio"
 
 	"cland.org/cland-chat-service/core/usecase"
 	"go.uber.org/zap"
 
+	"cland.org/cland-chat-service/core/infrastructure/channel"
 	"cland.org/cland-chat-service/core/infrastructure/config"
 	cland_http "cland.org/cland-chat-service/core/infrastructure/delivery/http"
 	"cland.org/cland-chat-service/core/infrastructure/logger"
+	"cland.org/cland-chat-service/core/infrastructure/mail"
+	"cland.org/cland-chat-service/core/infrastructure/metrics"
 	"cland.org/cland-chat-service/core/infrastructure/repository"
 )
 
@@ -37,26 +49,326 @@ func main() {
 		zapLogger.Fatal("Invalid server port configuration")
 	}
 
+	// Spans from HTTP, socket, use case and repository layers go to the configured exporter
+	closeTracing, err := setupTracing(cfg.Tracing)
+	if err != nil {
+		zapLogger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer closeTracing()
+
 	// Initialize repositories
-	_, messageRepo, sessionRepo, userRepo, err := repository.NewSQLiteRepository("E:/data/cland_chat.db")
+	baseRepo, messageRepo, sessionRepo, userRepo, err := repository.NewSQLiteRepository("E:/data/cland_chat.db")
 	if err != nil {
 		zapLogger.Fatal("Failed to initialize SQLite repository", zap.Error(err))
 	}
+	reactionRepo := repository.NewSQLiteReactionRepository(baseRepo)
+	ratingRepo := repository.NewSQLiteRatingRepository(baseRepo)
+	cannedRepo := repository.NewSQLiteCannedResponseRepository(baseRepo)
+	auditRepo := repository.NewSQLiteAuditRepository(baseRepo)
+	tokenRepo := repository.NewSQLiteTokenRepository(baseRepo)
+	identityRepo := repository.NewSQLiteIdentityRepository(baseRepo)
+	webhookRepo := repository.NewSQLiteWebhookRepository(baseRepo)
+	outboxRepo := repository.NewSQLiteOutboxRepository(baseRepo)
+	txManager := repository.NewSQLiteTxManager(baseRepo)
+	retentionRepo := repository.NewSQLiteRetentionRepository(baseRepo)
+
+	// Initialize token signing
+	jwtOptions, err := cfg.Auth.JWTOptions()
+	if err != nil {
+		zapLogger.Fatal("Failed to load JWT keys", zap.Error(err))
+	}
+	if cfg.Server.Mode == "debug" {
+		useEphemeralSecret(&jwtOptions, zapLogger)
+	}
+	jwtManager, err := utils.NewJWTManager(jwtOptions)
+	if err != nil {
+		zapLogger.Fatal("Invalid auth configuration", zap.Error(err))
+	}
+	authUseCase := usecase.NewAuthUseCase(jwtManager, tokenRepo, userRepo, cfg.Auth.RefreshTokenTTL, zapLogger)
+
+	accountUseCase := usecase.NewAccountUseCase(userRepo, authUseCase)
+
+	// Verify identity tokens issued by the main site, disabled without keys
+	var identityVerifier *utils.JWTManager
+	if cfg.Auth.Identity.Enabled() {
+		identityOptions, err := cfg.Auth.Identity.JWTOptions()
+		if err != nil {
+			zapLogger.Fatal("Failed to load identity keys", zap.Error(err))
+		}
+		if identityVerifier, err = utils.NewJWTManager(identityOptions); err != nil {
+			zapLogger.Fatal("Invalid identity configuration", zap.Error(err))
+		}
+	} else {
+		zapLogger.Info("Identity linking disabled, no auth.identity keys configured")
+	}
+	identityUseCase := usecase.NewIdentityUseCase(identityVerifier, cfg.Auth.Identity.MaxAge, userRepo, identityRepo, authUseCase, zapLogger)
+	if len(os.Args) > 1 && os.Args[1] == "create-admin" {
+		if err := runCreateAdmin(context.Background(), accountUseCase, os.Args[2:]); err != nil {
+			zapLogger.Fatal("Failed to create admin account", zap.Error(err))
+		}
+		return
+	}
 
 	// Initialize use cases
 	chatUseCase := usecase.NewChatUseCase(
-		messageRepo, // messageRepo
-		sessionRepo, // sessionRepo
-		userRepo,    // userRepo
+		messageRepo,  // messageRepo
+		sessionRepo,  // sessionRepo
+		userRepo,     // userRepo
+		reactionRepo, // reactionRepo
 	)
+	ratingUseCase := usecase.NewRatingUseCase(ratingRepo, sessionRepo)
+	cannedUseCase := usecase.NewCannedResponseUseCase(cannedRepo, sessionRepo, userRepo)
+	chatUseCase.SetCannedResponseExpander(cannedUseCase)
+
+	// Lifecycle events are delivered to webhook subscribers in the background
+	webhookUseCase := usecase.NewWebhookUseCase(webhookRepo, nil, usecase.WebhookOptions{
+		Timeout:        cfg.Webhook.Timeout,
+		MaxAttempts:    cfg.Webhook.MaxAttempts,
+		InitialBackoff: cfg.Webhook.InitialBackoff,
+		MaxBackoff:     cfg.Webhook.MaxBackoff,
+	}, zapLogger)
+	ratingUseCase.SetEventPublisher(webhookUseCase)
+
+	// Connection manager is shared by HTTP and WebSocket delivery
+	connManager := connection.NewManager(zapLogger)
+	chatUseCase.SetEventEmitter(connManager)
+	supervisorUseCase := usecase.NewSupervisorUseCase(chatUseCase, auditRepo, connManager, zapLogger)
+
+	// Message and session changes are written with their events and dispatched from the outbox
+	outboxDispatcher := usecase.NewOutboxDispatcher(outboxRepo, usecase.OutboxOptions{
+		MaxAttempts:    cfg.Outbox.MaxAttempts,
+		InitialBackoff: cfg.Outbox.InitialBackoff,
+		MaxBackoff:     cfg.Outbox.MaxBackoff,
+	}, zapLogger)
+	outboxDispatcher.AddSink("socket", &wshandler.MessagePusher{ChatUseCase: chatUseCase, ConnectionManager: connManager})
+
+	// Message, socket and outbox metrics are exported on GET /metrics with HTTP and repository latency
+	chatUseCase.SetMetrics(metrics.Recorder{})
+	outboxDispatcher.SetMetrics(metrics.Recorder{})
+	metrics.Default.NewGaugeFunc("cland_ws_connections", "Open WebSocket connections.", func() float64 {
+		return float64(connManager.ConnectionCount())
+	})
+	metrics.Default.NewGaugeFunc("cland_outbox_pending_events", "Outbox events waiting to be dispatched or retried.", func() float64 {
+		n, err := outboxRepo.CountPending(context.Background())
+		if err != nil {
+			return math.NaN()
+		}
+		return float64(n)
+	})
+	outboxDispatcher.AddSink("webhook", webhookUseCase)
+
+	// Session transcripts, emailed to the customer on close when enabled
+	transcriptTimezone, err := time.LoadLocation(cfg.Transcript.Timezone)
+	if err != nil {
+		zapLogger.Fatal("Invalid transcript timezone", zap.String("timezone", cfg.Transcript.Timezone), zap.Error(err))
+	}
+	var mailSender usecase.MailSender
+	if cfg.Mail.Host != "" {
+		mailSender = mail.NewSMTPSender(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From)
+	} else if cfg.Transcript.EmailOnClose {
+		zapLogger.Warn("transcript.email_on_close is set but no mail.host is configured, transcripts will not be emailed")
+	}
+	transcriptUseCase := usecase.NewTranscriptUseCase(chatUseCase, userRepo, mailSender, usecase.TranscriptOptions{
+		Timezone:     transcriptTimezone,
+		EmailOnClose: cfg.Transcript.EmailOnClose,
+		From:         cfg.Mail.From,
+		Bcc:          cfg.Transcript.Bcc,
+	}, zapLogger)
+	outboxDispatcher.AddSink("transcript", transcriptUseCase)
+
+	// External channels turn provider callbacks into customer messages and send agent replies back
+	channelUseCase := usecase.NewChannelUseCase(chatUseCase, userRepo, messageRepo, repository.NewSQLiteChannelIdentityRepository(baseRepo), zapLogger)
+	channelUseCase.SetTxManager(txManager)
+	channelUseCase.SetThreadRepository(repository.NewSQLiteChannelThreadRepository(baseRepo))
+	attachmentStore := repository.NewLocalArchiveStore(cfg.Attachment.Dir)
+	attachmentUseCase := usecase.NewAttachmentUseCase(attachmentStore)
+	var maildirPollers []*channel.MaildirPoller
+	var maildirIntervals []time.Duration
+	for name, channelCfg := range cfg.Channels {
+		switch channelCfg.Type {
+		case "webhook":
+			if channelCfg.Secret == "" || channelCfg.OutboundURL == "" {
+				err = fmt.Errorf("outbound_url and secret are required")
+				break
+			}
+			err = channelUseCase.Register(channel.NewWebhookChannel(channel.WebhookOptions{
+				Name:        name,
+				OutboundURL: channelCfg.OutboundURL,
+				Secret:      channelCfg.Secret,
+				Timeout:     channelCfg.Timeout,
+			}, nil))
+		case "email":
+			if mailSender == nil || channelCfg.Address == "" {
+				err = fmt.Errorf("address and mail.host are required")
+				break
+			}
+			if channelCfg.Secret == "" && channelCfg.Maildir == "" {
+				err = fmt.Errorf("secret or maildir is required")
+				break
+			}
+			emailChannel := channel.NewEmailChannel(channel.EmailOptions{
+				Name:    name,
+				Address: channelCfg.Address,
+				Secret:  channelCfg.Secret,
+			}, mailSender, attachmentUseCase)
+			err = channelUseCase.Register(emailChannel)
+			if err == nil && channelCfg.Maildir != "" {
+				maildirPollers = append(maildirPollers, channel.NewMaildirPoller(channelCfg.Maildir, emailChannel, channelUseCase, zapLogger))
+				maildirIntervals = append(maildirIntervals, channelCfg.PollInterval)
+			}
+		default:
+			err = fmt.Errorf("unknown channel type %q", channelCfg.Type)
+		}
+		if err != nil {
+			zapLogger.Fatal("Invalid channel configuration", zap.String("channel", name), zap.Error(err))
+		}
+	}
+	outboxDispatcher.AddSink("channel", channelUseCase)
+
+	// Customers who stay offline get a digest of unread agent replies by email
+	var offlineNotifyUseCase *usecase.OfflineNotifyUseCase
+	if cfg.Offline.Enabled && mailSender != nil {
+		offlineNotifyUseCase = usecase.NewOfflineNotifyUseCase(
+			repository.NewSQLiteOfflineNotificationRepository(baseRepo),
+			userRepo,
+			mail.NewOfflineNotifier(mailSender, cfg.Mail.From),
+			connManager,
+			usecase.OfflineNotifyOptions{
+				Delay:       cfg.Offline.Delay,
+				MinInterval: cfg.Offline.MinInterval,
+				ChatURL:     cfg.Offline.ChatURL,
+				Timezone:    transcriptTimezone,
+			}, zapLogger)
+	} else if cfg.Offline.Enabled {
+		zapLogger.Warn("offline_notify.enabled is set but no mail.host is configured, offline customers will not be notified")
+	}
+	chatUseCase.SetOutbox(outboxRepo, outboxDispatcher)
+	// Session closes and barge-ins commit their system notices in the same transaction
+	chatUseCase.SetTxManager(txManager)
+
+	// Guest users and their first session are created in one transaction
+	userUseCase := usecase.NewUserUseCase(userRepo, sessionRepo, authUseCase)
+	userUseCase.SetTxManager(txManager)
+	userUseCase.SetOutbox(outboxRepo, outboxDispatcher)
+
+	// Closed sessions are archived and purged according to the per-channel retention policies
+	retentionPolicies := make(map[string]entity.RetentionPolicy, len(cfg.Retention.Policies))
+	for channel, policy := range cfg.Retention.Policies {
+		retentionPolicies[channel] = entity.RetentionPolicy{
+			After:   policy.After,
+			Action:  policy.Action,
+			Archive: policy.Archive,
+		}
+	}
+	var archiveStore corerepo.ArchiveStore
+	if cfg.Retention.ArchiveDir != "" {
+		archiveStore = repository.NewLocalArchiveStore(cfg.Retention.ArchiveDir)
+	}
+	retentionUseCase := usecase.NewRetentionUseCase(retentionRepo, messageRepo, archiveStore, usecase.RetentionOptions{
+		Policies:     retentionPolicies,
+		SessionBatch: cfg.Retention.SessionBatch,
+		DeleteBatch:  cfg.Retention.DeleteBatch,
+		BatchPause:   cfg.Retention.BatchPause,
+		RestoreTTL:   cfg.Retention.RestoreTTL,
+	}, zapLogger)
+	privacyRepo := repository.NewSQLitePrivacyRepository(baseRepo)
+	privacyUseCase := usecase.NewPrivacyUseCase(userRepo, sessionRepo, messageRepo, reactionRepo, ratingRepo, privacyRepo, auditRepo, zapLogger)
+	privacyUseCase.SetTxManager(txManager)
+	privacyUseCase.SetAttachmentStore(attachmentStore)
+	if archiveStore != nil {
+		privacyUseCase.SetArchive(retentionRepo, archiveStore)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "privacy" {
+		if err := runPrivacy(context.Background(), privacyUseCase, os.Args[2:]); err != nil {
+			zapLogger.Fatal("Failed to process data subject request", zap.Error(err))
+		}
+		return
+	}
+
+	// Create main context for the application
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start session inactivity scheduler
+	policies := make(map[string]usecase.SessionTimeoutPolicy, len(cfg.Session.Timeouts))
+	for channel, timeout := range cfg.Session.Timeouts {
+		policies[channel] = usecase.SessionTimeoutPolicy{
+			WarnAfter:  timeout.WarnAfter,
+			CloseAfter: timeout.CloseAfter,
+		}
+	}
+	sessionScheduler := usecase.NewSessionScheduler(chatUseCase, policies, cfg.Session.CheckInterval, zapLogger)
+	go sessionScheduler.Run(ctx)
+	go authUseCase.Run(ctx, time.Hour)
+	webhookInterval := cfg.Webhook.PollInterval
+	if webhookInterval <= 0 {
+		webhookInterval = 5 * time.Second
+	}
+	go webhookUseCase.Run(ctx, webhookInterval)
+	outboxInterval := cfg.Outbox.PollInterval
+	if outboxInterval <= 0 {
+		outboxInterval = 2 * time.Second
+	}
+	go outboxDispatcher.Run(ctx, outboxInterval)
+	if retentionUseCase.Enabled() {
+		retentionInterval := cfg.Retention.Interval
+		if retentionInterval <= 0 {
+			retentionInterval = time.Hour
+		}
+		go retentionUseCase.Run(ctx, retentionInterval)
+	}
+	if offlineNotifyUseCase != nil {
+		offlineInterval := cfg.Offline.Interval
+		if offlineInterval <= 0 {
+			offlineInterval = time.Minute
+		}
+		go offlineNotifyUseCase.Run(ctx, offlineInterval)
+	}
+	for i, poller := range maildirPollers {
+		pollInterval := maildirIntervals[i]
+		if pollInterval <= 0 {
+			pollInterval = 30 * time.Second
+		}
+		go poller.Run(ctx, pollInterval)
+	}
+
+	healthUseCase := usecase.NewHealthUseCase(cfg.Health.CheckTimeout)
+
+	// Socket.IO is mounted on the main HTTP server unless ws.port asks for a standalone listener
+	wsServer := sockio.NewWsServer(zapLogger, chatUseCase, authUseCase, connManager)
+	var socketIOHandler http.Handler
+	if cfg.WS.Port == 0 {
+		socketIOHandler = wsServer.Handler()
+	}
 
 	// Initialize HTTP router
-	httpRouter := cland_http.GetRouter(chatUseCase)
-	httpRouter.Use(logger.GinRecovery(zapLogger, true))
-	httpRouter.Use(logger.GinLogger(zapLogger))
+	httpRouter := cland_http.GetRouter(cland_http.UseCases{
+		Chat:       chatUseCase,
+		User:       userUseCase,
+		Rating:     ratingUseCase,
+		Canned:     cannedUseCase,
+		Supervisor: supervisorUseCase,
+		Account:    accountUseCase,
+		Auth:       authUseCase,
+		Identity:   identityUseCase,
+		Webhook:    webhookUseCase,
+		Retention:  retentionUseCase,
+		Privacy:    privacyUseCase,
+		Transcript: transcriptUseCase,
+		Channel:    channelUseCase,
+		Attachment: attachmentUseCase,
+		Health:     healthUseCase,
+		SocketIO:   socketIOHandler,
 
-	// Initialize WebSocket server
-	go sockio.InitWsServer(zapLogger, chatUseCase)
+		IdempotencyWindow: cfg.Server.IdempotencyWindow,
+	}, logger.GinRecovery(zapLogger, true), logger.GinLogger(zapLogger))
+
+	// Component checks behind GET /livez and GET /readyz
+	healthUseCase.AddLivenessCheck("websocket", wsServer.CheckServing)
+	healthUseCase.AddReadinessCheck("websocket", wsServer.CheckListening)
+	healthUseCase.AddReadinessCheck("database", baseRepo.Ping)
+	healthUseCase.AddReadinessCheck("schema", baseRepo.CheckSchema)
+	healthUseCase.AddReadinessCheck("outbox", outboxDispatcher.CheckHealth)
 
 	// Create HTTP server
 	httpServer := &http.Server{
@@ -67,7 +379,7 @@ func main() {
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	// Start HTTP server (which now includes WebSocket)
+	// Start HTTP server (which includes Socket.IO unless it listens on ws.port)
 	go func() {
 		defer wg.Done()
 		zapLogger.Info("Starting HTTP server",
@@ -76,15 +388,21 @@ func main() {
 			zapLogger.Fatal("Failed to start HTTP server", zap.Error(err))
 		}
 	}()
+	if cfg.WS.Port > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A failed listener is reported by GET /livez rather than stopping the service
+			if err := wsServer.ListenAndServe(fmt.Sprintf(":%d", cfg.WS.Port)); err != nil && err != http.ErrServerClosed {
+				zapLogger.Error("Socket.IO server stopped", zap.Error(err))
+			}
+		}()
+	}
 
 	// Graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
-	// Create main context for the application
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	select {
 	case sig := <-sigChan:
 		zapLogger.Info("Received shutdown signal", zap.String("signal", sig.String()))
@@ -93,11 +411,29 @@ func main() {
 	}
 
 	zapLogger.Info("Shutting down server gracefully...")
+	// Report not ready first so load balancers stop sending traffic before connections are closed
+	healthUseCase.SetShuttingDown()
+	if cfg.Health.ShutdownDelay > 0 {
+		zapLogger.Info("Waiting for load balancers to drain", zap.Duration("delay", cfg.Health.ShutdownDelay))
+		time.Sleep(cfg.Health.ShutdownDelay)
+	}
+	cancel()
 
 	// First try graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
+	// Close Socket.IO connections first: the HTTP server does not track upgraded connections
+	wsTimeout := cfg.WS.ShutdownTimeout
+	if wsTimeout <= 0 {
+		wsTimeout = 10 * time.Second
+	}
+	wsCtx, wsCancel := context.WithTimeout(shutdownCtx, wsTimeout)
+	if err := wsServer.Shutdown(wsCtx); err != nil {
+		zapLogger.Warn("Socket.IO connections did not close in time", zap.Error(err))
+	}
+	wsCancel()
+
 	var shutdownErr error
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		zapLogger.Error("Failed to shutdown server gracefully", zap.Error(err))
@@ -111,3 +447,49 @@ func main() {
 	}
 	zapLogger.Info("Server stopped gracefully")
 }
+
+// setupTracing 按配置设置追踪导出器与采样比例，返回的函数在退出时关闭导出文件
+func setupTracing(cfg config.TracingConfig) (func(), error) {
+	tracing.SetSampleRatio(cfg.SampleRatio)
+	switch cfg.Exporter {
+	case "", "none":
+		return func() {}, nil
+	case "stdout":
+		tracing.SetExporter(tracing.NewWriterExporter(os.Stdout))
+		return func() {}, nil
+	case "file":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("tracing.file is required for the file exporter")
+		}
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(cfg.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		tracing.SetExporter(tracing.NewWriterExporter(f))
+		return func() {
+			tracing.SetExporter(nil)
+			_ = f.Close()
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+}
+
+// useEphemeralSecret 调试模式下未配置 HS256 签名密钥时生成临时密钥，重启后已签发的令牌全部失效
+func useEphemeralSecret(opts *utils.JWTOptions, log *zap.Logger) {
+	for i := range opts.Keys {
+		key := &opts.Keys[i]
+		if key.ID != opts.SigningKeyID || key.Algorithm != utils.JWTAlgHS256 || len(key.Secret) > 0 {
+			continue
+		}
+		secret, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			log.Fatal("Failed to generate JWT secret", zap.Error(err))
+		}
+		key.Secret = []byte(secret)
+		log.Warn("CLAND_AUTH_SECRET is not set, using an ephemeral JWT secret", zap.String("kid", key.ID))
+	}
+}
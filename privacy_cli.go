@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"cland.org/cland-chat-service/core/usecase"
+)
+
+// runPrivacy 处理客户的数据访问与擦除请求:
+//
+//	cland-chat-service privacy export -cid <cid> [-out file]
+//	cland-chat-service privacy erase -cid <cid> -yes
+func runPrivacy(ctx context.Context, privacyUC *usecase.PrivacyUseCase, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: privacy export|erase -cid <cid>")
+	}
+	ctx = usecase.WithSystemPrincipal(ctx)
+
+	switch args[0] {
+	case "export":
+		fs := flag.NewFlagSet("privacy export", flag.ContinueOnError)
+		cid := fs.String("cid", "", "customer ID")
+		out := fs.String("out", "", "output file (default stdout)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *cid == "" {
+			fs.Usage()
+			return errors.New("cid is required")
+		}
+
+		export, err := privacyUC.ExportCustomer(ctx, *cid)
+		if err != nil {
+			return err
+		}
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(export)
+
+	case "erase":
+		fs := flag.NewFlagSet("privacy erase", flag.ContinueOnError)
+		cid := fs.String("cid", "", "customer ID")
+		yes := fs.Bool("yes", false, "confirm the erasure, which cannot be undone")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *cid == "" || !*yes {
+			fs.Usage()
+			return errors.New("cid and -yes are required")
+		}
+
+		result, err := privacyUC.EraseCustomer(ctx, *cid)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("customer %s erased: %d users, %d sessions, %d messages, %d archives\n",
+			result.CID, result.Users, result.Sessions, result.Messages, result.Archives)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown privacy command %q", args[0])
+	}
+}
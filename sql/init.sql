@@ -2,7 +2,17 @@
 CREATE TABLE t_user (
     cid VARCHAR(50) NOT NULL,
     uid VARCHAR(50) NOT NULL,
+    username VARCHAR(100),
+    display_name VARCHAR(100),
+    email VARCHAR(255),
+    external_id VARCHAR(100),
+    merged_into VARCHAR(50),
     query TEXT,
+    role VARCHAR(20) NOT NULL DEFAULT 'customer',
+    status VARCHAR(20),
+    skills TEXT,
+    max_concurrent_chats INTEGER NOT NULL DEFAULT 0,
+    password_hash VARCHAR(100),
     is_deleted INTEGER DEFAULT 0,
     created_by VARCHAR(50) NOT NULL,
     updated_by VARCHAR(50) NOT NULL,
@@ -12,11 +22,18 @@ CREATE TABLE t_user (
     UNIQUE (uid)
 );
 CREATE INDEX idx_t_user_created_at ON t_user(created_at);
+CREATE INDEX idx_t_user_role ON t_user(role);
+-- 员工账号的登录名唯一；访客用户名自动生成，不参与唯一约束
+CREATE UNIQUE INDEX idx_t_user_username ON t_user(username) WHERE role <> 'customer' AND is_deleted = 0;
+-- 一个主站客户只对应一条客户记录，合并后的访客记录标记 merged_into 并软删除
+CREATE UNIQUE INDEX idx_t_user_external_id ON t_user(external_id) WHERE external_id IS NOT NULL AND is_deleted = 0;
 
 -- Table: t_session
 CREATE TABLE t_session (
     session_id VARCHAR(50) NOT NULL,
     cid VARCHAR(50) NOT NULL,
+    agent_id VARCHAR(50),
+    channel VARCHAR(20) NOT NULL DEFAULT 'web',
     start_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     end_time DATETIME,
     status INTEGER NOT NULL DEFAULT 1,
@@ -30,6 +47,9 @@ CREATE TABLE t_session (
 );
 CREATE INDEX idx_t_session_cid ON t_session(cid);
 CREATE INDEX idx_t_session_start_time ON t_session(start_time);
+CREATE INDEX idx_t_session_agent_id ON t_session(agent_id);
+CREATE INDEX idx_t_session_status ON t_session(status);
+CREATE INDEX idx_t_session_end_time ON t_session(end_time);
 
 -- Table: t_chat_message
 CREATE TABLE t_chat_message (
@@ -43,6 +63,7 @@ CREATE TABLE t_chat_message (
     ts DATETIME NOT NULL,
     status INTEGER NOT NULL DEFAULT 1,
     ext TEXT,
+    reply_to VARCHAR(50),
     is_deleted INTEGER DEFAULT 0,
     created_by VARCHAR(50) NOT NULL,
     updated_by VARCHAR(50) NOT NULL,
@@ -53,4 +74,212 @@ CREATE TABLE t_chat_message (
 );
 CREATE INDEX idx_t_chat_message_session_id ON t_chat_message(session_id);
 CREATE INDEX idx_t_chat_message_ts ON t_chat_message(ts);
-CREATE INDEX idx_t_chat_message_status ON t_chat_message(status);
\ No newline at end of file
+CREATE INDEX idx_t_chat_message_status ON t_chat_message(status);
+CREATE INDEX idx_t_chat_message_reply_to ON t_chat_message(reply_to);
+
+-- Table: message_reactions
+CREATE TABLE message_reactions (
+    msg_id VARCHAR(50) NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    session_id VARCHAR(50) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (msg_id, user_id, emoji),
+    FOREIGN KEY (msg_id) REFERENCES t_chat_message(msg_id) ON DELETE CASCADE
+);
+CREATE INDEX idx_message_reactions_session_id ON message_reactions(session_id);
+
+-- Table: session_ratings
+CREATE TABLE session_ratings (
+    session_id VARCHAR(50) NOT NULL,
+    cid VARCHAR(50) NOT NULL,
+    agent_id VARCHAR(50),
+    score INTEGER NOT NULL CHECK (score BETWEEN 1 AND 5),
+    comment TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id),
+    FOREIGN KEY (session_id) REFERENCES t_session(session_id) ON DELETE CASCADE
+);
+CREATE INDEX idx_session_ratings_agent_id ON session_ratings(agent_id);
+CREATE INDEX idx_session_ratings_created_at ON session_ratings(created_at);
+
+-- Table: t_canned_response
+CREATE TABLE t_canned_response (
+    id VARCHAR(50) NOT NULL,
+    shortcut VARCHAR(50) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    scope VARCHAR(20) NOT NULL DEFAULT 'team',
+    team VARCHAR(50),
+    owner_id VARCHAR(50),
+    is_deleted INTEGER DEFAULT 0,
+    created_by VARCHAR(50) NOT NULL,
+    updated_by VARCHAR(50) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_t_canned_response_shortcut ON t_canned_response(shortcut);
+CREATE INDEX idx_t_canned_response_owner_id ON t_canned_response(owner_id);
+
+-- Table: t_audit_log
+CREATE TABLE t_audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id VARCHAR(50) NOT NULL,
+    actor_role VARCHAR(20) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    session_id VARCHAR(50),
+    target_id VARCHAR(50),
+    detail TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_t_audit_log_session_id ON t_audit_log(session_id);
+CREATE INDEX idx_t_audit_log_actor_id ON t_audit_log(actor_id);
+
+-- Table: t_refresh_token
+CREATE TABLE t_refresh_token (
+    token_hash VARCHAR(64) NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    family_id VARCHAR(50) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    replaced_by VARCHAR(64),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (token_hash)
+);
+CREATE INDEX idx_t_refresh_token_user_id ON t_refresh_token(user_id);
+CREATE INDEX idx_t_refresh_token_family_id ON t_refresh_token(family_id);
+CREATE INDEX idx_t_refresh_token_expires_at ON t_refresh_token(expires_at);
+
+-- Table: t_revoked_token
+CREATE TABLE t_revoked_token (
+    jti VARCHAR(64) NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (jti)
+);
+CREATE INDEX idx_t_revoked_token_expires_at ON t_revoked_token(expires_at);
+
+-- Table: t_webhook_subscription
+CREATE TABLE t_webhook_subscription (
+    id VARCHAR(50) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    events TEXT NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    description VARCHAR(200),
+    active INTEGER NOT NULL DEFAULT 1,
+    is_deleted INTEGER DEFAULT 0,
+    created_by VARCHAR(50) NOT NULL,
+    updated_by VARCHAR(50) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+-- Table: t_webhook_delivery
+CREATE TABLE t_webhook_delivery (
+    id VARCHAR(50) NOT NULL,
+    subscription_id VARCHAR(50) NOT NULL,
+    event_id VARCHAR(50) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME,
+    last_error TEXT,
+    response_status INTEGER,
+    delivered_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_t_webhook_delivery_due ON t_webhook_delivery(status, next_attempt_at);
+CREATE INDEX idx_t_webhook_delivery_subscription_id ON t_webhook_delivery(subscription_id, created_at);
+
+-- Table: t_outbox
+-- 事务性发件箱，seq 保证按写入顺序分发
+CREATE TABLE t_outbox (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    id VARCHAR(50) NOT NULL UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME,
+    last_error TEXT,
+    completed_sinks TEXT, -- 已成功处理的下游，逗号分隔，重试时跳过
+    published_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_t_outbox_due ON t_outbox(status, next_attempt_at);
+
+-- Table: t_session_retention
+-- 会话的保留处理进度：先归档再清理，任务中断后从未完成的步骤继续
+CREATE TABLE t_session_retention (
+    session_id VARCHAR(50) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    archive_key VARCHAR(255),
+    message_count INTEGER NOT NULL DEFAULT 0,
+    archived_at DATETIME,
+    purged_at DATETIME,
+    restored_at DATETIME,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id)
+);
+
+-- Table: t_retention_run
+CREATE TABLE t_retention_run (
+    run_id VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    sessions_scanned INTEGER NOT NULL DEFAULT 0,
+    sessions_archived INTEGER NOT NULL DEFAULT 0,
+    sessions_purged INTEGER NOT NULL DEFAULT 0,
+    sessions_failed INTEGER NOT NULL DEFAULT 0,
+    messages_deleted INTEGER NOT NULL DEFAULT 0,
+    messages_anonymized INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    PRIMARY KEY (run_id)
+);
+CREATE INDEX idx_t_retention_run_started_at ON t_retention_run(started_at);
+
+-- Table: t_offline_notification
+-- 离线客户的邮件提醒记录，限制发送频率并避免重复提醒同一批消息
+CREATE TABLE t_offline_notification (
+    cid VARCHAR(50) NOT NULL,
+    last_sent_at DATETIME NOT NULL,
+    last_msg_ts INTEGER NOT NULL, -- 已提醒过的最新消息时间戳(毫秒)
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (cid)
+);
+
+-- Table: t_channel_identity
+-- 外部渠道用户与客户的对应关系，同一渠道用户的后续消息归到同一客户
+CREATE TABLE t_channel_identity (
+    channel VARCHAR(20) NOT NULL,
+    external_id VARCHAR(255) NOT NULL, -- 服务商侧的用户标识
+    cid VARCHAR(50) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel, external_id)
+);
+CREATE INDEX idx_t_channel_identity_cid ON t_channel_identity(cid);
+
+-- Table: t_channel_thread
+-- 渠道线索标识(如邮件 Message-ID)与会话的对应关系，客户回复据此归入原会话
+CREATE TABLE t_channel_thread (
+    channel VARCHAR(20) NOT NULL,
+    thread_id VARCHAR(255) NOT NULL,
+    session_id VARCHAR(50) NOT NULL,
+    subject VARCHAR(255),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel, thread_id)
+);
+CREATE INDEX idx_t_channel_thread_session_id ON t_channel_thread(session_id);
+
+-- 表结构版本，与 repository.SchemaVersion 一致；变更表结构时同时递增两处，就绪检查据此发现未迁移的数据库
+PRAGMA user_version = 1;